@@ -0,0 +1,61 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// adaptiveTimeout is the time after which requests are aborted,
+// recomputed from the observed latencies while the test is running.
+// It's shared by all clients, hence atomics.
+type adaptiveTimeout struct {
+	// current timeout in nanoseconds, zero until there is an estimate
+	ns int64
+	// factor is what p99 latency is multiplied by
+	factor float64
+}
+
+func newAdaptiveTimeout(factor float64) *adaptiveTimeout {
+	return &adaptiveTimeout{factor: factor}
+}
+
+func (a *adaptiveTimeout) set(d time.Duration) {
+	atomic.StoreInt64(&a.ns, int64(d))
+}
+
+// limit returns the lesser of the current timeout and fixed, ignoring
+// the ones that are zero. It's safe to call on nil.
+func (a *adaptiveTimeout) limit(fixed time.Duration) time.Duration {
+	if a == nil {
+		return fixed
+	}
+	current := time.Duration(atomic.LoadInt64(&a.ns))
+	if current > 0 && (fixed == 0 || current < fixed) {
+		return current
+	}
+	return fixed
+}
+
+// update sets the timeout to p99 of latencies (in microseconds)
+// times the factor, once there are enough of them.
+func (a *adaptiveTimeout) update(latencies internal.ReadonlyUint64Histogram) {
+	count := uint64(0)
+	latencies.VisitAll(func(_, c uint64) bool {
+		count += c
+		return true
+	})
+	if count < adaptiveTimeoutMinSamples {
+		return
+	}
+	stats := internal.Results{Latencies: latencies}.
+		LatenciesStats([]float64{0.99})
+	p99 := time.Duration(stats.Percentiles[0.99]) * time.Microsecond
+	timeout := time.Duration(float64(p99) * a.factor)
+	if timeout < adaptiveTimeoutFloor {
+		// don't abort requests delayed just by scheduling hiccups
+		timeout = adaptiveTimeoutFloor
+	}
+	a.set(timeout)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+func TestAdaptiveTimeoutLimit(t *testing.T) {
+	var none *adaptiveTimeout
+	if l := none.limit(time.Second); l != time.Second {
+		t.Errorf("Expected fixed timeout without adaptive one, got %v", l)
+	}
+	a := newAdaptiveTimeout(3)
+	expectations := []struct {
+		current, fixed, expected time.Duration
+	}{
+		{0, 0, 0},
+		{0, time.Second, time.Second},
+		{time.Millisecond, 0, time.Millisecond},
+		{time.Millisecond, time.Second, time.Millisecond},
+		{time.Minute, time.Second, time.Second},
+	}
+	for _, e := range expectations {
+		a.set(e.current)
+		if l := a.limit(e.fixed); l != e.expected {
+			t.Errorf("Expected %v for %v and %v, but got %v",
+				e.expected, e.current, e.fixed, l)
+		}
+	}
+}
+
+func TestAdaptiveTimeoutUpdate(t *testing.T) {
+	a := newAdaptiveTimeout(3)
+	h := uhist.Default()
+	for i := uint64(0); i < adaptiveTimeoutMinSamples-1; i++ {
+		h.Increment(10000)
+	}
+	a.update(h)
+	if l := a.limit(0); l != 0 {
+		t.Errorf("Expected no timeout with too few samples, got %v", l)
+	}
+	h.Increment(10000)
+	a.update(h)
+	if l := a.limit(0); l != 30*time.Millisecond {
+		t.Errorf("Expected timeout of 30ms, but got %v", l)
+	}
+	small := uhist.Default()
+	for i := uint64(0); i < adaptiveTimeoutMinSamples; i++ {
+		small.Increment(10)
+	}
+	a.update(small)
+	if l := a.limit(0); l != adaptiveTimeoutFloor {
+		t.Errorf("Expected timeout of %v, but got %v",
+			adaptiveTimeoutFloor, l)
+	}
+}
+
+func TestBombardierAdaptiveTimeout(t *testing.T) {
+	testAllClients(t, testBombardierAdaptiveTimeout)
+}
+
+func testBombardierAdaptiveTimeout(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(2)
+	b, e := newBombardier(config{
+		numConns:        defaultNumberOfConns,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		adaptiveTimeout: 3,
+		method:          "GET",
+		format:          knownFormat("plain-text"),
+		clientType:      clientType,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	// as if computed from latencies before
+	b.adaptiveTimeout.set(10 * time.Millisecond)
+	b.disableOutput()
+	b.bombard()
+	if b.aborted != numReqs {
+		t.Errorf("Expected %v aborted requests, but got %v (errors: %v)",
+			numReqs, b.aborted, b.errors.byFrequency())
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// alpnList is a list of protocols to offer during ALPN negotiation,
+// in order of preference, specified as comma-separated list on the
+// command line.
+type alpnList []string
+
+func (a *alpnList) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *alpnList) Set(value string) error {
+	res := alpnList{}
+	for _, proto := range strings.Split(value, ",") {
+		proto = strings.TrimSpace(proto)
+		if !validALPNProtocol(proto) {
+			return &invalidALPNProtocolError{proto}
+		}
+		res = append(res, proto)
+	}
+	*a = res
+	return nil
+}
+
+// validALPNProtocol reports whether proto can be sent as ALPN
+// protocol name, which is 1 to 255 bytes long (RFC 7301). Only
+// printable ASCII is allowed, since that's what all registered
+// names are.
+func validALPNProtocol(proto string) bool {
+	if len(proto) == 0 || len(proto) > 255 {
+		return false
+	}
+	for i := 0; i < len(proto); i++ {
+		if proto[i] <= ' ' || proto[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type invalidALPNProtocolError struct {
+	proto string
+}
+
+func (i *invalidALPNProtocolError) Error() string {
+	return fmt.Sprintf("%q is not a valid ALPN protocol", i.proto)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestALPNListSet(t *testing.T) {
+	expectations := []struct {
+		in       string
+		expected alpnList
+		err      error
+	}{
+		{"h2", alpnList{"h2"}, nil},
+		{"h2, http/1.1", alpnList{"h2", "http/1.1"}, nil},
+		{"h2,", nil, &invalidALPNProtocolError{""}},
+		{"h 2", nil, &invalidALPNProtocolError{"h 2"}},
+		{"h2\x00", nil, &invalidALPNProtocolError{"h2\x00"}},
+		{
+			strings.Repeat("a", 256), nil,
+			&invalidALPNProtocolError{strings.Repeat("a", 256)},
+		},
+	}
+	for _, e := range expectations {
+		var a alpnList
+		err := a.Set(e.in)
+		if e.err != nil {
+			if err == nil || err.Error() != e.err.Error() {
+				t.Errorf("Expected %q for %q, but got %v", e.err, e.in, err)
+			}
+			continue
+		}
+		if err != nil || a.String() != e.expected.String() {
+			t.Errorf("Expected %v for %q, but got %v (%v)",
+				e.expected.String(), e.in, a.String(), err)
+		}
+	}
+}
+
+func TestGenerateTLSConfigALPN(t *testing.T) {
+	alpn := alpnList{"h2", "http/1.1"}
+	tlsConfig, err := generateTLSConfig(config{alpn: &alpn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tlsConfig.NextProtos, ",") != "h2,http/1.1" {
+		t.Errorf("Expected NextProtos %v, but got %v",
+			alpn, tlsConfig.NextProtos)
+	}
+}
+
+func TestBombardierALPN(t *testing.T) {
+	testAllClients(t, testBombardierALPN)
+}
+
+func testBombardierALPN(clientType clientTyp, t *testing.T) {
+	// server prefers h2, which is only offered with --http2 regardless
+	// of --alpn
+	expected := "http/1.1"
+	if clientType == nhttp2 {
+		expected = "h2"
+	}
+	negotiated := uint64(0)
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil && r.TLS.NegotiatedProtocol == expected {
+				atomic.AddUint64(&negotiated, 1)
+			}
+		}),
+	)
+	s.EnableHTTP2 = true
+	s.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	s.StartTLS()
+	defer s.Close()
+	numReqs := uint64(3)
+	alpn := alpnList{"http/1.1"}
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+		insecure:   true,
+		alpn:       &alpn,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if negotiated != numReqs {
+		t.Errorf("Expected %v requests over %v, but got %v (errors: %v)",
+			numReqs, expected, negotiated, b.errors.byFrequency())
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// connRamp implements --connections-auto. It starts with a single
+// active connection and doubles their number (up to -c) every
+// autoConnsStep while throughput grows, then settles at the "knee" -
+// the number of connections after which throughput plateaus or
+// latency degrades.
+type connRamp struct {
+	max uint64
+
+	// number of active workers, accessed atomically and changed
+	// under mu, which also guards changed, best and settled
+	active  uint64
+	mu      sync.Mutex
+	changed chan struct{}
+
+	best    *rampStep
+	settled bool
+
+	// completed requests and their total latency in microseconds,
+	// since the start of the test
+	reqs, usSum uint64
+}
+
+// rampStep is what was measured with some number of connections.
+type rampStep struct {
+	conns  uint64
+	rps    float64
+	meanUs float64
+}
+
+func newConnRamp(max uint64) *connRamp {
+	return &connRamp{
+		max:     max,
+		active:  1,
+		changed: make(chan struct{}),
+	}
+}
+
+func (r *connRamp) record(usTaken uint64) {
+	atomic.AddUint64(&r.reqs, 1)
+	atomic.AddUint64(&r.usSum, usTaken)
+}
+
+// wait blocks worker n until it's one of the active ones. It returns
+// false if the test was done before that.
+func (r *connRamp) wait(n uint64, done <-chan struct{}) bool {
+	for {
+		if n < atomic.LoadUint64(&r.active) {
+			return true
+		}
+		r.mu.Lock()
+		changed := r.changed
+		active := atomic.LoadUint64(&r.active)
+		r.mu.Unlock()
+		if n < active {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return false
+		}
+	}
+}
+
+func (r *connRamp) setActive(conns uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	atomic.StoreUint64(&r.active, conns)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// advance accounts s and returns the number of connections to use
+// from now on and whether the knee was found.
+func (r *connRamp) advance(s rampStep) (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s.rps == 0 {
+		// nothing completed during the step (e.g. the target is
+		// slower than autoConnsStep), so there is nothing to compare
+		// against; keep the connections and measure again
+		return s.conns, false
+	}
+	improved := r.best == nil ||
+		s.rps > r.best.rps*(1+autoConnsMinGain) &&
+			(r.best.meanUs == 0 ||
+				s.meanUs <= r.best.meanUs*autoConnsLatencyFactor)
+	if improved {
+		r.best = &s
+	}
+	if !improved || s.conns >= r.max {
+		r.settled = true
+		return r.best.conns, true
+	}
+	next := s.conns * 2
+	if next > r.max {
+		next = r.max
+	}
+	return next, false
+}
+
+// rampConnections measures each step of the ramp and activates
+// workers accordingly until the knee is found or the test is done.
+func (b *bombardier) rampConnections() {
+	ticker := time.NewTicker(autoConnsStep)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	stepStart := time.Now()
+	var reqs, usSum uint64
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		elapsed := time.Since(stepStart)
+		stepStart = time.Now()
+		curReqs := atomic.LoadUint64(&b.ramp.reqs)
+		curSum := atomic.LoadUint64(&b.ramp.usSum)
+		step := rampStep{conns: atomic.LoadUint64(&b.ramp.active)}
+		if n := curReqs - reqs; n > 0 {
+			step.rps = float64(n) / elapsed.Seconds()
+			step.meanUs = float64(curSum-usSum) / float64(n)
+		}
+		reqs, usSum = curReqs, curSum
+		next, settled := b.ramp.advance(step)
+		b.ramp.setActive(next)
+		if settled {
+			return
+		}
+	}
+}
+
+func (r *connRamp) result() *internal.ConnectionsAutoResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.best == nil {
+		return nil
+	}
+	return &internal.ConnectionsAutoResult{
+		Connections:       r.best.conns,
+		RequestsPerSecond: r.best.rps,
+		MeanLatency:       r.best.meanUs,
+		Settled:           r.settled,
+	}
+}
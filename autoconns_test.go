@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConnRampAdvance(t *testing.T) {
+	type advance struct {
+		step     rampStep
+		next     uint64
+		settled  bool
+		bestConn uint64
+	}
+	expectations := []struct {
+		name  string
+		max   uint64
+		steps []advance
+	}{
+		{
+			"plateau", 100,
+			[]advance{
+				{rampStep{1, 100, 1000}, 2, false, 1},
+				{rampStep{2, 190, 1050}, 4, false, 2},
+				{rampStep{4, 195, 2000}, 2, true, 2},
+			},
+		},
+		{
+			"latency", 100,
+			[]advance{
+				{rampStep{1, 100, 1000}, 2, false, 1},
+				{rampStep{2, 150, 2500}, 1, true, 1},
+			},
+		},
+		{
+			"max", 3,
+			[]advance{
+				{rampStep{1, 100, 1000}, 2, false, 1},
+				{rampStep{2, 200, 1000}, 3, false, 2},
+				{rampStep{3, 300, 1000}, 3, true, 3},
+			},
+		},
+		{
+			"no completions", 100,
+			[]advance{
+				{rampStep{1, 0, 0}, 1, false, 0},
+				{rampStep{1, 1, 1500000}, 2, false, 1},
+				{rampStep{2, 0, 0}, 2, false, 1},
+				{rampStep{2, 2, 1600000}, 4, false, 2},
+			},
+		},
+		{
+			"no latency baseline", 100,
+			[]advance{
+				{rampStep{1, 100, 0}, 2, false, 1},
+				{rampStep{2, 200, 1}, 4, false, 2},
+			},
+		},
+	}
+	for _, e := range expectations {
+		r := newConnRamp(e.max)
+		for i, a := range e.steps {
+			next, settled := r.advance(a.step)
+			bestConn := uint64(0)
+			if r.best != nil {
+				bestConn = r.best.conns
+			}
+			if next != a.next || settled != a.settled ||
+				bestConn != a.bestConn {
+				t.Errorf("%v, step %v: expected %v, %v (best %v), "+
+					"but got %v, %v (best %v)", e.name, i+1,
+					a.next, a.settled, a.bestConn,
+					next, settled, bestConn)
+			}
+		}
+	}
+}
+
+func TestConnRampWait(t *testing.T) {
+	r := newConnRamp(4)
+	done := make(chan struct{})
+	if !r.wait(0, done) {
+		t.Error("First worker should be active right away")
+	}
+	activated := make(chan bool)
+	go func() {
+		activated <- r.wait(1, done)
+	}()
+	select {
+	case <-activated:
+		t.Fatal("Second worker shouldn't be active yet")
+	case <-time.After(10 * time.Millisecond):
+	}
+	r.setActive(2)
+	if !<-activated {
+		t.Error("Second worker should be active")
+	}
+	go func() {
+		activated <- r.wait(2, done)
+	}()
+	close(done)
+	if <-activated {
+		t.Error("Third worker shouldn't be active after the test")
+	}
+}
+
+func TestBombardierConnectionsAuto(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+		}),
+	)
+	defer s.Close()
+	duration := 2*autoConnsStep + autoConnsStep/2
+	b, e := newBombardier(config{
+		numConns:        2,
+		duration:        &duration,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		format:          knownFormat("plain-text"),
+		connectionsAuto: true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.ConnectionsAuto
+	if res == nil || res.Connections != 2 || !res.Settled ||
+		res.RequestsPerSecond == 0 || res.MeanLatency == 0 {
+		t.Errorf("Unexpected result of --connections-auto: %+v", res)
+	}
+}
+
+func TestBombardierConnectionsAutoSlowTarget(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(autoConnsStep + autoConnsStep/4)
+		}),
+	)
+	defer s.Close()
+	duration := 2*autoConnsStep + autoConnsStep/2
+	b, e := newBombardier(config{
+		numConns:        2,
+		duration:        &duration,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		format:          knownFormat("plain-text"),
+		connectionsAuto: true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.ConnectionsAuto
+	if res == nil || res.Settled ||
+		res.RequestsPerSecond == 0 || res.MeanLatency == 0 {
+		t.Errorf("Unexpected result of --connections-auto: %+v", res)
+	}
+}
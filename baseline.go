@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// baseline is the part of the json output format that is used to
+// detect regressions.
+type baseline struct {
+	Result struct {
+		Latency struct {
+			Mean        float64           `json:"mean"`
+			Percentiles map[string]uint64 `json:"percentiles"`
+		} `json:"latency"`
+		RPS struct {
+			Mean float64 `json:"mean"`
+		} `json:"rps"`
+	} `json:"result"`
+}
+
+func loadBaseline(path string) (*baseline, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bl := new(baseline)
+	if err := json.Unmarshal(data, bl); err != nil {
+		return nil, fmt.Errorf("can't parse baseline %q: %v", path, err)
+	}
+	if _, ok := bl.Result.Latency.Percentiles["99"]; !ok {
+		return nil, errBaselineNoP99
+	}
+	return bl, nil
+}
+
+// percentChange returns change from old to new in percents.
+func percentChange(old, new float64) float64 {
+	if old == 0 {
+		if new == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (new - old) / old * 100
+}
+
+// compareWithBaseline prints deltas between the baseline and the
+// current results and reports whether p99 latency regressed by no
+// more than the threshold.
+func (b *bombardier) compareWithBaseline(out io.Writer) bool {
+	threshold := defaultRegressionThreshold
+	if b.conf.regressionThreshold != nil {
+		threshold = *b.conf.regressionThreshold
+	}
+	result := b.gatherInfo().Result
+	lats := result.LatenciesStats([]float64{0.99})
+	if lats == nil {
+		fmt.Fprintln(out, "FAILED: not enough data to compare with baseline")
+		return false
+	}
+	bl := b.baseline.Result
+	blP99 := float64(bl.Latency.Percentiles["99"])
+	p99 := float64(lats.Percentiles[0.99])
+	fmt.Fprintln(out, "Baseline comparison:")
+	if rps := result.RequestsStats(nil); rps != nil {
+		fmt.Fprintf(out, "  %-12v %10.2f %10.2f %+9.2f%%\n", "Reqs/sec",
+			bl.RPS.Mean, rps.Mean, percentChange(bl.RPS.Mean, rps.Mean))
+	}
+	fmt.Fprintf(out, "  %-12v %10v %10v %+9.2f%%\n", "Latency",
+		b.conf.formatLatency(bl.Latency.Mean),
+		b.conf.formatLatency(lats.Mean),
+		percentChange(bl.Latency.Mean, lats.Mean))
+	change := percentChange(blP99, p99)
+	fmt.Fprintf(out, "  %-12v %10v %10v %+9.2f%%\n", "Latency p99",
+		b.conf.formatLatency(blP99), b.conf.formatLatency(p99), change)
+	if change > threshold {
+		fmt.Fprintf(out,
+			"FAILED: p99 latency regressed by %.2f%% (threshold %.2f%%)\n",
+			change, threshold)
+		return false
+	}
+	fmt.Fprintf(out,
+		"PASSED: p99 latency changed by %+.2f%% (threshold %.2f%%)\n",
+		change, threshold)
+	return true
+}
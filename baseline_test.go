@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func writeBaseline(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "bombardier-baseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoadBaseline(t *testing.T) {
+	noP99 := writeBaseline(t,
+		`{"result":{"latency":{"mean":100,"percentiles":{"50":90}}}}`)
+	defer os.Remove(noP99)
+	if _, err := loadBaseline(noP99); err != errBaselineNoP99 {
+		t.Errorf("Expected %v, but got %v", errBaselineNoP99, err)
+	}
+	// recorded with --summary-percentiles 50,99.9
+	otherPercentiles := writeBaseline(t,
+		`{"result":{"latency":{"mean":100,`+
+			`"percentiles":{"50":90,"99.9":300}}}}`)
+	defer os.Remove(otherPercentiles)
+	_, err := loadBaseline(otherPercentiles)
+	if err != errBaselineNoP99 {
+		t.Errorf("Expected %v, but got %v", errBaselineNoP99, err)
+	} else if !strings.Contains(err.Error(), "--summary-percentiles") {
+		t.Errorf("Expected %q to mention --summary-percentiles", err)
+	}
+	invalid := writeBaseline(t, `{"result":`)
+	defer os.Remove(invalid)
+	if _, err := loadBaseline(invalid); err == nil {
+		t.Error("Should fail on invalid json")
+	}
+	valid := writeBaseline(t,
+		`{"result":{"latency":{"mean":100,"percentiles":{"99":250}},`+
+			`"rps":{"mean":1000}}}`)
+	defer os.Remove(valid)
+	bl, err := loadBaseline(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p99 := bl.Result.Latency.Percentiles["99"]; p99 != 250 {
+		t.Errorf("Expected p99 to be 250, but got %v", p99)
+	}
+	if mean := bl.Result.RPS.Mean; mean != 1000 {
+		t.Errorf("Expected mean rps to be 1000, but got %v", mean)
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	expectations := []struct {
+		old, new, change float64
+	}{
+		{100, 110, 10},
+		{100, 50, -50},
+		{0, 0, 0},
+	}
+	for _, e := range expectations {
+		if c := percentChange(e.old, e.new); c != e.change {
+			t.Errorf("Expected %v -> %v to be %v%%, but got %v%%",
+				e.old, e.new, e.change, c)
+		}
+	}
+}
+
+func TestBombardierComparesWithBaseline(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	// p99 of 1us is going to be beaten by any real request and p99 of
+	// an hour by none
+	slow := writeBaseline(t,
+		`{"result":{"latency":{"mean":1,"percentiles":{"99":3600000000}}}}`)
+	defer os.Remove(slow)
+	fast := writeBaseline(t,
+		`{"result":{"latency":{"mean":1,"percentiles":{"99":1}}}}`)
+	defer os.Remove(fast)
+	threshold := 50.0
+	expectations := []struct {
+		path   string
+		passed bool
+		output string
+	}{
+		{slow, true, "PASSED"},
+		{fast, false, "FAILED"},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(20)
+		b, err := newBombardier(config{
+			numConns:            defaultNumberOfConns,
+			numReqs:             &numReqs,
+			url:                 s.URL,
+			headers:             new(headersList),
+			timeout:             defaultTimeout,
+			method:              "GET",
+			format:              knownFormat("plain-text"),
+			compareBaseline:     e.path,
+			regressionThreshold: &threshold,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if passed := b.gatesExitCode() == 0; passed != e.passed {
+			t.Errorf("Expected gates to pass: %v, but got %v\n%s",
+				e.passed, passed, out)
+		}
+		if !strings.Contains(out.String(), e.output) {
+			t.Errorf("Expected %q in output:\n%s", e.output, out)
+		}
+		if !strings.Contains(out.String(), "threshold 50.00%") {
+			t.Errorf("Expected threshold in output:\n%s", out)
+		}
+	}
+}
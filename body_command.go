@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// bodyCommand provides bodies of requests with --body-command: output
+// of the command, which is run over and over by bodyCommandRunners
+// goroutines into a pool of up to bodyCommandPoolSize bodies. Requests
+// take bodies from the pool and wait if it's empty, so runs are
+// decoupled from requests, but throughput is still capped by how fast
+// the command produces bodies.
+type bodyCommand struct {
+	name string
+	args []string
+	pool chan bodyCommandOutput
+	done <-chan struct{}
+
+	// accessed atomically
+	generated, failed, waits uint64
+}
+
+type bodyCommandOutput struct {
+	body []byte
+	err  error
+}
+
+type bodyCommandError struct {
+	err    error
+	stderr string
+}
+
+func (e *bodyCommandError) Error() string {
+	msg := fmt.Sprintf("Body command failed: %v", e.err)
+	if e.stderr != "" {
+		msg += " (" + e.stderr + ")"
+	}
+	return msg
+}
+
+// newBodyCommand runs the command once, so that it fails early if it
+// can't be run, and starts filling the pool until done is closed.
+// The command line is split into arguments on whitespace, no quoting
+// or shell expansions are supported.
+func newBodyCommand(
+	command string, done <-chan struct{},
+) (*bodyCommand, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errEmptyBodyCommand
+	}
+	c := &bodyCommand{
+		name: fields[0],
+		args: fields[1:],
+		pool: make(chan bodyCommandOutput, bodyCommandPoolSize),
+		done: done,
+	}
+	first := c.run()
+	if first.err != nil {
+		return nil, first.err
+	}
+	c.pool <- first
+	for i := 0; i < bodyCommandRunners; i++ {
+		go c.fill()
+	}
+	return c, nil
+}
+
+func (c *bodyCommand) run() bodyCommandOutput {
+	cmd := exec.Command(c.name, c.args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		atomic.AddUint64(&c.failed, 1)
+		return bodyCommandOutput{err: &bodyCommandError{
+			err, strings.TrimSpace(stderr.String()),
+		}}
+	}
+	atomic.AddUint64(&c.generated, 1)
+	return bodyCommandOutput{body: stdout.Bytes()}
+}
+
+func (c *bodyCommand) fill() {
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		out := c.run()
+		select {
+		case c.pool <- out:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// next returns the body of the next request or the error of the run
+// that should have produced it. It waits for the command if the pool
+// is empty, unless the test is done, errTestDone is returned then.
+func (c *bodyCommand) next() ([]byte, error) {
+	var out bodyCommandOutput
+	select {
+	case out = <-c.pool:
+	default:
+		atomic.AddUint64(&c.waits, 1)
+		select {
+		case out = <-c.pool:
+		case <-c.done:
+			return nil, errTestDone
+		}
+	}
+	return out.body, out.err
+}
+
+func (c *bodyCommand) result() *internal.BodyCommandResult {
+	return &internal.BodyCommandResult{
+		Generated: atomic.LoadUint64(&c.generated),
+		Failed:    atomic.LoadUint64(&c.failed),
+		Waits:     atomic.LoadUint64(&c.waits),
+	}
+}
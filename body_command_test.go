@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+const bodyCommandHelperEnv = "BOMBARDIER_BODY_COMMAND_HELPER"
+
+// TestBodyCommandHelper isn't a real test, it's run as --body-command
+// by tests below and prints the body from bodyCommandHelperEnv.
+func TestBodyCommandHelper(t *testing.T) {
+	body := os.Getenv(bodyCommandHelperEnv)
+	switch body {
+	case "":
+		return
+	case "fail":
+		fmt.Fprintln(os.Stderr, "broken")
+		os.Exit(1)
+	}
+	fmt.Print(body)
+	os.Exit(0)
+}
+
+func bodyCommandHelper(body string) (command string, cleanup func()) {
+	_ = os.Setenv(bodyCommandHelperEnv, body)
+	return os.Args[0] + " -test.run=^TestBodyCommandHelper$", func() {
+		_ = os.Unsetenv(bodyCommandHelperEnv)
+	}
+}
+
+func TestBombardierBodyCommand(t *testing.T) {
+	testAllClients(t, testBombardierBodyCommand)
+}
+
+func testBombardierBodyCommand(clientType clientTyp, t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies = make(map[string]int)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			bodies[string(body)]++
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	command, cleanup := bodyCommandHelper("generated body")
+	defer cleanup()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:    2,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "POST",
+		bodyCommand: command,
+		clientType:  clientType,
+		format:      knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	if len(bodies) != 1 || bodies["generated body"] != int(numReqs) {
+		t.Errorf("Expected generated bodies to be sent, but got %v", bodies)
+	}
+	res := b.gatherInfo().Result.BodyCommand
+	if res == nil || res.Generated < numReqs || res.Failed != 0 {
+		t.Errorf("Expected at least %v bodies to be generated, but got %+v",
+			numReqs, res)
+	}
+}
+
+func TestBodyCommandFailure(t *testing.T) {
+	command, cleanup := bodyCommandHelper("fail")
+	defer cleanup()
+	done := make(chan struct{})
+	defer close(done)
+	_, err := newBodyCommand(command, done)
+	expected := "Body command failed: exit status 1 (broken)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, but got %v", expected, err)
+	}
+	if _, err := newBodyCommand(" ", done); err != errEmptyBodyCommand {
+		t.Errorf("Expected %v, but got %v", errEmptyBodyCommand, err)
+	}
+}
+
+func TestBodyCommandNextWhenDone(t *testing.T) {
+	done := make(chan struct{})
+	c := &bodyCommand{pool: make(chan bodyCommandOutput), done: done}
+	close(done)
+	if _, err := c.next(); err != errTestDone {
+		t.Errorf("Expected %v, but got %v", errTestDone, err)
+	}
+	if c.waits != 1 {
+		t.Errorf("Expected a wait to be counted, but got %v", c.waits)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// bodyDir provides bodies of requests with --body-dir: contents of
+// the regular files in the directory, sorted by name, in turn. Files
+// are buffered at startup while they fit into bodyDirBufferSize,
+// the rest is read from disk for each request.
+type bodyDir struct {
+	files []bodyFile
+	next  uint64
+
+	expectStatus *statusRanges
+}
+
+type bodyFile struct {
+	path string
+	size int64
+	// data is nil if the file is read for each request
+	data []byte
+
+	// requests that used the file and failed, accessed atomically
+	errors uint64
+}
+
+func newBodyDir(dir string, expectStatus *statusRanges) (*bodyDir, error) {
+	// entries are sorted by name
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &bodyDir{expectStatus: expectStatus}
+	buffered := int64(0)
+	for _, e := range entries {
+		if !e.Mode().IsRegular() {
+			continue
+		}
+		f := bodyFile{path: filepath.Join(dir, e.Name()), size: e.Size()}
+		if buffered+f.size <= bodyDirBufferSize {
+			f.data, err = ioutil.ReadFile(f.path)
+			if err != nil {
+				return nil, err
+			}
+			// the file might have changed since it was listed
+			f.size = int64(len(f.data))
+			buffered += f.size
+		}
+		d.files = append(d.files, f)
+	}
+	if len(d.files) == 0 {
+		return nil, errBodyDirEmpty
+	}
+	return d, nil
+}
+
+// pick returns the file to send next.
+func (d *bodyDir) pick() *bodyFile {
+	n := atomic.AddUint64(&d.next, 1) - 1
+	return &d.files[n%uint64(len(d.files))]
+}
+
+// open returns the contents of the file.
+func (f *bodyFile) open() (io.ReadCloser, error) {
+	if f.data != nil {
+		return ioutil.NopCloser(bytes.NewReader(f.data)), nil
+	}
+	return os.Open(f.path)
+}
+
+// done accounts the outcome of the request the file was sent with.
+// Requests fail with errors or, unless --expect-status is set,
+// 4xx and 5xx codes.
+func (d *bodyDir) done(f *bodyFile, code int, err error) {
+	failed := err != nil
+	if !failed && d.expectStatus != nil {
+		failed = !d.expectStatus.contains(code)
+	} else if !failed {
+		failed = code >= 400
+	}
+	if failed {
+		atomic.AddUint64(&f.errors, 1)
+	}
+}
+
+func (d *bodyDir) results() []internal.BodyFileErrors {
+	var res []internal.BodyFileErrors
+	for i := range d.files {
+		f := &d.files[i]
+		if errs := atomic.LoadUint64(&f.errors); errs > 0 {
+			res = append(res, internal.BodyFileErrors{
+				File:   f.path,
+				Errors: errs,
+			})
+		}
+	}
+	return res
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeBodyDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "bombardier-body-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			_ = os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBombardierBodyDir(t *testing.T) {
+	testAllClients(t, func(clientType clientTyp, t *testing.T) {
+		testBombardierBodyDir(clientType, t, true)
+	})
+}
+
+func TestBombardierBodyDirUnbuffered(t *testing.T) {
+	testAllClients(t, func(clientType clientTyp, t *testing.T) {
+		testBombardierBodyDir(clientType, t, false)
+	})
+}
+
+func testBombardierBodyDir(clientType clientTyp, t *testing.T, buffered bool) {
+	dir := writeBodyDir(t, map[string]string{
+		"b.json": `{"b":2}`,
+		"a.json": `{"a":1}`,
+		"c.txt":  "fail",
+		"empty":  "",
+	})
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "skipped"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if r.ContentLength != int64(len(body)) {
+				t.Errorf("Expected Content-Length %v, but got %v",
+					len(body), r.ContentLength)
+			}
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			if string(body) == "fail" {
+				rw.WriteHeader(http.StatusBadRequest)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(8)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		bodyDir:    dir,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !buffered {
+		for i := range b.bodyDir.files {
+			b.bodyDir.files[i].data = nil
+		}
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := strings.Repeat(`{"a":1},{"b":2},fail,,`, 2)
+	if got := strings.Join(bodies, ",") + ","; got != expected {
+		t.Errorf("Expected bodies %q, but got %q", expected, got)
+	}
+	res := b.gatherInfo().Result.BodyFileErrors
+	if len(res) != 1 || res[0].File != filepath.Join(dir, "c.txt") ||
+		res[0].Errors != 2 {
+		t.Errorf("Expected 2 errors for c.txt, but got %+v", res)
+	}
+}
+
+func TestBodyDirExpectStatus(t *testing.T) {
+	dir := writeBodyDir(t, map[string]string{"a": "a"})
+	defer os.RemoveAll(dir)
+	expectStatus := new(statusRanges)
+	if err := expectStatus.Set("404"); err != nil {
+		t.Fatal(err)
+	}
+	d, err := newBodyDir(dir, expectStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := d.pick()
+	d.done(f, http.StatusNotFound, nil)
+	d.done(f, http.StatusOK, nil)
+	if res := d.results(); len(res) != 1 || res[0].Errors != 1 {
+		t.Errorf("Expected only 200 to be counted, but got %+v", res)
+	}
+}
+
+func TestBodyDirEmpty(t *testing.T) {
+	dir := writeBodyDir(t, nil)
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newBodyDir(dir, nil); err != errBodyDirEmpty {
+		t.Errorf("Expected %v, but got %v", errBodyDirEmpty, err)
+	}
+}
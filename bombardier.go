@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -22,6 +23,8 @@ import (
 
 type bombardier struct {
 	bytesRead, bytesWritten int64
+	// Connections established, reported with --connection-count
+	connsOpened uint64
 
 	// HTTP codes
 	req1xx uint64
@@ -31,32 +34,154 @@ type bombardier struct {
 	req5xx uint64
 	others uint64
 
+	// Requests aborted due to --abort-slower-than, also counted
+	// as others
+	aborted uint64
+	// Requests which latency was clamped to --latency-cap
+	latencyCapped uint64
+	// Responses shorter than their Content-Length, with
+	// --strict-content-length
+	lengthMismatches uint64
+	// Requests in flight canceled by interrupt, they aren't counted
+	// anywhere else
+	canceled uint64
+	// Failed requests, split into those that couldn't establish
+	// a connection and the rest
+	connErrors, reqErrors uint64
+
+	// Completes the test after --max-duration, nil if not set
+	maxDurationBarrier completionBarrier
+
 	conf        config
 	barrier     completionBarrier
 	ratelimiter limiter
-	wg          sync.WaitGroup
+	// Limiter of --rate, also part of ratelimiter, nil if not set
+	rateLimiter *bucketlimiter
+	// Current token, if --oauth2-token-url is set, oauth2Failed is
+	// set to 1 if it couldn't be refreshed and the test was stopped
+	oauth2       *oauth2Token
+	oauth2Failed uint32
+	// firstError holds *internal.FirstErrorResult once the test was
+	// stopped by the first failed request with --abort-on-first-error
+	firstError     atomic.Value
+	firstErrorOnce sync.Once
+	// Requests before the first 2xx, if --stats-reset-on-code is set
+	statsReset *statsReset
+	// Canceled on interrupt to stop requests in flight
+	requestsCtx    context.Context
+	cancelRequests context.CancelFunc
+
+	wg sync.WaitGroup
 
 	timeTaken time.Duration
 	latencies *uhist.Histogram
 	requests  *fhist.Histogram
 
+	// Request phases, only filled if printWriteRead is set
+	connectLatencies, writeLatencies, readLatencies *uhist.Histogram
+	// DNS lookups of new connections, only filled if printDNS is set
+	dnsLatencies *uhist.Histogram
+	// Time spent waiting for the rate limiter, if printQueueTime is set
+	queueTimes       *uhist.Histogram
+	sortedQueueTimes *internal.SortedUint64Histogram
+	// Histograms above, sorted once for all statistics computed on
+	// the same data
+	sortedLatencies        *internal.SortedUint64Histogram
+	sortedConnectLatencies *internal.SortedUint64Histogram
+	sortedWriteLatencies   *internal.SortedUint64Histogram
+	sortedReadLatencies    *internal.SortedUint64Histogram
+	sortedDNSLatencies     *internal.SortedUint64Histogram
+	// Latencies by class of status codes, if --latency-by-code is set
+	codeLatencies *codeLatencies
+
 	client   client
 	doneChan chan struct{}
+	// Timeout recomputed by rateMeter, if --adaptive-timeout is set
+	adaptiveTimeout *adaptiveTimeout
+	// Activates workers gradually, if --connections-auto is set
+	ramp *connRamp
+	// Searching for the highest sustained rate, if --find-max-rps is
+	// set
+	rateSearch *rateSearch
+	// Controls the rate, if --target-p99 is set
+	latencyTarget *latencyTarget
+	// Target rate over the test, if --rate-schedule is set
+	rateSchedule rateSchedule
+	// Sizes of response bodies, if --decompress is set
+	compression *compressionStats
+	// Sizes of successful requests, if
+	// --count-only-successful-for-throughput is set
+	successBytes *successBytes
+	// Sizes of response bodies, if --print-goodput is set
+	goodput *goodputStats
+	// Redirects followed with --follow-https-redirect
+	httpsUpgrades *httpsUpgrades
+	// State of the first TLS connection, if --print-tls is set
+	tls *tlsInfo
+	// Outcomes of paced uploads, if --chunk-delay or --chunk-size is set
+	pacedUploads *pacedUploads
+	// Connections held with --slowloris, if set
+	slowloris *slowlorisStats
+	// Files to send bodies from, if --body-dir is set
+	bodyDir *bodyDir
+	// Runs of --body-command, if set
+	bodyCommand *bodyCommand
+	// Statistics of each worker, if --per-conn-stats is set
+	connStats []connStats
+	// Clients for each of --hosts, if specified
+	hosts []*hostStats
+	// Stats of each scheme, if --hosts mixed http:// and https://
+	schemes []*schemeStats
+	// methodMix picks methods of requests with --method-mix
+	methodMix *methodPicker
+	// tracer writes the first requests to --trace-file
+	tracer *tracer
+	// Latencies of all requests, if --output-raw-latencies is set
+	rawLatencies *rawLatencies
+	// Samples of requests per second, if --csv-out is set
+	rpsCSV *rpsCSV
+	// Depth of pipelines, if --print-pipeline-stats is set
+	pipelineStats *pipelineStats
+	// Retries of failed dials, if --dial-retries is set
+	dialer *retryingDialer
 
 	// RPS metrics
 	rpl   sync.Mutex
 	reqs  int64
 	start time.Time
+	// errors (connErrors + reqErrors) at the last recordRps
+	lastErrs uint64
 
 	// Errors
 	errors *errorMap
+	// Distinct errors yet to be printed with --print-errors-only
+	pendingErrorsMu sync.Mutex
+	pendingErrors   []string
 
 	// Progress bar
 	bar *pb.ProgressBar
+	// Latencies shown on the progress bar, if --live-p99 is set
+	liveLatencies *liveLatencies
+	// Last p99 shown with --live-p99
+	liveP99 string
+	// Latencies of the last snapshot interval, if
+	// --report-interval-percentiles is set
+	intervalLatencies *intervalLatencies
+	// Shown instead of the progress bar with --tui
+	dashboard *dashboard
 
 	// Output
 	out      io.Writer
-	template *template.Template
+	errOut   io.Writer
+	reporter reporter
+
+	// Used to format results sent to --notify-url
+	notifyReporter reporter
+
+	// Loaded from --compare-baseline
+	baseline *baseline
+	// Loaded from --scenario
+	scenario *scenario
 }
 
 func newBombardier(c config) (*bombardier, error) {
@@ -67,6 +192,39 @@ func newBombardier(c config) (*bombardier, error) {
 	b.conf = c
 	b.latencies = uhist.Default()
 	b.requests = fhist.Default()
+	b.connectLatencies = uhist.Default()
+	b.writeLatencies = uhist.Default()
+	b.readLatencies = uhist.Default()
+	b.dnsLatencies = uhist.Default()
+	b.sortedLatencies = internal.NewSortedUint64Histogram(b.latencies)
+	b.sortedConnectLatencies = internal.NewSortedUint64Histogram(
+		b.connectLatencies,
+	)
+	b.sortedWriteLatencies = internal.NewSortedUint64Histogram(
+		b.writeLatencies,
+	)
+	b.sortedReadLatencies = internal.NewSortedUint64Histogram(
+		b.readLatencies,
+	)
+	b.sortedDNSLatencies = internal.NewSortedUint64Histogram(
+		b.dnsLatencies,
+	)
+	if c.printQueueTime {
+		b.queueTimes = uhist.Default()
+		b.sortedQueueTimes = internal.NewSortedUint64Histogram(b.queueTimes)
+	}
+	if c.latencyByCode {
+		b.codeLatencies = newCodeLatencies()
+	}
+	if c.liveP99 {
+		b.liveLatencies = new(liveLatencies)
+	}
+	if c.intervalLatency {
+		b.intervalLatencies = newIntervalLatencies()
+	}
+	if c.statsResetOnCode {
+		b.statsReset = new(statsReset)
+	}
 
 	if b.conf.testType() == counted {
 		b.bar = pb.New64(int64(*b.conf.numReqs))
@@ -80,29 +238,93 @@ func newBombardier(c config) (*bombardier, error) {
 
 	if b.conf.testType() == counted {
 		b.barrier = newCountingCompletionBarrier(*b.conf.numReqs)
+		if c.maxDuration > 0 {
+			b.maxDurationBarrier = newTimedCompletionBarrier(c.maxDuration)
+			b.barrier = newCompositeCompletionBarrier(
+				b.barrier, b.maxDurationBarrier,
+			)
+		}
 	} else {
 		b.barrier = newTimedCompletionBarrier(*b.conf.duration)
 	}
+	b.requestsCtx, b.cancelRequests = context.WithCancel(
+		context.Background(),
+	)
 
+	var limiters compositeLimiter
+	burst := c.burstOrZero()
 	if b.conf.rate != nil {
-		b.ratelimiter = newBucketLimiter(*b.conf.rate)
-	} else {
+		b.rateLimiter = newBucketLimiter(*b.conf.rate, burst)
+		limiters = append(limiters, b.rateLimiter)
+	}
+	if c.findMaxRPS {
+		if b.rateLimiter == nil {
+			b.rateLimiter = newBucketLimiter(findMaxRPSStart, burst)
+			limiters = append(limiters, b.rateLimiter)
+		}
+		b.rateSearch = newRateSearch(&c, b.rateLimiter.rate)
+	}
+	if c.targetP99 > 0 {
+		if b.rateLimiter == nil {
+			b.rateLimiter = newBucketLimiter(targetP99Start, burst)
+			limiters = append(limiters, b.rateLimiter)
+		}
+		b.latencyTarget = newLatencyTarget(c.targetP99, b.rateLimiter.rate)
+	}
+	if c.rateSchedule != "" {
+		schedule, err := loadRateSchedule(c.rateSchedule)
+		if err != nil {
+			return nil, err
+		}
+		b.rateSchedule = schedule
+		b.rateLimiter = newBucketLimiter(b.rateSchedule[0].rate, burst)
+		limiters = append(limiters, b.rateLimiter)
+	}
+	if b.conf.rateBytes != nil {
+		limiters = append(limiters, newBytesLimiter(
+			*b.conf.rateBytes, &b.bytesRead, &b.bytesWritten,
+		))
+	}
+	switch len(limiters) {
+	case 0:
 		b.ratelimiter = &nooplimiter{}
+	case 1:
+		b.ratelimiter = limiters[0]
+	default:
+		b.ratelimiter = limiters
 	}
 
 	b.out = os.Stdout
+	b.errOut = os.Stderr
 
 	tlsConfig, err := generateTLSConfig(c)
 	if err != nil {
 		return nil, err
 	}
+	dialer, err := newDialer(c.proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.dialRetries > 0 {
+		b.dialer = &retryingDialer{dialer: dialer, retries: c.dialRetries}
+		dialer = b.dialer
+	}
+	if c.printTLS {
+		b.tls = new(tlsInfo)
+		tlsConfig.VerifyConnection = b.tls.record
+	}
 
 	var (
 		pbody *string
 		bsp   bodyStreamProducer
 	)
 	if c.stream {
-		if c.bodyFilePath != "" {
+		if c.bodyFilePath != "" && c.streamRewind {
+			bsp, err = rewindingBodyProducer(c.bodyFilePath)
+			if err != nil {
+				return nil, err
+			}
+		} else if c.bodyFilePath != "" {
 			bsp = func() (io.ReadCloser, error) {
 				return os.Open(c.bodyFilePath)
 			}
@@ -113,6 +335,11 @@ func newBombardier(c config) (*bombardier, error) {
 				), nil
 			}
 		}
+		if c.chunkDelay > 0 || c.chunkSize != nil {
+			b.pacedUploads = new(pacedUploads)
+			bsp = pacedBodyProducer(bsp, c.chunkSizeOrDefault(),
+				c.chunkDelay, b.pacedUploads)
+		}
 	} else {
 		pbody = &c.body
 		if c.bodyFilePath != "" {
@@ -126,40 +353,245 @@ func newBombardier(c config) (*bombardier, error) {
 		}
 	}
 
+	if c.bodyDir != "" {
+		b.bodyDir, err = newBodyDir(c.bodyDir, c.expectStatus)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.bodyCommand != "" {
+		b.bodyCommand, err = newBodyCommand(c.bodyCommand, b.barrier.done())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var rawRequest []byte
+	if c.rawRequestFile != "" {
+		rawRequest, err = ioutil.ReadFile(c.rawRequestFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers := c.headers
+	if c.grpcWeb != grpcWebNone {
+		framed := c.grpcWeb.frame([]byte(*pbody))
+		pbody = &framed
+		headers = grpcWebHeaders(c.headers, c.grpcWeb)
+	}
+	if c.acceptEncoding != "" {
+		headers, err = mergeHeaders(
+			headers, []string{"Accept-Encoding: " + c.acceptEncoding},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.decompress {
+		b.compression = new(compressionStats)
+	}
+	if c.successfulThroughput {
+		b.successBytes = new(successBytes)
+	}
+	if c.printGoodput {
+		b.goodput = new(goodputStats)
+	}
+	if c.httpsRedirect {
+		b.httpsUpgrades = newHTTPSUpgrades()
+	}
+	if c.oauth2TokenURL != "" {
+		b.oauth2 = newOAuth2Token(&c)
+		if err := b.oauth2.fetch(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.adaptiveTimeout > 0 {
+		b.adaptiveTimeout = newAdaptiveTimeout(c.adaptiveTimeout)
+	}
+	if c.connectionsAuto {
+		b.ramp = newConnRamp(c.numConns)
+	}
+	if c.methodMix != nil {
+		b.methodMix = newMethodPicker(*c.methodMix)
+	}
+	if c.printPipelineStats {
+		b.pipelineStats = newPipelineStats(c.pipeline)
+	}
+	if c.traceFirst > 0 {
+		b.tracer, err = newTracer(c.traceFile, c.traceFirst)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.rawLatenciesFile != "" {
+		b.rawLatencies, err = newRawLatencies(c.rawLatenciesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.rpsCSVFile != "" {
+		b.rpsCSV, err = newRPSCSV(c.rpsCSVFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var randomHeaders *randomHeaders
+	if c.randomHeaders != nil {
+		randomHeaders = newRandomHeaders(
+			*c.randomHeaders, c.randomHeaderBytesOrDefault(),
+		)
+	}
+	var queryTemplates *queryTemplates
+	if c.queryTemplates != nil {
+		queryTemplates, err = newQueryTemplates(c.queryTemplates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var headerRotation *headerRotation
+	if c.headerRotate != nil {
+		headerRotation = newHeaderRotation(*c.headerRotate)
+	}
+	var traceContext *traceContext
+	if c.traceparent {
+		traceContext = newTraceContext(c.traceparentSpanID, c.tracestate)
+	}
+
+	writeTimeout, readTimeout := c.phaseTimeouts()
 	cc := &clientOpts{
 		HTTP2:             false,
 		maxConns:          c.numConns,
 		timeout:           c.timeout,
+		writeTimeout:      writeTimeout,
+		readTimeout:       readTimeout,
+		phaseTimeouts:     c.writeTimeout > 0 || c.readTimeout > 0,
+		idleTimeout:       c.idleTimeout,
 		tlsConfig:         tlsConfig,
 		disableKeepAlives: c.disableKeepAlives,
+		dialer:            dialer,
+
+		headers:            headers,
+		headerCasePreserve: c.headerCasePreserve,
+		noDefaultHeaders:   c.noDefaultHeaders,
+		url:                c.url,
+		rawPath:            c.rawPath,
+		method:             c.method,
+		connectTarget:      c.connectTarget,
+		rawRequest:         rawRequest,
+		body:               pbody,
+		bodProd:            bsp,
+		bytesRead:          &b.bytesRead,
+		bytesWritten:       &b.bytesWritten,
+		connsOpened:        &b.connsOpened,
+		tracer:             b.tracer,
+
+		tracePhases:   c.printWriteRead,
+		traceDNS:      c.printDNS,
+		pipeline:      c.pipeline,
+		pipelineStats: b.pipelineStats,
+		abortAfter:    c.abortSlowerThan,
+
+		adaptiveTimeout: b.adaptiveTimeout,
+		maxResponseSize: c.maxResponseSizeOrZero(),
+		readBufferSize:  bufferSizeOrZero(c.readBufferSize),
+		writeBufferSize: bufferSizeOrZero(c.writeBufferSize),
+		cacheBust:       c.cacheBust,
+		queryTemplates:  queryTemplates,
+		randomHeaders:   randomHeaders,
+		headerRotation:  headerRotation,
+		traceContext:    traceContext,
+		grpcWeb:         c.grpcWeb,
+		compression:     b.compression,
+		successBytes:    b.successBytes,
+		goodput:         b.goodput,
+		httpsUpgrades:   b.httpsUpgrades,
+		ignoreBody:      c.ignoreBody,
+		oauth2:          b.oauth2,
+		bodyDir:         b.bodyDir,
+		bodyCommand:     b.bodyCommand,
+		methodMix:       b.methodMix,
 
-		headers:      c.headers,
-		url:          c.url,
-		method:       c.method,
-		body:         pbody,
-		bodProd:      bsp,
-		bytesRead:    &b.bytesRead,
-		bytesWritten: &b.bytesWritten,
+		responseReadDelay:   c.responseReadDelay,
+		strictContentLength: c.strictContentLength,
+		done:                b.barrier.done(),
+		interrupted:         b.requestsCtx,
+	}
+	if c.slowloris {
+		b.slowloris = new(slowlorisStats)
+		b.client = newSlowlorisClient(
+			cc, c.slowlorisDelayOrDefault(), b.barrier.done(), b.slowloris,
+		)
+	} else if c.hosts != nil {
+		b.hosts, b.schemes = newHostClients(c.clientType, cc, *c.hosts)
+		b.client = b.hosts[0].client
+	} else {
+		b.client = makeHTTPClient(c.clientType, cc)
 	}
-	b.client = makeHTTPClient(c.clientType, cc)
 
-	if !b.conf.printProgress {
+	if c.tui && c.printProgress && isTerminal(os.Stdout) {
+		b.dashboard = newDashboard(b.out)
+	}
+	if !b.conf.printProgress || b.dashboard != nil {
 		b.bar.Output = ioutil.Discard
 		b.bar.NotPrint = true
 	}
 
-	b.template, err = b.prepareTemplate()
+	if c.reportTemplateFile != "" {
+		b.reporter, err = b.prepareReportTemplate(c.reportTemplateFile)
+	} else {
+		b.reporter, err = b.prepareReporter(c.format)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if c.jsonPretty {
+		// results sent with --notify-url stay compact
+		b.reporter = &prettyJSONReporter{b.reporter}
+	}
+	if c.notifyURL != "" {
+		b.notifyReporter, err = b.prepareReporter(knownFormat("json"))
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	b.wg.Add(int(c.numConns))
+	if c.compareBaseline != "" {
+		b.baseline, err = loadBaseline(c.compareBaseline)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.scenario != "" {
+		b.scenario, err = loadScenario(c.scenario, c.url, headers)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.scenario.checkTimeouts(c.timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.perConnStats != "" {
+		b.connStats = make([]connStats, c.numWorkers())
+	}
+
+	b.wg.Add(int(c.numWorkers()))
 	b.errors = newErrorMap()
-	b.doneChan = make(chan struct{}, 2)
+	b.doneChan = make(chan struct{}, 3)
 	return b, nil
 }
 
 func makeHTTPClient(clientType clientTyp, cc *clientOpts) client {
+	if cc.method == "CONNECT" {
+		// neither of HTTP clients is able to benchmark tunneling
+		return newConnectClient(cc)
+	}
+	if cc.rawRequest != nil {
+		return client(newRawClient(cc, cc.rawRequest))
+	}
 	var cl client
 	switch clientType {
 	case nhttp1:
@@ -175,31 +607,36 @@ func makeHTTPClient(clientType clientTyp, cc *clientOpts) client {
 	return cl
 }
 
-func (b *bombardier) prepareTemplate() (*template.Template, error) {
-	var (
-		templateBytes []byte
-		err           error
-	)
-	switch f := b.conf.format.(type) {
-	case knownFormat:
-		templateBytes = f.template()
-	case userDefinedTemplate:
-		templateBytes, err = ioutil.ReadFile(string(f))
-		if err != nil {
-			return nil, err
-		}
-	default:
-		panic("format can't be nil at this point, this is a bug")
-	}
+func (b *bombardier) parseTemplate(
+	templateBytes []byte,
+) (*template.Template, error) {
 	outputTemplate, err := template.New("output-template").
 		Funcs(template.FuncMap{
 			"WithLatencies": func() bool {
 				return b.conf.printLatencies
 			},
+			"WithWriteRead": func() bool {
+				return b.conf.printWriteRead
+			},
+			"WithDNS": func() bool {
+				return b.conf.printDNS
+			},
+			"SummaryPercentiles": func() []float64 {
+				if b.conf.summaryPercentiles != nil {
+					return *b.conf.summaryPercentiles
+				}
+				return defaultSummaryPercentiles
+			},
+			"LatencyPercentiles": func() []float64 {
+				if b.conf.latencyPercentiles != nil {
+					return *b.conf.latencyPercentiles
+				}
+				return defaultLatencyPercentiles
+			},
 			"FormatBinary": formatBinary,
-			"FormatTimeUs": formatTimeUs,
+			"FormatTimeUs": b.conf.formatLatency,
 			"FormatTimeUsUint64": func(us uint64) string {
-				return formatTimeUs(float64(us))
+				return b.conf.formatLatency(float64(us))
 			},
 			"FloatsToArray": func(ps ...float64) []float64 {
 				return ps
@@ -224,9 +661,43 @@ func (b *bombardier) prepareTemplate() (*template.Template, error) {
 }
 
 func (b *bombardier) writeStatistics(
-	code int, usTaken uint64,
+	code int, usTaken uint64, phases phaseTimings,
 ) {
+	if b.rawLatencies != nil {
+		b.rawLatencies.record(code, usTaken)
+	}
+	if usCap := uint64(b.conf.latencyCap / time.Microsecond); usCap > 0 &&
+		usTaken > usCap {
+		usTaken = usCap
+		atomic.AddUint64(&b.latencyCapped, 1)
+	}
 	b.latencies.Increment(usTaken)
+	if b.ramp != nil {
+		b.ramp.record(usTaken)
+	}
+	if b.rateSearch != nil {
+		b.rateSearch.record(usTaken)
+	}
+	if b.latencyTarget != nil {
+		b.latencyTarget.record(usTaken)
+	}
+	if b.codeLatencies != nil {
+		b.codeLatencies.record(code, usTaken)
+	}
+	if b.liveLatencies != nil {
+		b.liveLatencies.record(usTaken)
+	}
+	if b.intervalLatencies != nil {
+		b.intervalLatencies.record(usTaken)
+	}
+	if phases.measured {
+		b.connectLatencies.Increment(phases.usConnect)
+		b.writeLatencies.Increment(phases.usWrite)
+		b.readLatencies.Increment(phases.usRead)
+	}
+	if phases.dnsMeasured {
+		b.dnsLatencies.Increment(phases.usDNS)
+	}
 	b.rpl.Lock()
 	b.reqs++
 	b.rpl.Unlock()
@@ -248,40 +719,201 @@ func (b *bombardier) writeStatistics(
 	atomic.AddUint64(counter, 1)
 }
 
-func (b *bombardier) performSingleRequest() {
-	code, usTaken, err := b.client.do()
+func (b *bombardier) performSingleRequest(
+	cl client, host *hostStats, conn *connStats,
+) {
+	code, usTaken, phases, err := cl.do()
+	if err == errTestDone {
+		// the request was cut short by the end of the test
+		return
+	}
+	if err == errRequestCanceled {
+		atomic.AddUint64(&b.canceled, 1)
+		return
+	}
+	if b.statsReset != nil && !b.statsReset.measured(code, err, b.resetStats) {
+		return
+	}
+	err = b.recordError(code, err)
+	b.writeStatistics(code, usTaken, phases)
+	conn.record(usTaken, err)
+	if host != nil {
+		host.record(code, usTaken)
+	}
+}
+
+// recordError accounts the outcome of a request with the given code
+// and error, which also fails if --expect-status doesn't match the
+// code. The resulting error, if any, is returned.
+func (b *bombardier) recordError(code int, err error) error {
+	return b.recordStatusError(code, err, b.conf.expectStatus)
+}
+
+// recordStatusError is recordError with the set of expected status
+// codes given, instead of --expect-status, i.e. by a scenario step.
+func (b *bombardier) recordStatusError(
+	code int, err error, expectStatus *statusRanges,
+) error {
+	if err == nil && expectStatus != nil && !expectStatus.contains(code) {
+		err = &unexpectedStatusError{code}
+	}
 	if err != nil {
-		b.errors.add(err)
+		if b.rateSearch != nil {
+			b.rateSearch.recordError()
+		}
+		if isConnectionError(err) {
+			atomic.AddUint64(&b.connErrors, 1)
+		} else {
+			atomic.AddUint64(&b.reqErrors, 1)
+		}
+	}
+	if err == errContentLengthMismatch {
+		atomic.AddUint64(&b.lengthMismatches, 1)
+	}
+	if err == errAborted {
+		atomic.AddUint64(&b.aborted, 1)
+	} else if err != nil {
+		if b.errors.add(err) && b.conf.printErrorsOnly {
+			b.queueError(err)
+		}
 	}
-	b.writeStatistics(code, usTaken)
+	if err != nil && b.conf.abortOnFirstError {
+		b.abortOnError(err)
+	}
+	return err
+}
+
+// abortOnError stops the test because of the first failed request
+// with --abort-on-first-error, requests failing afterwards are
+// accounted as usual.
+func (b *bombardier) abortOnError(err error) {
+	b.firstErrorOnce.Do(func() {
+		// the number is approximate, requests complete concurrently
+		n := b.completedRequests() + 1
+		b.firstError.Store(&internal.FirstErrorResult{
+			Request: n,
+			Error:   err.Error(),
+		})
+		fmt.Fprintf(b.errOut, "Request %v failed: %v, stopping the test\n",
+			n, err)
+		b.barrier.cancel()
+	})
 }
 
-func (b *bombardier) worker() {
+// firstErrorResult returns the error that stopped the test with
+// --abort-on-first-error, nil if there was none.
+func (b *bombardier) firstErrorResult() *internal.FirstErrorResult {
+	fe, _ := b.firstError.Load().(*internal.FirstErrorResult)
+	return fe
+}
+
+// completedRequests returns the number of requests completed so far,
+// successfully or not.
+func (b *bombardier) completedRequests() uint64 {
+	return atomic.LoadUint64(&b.req1xx) + atomic.LoadUint64(&b.req2xx) +
+		atomic.LoadUint64(&b.req3xx) + atomic.LoadUint64(&b.req4xx) +
+		atomic.LoadUint64(&b.req5xx) + atomic.LoadUint64(&b.others)
+}
+
+// worker sends requests until the test is done, n is the number of
+// the worker used to pick one of --hosts.
+func (b *bombardier) worker(n uint64) {
+	cl := b.client
+	var host *hostStats
+	if b.hosts != nil {
+		host = b.hosts[n%uint64(len(b.hosts))]
+		cl = host.client
+	}
+	var conn *connStats
+	if b.connStats != nil {
+		conn = &b.connStats[n]
+	}
 	done := b.barrier.done()
-	for b.barrier.tryGrabWork() {
+	var it scenarioIteration
+	for b.active(n, done) && b.barrier.tryGrabWork() {
+		var queued time.Time
+		if b.queueTimes != nil {
+			queued = time.Now()
+		}
 		if b.ratelimiter.pace(done) == brk {
 			break
 		}
-		b.performSingleRequest()
+		if b.queueTimes != nil {
+			b.queueTimes.Increment(durationUs(time.Since(queued)))
+		}
+		if b.scenario != nil {
+			b.performScenarioStep(&it, conn)
+		} else {
+			b.performSingleRequest(cl, host, conn)
+		}
 		b.barrier.jobDone()
+		if b.conf.clientDelay > 0 &&
+			!sleepOrDone(b.conf.clientDelay, done) {
+			break
+		}
 	}
 }
 
-func (b *bombardier) barUpdater() {
+// sleepOrDone sleeps for d and returns false if done was closed
+// before that. A nil done is never closed.
+func sleepOrDone(d time.Duration, done <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// active waits until worker n is allowed to send requests. It's always
+// allowed to, unless --connections-auto is set.
+func (b *bombardier) active(n uint64, done <-chan struct{}) bool {
+	return b.ramp == nil || b.ramp.wait(n, done)
+}
+
+// completionMessage tells whether there were any errors or requests
+// aborted with --abort-slower-than or canceled on interrupt, it's only
+// accurate once all workers are done.
+func (b *bombardier) completionMessage() string {
+	var failures []string
+	if errs := b.errors.sum(); errs > 0 {
+		failures = append(failures, fmt.Sprintf("%v error(s)", errs))
+	}
+	if aborted := atomic.LoadUint64(&b.aborted); aborted > 0 {
+		failures = append(failures, fmt.Sprintf("%v aborted", aborted))
+	}
+	if canceled := atomic.LoadUint64(&b.canceled); canceled > 0 {
+		failures = append(failures, fmt.Sprintf("%v canceled", canceled))
+	}
+	if len(failures) > 0 {
+		return "Done with " + strings.Join(failures, ", ")
+	}
+	return "Completed successfully"
+}
+
+func (b *bombardier) barUpdater(begin time.Time) {
 	done := b.barrier.done()
 	for {
 		select {
 		case <-done:
+			// requests in flight may still fail
+			b.wg.Wait()
+			b.flushErrors()
+			b.updatePostfix(begin, true)
 			b.bar.Set64(b.bar.Total)
 			b.bar.Update()
 			b.bar.Finish()
 			if b.conf.printProgress {
-				fmt.Fprintln(b.out, "Done!")
+				fmt.Fprintln(b.out, b.completionMessage())
 			}
 			b.doneChan <- struct{}{}
 			return
 		default:
+			b.flushErrors()
 			current := int64(b.barrier.completed() * float64(b.bar.Total))
+			b.updatePostfix(begin, false)
 			b.bar.Set64(current)
 			b.bar.Update()
 			time.Sleep(b.bar.RefreshRate)
@@ -297,12 +929,21 @@ func (b *bombardier) rateMeter() {
 	requestsInterval += 10 * time.Millisecond
 	ticker := time.NewTicker(requestsInterval)
 	defer ticker.Stop()
+	var recompute <-chan time.Time
+	if b.adaptiveTimeout != nil {
+		recomputeTicker := time.NewTicker(adaptiveTimeoutInterval)
+		defer recomputeTicker.Stop()
+		recompute = recomputeTicker.C
+	}
 	done := b.barrier.done()
 	for {
 		select {
 		case <-ticker.C:
 			b.recordRps()
 			continue
+		case <-recompute:
+			b.adaptiveTimeout.update(b.sortedLatencies)
+			continue
 		case <-done:
 			b.wg.Wait()
 			b.recordRps()
@@ -313,15 +954,35 @@ func (b *bombardier) rateMeter() {
 }
 
 func (b *bombardier) recordRps() {
+	if b.statsReset != nil && !b.statsReset.done() {
+		return
+	}
 	b.rpl.Lock()
 	duration := time.Since(b.start)
 	reqs := b.reqs
 	b.reqs = 0
 	b.start = time.Now()
+	totalErrs := atomic.LoadUint64(&b.connErrors) +
+		atomic.LoadUint64(&b.reqErrors)
+	errs := totalErrs - b.lastErrs
+	b.lastErrs = totalErrs
 	b.rpl.Unlock()
 
 	reqsf := float64(reqs) / duration.Seconds()
 	b.requests.Increment(reqsf)
+	if b.rpsCSV != nil {
+		b.rpsCSV.record(time.Now(), reqsf)
+	}
+	if b.dashboard != nil {
+		b.dashboard.addRequests(uint64(reqs), errs)
+	}
+}
+
+// interrupt stops the test, canceling requests in flight.
+func (b *bombardier) interrupt() {
+	// workers must not start new requests with the canceled context
+	b.barrier.cancel()
+	b.cancelRequests()
 }
 
 func (b *bombardier) bombard() {
@@ -331,28 +992,93 @@ func (b *bombardier) bombard() {
 	b.bar.Start()
 	bombardmentBegin := time.Now()
 	b.start = time.Now()
-	for i := uint64(0); i < b.conf.numConns; i++ {
-		go func() {
+	if b.statsReset != nil {
+		b.statsReset.begin = bombardmentBegin
+	}
+	if b.rawLatencies != nil {
+		b.rawLatencies.begin = bombardmentBegin
+	}
+	for i := uint64(0); i < b.conf.numWorkers(); i++ {
+		go func(n uint64) {
 			defer b.wg.Done()
-			b.worker()
-		}()
+			b.worker(n)
+		}(i)
 	}
 	go b.rateMeter()
-	go b.barUpdater()
+	if b.ramp != nil {
+		go b.rampConnections()
+	}
+	if b.rateSearch != nil {
+		go b.searchMaxRPS()
+	}
+	if b.rateSchedule != nil {
+		go b.followRateSchedule(bombardmentBegin)
+	}
+	if b.latencyTarget != nil {
+		go b.followTargetP99()
+	}
+	if b.pipelineStats != nil {
+		go b.samplePipelineDepth()
+	}
+	if b.oauth2 != nil {
+		go b.refreshOAuth2Token()
+	}
+	if b.conf.snapshotInterval > 0 {
+		go b.snapshotter(bombardmentBegin)
+	}
+	if b.dashboard != nil {
+		go b.dashboardUpdater()
+	} else {
+		go b.barUpdater(bombardmentBegin)
+	}
 	b.wg.Wait()
 	b.timeTaken = time.Since(bombardmentBegin)
+	if b.statsReset != nil && b.statsReset.done() {
+		b.timeTaken -= b.statsReset.reached
+	}
 	<-b.doneChan
 	<-b.doneChan
+	if b.conf.snapshotInterval > 0 {
+		<-b.doneChan
+	}
+	if completed, stopped := b.stoppedByMaxDuration(); stopped &&
+		(b.conf.printProgress || b.conf.printResult) {
+		fmt.Fprintf(b.out,
+			"Stopped by max-duration with %v/%v requests completed\n",
+			completed, *b.conf.numReqs)
+	}
+}
+
+// stoppedByMaxDuration tells whether --max-duration elapsed before
+// the requested number of requests was completed and how many were.
+func (b *bombardier) stoppedByMaxDuration() (uint64, bool) {
+	if b.maxDurationBarrier == nil {
+		return 0, false
+	}
+	select {
+	case <-b.maxDurationBarrier.done():
+	default:
+		return 0, false
+	}
+	completed := b.completedRequests()
+	return completed, completed < *b.conf.numReqs
 }
 
 func (b *bombardier) printIntro() {
+	conns := fmt.Sprint(b.conf.numConns)
+	if b.ramp != nil {
+		conns = "up to " + conns
+	}
 	if b.conf.testType() == counted {
 		fmt.Fprintf(b.out,
 			"Bombarding %v with %v request(s) using %v connection(s)\n",
-			b.conf.url, *b.conf.numReqs, b.conf.numConns)
+			b.conf.displayURL(), *b.conf.numReqs, conns)
 	} else if b.conf.testType() == timed {
 		fmt.Fprintf(b.out, "Bombarding %v for %v using %v connection(s)\n",
-			b.conf.url, *b.conf.duration, b.conf.numConns)
+			b.conf.displayURL(), *b.conf.duration, conns)
+	}
+	if b.rateSchedule != nil {
+		b.rateSchedule.print(b.out)
 	}
 }
 
@@ -362,7 +1088,7 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 			NumberOfConnections: b.conf.numConns,
 
 			Method: b.conf.method,
-			URL:    b.conf.url,
+			URL:    b.conf.displayURL(),
 
 			Body:         b.conf.body,
 			BodyFilePath: b.conf.bodyFilePath,
@@ -370,26 +1096,46 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 			CertPath: b.conf.certPath,
 			KeyPath:  b.conf.keyPath,
 
-			Stream:     b.conf.stream,
-			Timeout:    b.conf.timeout,
-			ClientType: internal.ClientType(b.conf.clientType),
+			Stream:          b.conf.stream,
+			Timeout:         b.conf.timeout,
+			AbortSlowerThan: b.conf.abortSlowerThan,
+			LatencyCap:      b.conf.latencyCap,
+			ClientType:      internal.ClientType(b.conf.clientType),
+			Pipeline:        b.conf.pipeline,
 
-			Rate: b.conf.rate,
+			Rate:      b.conf.rate,
+			RateBytes: b.conf.rateBytes,
 		},
+		// counters are loaded atomically, since results may be
+		// gathered while the test is running (i.e. for snapshots)
 		Result: internal.Results{
-			BytesRead:    b.bytesRead,
-			BytesWritten: b.bytesWritten,
+			BytesRead:    atomic.LoadInt64(&b.bytesRead),
+			BytesWritten: atomic.LoadInt64(&b.bytesWritten),
 			TimeTaken:    b.timeTaken,
 
-			Req1XX: b.req1xx,
-			Req2XX: b.req2xx,
-			Req3XX: b.req3xx,
-			Req4XX: b.req4xx,
-			Req5XX: b.req5xx,
-			Others: b.others,
+			Req1XX: atomic.LoadUint64(&b.req1xx),
+			Req2XX: atomic.LoadUint64(&b.req2xx),
+			Req3XX: atomic.LoadUint64(&b.req3xx),
+			Req4XX: atomic.LoadUint64(&b.req4xx),
+			Req5XX: atomic.LoadUint64(&b.req5xx),
+			Others: atomic.LoadUint64(&b.others),
+
+			Aborted:       atomic.LoadUint64(&b.aborted),
+			LatencyCapped: atomic.LoadUint64(&b.latencyCapped),
+
+			ContentLengthMismatches: atomic.LoadUint64(&b.lengthMismatches),
+			Canceled:                atomic.LoadUint64(&b.canceled),
+
+			ConnectionErrors: atomic.LoadUint64(&b.connErrors),
+			RequestErrors:    atomic.LoadUint64(&b.reqErrors),
 
-			Latencies: b.latencies,
+			Latencies: b.sortedLatencies,
 			Requests:  b.requests,
+
+			ConnectLatencies: b.sortedConnectLatencies,
+			WriteLatencies:   b.sortedWriteLatencies,
+			ReadLatencies:    b.sortedReadLatencies,
+			DNSLatencies:     b.sortedDNSLatencies,
 		},
 	}
 
@@ -401,8 +1147,8 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 		info.Spec.NumberOfRequests = *b.conf.numReqs
 	}
 
-	if b.conf.headers != nil {
-		for _, h := range *b.conf.headers {
+	if headers := b.conf.displayHeaders(); headers != nil {
+		for _, h := range *headers {
 			info.Spec.Headers = append(info.Spec.Headers,
 				internal.Header{
 					Key:   h.key,
@@ -411,6 +1157,82 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 		}
 	}
 
+	if b.codeLatencies != nil {
+		info.Result.LatenciesByCode = b.codeLatencies.results()
+	}
+	if b.scenario != nil {
+		info.Result.Steps = b.scenario.results()
+	}
+	if b.hosts != nil {
+		info.Result.Hosts = b.hostResults()
+	}
+	if b.schemes != nil {
+		info.Result.Schemes = b.schemeResults()
+	}
+	if b.methodMix != nil {
+		info.Result.Methods = b.methodMix.results()
+	}
+	if b.ramp != nil {
+		info.Result.ConnectionsAuto = b.ramp.result()
+	}
+	if b.rateSearch != nil {
+		info.Result.MaxRPS = b.rateSearch.result()
+	}
+	if b.latencyTarget != nil {
+		info.Result.TargetP99 = b.latencyTarget.result()
+	}
+	if b.tls != nil {
+		info.Result.TLS = b.tls.result()
+	}
+	info.Result.FirstError = b.firstErrorResult()
+	if b.dialer != nil {
+		info.Result.DialRetries = b.dialer.result()
+	}
+	if b.queueTimes != nil {
+		info.Result.QueueTimes = b.sortedQueueTimes
+	}
+	if b.statsReset != nil {
+		info.Result.StatsReset = b.statsReset.result()
+	}
+	if b.conf.connectionCount {
+		info.Result.ConnectionCount = &internal.ConnectionCountResult{
+			Opened:     atomic.LoadUint64(&b.connsOpened),
+			Configured: b.conf.numConns,
+		}
+	}
+	if b.httpsUpgrades != nil {
+		info.Result.HTTPSUpgrade = b.httpsUpgrades.result()
+	}
+	if b.pipelineStats != nil {
+		info.Result.Pipeline = b.pipelineStats.results(
+			b.completedRequests(), atomic.LoadUint64(&b.connsOpened),
+		)
+	}
+	if b.bodyDir != nil {
+		info.Result.BodyFileErrors = b.bodyDir.results()
+	}
+	if b.bodyCommand != nil {
+		info.Result.BodyCommand = b.bodyCommand.result()
+	}
+	if b.pacedUploads != nil {
+		info.Result.PacedUploads = b.pacedUploads.result()
+	}
+	if b.slowloris != nil {
+		info.Result.Slowloris = b.slowloris.result()
+	}
+	if b.compression != nil {
+		info.Result.CompressedBytes, info.Result.DecompressedBytes =
+			b.compression.load()
+	}
+	if b.successBytes != nil {
+		info.Result.SuccessfulOnly = true
+		info.Result.SuccessfulBytesRead, info.Result.SuccessfulBytesWritten =
+			b.successBytes.load()
+	}
+	if b.goodput != nil {
+		info.Result.Goodput = b.goodput.result(info.Result)
+	}
+
 	for _, ewc := range b.errors.byFrequency() {
 		info.Result.Errors = append(info.Result.Errors,
 			internal.ErrorWithCount{
@@ -424,10 +1246,16 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 
 func (b *bombardier) printStats() {
 	info := b.gatherInfo()
-	err := b.template.Execute(b.out, info)
+	err := b.reporter.report(b.out, info)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	}
+	if b.conf.printGraph {
+		printLatencyGraph(b.out, b.latencies, graphWidth())
+	}
+	if b.conf.printBuckets {
+		printHistogramBuckets(b.out, b.latencies)
+	}
 }
 
 func (b *bombardier) redirectOutputTo(out io.Writer) {
@@ -437,6 +1265,7 @@ func (b *bombardier) redirectOutputTo(out io.Writer) {
 
 func (b *bombardier) disableOutput() {
 	b.redirectOutputTo(ioutil.Discard)
+	b.errOut = ioutil.Discard
 	b.bar.NotPrint = true
 }
 
@@ -446,19 +1275,125 @@ func main() {
 		fmt.Println(err)
 		os.Exit(exitFailure)
 	}
+	if cfg.waitReady > 0 {
+		// newBombardier checks it as well, but there is no point in
+		// waiting for the target with invalid arguments
+		if err := cfg.checkArgs(); err != nil {
+			fmt.Println(err)
+			os.Exit(exitFailure)
+		}
+		waited, err := waitReady(cfg)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(exitFailure)
+		}
+		if cfg.printIntro {
+			fmt.Printf("Target was ready after %v\n",
+				waited.Round(time.Millisecond))
+		}
+	}
+	if cfg.compareClients {
+		if err := cfg.checkArgs(); err != nil {
+			fmt.Println(err)
+			os.Exit(exitFailure)
+		}
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		if err := compareClients(cfg, os.Stdout, c); err != nil {
+			fmt.Println(err)
+			os.Exit(exitFailure)
+		}
+		return
+	}
 	bombardier, err := newBombardier(cfg)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(exitFailure)
 	}
+	if cfg.rawLatenciesFile != "" {
+		fmt.Fprintln(os.Stderr, "Warning: --output-raw-latencies "+
+			"writes a record for every request, which may slow down "+
+			"high rate tests")
+	}
+	if cfg.ignoreBody && cfg.clientType != nhttp2 {
+		fmt.Fprintln(os.Stderr, "Warning: with --ignore-body "+
+			"connections are closed after each response, since bodies "+
+			"aren't drained")
+	}
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
 		<-c
-		bombardier.barrier.cancel()
+		bombardier.interrupt()
 	}()
+	if cfg.rateStep != nil {
+		rc := make(chan os.Signal, 1)
+		signal.Notify(rc, rateUpSignal, rateDownSignal)
+		go bombardier.handleRateSignals(rc)
+	}
 	bombardier.bombard()
-	if bombardier.conf.printResult {
+	if bombardier.conf.printErrorsOnly {
+		bombardier.printErrorCounts()
+	} else if bombardier.conf.printResult {
 		bombardier.printStats()
 	}
+	if bombardier.conf.perConnStats != "" {
+		if err := bombardier.dumpConnStats(
+			bombardier.conf.perConnStats,
+		); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to write per-connection stats to %v: %v\n",
+				bombardier.conf.perConnStats, err)
+		}
+	}
+	if bombardier.conf.rpsHistogramFile != "" {
+		if err := bombardier.dumpRPSHistogram(
+			bombardier.conf.rpsHistogramFile,
+		); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to write requests per second histogram "+
+					"to %v: %v\n", bombardier.conf.rpsHistogramFile, err)
+		}
+	}
+	if bombardier.tracer != nil {
+		if err := bombardier.tracer.close(); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to write traced requests to %v: %v\n",
+				bombardier.conf.traceFile, err)
+		}
+	}
+	if bombardier.rawLatencies != nil {
+		if err := bombardier.rawLatencies.close(); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to write latencies to %v: %v\n",
+				bombardier.conf.rawLatenciesFile, err)
+		}
+	}
+	if bombardier.rpsCSV != nil {
+		if err := bombardier.rpsCSV.close(); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to write requests per second to %v: %v\n",
+				bombardier.conf.rpsCSVFile, err)
+		}
+	}
+	if bombardier.conf.recoveryProbe > 0 {
+		if err := bombardier.probeRecovery(bombardier.out); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to probe recovery: %v\n", err)
+		}
+	}
+	if bombardier.conf.notifyURL != "" {
+		if err := bombardier.notify(); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: failed to notify %v: %v\n",
+				bombardier.conf.notifyURL, err)
+		}
+	}
+	code := bombardier.gatesExitCode()
+	if code == 0 && atomic.LoadUint32(&bombardier.oauth2Failed) == 1 {
+		code = exitFailure
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
 }
@@ -1,22 +1,29 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"container/ring"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"regexp"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/codesenberg/bombardier/internal"
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
 	"github.com/valyala/fasthttp"
 )
 
@@ -757,3 +764,1124 @@ func testBombardierShouldSendCustomHostHeader(
 	b.disableOutput()
 	b.bombard()
 }
+
+func TestBombardierHeaderCasePreserve(t *testing.T) {
+	expectations := []struct {
+		clientType   clientTyp
+		preserveCase bool
+		expected     string
+	}{
+		{fhttp, true, "x-my-header: value"},
+		{fhttp, false, "X-My-Header: value"},
+		// net/http clients send -H names as given regardless
+		{nhttp1, true, "x-my-header: value"},
+		{nhttp1, false, "x-my-header: value"},
+	}
+	for _, e := range expectations {
+		e := e
+		name := fmt.Sprintf("%v/%v", e.clientType, e.preserveCase)
+		t.Run(name, func(t *testing.T) {
+			testBombardierHeaderCasePreserve(
+				e.clientType, e.preserveCase, e.expected, t,
+			)
+		})
+	}
+}
+
+func testBombardierHeaderCasePreserve(
+	clientType clientTyp, preserveCase bool, expected string, t *testing.T,
+) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer ln.Close()
+	headerLines := make(chan string, 64)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line != "\r\n" {
+						headerLines <- strings.TrimSpace(line)
+						continue
+					}
+					_, err = conn.Write([]byte(
+						"HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n",
+					))
+					if err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	one := uint64(1)
+	headers := headersList([]header{
+		{"x-my-header", "value"},
+	})
+	b, e := newBombardier(config{
+		numConns:           defaultNumberOfConns,
+		numReqs:            &one,
+		url:                "http://" + ln.Addr().String(),
+		headers:            &headers,
+		headerCasePreserve: preserveCase,
+		timeout:            defaultTimeout,
+		method:             "GET",
+		clientType:         clientType,
+		format:             knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	found := false
+	for drained := false; !drained && !found; {
+		select {
+		case line := <-headerLines:
+			found = line == expected
+		default:
+			drained = true
+		}
+	}
+	if !found {
+		t.Errorf("%q wasn't sent", expected)
+	}
+}
+
+func TestBombardierWriteReadRecording(t *testing.T) {
+	testAllClients(t, testBombardierWriteReadRecording)
+}
+
+func testBombardierWriteReadRecording(clientType clientTyp, t *testing.T) {
+	const thinkTime = 5 * time.Millisecond
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(thinkTime)
+			_, err := rw.Write([]byte("OK"))
+			if err != nil {
+				t.Error(err)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		printWriteRead: true,
+		clientType:     clientType,
+		format:         knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := numReqs
+	if clientType == fhttp {
+		expected = 0
+	}
+	if c := totalCount(b.connectLatencies); c != expected {
+		t.Errorf("expected %v connect latencies, but got %v", expected, c)
+	}
+	if c := totalCount(b.writeLatencies); c != expected {
+		t.Errorf("expected %v write latencies, but got %v", expected, c)
+	}
+	if c := totalCount(b.readLatencies); c != expected {
+		t.Errorf("expected %v read latencies, but got %v", expected, c)
+	}
+	if expected == 0 {
+		return
+	}
+	phases := totalSum(b.connectLatencies) + totalSum(b.writeLatencies) +
+		totalSum(b.readLatencies)
+	if total := totalSum(b.latencies); phases != total {
+		t.Errorf("expected phases to sum to %v, but got %v", total, phases)
+	}
+	// server's think time is spent waiting for the response
+	minRead := numReqs * uint64(thinkTime/time.Microsecond)
+	if read := totalSum(b.readLatencies); read < minRead {
+		t.Errorf("expected at least %v of reads, but got %v", minRead, read)
+	}
+}
+
+func totalSum(h *uhist.Histogram) uint64 {
+	total := uint64(0)
+	h.VisitAll(func(v uint64, c uint64) bool {
+		total += v * c
+		return true
+	})
+	return total
+}
+
+func totalCount(h *uhist.Histogram) uint64 {
+	total := uint64(0)
+	h.VisitAll(func(_ uint64, c uint64) bool {
+		total += c
+		return true
+	})
+	return total
+}
+
+func TestBombardierDNSRecording(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	byName := strings.Replace(s.URL, "127.0.0.1", "localhost", 1)
+	for _, url := range []string{s.URL, byName} {
+		numReqs := uint64(10)
+		b, e := newBombardier(config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        url,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			printDNS:   true,
+			clientType: nhttp1,
+			format:     knownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.out = out
+		b.printStats()
+		lookups := totalCount(b.dnsLatencies)
+		if url == s.URL {
+			if lookups != 0 ||
+				!strings.Contains(out.String(), "No DNS lookups were made.") {
+				t.Errorf("Expected no lookups for %v, but got %v:\n%s",
+					url, lookups, out)
+			}
+			continue
+		}
+		// the connection is reused, though dials may race
+		if lookups < 1 || lookups >= numReqs {
+			t.Errorf("Expected a lookup per connection for %v, but got %v",
+				url, lookups)
+		}
+		expected := fmt.Sprintf("%v lookups, p50", lookups)
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+}
+
+func TestBombardierDNSLookupsCount(t *testing.T) {
+	for _, format := range []string{"plain-text", "json"} {
+		numReqs := uint64(10)
+		b, e := newBombardier(config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        "http://localhost:8080",
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			printDNS:   true,
+			clientType: nhttp1,
+			format:     knownFormat(format),
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		// lookups that took the same time share a key
+		for i := 0; i < 3; i++ {
+			b.dnsLatencies.Increment(100)
+		}
+		b.dnsLatencies.Increment(200)
+		out := new(bytes.Buffer)
+		b.out = out
+		b.printStats()
+		expected := "4 lookups, p50"
+		if format == "json" {
+			expected = `"dnsLatency":{"lookups":4,`
+		}
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+}
+
+func TestBombardierBytesRateLimiting(t *testing.T) {
+	testAllClients(t, testBombardierBytesRateLimiting)
+}
+
+func testBombardierBytesRateLimiting(clientType clientTyp, t *testing.T) {
+	response := bytes.Repeat([]byte{'a'}, 1024)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write(response)
+			if err != nil {
+				t.Error(err)
+			}
+		}),
+	)
+	defer s.Close()
+	rateBytes := uint64(512 * 1024)
+	numConns := uint64(10)
+	testDuration := 1 * time.Second
+	b, e := newBombardier(config{
+		numConns:   numConns,
+		duration:   &testDuration,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		rateBytes:  &rateBytes,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	transferred := float64(b.bytesRead + b.bytesWritten)
+	// Each connection may overshoot by a request or so
+	slack := float64(numConns) * 2 * float64(len(response))
+	if transferred < float64(rateBytes)*0.75 ||
+		transferred > float64(rateBytes)*1.25+slack {
+		t.Error(rateBytes, transferred)
+	}
+}
+
+func TestBombardierSummaryPercentiles(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte("OK"))
+			if err != nil {
+				t.Error(err)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:           defaultNumberOfConns,
+		numReqs:            &numReqs,
+		url:                s.URL,
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		printLatencies:     true,
+		printResult:        true,
+		format:             knownFormat("json"),
+		summaryPercentiles: &percentileList{0.5, 0.999},
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printStats()
+	var result struct {
+		Result struct {
+			Latency struct {
+				Percentiles map[string]uint64
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	percentiles := result.Result.Latency.Percentiles
+	if len(percentiles) != 2 {
+		t.Errorf("expected exactly 2 percentiles, but got %v", percentiles)
+	}
+	for _, pc := range []string{"50", "99.9"} {
+		if _, ok := percentiles[pc]; !ok {
+			t.Errorf("percentile %v is missing from %v", pc, percentiles)
+		}
+	}
+}
+
+func TestBombardierLatencyPercentiles(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:           defaultNumberOfConns,
+		numReqs:            &numReqs,
+		url:                s.URL,
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		printLatencies:     true,
+		printResult:        true,
+		format:             knownFormat("plain-text"),
+		latencyPercentiles: &percentileList{0.95, 0.999},
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printStats()
+	for _, pc := range []string{"\n     95% ", "\n     99.9% "} {
+		if !strings.Contains(out.String(), pc) {
+			t.Errorf("percentile %q is missing from %q", pc, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "\n     50% ") {
+		t.Errorf("expected only given percentiles, but got %q", out.String())
+	}
+}
+
+func TestBombardierPipelining(t *testing.T) {
+	var (
+		m       sync.Mutex
+		remotes = make(map[string]bool)
+		served  uint64
+	)
+	server := fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			m.Lock()
+			remotes[ctx.RemoteAddr().String()] = true
+			m.Unlock()
+			atomic.AddUint64(&served, 1)
+			ctx.Success("text/plain; charset=utf-8", []byte("OK"))
+		},
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	defer ln.Close()
+
+	numConns, numReqs := uint64(2), uint64(1000)
+	b, e := newBombardier(config{
+		numConns:   numConns,
+		numReqs:    &numReqs,
+		url:        "http://" + ln.Addr().String(),
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: fhttp,
+		pipeline:   8,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs || atomic.LoadUint64(&served) != numReqs {
+		t.Errorf("expected %v requests, but got %v 2xx and %v served",
+			numReqs, b.req2xx, served)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if len(remotes) > int(numConns) {
+		t.Errorf("expected at most %v connections, but got %v",
+			numConns, len(remotes))
+	}
+}
+
+func TestBombardierAbortsSlowRequests(t *testing.T) {
+	testAllClients(t, testBombardierAbortsSlowRequests)
+}
+
+func testBombardierAbortsSlowRequests(clientType clientTyp, t *testing.T) {
+	abortAfter := 20 * time.Millisecond
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(abortAfter * 5)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:        defaultNumberOfConns,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		abortSlowerThan: abortAfter,
+		method:          "GET",
+		clientType:      clientType,
+		format:          knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.aborted != numReqs {
+		t.Errorf("expected %v aborted requests, but got %v",
+			numReqs, b.aborted)
+	}
+	if sum := b.errors.sum(); sum != 0 {
+		t.Errorf("aborted requests shouldn't be reported as errors: %v",
+			b.errors.byFrequency())
+	}
+}
+
+func TestBombardierLimitsResponseSize(t *testing.T) {
+	testAllClients(t, testBombardierLimitsResponseSize)
+}
+
+func testBombardierLimitsResponseSize(clientType clientTyp, t *testing.T) {
+	maxResponseSize := uint64(1024)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			size := maxResponseSize
+			if r.URL.Query().Get("big") != "" {
+				size *= 64
+			}
+			_, _ = rw.Write(bytes.Repeat([]byte{'a'}, int(size)))
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		url       string
+		oversized uint64
+	}{
+		{s.URL, 0},
+		{s.URL + "?big=true", 10},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(10)
+		b, err := newBombardier(config{
+			numConns:        defaultNumberOfConns,
+			numReqs:         &numReqs,
+			url:             e.url,
+			headers:         new(headersList),
+			timeout:         defaultTimeout,
+			maxResponseSize: &maxResponseSize,
+			method:          "GET",
+			clientType:      clientType,
+			format:          knownFormat("plain-text"),
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		if sum := b.errors.sum(); sum != e.oversized {
+			t.Errorf("Expected %v oversized responses for %v, but got %v",
+				e.oversized, e.url, b.errors.byFrequency())
+		}
+		if e.oversized > 0 && b.req2xx != e.oversized {
+			t.Errorf("Status codes of oversized responses should be "+
+				"recorded, but got %v 2xx", b.req2xx)
+		}
+	}
+}
+
+func TestBombardierCacheBusting(t *testing.T) {
+	testAllClients(t, testBombardierCacheBusting)
+}
+
+func testBombardierCacheBusting(clientType clientTyp, t *testing.T) {
+	var (
+		mu     sync.Mutex
+		seen   = make(map[string]bool)
+		static = 0
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[r.URL.Query().Get(cacheBustParam)] = true
+			if r.URL.Query().Get("static") == "value" {
+				static++
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(50)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL + "?static=value",
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+		cacheBust:  true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if len(seen) != int(numReqs) {
+		t.Errorf("Expected %v unique cache-busting values, but got %v",
+			numReqs, len(seen))
+	}
+	if seen[""] {
+		t.Error("Some requests were sent without cache-busting parameter")
+	}
+	if static != int(numReqs) {
+		t.Errorf("Existing query was lost in %v requests",
+			int(numReqs)-static)
+	}
+}
+
+func TestBombardierQueryTemplates(t *testing.T) {
+	testAllClients(t, testBombardierQueryTemplates)
+}
+
+func testBombardierQueryTemplates(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			q := r.URL.Query()
+			if q.Get("static") == "value" && q.Get("id") == "req-"+q.Get("n") {
+				seen[q.Get("n")] = true
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(50)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL + "?static=value",
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+		queryTemplates: &queryList{
+			{"n", "{{ .Seq }}"}, {"id", "req-{{ .Seq }}"},
+		},
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if len(seen) != int(numReqs) {
+		t.Errorf("Expected %v requests with rendered parameters, "+
+			"but got %v", numReqs, len(seen))
+	}
+}
+
+func TestBombardierExpectStatus(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusCreated)
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		expectStatus statusRanges
+		errors       uint64
+	}{
+		{statusRanges{{201, 201}}, 0},
+		{statusRanges{{200, 200}}, 10},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(10)
+		expectStatus := e.expectStatus
+		b, err := newBombardier(config{
+			numConns:     defaultNumberOfConns,
+			numReqs:      &numReqs,
+			url:          s.URL,
+			headers:      new(headersList),
+			timeout:      defaultTimeout,
+			method:       "GET",
+			format:       knownFormat("plain-text"),
+			expectStatus: &expectStatus,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		if sum := b.errors.sum(); sum != e.errors {
+			t.Errorf("Expected %v errors with %v, but got %v",
+				e.errors, e.expectStatus.String(), b.errors.byFrequency())
+		}
+		if b.req2xx != numReqs {
+			t.Errorf("Status codes should still be recorded, got %v 2xx",
+				b.req2xx)
+		}
+	}
+}
+
+func TestBombardierLatencyCap(t *testing.T) {
+	latencyCap := 5 * time.Millisecond
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(latencyCap * 4)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		latencyCap: latencyCap,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.latencyCapped != numReqs {
+		t.Errorf("Expected %v capped latencies, but got %v",
+			numReqs, b.latencyCapped)
+	}
+	capUs := uint64(latencyCap / time.Microsecond)
+	b.latencies.VisitAll(func(us uint64, count uint64) bool {
+		if us > capUs {
+			t.Errorf("Latency %vus exceeds the cap", us)
+		}
+		return true
+	})
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printStats()
+	if !strings.Contains(out.String(),
+		"10 requests exceeded the latency cap") {
+		t.Errorf("No capped latencies in output:\n%s", out)
+	}
+}
+
+func TestBombardierPrintErrorsOnly(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:        defaultNumberOfConns,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		format:          knownFormat("plain-text"),
+		expectStatus:    &statusRanges{{200, 299}},
+		printErrorsOnly: true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	errOut := new(bytes.Buffer)
+	b.errOut = errOut
+	b.bombard()
+	expected := "Error: " + (&unexpectedStatusError{500}).Error() + "\n"
+	if s := errOut.String(); s != expected {
+		t.Errorf("Expected distinct error to be printed once: %q, "+
+			"but got %q", expected, s)
+	}
+	errOut.Reset()
+	b.printErrorCounts()
+	if s := errOut.String(); !strings.Contains(s, "20 - Unexpected") {
+		t.Errorf("Expected error counts, but got %q", s)
+	}
+}
+
+func TestBombardierMaxDuration(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(1000)
+	maxDuration := 200 * time.Millisecond
+	b, e := newBombardier(config{
+		numConns:    1,
+		numReqs:     &numReqs,
+		maxDuration: maxDuration,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		format:      knownFormat("plain-text"),
+		printResult: true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	out := new(bytes.Buffer)
+	b.out = out
+	start := time.Now()
+	b.bombard()
+	if taken := time.Since(start); taken > maxDuration*5 {
+		t.Errorf("Expected test to stop after %v, but it took %v",
+			maxDuration, taken)
+	}
+	completed, stopped := b.stoppedByMaxDuration()
+	if !stopped || completed == 0 || completed >= numReqs {
+		t.Errorf("Expected test to be stopped, but got %v, %v",
+			completed, stopped)
+	}
+	expected := fmt.Sprintf(
+		"Stopped by max-duration with %v/%v requests completed\n",
+		completed, numReqs)
+	if s := out.String(); s != expected {
+		t.Errorf("Expected %q, but got %q", expected, s)
+	}
+}
+
+func TestBombardierSnapshots(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(30)
+	b, e := newBombardier(config{
+		numConns:         1,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		format:           knownFormat("plain-text"),
+		snapshotInterval: 100 * time.Millisecond,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	out := new(bytes.Buffer)
+	b.out = out
+	b.bombard()
+	snapshots := strings.Count(out.String(), "Snapshot after ")
+	if snapshots < 1 {
+		t.Errorf("Expected snapshots to be printed, but got %q", out)
+	}
+	if stats := strings.Count(out.String(), "HTTP codes:"); stats != snapshots {
+		t.Errorf("Expected %v snapshots of stats, but got %v",
+			snapshots, stats)
+	}
+}
+
+func TestBombardierCompletionMessage(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/fail" {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		path     string
+		expected string
+	}{
+		{"/", "Completed successfully"},
+		{"/fail", "Done with 3 error(s)"},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(3)
+		expectStatus := statusRanges{{200, 299}}
+		b, err := newBombardier(config{
+			numConns:      defaultNumberOfConns,
+			numReqs:       &numReqs,
+			url:           s.URL + e.path,
+			headers:       new(headersList),
+			timeout:       defaultTimeout,
+			method:        "GET",
+			format:        knownFormat("plain-text"),
+			expectStatus:  &expectStatus,
+			printProgress: true,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		out := new(bytes.Buffer)
+		b.out = out
+		b.bombard()
+		if !strings.Contains(out.String(), e.expected) {
+			t.Errorf("Expected %q in output:\n%s", e.expected, out)
+		}
+	}
+}
+
+func TestBombardierCompletionMessageAbortedAndCanceled(t *testing.T) {
+	numReqs := uint64(3)
+	b, err := newBombardier(config{
+		numConns: defaultNumberOfConns,
+		numReqs:  &numReqs,
+		url:      "http://localhost:8080",
+		headers:  new(headersList),
+		timeout:  defaultTimeout,
+		method:   "GET",
+		format:   knownFormat("plain-text"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.aborted = 2
+	if m := b.completionMessage(); m != "Done with 2 aborted" {
+		t.Errorf("Unexpected message %q", m)
+	}
+	b.canceled = 1
+	if m := b.completionMessage(); m != "Done with 2 aborted, 1 canceled" {
+		t.Errorf("Unexpected message %q", m)
+	}
+}
+
+func TestBombardierErrorClassification(t *testing.T) {
+	testAllClients(t, testBombardierErrorClassification)
+}
+
+func testBombardierErrorClassification(clientType clientTyp, t *testing.T) {
+	hangUp := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			conn, _, err := rw.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}),
+	)
+	defer hangUp.Close()
+	refused := httptest.NewServer(http.NotFoundHandler())
+	refused.Close()
+	expectations := []struct {
+		url                   string
+		connErrors, reqErrors uint64
+	}{
+		{refused.URL, 5, 0},
+		{hangUp.URL, 0, 5},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(5)
+		b, err := newBombardier(config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        e.url,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			clientType: clientType,
+			format:     knownFormat("plain-text"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		b.bombard()
+		if b.connErrors != e.connErrors || b.reqErrors != e.reqErrors {
+			t.Errorf("%v: expected %v connection and %v request errors, "+
+				"but got %v and %v (%v)", e.url, e.connErrors, e.reqErrors,
+				b.connErrors, b.reqErrors, b.errors.byFrequency())
+		}
+		out := new(bytes.Buffer)
+		b.out = out
+		b.printStats()
+		expected := fmt.Sprintf("Failed: %v connection errors, "+
+			"%v request errors", e.connErrors, e.reqErrors)
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+}
+
+func TestBombardierLatencyPrecision(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	precision := uint64(4)
+	b, e := newBombardier(config{
+		numConns:         defaultNumberOfConns,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		printLatencies:   true,
+		latencyPrecision: &precision,
+		format:           knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printStats()
+	latency := regexp.MustCompile(`\d+\.(\d+)(us|ms|s)\b`)
+	matches := latency.FindAllStringSubmatch(out.String(), -1)
+	// mean, stddev, max and 5 percentiles
+	if len(matches) != 8 {
+		t.Fatalf("Expected 8 latencies in output:\n%s", out)
+	}
+	for _, m := range matches {
+		if len(m[1]) != int(precision) {
+			t.Errorf("Expected %v digits in %q", precision, m[0])
+		}
+	}
+}
+
+func TestBombardierInterrupt(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}),
+	)
+	defer s.Close()
+	defer close(release)
+	duration := time.Hour
+	b, e := newBombardier(config{
+		numConns:   4,
+		duration:   &duration,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    time.Hour,
+		method:     "GET",
+		clientType: nhttp1,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	time.AfterFunc(200*time.Millisecond, b.interrupt)
+	start := time.Now()
+	b.bombard()
+	if took := time.Since(start); took > 10*time.Second {
+		t.Errorf("Expected requests in flight to be canceled, "+
+			"but the test took %v", took)
+	}
+	res := b.gatherInfo().Result
+	if res.Canceled != 4 {
+		t.Errorf("Expected 4 canceled requests, but got %v", res.Canceled)
+	}
+	if len(res.Errors) != 0 || b.latencies.Count() != 0 {
+		t.Errorf("Expected canceled requests not to be accounted, "+
+			"but got errors %v and %v latencies", res.Errors,
+			b.latencies.Count())
+	}
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printStats()
+	if !strings.Contains(out.String(), "4 requests canceled on interrupt") {
+		t.Errorf("Expected canceled requests to be reported:\n%s", out)
+	}
+}
+
+func TestBombardierAbortOnFirstError(t *testing.T) {
+	testAllClients(t, testBombardierAbortOnFirstError)
+}
+
+func testBombardierAbortOnFirstError(clientType clientTyp, t *testing.T) {
+	var served uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&served, 1) > 5 {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	duration := time.Hour
+	expectStatus := statusRanges{{200, 299}}
+	b, e := newBombardier(config{
+		numConns:          1,
+		duration:          &duration,
+		url:               s.URL,
+		headers:           new(headersList),
+		timeout:           defaultTimeout,
+		method:            "GET",
+		expectStatus:      &expectStatus,
+		abortOnFirstError: true,
+		clientType:        clientType,
+		format:            knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	errOut := new(bytes.Buffer)
+	b.errOut = errOut
+	start := time.Now()
+	b.bombard()
+	if took := time.Since(start); took > 10*time.Second {
+		t.Errorf("Expected the test to stop on the first error, "+
+			"but it took %v", took)
+	}
+	res := b.gatherInfo().Result
+	expected := &internal.FirstErrorResult{
+		Request: 6,
+		Error:   "Unexpected status code 500",
+	}
+	if res.FirstError == nil || *res.FirstError != *expected {
+		t.Errorf("Expected %+v, but got %+v", expected, res.FirstError)
+	}
+	msg := "Request 6 failed: Unexpected status code 500, stopping the test"
+	if !strings.Contains(errOut.String(), msg) {
+		t.Errorf("Expected %q, but got %q", msg, errOut)
+	}
+	if b.req2xx != 5 || b.req5xx != 1 {
+		t.Errorf("Expected 5 2xx and a 5xx, but got %v and %v",
+			b.req2xx, b.req5xx)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// captureSpec is the format of capture directives in scenario files.
+type captureSpec struct {
+	Var   string `json:"var"`
+	JSON  string `json:"json"`
+	Regex string `json:"regex"`
+}
+
+// capture extracts a value from the response body, either by
+// a JSONPath-like path (i.e. "$.data.items[0].id") or by a regular
+// expression, in which case the first group (or the whole match, if
+// there are no groups) is used.
+type capture struct {
+	name string
+	path []string
+	re   *regexp.Regexp
+}
+
+type captureError struct {
+	name string
+}
+
+func (c *captureError) Error() string {
+	return fmt.Sprintf("Capture of %q matched nothing", c.name)
+}
+
+type uncapturedVariableError struct {
+	name string
+}
+
+func (u *uncapturedVariableError) Error() string {
+	return fmt.Sprintf("Variable %q wasn't captured, step skipped", u.name)
+}
+
+type undefinedVariableError struct {
+	name string
+}
+
+func (u *undefinedVariableError) Error() string {
+	return fmt.Sprintf(
+		"Variable %q isn't captured by any of the previous steps", u.name)
+}
+
+func newCapture(spec captureSpec) (*capture, error) {
+	if spec.Var == "" {
+		return nil, errCaptureNoVar
+	}
+	if (spec.JSON == "") == (spec.Regex == "") {
+		return nil, errCaptureExpression
+	}
+	c := &capture{name: spec.Var}
+	if spec.Regex != "" {
+		re, err := regexp.Compile(spec.Regex)
+		if err != nil {
+			return nil, err
+		}
+		c.re = re
+		return c, nil
+	}
+	path, err := parseJSONPath(spec.JSON)
+	if err != nil {
+		return nil, err
+	}
+	c.path = path
+	return c, nil
+}
+
+// parseJSONPath splits paths like "$.data.items[0].id" into
+// ["data", "items", "0", "id"].
+func parseJSONPath(path string) ([]string, error) {
+	p := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	p = strings.Replace(p, "[", ".", -1)
+	p = strings.Replace(p, "]", "", -1)
+	if p == "" {
+		return nil, nil
+	}
+	res := strings.Split(p, ".")
+	for _, key := range res {
+		if key == "" {
+			return nil, fmt.Errorf("invalid json path %q", path)
+		}
+	}
+	return res, nil
+}
+
+// extract returns the captured value and whether there was a match.
+func (c *capture) extract(body []byte) (string, bool) {
+	if c.re != nil {
+		m := c.re.FindSubmatch(body)
+		if m == nil {
+			return "", false
+		}
+		if len(m) > 1 {
+			return string(m[1]), true
+		}
+		return string(m[0]), true
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", false
+	}
+	for _, key := range c.path {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return "", false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch value := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return value, true
+	default:
+		// numbers, booleans, objects and arrays are used as is
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+}
+
+// substitute replaces {{name}} in s with values of variables in names.
+func substitute(s string, names []string, vars map[string]string) string {
+	for _, name := range names {
+		s = strings.Replace(s, "{{"+name+"}}", vars[name], -1)
+	}
+	return s
+}
+
+// substituteURL is substitute for URLs, values are escaped for the
+// part of the URL they are substituted in, so that reserved characters
+// in them don't change its structure. Everything after "?" or "#" is
+// escaped as a query.
+func substituteURL(
+	rawURL string, names []string, vars map[string]string,
+) string {
+	escaped := func(escape func(string) string) map[string]string {
+		res := make(map[string]string, len(names))
+		for _, name := range names {
+			res[name] = escape(vars[name])
+		}
+		return res
+	}
+	path, rest := rawURL, ""
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		path, rest = rawURL[:i], rawURL[i:]
+	}
+	return substitute(path, names, escaped(url.PathEscape)) +
+		substitute(rest, names, escaped(url.QueryEscape))
+}
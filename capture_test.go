@@ -0,0 +1,100 @@
+package main
+
+import "testing"
+
+func TestCaptureExtract(t *testing.T) {
+	body := []byte(`{"data":{"token":"abc","items":[{"id":7},{"id":8}],` +
+		`"ok":true,"none":null}}`)
+	expectations := []struct {
+		spec  captureSpec
+		value string
+		ok    bool
+	}{
+		{captureSpec{Var: "v", JSON: "$.data.token"}, "abc", true},
+		{captureSpec{Var: "v", JSON: "data.token"}, "abc", true},
+		{captureSpec{Var: "v", JSON: "$.data.items[1].id"}, "8", true},
+		{captureSpec{Var: "v", JSON: "$.data.ok"}, "true", true},
+		{captureSpec{Var: "v", JSON: "$.data.items[0]"}, `{"id":7}`, true},
+		{captureSpec{Var: "v", JSON: "$.data.items[2].id"}, "", false},
+		{captureSpec{Var: "v", JSON: "$.data.token.x"}, "", false},
+		{captureSpec{Var: "v", JSON: "$.data.none"}, "", false},
+		{captureSpec{Var: "v", JSON: "$.missing"}, "", false},
+		{captureSpec{Var: "v", Regex: `"token":"(\w+)"`}, "abc", true},
+		{captureSpec{Var: "v", Regex: `\d+`}, "7", true},
+		{captureSpec{Var: "v", Regex: `"secret":"(\w+)"`}, "", false},
+	}
+	for _, e := range expectations {
+		c, err := newCapture(e.spec)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		value, ok := c.extract(body)
+		if value != e.value || ok != e.ok {
+			t.Errorf("Expected (%q, %v) for %+v, but got (%q, %v)",
+				e.value, e.ok, e.spec, value, ok)
+		}
+	}
+	c, _ := newCapture(captureSpec{Var: "v", JSON: "$.data"})
+	if _, ok := c.extract([]byte("not json")); ok {
+		t.Error("Capture by json path shouldn't match non-json body")
+	}
+}
+
+func TestNewCaptureErrors(t *testing.T) {
+	expectations := []struct {
+		spec captureSpec
+		err  string
+	}{
+		{captureSpec{JSON: "$.a"}, errCaptureNoVar.Error()},
+		{captureSpec{Var: "v"}, errCaptureExpression.Error()},
+		{
+			captureSpec{Var: "v", JSON: "$.a", Regex: "a"},
+			errCaptureExpression.Error(),
+		},
+		{
+			captureSpec{Var: "v", JSON: "$.a..b"},
+			`invalid json path "$.a..b"`,
+		},
+		{
+			captureSpec{Var: "v", Regex: "("},
+			"error parsing regexp: missing closing ): `(`",
+		},
+	}
+	for _, e := range expectations {
+		_, err := newCapture(e.spec)
+		if err == nil || err.Error() != e.err {
+			t.Errorf("Expected %q for %+v, but got %v", e.err, e.spec, err)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	vars := map[string]string{"token": "abc", "id": "7"}
+	s := substitute("/items/{{id}}?t={{token}}&x={{other}}",
+		[]string{"token", "id"}, vars)
+	if expected := "/items/7?t=abc&x={{other}}"; s != expected {
+		t.Errorf("Expected %q, but got %q", expected, s)
+	}
+}
+
+func TestSubstituteURL(t *testing.T) {
+	vars := map[string]string{"id": "a b&c?d#e/f", "token": "x=1&y 2#z"}
+	expectations := []struct {
+		in, out string
+	}{
+		{"/items/{{id}}", "/items/a%20b&c%3Fd%23e%2Ff"},
+		{"/items?id={{id}}", "/items?id=a+b%26c%3Fd%23e%2Ff"},
+		{
+			"/items/{{id}}?t={{token}}#{{id}}",
+			"/items/a%20b&c%3Fd%23e%2Ff?t=x%3D1%26y+2%23z" +
+				"#a+b%26c%3Fd%23e%2Ff",
+		},
+	}
+	for _, e := range expectations {
+		act := substituteURL(e.in, []string{"id", "token"}, vars)
+		if act != e.out {
+			t.Errorf("Expected %q for %q, but got %q", e.out, e.in, act)
+		}
+	}
+}
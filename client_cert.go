@@ -2,23 +2,55 @@ package main
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"strings"
 )
 
-// readClientCert - helper function to read client certificate
-// from pem formatted certPath and keyPath files
+// readClientCert - helper function to read client certificates
+// from pem formatted certPath and keyPath files, both of which may be
+// comma-separated lists of paths paired by position
 func readClientCert(certPath, keyPath string) ([]tls.Certificate, error) {
 	if certPath != "" && keyPath != "" {
-		// load keypair
-		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
-		if err != nil {
-			return nil, err
+		certPaths := strings.Split(certPath, ",")
+		keyPaths := strings.Split(keyPath, ",")
+		if len(certPaths) != len(keyPaths) {
+			return nil, errCertKeyCount
 		}
-
-		return []tls.Certificate{cert}, nil
+		certs := make([]tls.Certificate, 0, len(certPaths))
+		for i := range certPaths {
+			// load keypair
+			cert, err := tls.LoadX509KeyPair(certPaths[i], keyPaths[i])
+			if err != nil {
+				return nil, err
+			}
+			// parsed once here rather than on every handshake
+			cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, cert)
+		}
+		return certs, nil
 	}
 	return nil, nil
 }
 
+// selectClientCert returns GetClientCertificate callback, which picks
+// the first of certs issued by one of CAs the server accepts, or the
+// first one of them, if none is (or the server didn't say).
+func selectClientCert(
+	certs []tls.Certificate,
+) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		for i := range certs {
+			if cri.SupportsCertificate(&certs[i]) == nil {
+				return &certs[i], nil
+			}
+		}
+		return &certs[0], nil
+	}
+}
+
 // generateTLSConfig - helper function to generate a TLS configuration based on
 // config
 func generateTLSConfig(c config) (*tls.Config, error) {
@@ -33,5 +65,11 @@ func generateTLSConfig(c config) (*tls.Config, error) {
 		InsecureSkipVerify: c.insecure,
 		Certificates:       certs,
 	}
+	if len(certs) > 1 {
+		tlsConfig.GetClientCertificate = selectClientCert(certs)
+	}
+	if c.alpn != nil {
+		tlsConfig.NextProtos = append([]string(nil), *c.alpn...)
+	}
 	return tlsConfig, nil
 }
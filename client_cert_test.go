@@ -1,7 +1,21 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGenerateTLSConfig(t *testing.T) {
@@ -39,3 +53,164 @@ func TestGenerateTLSConfig(t *testing.T) {
 		}
 	}
 }
+
+// testCA is a CA issuing client certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(
+		rand.Reader, template, template, &key.PublicKey, key,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue writes a client certificate signed by the CA and its key into
+// dir, returning their paths.
+func (ca *testCA) issue(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(
+		rand.Reader, template, ca.cert, &key.PublicKey, ca.key,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath := filepath.Join(dir, name+".cert")
+	keyPath := filepath.Join(dir, name+".key")
+	writePEM(t, certPath, "CERTIFICATE", der)
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyDer)
+	return certPath, keyPath
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCertSelectionByCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bombardier-client-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caA, caB := newTestCA(t, "CA A"), newTestCA(t, "CA B")
+	certA, keyA := caA.issue(t, dir, "client-a")
+	certB, keyB := caB.issue(t, dir, "client-b")
+	tlsConfig, err := generateTLSConfig(config{
+		url:      "https://doesnt.exist.com",
+		certPath: certA + "," + certB,
+		keyPath:  keyA + "," + keyB,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.GetClientCertificate == nil {
+		t.Fatal("Expected client certificate to be selected by CA")
+	}
+	expectations := []struct {
+		acceptable [][]byte
+		expected   string
+	}{
+		{[][]byte{caB.cert.RawSubject}, "client-b"},
+		{[][]byte{caA.cert.RawSubject}, "client-a"},
+		// nothing matches or no hint, the first one is sent
+		{[][]byte{[]byte("unknown")}, "client-a"},
+		{nil, "client-a"},
+	}
+	for _, e := range expectations {
+		cert, err := tlsConfig.GetClientCertificate(
+			&tls.CertificateRequestInfo{
+				AcceptableCAs: e.acceptable,
+				SignatureSchemes: []tls.SignatureScheme{
+					tls.ECDSAWithP256AndSHA256,
+				},
+				Version: tls.VersionTLS13,
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := cert.Leaf.Subject.CommonName; name != e.expected {
+			t.Errorf("Expected %v, but got %v", e.expected, name)
+		}
+	}
+
+	// the server only accepts certificates issued by CA B
+	pool := x509.NewCertPool()
+	pool.AddCert(caB.cert)
+	s := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			name := r.TLS.PeerCertificates[0].Subject.CommonName
+			if name != "client-b" {
+				t.Errorf("Expected client-b, but got %v", name)
+			}
+		},
+	))
+	s.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	s.StartTLS()
+	defer s.Close()
+	numReqs := uint64(3)
+	b, e := newBombardier(config{
+		numConns: 1,
+		numReqs:  &numReqs,
+		url:      s.URL,
+		headers:  new(headersList),
+		timeout:  defaultTimeout,
+		method:   "GET",
+		format:   knownFormat("plain-text"),
+		insecure: true,
+		certPath: certA + "," + certB,
+		keyPath:  keyA + "," + keyB,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+}
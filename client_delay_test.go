@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBombardierClientDelay(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(4)
+	delay := 100 * time.Millisecond
+	b, e := newBombardier(config{
+		numConns:    1,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		clientDelay: delay,
+		format:      knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Fatalf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < delay {
+			t.Errorf("Expected requests at least %v apart, but got %v",
+				delay, gap)
+		}
+	}
+	// delay isn't accounted as latency
+	delayUs := uint64(delay / time.Microsecond)
+	b.latencies.VisitAll(func(us uint64, count uint64) bool {
+		if us >= delayUs {
+			t.Errorf("Expected latencies below %v, but got %vus", delay, us)
+		}
+		return true
+	})
+}
+
+func TestBombardierClientDelayCancellation(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	duration := time.Second
+	b, e := newBombardier(config{
+		numConns:    1,
+		duration:    &duration,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		clientDelay: time.Hour,
+		format:      knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	start := time.Now()
+	b.bombard()
+	if took := time.Since(start); took > 10*time.Second {
+		t.Errorf("Expected test to stop after %v, but it took %v",
+			duration, took)
+	}
+	if b.req2xx != 1 {
+		t.Errorf("Expected a single request, but got %v", b.req2xx)
+	}
+}
+
+func TestBombardierResponseReadDelay(t *testing.T) {
+	body := strings.Repeat("x", 3*responseReadChunkSize)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write([]byte(body))
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(2)
+	delay := 50 * time.Millisecond
+	b, e := newBombardier(config{
+		numConns:          1,
+		numReqs:           &numReqs,
+		url:               s.URL,
+		headers:           new(headersList),
+		timeout:           defaultTimeout,
+		method:            "GET",
+		responseReadDelay: delay,
+		clientType:        nhttp1,
+		format:            knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Fatalf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	// no delay before the first chunk
+	minUs := uint64(2 * delay / time.Microsecond)
+	b.latencies.VisitAll(func(us uint64, count uint64) bool {
+		if us < minUs {
+			t.Errorf("Expected latencies of at least %vus, but got %vus",
+				minUs, us)
+		}
+		return true
+	})
+}
+
+func TestBombardierResponseReadDelayCancellation(t *testing.T) {
+	body := strings.Repeat("x", 3*responseReadChunkSize)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write([]byte(body))
+		}),
+	)
+	defer s.Close()
+	duration := time.Second
+	b, e := newBombardier(config{
+		numConns:          1,
+		duration:          &duration,
+		url:               s.URL,
+		headers:           new(headersList),
+		timeout:           time.Hour,
+		method:            "GET",
+		responseReadDelay: time.Hour,
+		clientType:        nhttp1,
+		format:            knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	start := time.Now()
+	b.bombard()
+	if took := time.Since(start); took > 10*time.Second {
+		t.Errorf("Expected test to stop after %v, but it took %v",
+			duration, took)
+	}
+	if count := b.latencies.Count(); count != 0 {
+		t.Errorf("Expected interrupted read not to be counted, but got %v",
+			count)
+	}
+	if errs := b.errors.sum(); errs != 0 {
+		t.Errorf("Expected no errors, but got %v", b.errors.byFrequency())
+	}
+}
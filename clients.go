@@ -1,20 +1,73 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/http2"
+	"golang.org/x/net/proxy"
 )
 
 type client interface {
-	do() (code int, usTaken uint64, err error)
+	do() (code int, usTaken uint64, phases phaseTimings, err error)
+	// doRequest performs r instead of the request the client was
+	// created for (i.e. a scenario step), body of the response is
+	// returned only if r.readBody is set
+	doRequest(r *request) (
+		code int, usTaken uint64, body []byte, err error,
+	)
+}
+
+// request is a request other than the one specified on the command
+// line. headers replace, rather than complement, those of the client.
+type request struct {
+	method  string
+	url     *url.URL
+	headers *headersList
+	body    string
+
+	readBody bool
+	// timeout, if non-zero, limits the request like
+	// --abort-slower-than, but is reported with errStepTimeout
+	timeout time.Duration
+}
+
+// phaseTimings holds time (in microseconds) spent obtaining a
+// connection (dialing and TLS handshake, if it's a new one), writing
+// the request, and waiting for and reading the response, which sum to
+// the latency of the request. measured is false for clients that are
+// unable to observe these phases (i.e. fasthttp).
+type phaseTimings struct {
+	usConnect, usWrite, usRead uint64
+	measured                   bool
+	// usDNS is the time the DNS lookup of a new connection took, if
+	// dnsMeasured is set
+	usDNS       uint64
+	dnsMeasured bool
+}
+
+// durationUs converts a measured duration to microseconds. Durations
+// are differences of monotonic clock readings, but phases are computed
+// from timestamps taken by transport's goroutines, which may come in
+// unexpected order, and a start without monotonic reading is subject
+// to wall clock adjustments. Negative durations are reported as zero
+// instead of wrapping around to huge latencies.
+func durationUs(d time.Duration) uint64 {
+	if d < 0 {
+		return 0
+	}
+	return uint64(d / time.Microsecond)
 }
 
 type bodyStreamProducer func() (io.ReadCloser, error)
@@ -24,26 +77,156 @@ type clientOpts struct {
 
 	maxConns          uint64
 	timeout           time.Duration
+	idleTimeout       time.Duration
 	tlsConfig         *tls.Config
 	disableKeepAlives bool
+	// dialer establishes connections, directly or through --proxy,
+	// connections are dialed directly if it's nil
+	dialer proxy.ContextDialer
+
+	// writeTimeout and readTimeout are timeouts of writing requests
+	// and reading responses, which default to timeout
+	writeTimeout, readTimeout time.Duration
+	// phaseTimeouts is set if either of them was set explicitly, so
+	// that net/http clients enforce them instead of timeout
+	phaseTimeouts bool
 
-	headers     *headersList
-	url, method string
+	headers            *headersList
+	headerCasePreserve bool
+	noDefaultHeaders   bool
+	url, method        string
+	// rawPath, if set, is sent instead of path and query of url
+	rawPath string
+	// connectTarget is the authority to establish tunnels to with
+	// CONNECT method
+	connectTarget string
+	// rawRequest, if set, is sent as is instead of building requests
+	rawRequest []byte
+
+	tracePhases bool
+	// traceDNS enables measuring DNS lookups (net/http only)
+	traceDNS bool
+
+	// pipeline, if non-zero, is the maximum number of pipelined
+	// requests per connection (fasthttp only)
+	pipeline uint64
+	// pipelineStats, if set, samples depth of pipelines
+	pipelineStats *pipelineStats
+	// abortAfter, if non-zero, is the time after which requests are
+	// aborted and reported with errAborted
+	abortAfter time.Duration
+	// adaptiveTimeout, if set, lowers abortAfter while the test is
+	// running
+	adaptiveTimeout *adaptiveTimeout
+	// maxResponseSize, if non-zero, is the maximum size of response
+	// body to read, larger responses are reported with
+	// errOversizedResponse
+	maxResponseSize uint64
+	// strictContentLength, if set, makes clients report bodies shorter
+	// than their Content-Length with errContentLengthMismatch
+	strictContentLength bool
+	// readBufferSize and writeBufferSize, if non-zero, are sizes of
+	// per-connection buffers (HTTP/1.x only)
+	readBufferSize, writeBufferSize int
+	// cacheBust, if set, adds a unique query parameter to each request
+	cacheBust bool
+	// queryTemplates, if set, add --query parameters rendered anew for
+	// each request
+	queryTemplates *queryTemplates
+	// randomHeaders, if set, adds headers with random values to each
+	// request
+	randomHeaders *randomHeaders
+	// headerRotation, if set, adds headers with values rotated over
+	// requests
+	headerRotation *headerRotation
+	// traceContext, if set, adds traceparent with new ids to each
+	// request
+	traceContext *traceContext
+	// grpcWeb, if set, makes clients interpret gRPC-Web responses
+	grpcWeb grpcWebMode
+	// compression, if set, makes clients decompress response bodies
+	// and account their sizes
+	compression *compressionStats
+	// successBytes, if set, accounts sizes of requests and responses
+	// with 2xx codes
+	successBytes *successBytes
+	// goodput, if set, accounts sizes of response bodies
+	goodput *goodputStats
+	// httpsUpgrades, if set, makes net/http clients follow redirects
+	// from http:// to https:// and account them
+	httpsUpgrades *httpsUpgrades
+	// ignoreBody, if set, makes clients close connections without
+	// reading response bodies, unless they're needed
+	ignoreBody bool
+	// oauth2, if set, provides Authorization header of requests
+	oauth2 *oauth2Token
+	// bodyDir, if set, provides bodies instead of body and bodProd
+	bodyDir *bodyDir
+	// bodyCommand, if set, provides bodies instead of body and bodProd
+	bodyCommand *bodyCommand
+	// methodMix, if set, picks methods instead of method, only write
+	// ones are sent with the body
+	methodMix *methodPicker
+	// responseReadDelay, if non-zero, is the time to wait before
+	// reading each responseReadChunkSize bytes of response bodies
+	// (net/http only)
+	responseReadDelay time.Duration
+	// done, if set, is closed once the test is done
+	done <-chan struct{}
+	// interrupted, if set, is canceled on interrupt to stop requests
+	// in flight (net/http only)
+	interrupted context.Context
 
 	body    *string
 	bodProd bodyStreamProducer
 
 	bytesRead, bytesWritten *int64
+	// connsOpened, if set, counts connections established
+	connsOpened *uint64
+	// tracer, if set, writes the first requests and their responses
+	// to --trace-file
+	tracer *tracer
+}
+
+// fasthttpDoer is implemented by both fasthttp.HostClient and
+// fasthttp.PipelineClient.
+type fasthttpDoer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoTimeout(
+		req *fasthttp.Request, resp *fasthttp.Response,
+		timeout time.Duration,
+	) error
 }
 
 type fasthttpClient struct {
-	client *fasthttp.HostClient
+	client fasthttpDoer
+	isTLS  bool
 
 	headers                  *fasthttp.RequestHeader
+	headerCasePreserve       bool
+	headerRotation           *headerRotation
 	host, requestURI, method string
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	abortAfter     time.Duration
+	adaptive       *adaptiveTimeout
+	strictLength   bool
+	cacheBuster    *cacheBuster
+	queryTemplates *queryTemplates
+	randomHeaders  *randomHeaders
+	traceContext   *traceContext
+	grpcWeb        grpcWebMode
+	compression    *compressionStats
+	successBytes   *successBytes
+	goodput        *goodputStats
+	ignoreBody     bool
+	oauth2         *oauth2Token
+	bodyDir        *bodyDir
+	bodyCommand    *bodyCommand
+	methodMix      *methodPicker
+	tracer         *tracer
 }
 
 func newFastHTTPClient(opts *clientOpts) client {
@@ -55,62 +238,274 @@ func newFastHTTPClient(opts *clientOpts) client {
 	}
 	c.host = u.Host
 	c.requestURI = u.RequestURI()
-	c.client = &fasthttp.HostClient{
-		Addr:                          u.Host,
-		IsTLS:                         u.Scheme == "https",
-		MaxConns:                      int(opts.maxConns),
-		ReadTimeout:                   opts.timeout,
-		WriteTimeout:                  opts.timeout,
-		DisableHeaderNamesNormalizing: true,
-		TLSConfig:                     opts.tlsConfig,
-		Dial: fasthttpDialFunc(
-			opts.bytesRead, opts.bytesWritten,
-		),
-	}
-	c.headers = headersToFastHTTPHeaders(opts.headers)
+	if opts.rawPath != "" {
+		c.requestURI = opts.rawPath
+	}
+	c.isTLS = u.Scheme == "https"
+	dial := fasthttpDialFunc(
+		opts.dialer, opts.bytesRead, opts.bytesWritten, opts.connsOpened,
+	)
+	if opts.pipeline > 0 {
+		if opts.pipelineStats != nil {
+			dial = opts.pipelineStats.dial(dial)
+		}
+		pc := &fasthttp.PipelineClient{
+			Addr:                u.Host,
+			IsTLS:               c.isTLS,
+			MaxConns:            int(opts.maxConns),
+			MaxPendingRequests:  int(opts.pipeline),
+			ReadBufferSize:      opts.readBufferSize,
+			WriteBufferSize:     opts.writeBufferSize,
+			MaxIdleConnDuration: opts.idleTimeout,
+			ReadTimeout:         opts.readTimeout,
+			WriteTimeout:        opts.writeTimeout,
+			TLSConfig:           opts.tlsConfig,
+			Dial:                dial,
+		}
+		if opts.pipelineStats != nil {
+			opts.pipelineStats.track(pc.PendingRequests)
+		}
+		c.client = pc
+	} else {
+		c.client = &fasthttp.HostClient{
+			Addr:                          u.Host,
+			IsTLS:                         c.isTLS,
+			MaxConns:                      int(opts.maxConns),
+			ReadTimeout:                   opts.readTimeout,
+			WriteTimeout:                  opts.writeTimeout,
+			DisableHeaderNamesNormalizing: true,
+			NoDefaultUserAgentHeader:      opts.noDefaultHeaders,
+			MaxResponseBodySize:           int(opts.maxResponseSize),
+			ReadBufferSize:                opts.readBufferSize,
+			WriteBufferSize:               opts.writeBufferSize,
+			MaxIdleConnDuration:           opts.idleTimeout,
+			TLSConfig:                     opts.tlsConfig,
+			Dial:                          dial,
+		}
+	}
+	c.headers = headersToFastHTTPHeaders(
+		opts.headers, opts.headerCasePreserve,
+	)
+	c.headerCasePreserve = opts.headerCasePreserve
 	c.method, c.body = opts.method, opts.body
 	c.bodProd = opts.bodProd
+	c.abortAfter, c.adaptive = opts.abortAfter, opts.adaptiveTimeout
+	c.strictLength = opts.strictContentLength
+	if opts.cacheBust {
+		c.cacheBuster = new(cacheBuster)
+	}
+	c.queryTemplates = opts.queryTemplates
+	c.randomHeaders, c.headerRotation = opts.randomHeaders, opts.headerRotation
+	c.traceContext = opts.traceContext
+	c.grpcWeb, c.compression = opts.grpcWeb, opts.compression
+	c.successBytes, c.goodput = opts.successBytes, opts.goodput
+	c.ignoreBody, c.oauth2 = opts.ignoreBody, opts.oauth2
+	c.bodyDir, c.bodyCommand = opts.bodyDir, opts.bodyCommand
+	c.methodMix, c.tracer = opts.methodMix, opts.tracer
 	return client(c)
 }
 
 func (c *fasthttpClient) do() (
-	code int, usTaken uint64, err error,
+	code int, usTaken uint64, phases phaseTimings, err error,
 ) {
 	// prepare the request
 	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
 	if c.headers != nil {
 		c.headers.CopyTo(&req.Header)
 	}
+	if c.oauth2 != nil {
+		req.Header.Set("Authorization", c.oauth2.authorization())
+	}
+	if c.randomHeaders != nil {
+		c.randomHeaders.set(req.Header.Set)
+	}
+	if c.headerRotation != nil {
+		c.headerRotation.set(req.Header.Set)
+	}
+	if c.traceContext != nil {
+		c.traceContext.set(req.Header.Set)
+	}
 	if len(req.Header.Host()) == 0 {
 		req.Header.SetHost(c.host)
 	}
-	req.Header.SetMethod(c.method)
-	if c.client.IsTLS {
-		req.URI().SetScheme("https")
-	} else {
-		req.URI().SetScheme("http")
+	method, withBody := c.method, true
+	if c.methodMix != nil {
+		m := c.methodMix.pick()
+		method, withBody = m.method, m.write
+		defer func() { m.done(code, err) }()
 	}
-	req.SetRequestURI(c.requestURI)
-	if c.body != nil {
+	req.Header.SetMethod(method)
+	c.setRequestURI(req, c.requestURI)
+	if c.bodyDir != nil {
+		f := c.bodyDir.pick()
+		if f.data != nil {
+			req.SetBody(f.data)
+		} else {
+			bs, bserr := f.open()
+			if bserr != nil {
+				c.bodyDir.done(f, 0, bserr)
+				return 0, 0, phases, bserr
+			}
+			req.SetBodyStream(bs, int(f.size))
+		}
+		code, usTaken, err = c.fire(req, nil, 0)
+		c.bodyDir.done(f, code, err)
+		return
+	}
+	if !withBody {
+		code, usTaken, err = c.fire(req, nil, 0)
+		return
+	}
+	if c.bodyCommand != nil {
+		body, berr := c.bodyCommand.next()
+		if berr != nil {
+			return 0, 0, phases, berr
+		}
+		req.SetBody(body)
+	} else if c.body != nil {
 		req.SetBodyString(*c.body)
 	} else {
 		bs, bserr := c.bodProd()
 		if bserr != nil {
-			return 0, 0, bserr
+			return 0, 0, phases, bserr
 		}
 		req.SetBodyStream(bs, -1)
 	}
 
-	// fire the request
+	code, usTaken, err = c.fire(req, nil, 0)
+	return
+}
+
+func (c *fasthttpClient) doRequest(r *request) (
+	code int, usTaken uint64, body []byte, err error,
+) {
+	req := fasthttp.AcquireRequest()
+	if c.headerCasePreserve {
+		req.Header.DisableNormalizing()
+	}
+	if c.oauth2 != nil {
+		req.Header.Set("Authorization", c.oauth2.authorization())
+	}
+	for _, h := range *r.headers {
+		req.Header.Set(h.key, h.value)
+	}
+	if len(req.Header.Host()) == 0 {
+		req.Header.SetHost(c.host)
+	}
+	req.Header.SetMethod(r.method)
+	c.setRequestURI(req, r.url.RequestURI())
+	req.SetBodyString(r.body)
+	if r.readBody {
+		code, usTaken, err = c.fire(req, &body, r.timeout)
+	} else {
+		code, usTaken, err = c.fire(req, nil, r.timeout)
+	}
+	return
+}
+
+// abortLimit returns the time after which the request is aborted,
+// zero if never, and the error it's reported with: timeout of the
+// request itself, if any, or abortAfter, whichever is shorter.
+func abortLimit(abortAfter, timeout time.Duration) (time.Duration, error) {
+	if timeout > 0 && (abortAfter == 0 || timeout < abortAfter) {
+		return timeout, errStepTimeout
+	}
+	return abortAfter, errAborted
+}
+
+// setRequestURI sets URI of the request, which is sent exactly as
+// given. fasthttp only normalizes the path (collapses slashes, resolves
+// dot segments and re-encodes it) when there's no Host header or the
+// URI was parsed, so requestURI must be set after Host and not
+// accessed through req.URI() afterwards.
+func (c *fasthttpClient) setRequestURI(
+	req *fasthttp.Request, requestURI string,
+) {
+	if c.isTLS {
+		req.URI().SetScheme("https")
+	} else {
+		req.URI().SetScheme("http")
+	}
+	if query := perRequestQuery(c.queryTemplates, c.cacheBuster); query != "" {
+		sep := "?"
+		if strings.Contains(requestURI, "?") {
+			sep = "&"
+		}
+		req.SetRequestURI(requestURI + sep + query)
+	} else {
+		req.SetRequestURI(requestURI)
+	}
+}
+
+// fire performs the prepared request and releases it. If body is not
+// nil, the response body is copied to it. timeout is the one of the
+// request, if any.
+func (c *fasthttpClient) fire(
+	req *fasthttp.Request, body *[]byte, timeout time.Duration,
+) (code int, usTaken uint64, err error) {
+	resp := fasthttp.AcquireResponse()
+	if c.ignoreBody && body == nil {
+		// the rest of the response is left unread, so the connection
+		// can't be reused
+		resp.SkipBody = true
+		req.SetConnectionClose()
+	}
+	var traced uint64
+	streamed := false
+	if c.tracer != nil {
+		traced = c.tracer.next()
+		streamed = req.IsBodyStream()
+	}
 	start := time.Now()
-	err = c.client.Do(req, resp)
-	if err != nil {
+	abortAfter, abortErr := abortLimit(
+		c.adaptive.limit(c.abortAfter), timeout,
+	)
+	if abortAfter > 0 {
+		// fasthttp doesn't interrupt the request itself, it's left to
+		// complete in the background
+		err = c.client.DoTimeout(req, resp, abortAfter)
+		if err == fasthttp.ErrTimeout {
+			err = abortErr
+		}
+	} else {
+		err = c.client.Do(req, resp)
+	}
+	if err == fasthttp.ErrBodyTooLarge {
+		code, err = resp.StatusCode(), errOversizedResponse
+	} else if err == io.ErrUnexpectedEOF && c.strictLength &&
+		resp.Header.ContentLength() > 0 {
+		code, err = resp.StatusCode(), errContentLengthMismatch
+	} else if err != nil {
 		code = -1
 	} else {
 		code = resp.StatusCode()
+		respBody := resp.Body()
+		if c.successBytes != nil && code/100 == 2 {
+			c.successBytes.add(fasthttpExchangeSize(req, resp, respBody))
+		}
+		if c.goodput != nil {
+			c.goodput.add(int64(len(respBody)))
+		}
+		if c.compression != nil {
+			compressed := len(respBody)
+			respBody, err = fasthttpDecompressedBody(resp)
+			if err == nil {
+				c.compression.add(int64(compressed), int64(len(respBody)))
+			}
+		}
+		if err == nil && c.grpcWeb != grpcWebNone {
+			code, err = c.grpcWeb.responseCode(
+				code, fasthttpGRPCStatus(resp), respBody,
+			)
+		}
+		if body != nil {
+			*body = append((*body)[:0], respBody...)
+		}
+	}
+	usTaken = durationUs(time.Since(start))
+	if traced > 0 {
+		c.tracer.traceFastHTTP(traced, req, resp, streamed, err)
 	}
-	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
 
 	// release resources
 	fasthttp.ReleaseRequest(req)
@@ -122,12 +517,50 @@ func (c *fasthttpClient) do() (
 type httpClient struct {
 	client *http.Client
 
-	headers http.Header
-	url     *url.URL
-	method  string
+	headers            http.Header
+	headerCasePreserve bool
+	noDefaultHeaders   bool
+	headerRotation     *headerRotation
+	host               string
+	url                *url.URL
+	method             string
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	tracePhases     bool
+	traceDNS        bool
+	abortAfter      time.Duration
+	adaptive        *adaptiveTimeout
+	maxResponseSize uint64
+	strictLength    bool
+	cacheBuster     *cacheBuster
+	queryTemplates  *queryTemplates
+	randomHeaders   *randomHeaders
+	traceContext    *traceContext
+	grpcWeb         grpcWebMode
+	compression     *compressionStats
+	successBytes    *successBytes
+	goodput         *goodputStats
+	httpsUpgrades   *httpsUpgrades
+	ignoreBody      bool
+	oauth2          *oauth2Token
+	bodyDir         *bodyDir
+	bodyCommand     *bodyCommand
+	methodMix       *methodPicker
+	// closeIgnored is set with HTTP/1.x only, closing an unread body
+	// of HTTP/2 response resets the stream and the connection stays
+	// usable
+	closeIgnored bool
+
+	// writeTimeout and readTimeout are enforced by fire instead of
+	// the client's timeout, if phaseTimeouts is set
+	writeTimeout, readTimeout time.Duration
+	phaseTimeouts             bool
+
+	responseReadDelay time.Duration
+	done              <-chan struct{}
+	interrupted       context.Context
 }
 
 func newHTTPClient(opts *clientOpts) client {
@@ -136,8 +569,14 @@ func newHTTPClient(opts *clientOpts) client {
 		TLSClientConfig:     opts.tlsConfig,
 		MaxIdleConnsPerHost: int(opts.maxConns),
 		DisableKeepAlives:   opts.disableKeepAlives,
+		DisableCompression:  opts.noDefaultHeaders,
+		IdleConnTimeout:     opts.idleTimeout,
+		ReadBufferSize:      opts.readBufferSize,
+		WriteBufferSize:     opts.writeBufferSize,
 	}
-	tr.DialContext = httpDialContextFunc(opts.bytesRead, opts.bytesWritten)
+	tr.DialContext = httpDialContextFunc(
+		opts.dialer, opts.bytesRead, opts.bytesWritten, opts.connsOpened,
+	)
 	if opts.HTTP2 {
 		_ = http2.ConfigureTransport(tr)
 	} else {
@@ -153,71 +592,443 @@ func newHTTPClient(opts *clientOpts) client {
 			return http.ErrUseLastResponse
 		},
 	}
+	if opts.httpsUpgrades != nil {
+		cl.CheckRedirect = checkHTTPSRedirect
+	}
+	if opts.phaseTimeouts {
+		// fire enforces them instead
+		cl.Timeout = 0
+	}
+	if opts.tracer != nil {
+		cl.Transport = &tracingTransport{tr, opts.tracer}
+	}
 	c.client = cl
 
 	c.headers = headersToHTTPHeaders(opts.headers)
+	// net/http takes Host from the request itself, so pull it out of
+	// the headers, whatever case it was specified in
+	for k, v := range c.headers {
+		if strings.EqualFold(k, "Host") {
+			c.host = v[0]
+			delete(c.headers, k)
+		}
+	}
+	if _, ok := c.headers["User-Agent"]; !ok && opts.noDefaultHeaders {
+		// empty one suppresses the default and isn't sent itself
+		c.headers["User-Agent"] = []string{""}
+	}
+	c.headerCasePreserve = opts.headerCasePreserve
+	c.noDefaultHeaders = opts.noDefaultHeaders
 	c.method, c.body, c.bodProd = opts.method, opts.body, opts.bodProd
+	c.tracePhases, c.traceDNS = opts.tracePhases, opts.traceDNS
+	c.abortAfter, c.adaptive = opts.abortAfter, opts.adaptiveTimeout
+	c.writeTimeout, c.readTimeout = opts.writeTimeout, opts.readTimeout
+	c.phaseTimeouts = opts.phaseTimeouts
+	c.strictLength = opts.strictContentLength
+	c.maxResponseSize = opts.maxResponseSize
+	if opts.cacheBust {
+		c.cacheBuster = new(cacheBuster)
+	}
+	c.queryTemplates = opts.queryTemplates
+	c.randomHeaders, c.headerRotation = opts.randomHeaders, opts.headerRotation
+	c.traceContext = opts.traceContext
+	c.grpcWeb, c.compression = opts.grpcWeb, opts.compression
+	c.successBytes, c.httpsUpgrades = opts.successBytes, opts.httpsUpgrades
+	c.goodput = opts.goodput
+	c.ignoreBody, c.oauth2 = opts.ignoreBody, opts.oauth2
+	c.bodyDir, c.bodyCommand = opts.bodyDir, opts.bodyCommand
+	c.methodMix = opts.methodMix
+	c.closeIgnored = opts.ignoreBody && !opts.HTTP2
+	c.responseReadDelay, c.done = opts.responseReadDelay, opts.done
+	c.interrupted = opts.interrupted
 	var err error
 	c.url, err = url.Parse(opts.url)
 	if err != nil {
 		// opts.url guaranteed to be valid at this point
 		panic(err)
 	}
+	if opts.rawPath != "" {
+		c.url = withRawPath(c.url, opts.rawPath)
+	}
 
 	return client(c)
 }
 
 func (c *httpClient) do() (
-	code int, usTaken uint64, err error,
+	code int, usTaken uint64, phases phaseTimings, err error,
 ) {
 	req := &http.Request{}
 
 	req.Header = c.headers
+	if c.oauth2 != nil || c.randomHeaders != nil || c.headerRotation != nil ||
+		c.traceContext != nil {
+		// c.headers are shared by all requests
+		req.Header = c.headers.Clone()
+	}
+	if c.oauth2 != nil {
+		req.Header.Set("Authorization", c.oauth2.authorization())
+	}
+	if c.randomHeaders != nil || c.headerRotation != nil ||
+		c.traceContext != nil {
+		set := func(name, value string) {
+			if c.headerCasePreserve {
+				req.Header[name] = []string{value}
+			} else {
+				req.Header.Set(name, value)
+			}
+		}
+		if c.randomHeaders != nil {
+			c.randomHeaders.set(set)
+		}
+		if c.headerRotation != nil {
+			c.headerRotation.set(set)
+		}
+		if c.traceContext != nil {
+			c.traceContext.set(set)
+		}
+	}
 	req.Method = c.method
+	withBody := true
+	if c.methodMix != nil {
+		m := c.methodMix.pick()
+		req.Method, withBody = m.method, m.write
+		defer func() { m.done(code, err) }()
+	}
 	req.URL = c.url
+	if query := perRequestQuery(c.queryTemplates, c.cacheBuster); query != "" {
+		u := *c.url
+		u.RawQuery = appendQuery(u.RawQuery, query)
+		req.URL = &u
+	}
 
-	if host := req.Header.Get("Host"); host != "" {
-		req.Host = host
+	if c.host != "" {
+		req.Host = c.host
 	}
 
-	if c.body != nil {
+	if c.bodyDir != nil {
+		f := c.bodyDir.pick()
+		bs, bserr := f.open()
+		if bserr != nil {
+			c.bodyDir.done(f, 0, bserr)
+			return 0, 0, phases, bserr
+		}
+		req.ContentLength = f.size
+		if f.size > 0 {
+			req.Body = bs
+		} else {
+			// zero length with a body means it's unknown
+			_ = bs.Close()
+		}
+		code, usTaken, phases, err = c.fire(req, nil, 0)
+		c.bodyDir.done(f, code, err)
+		return
+	}
+	if !withBody {
+		return c.fire(req, nil, 0)
+	}
+	if c.bodyCommand != nil {
+		body, berr := c.bodyCommand.next()
+		if berr != nil {
+			return 0, 0, phases, berr
+		}
+		req.ContentLength = int64(len(body))
+		if len(body) > 0 {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+	} else if c.body != nil {
 		br := strings.NewReader(*c.body)
 		req.ContentLength = int64(len(*c.body))
 		req.Body = ioutil.NopCloser(br)
 	} else {
 		bs, bserr := c.bodProd()
 		if bserr != nil {
-			return 0, 0, bserr
+			return 0, 0, phases, bserr
 		}
 		req.Body = bs
 	}
 
-	start := time.Now()
+	return c.fire(req, nil, 0)
+}
+
+func (c *httpClient) doRequest(r *request) (
+	code int, usTaken uint64, body []byte, err error,
+) {
+	req := &http.Request{}
+
+	req.Header = http.Header{}
+	if c.oauth2 != nil {
+		req.Header.Set("Authorization", c.oauth2.authorization())
+	}
+	for _, h := range *r.headers {
+		if strings.EqualFold(h.key, "Host") {
+			req.Host = h.value
+		} else if c.headerCasePreserve {
+			req.Header[h.key] = []string{h.value}
+		} else {
+			req.Header.Set(h.key, h.value)
+		}
+	}
+	if req.Host == "" {
+		req.Host = c.host
+	}
+	if _, ok := req.Header["User-Agent"]; !ok && c.noDefaultHeaders {
+		req.Header["User-Agent"] = []string{""}
+	}
+	req.Method = r.method
+	req.URL = r.url
+	if query := perRequestQuery(c.queryTemplates, c.cacheBuster); query != "" {
+		u := *r.url
+		u.RawQuery = appendQuery(u.RawQuery, query)
+		req.URL = &u
+	}
+	if r.body != "" {
+		req.ContentLength = int64(len(r.body))
+		req.Body = ioutil.NopCloser(strings.NewReader(r.body))
+	}
+
+	if r.readBody {
+		code, usTaken, _, err = c.fire(req, &body, r.timeout)
+	} else {
+		code, usTaken, _, err = c.fire(req, nil, r.timeout)
+	}
+	return
+}
+
+// fire performs the prepared request, measuring its phases if
+// requested. If body is not nil, the response body is stored in it.
+// timeout is the one of the request, if any.
+func (c *httpClient) fire(
+	req *http.Request, body *[]byte, timeout time.Duration,
+) (code int, usTaken uint64, phases phaseTimings, err error) {
+	ctx := context.Background()
+	if c.interrupted != nil {
+		ctx = c.interrupted
+	}
+	abortAfter, abortErr := abortLimit(
+		c.adaptive.limit(c.abortAfter), timeout,
+	)
+	if abortAfter > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, abortAfter)
+		defer cancel()
+	}
+	abortCtx := ctx
+
+	// Trace hooks may be called from transport's goroutines, hence
+	// atomics. All values are in nanoseconds since start, but dnsTaken,
+	// which is set along with dnsDone.
+	var gotConn, wroteRequest, dnsStart, dnsTaken int64
+	var dnsDone uint32
+	var start time.Time
+	if c.tracePhases || c.traceDNS {
+		trace := new(httptrace.ClientTrace)
+		if c.tracePhases {
+			trace.GotConn = func(httptrace.GotConnInfo) {
+				atomic.StoreInt64(&gotConn, int64(time.Since(start)))
+			}
+			trace.WroteRequest = func(httptrace.WroteRequestInfo) {
+				atomic.StoreInt64(&wroteRequest, int64(time.Since(start)))
+			}
+		}
+		if c.traceDNS {
+			trace.DNSStart = func(httptrace.DNSStartInfo) {
+				atomic.StoreInt64(&dnsStart, int64(time.Since(start)))
+			}
+			trace.DNSDone = func(httptrace.DNSDoneInfo) {
+				atomic.StoreInt64(&dnsTaken,
+					int64(time.Since(start))-atomic.LoadInt64(&dnsStart))
+				atomic.StoreUint32(&dnsDone, 1)
+			}
+		}
+		ctx = httptrace.WithClientTrace(ctx, trace)
+	}
+	var hop *upgradeHop
+	if c.httpsUpgrades != nil {
+		hop = new(upgradeHop)
+		ctx = context.WithValue(ctx, upgradeHopKey{}, hop)
+	}
+	var deadlines *phaseDeadlines
+	if c.phaseTimeouts {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		deadlines = newPhaseDeadlines(c.writeTimeout, c.readTimeout, cancel)
+		// hooks of the trace above, if any, are still called
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			WroteRequest: func(httptrace.WroteRequestInfo) {
+				deadlines.wrote()
+			},
+		})
+	}
+	if abortAfter > 0 || c.tracePhases || c.traceDNS ||
+		c.interrupted != nil || hop != nil || deadlines != nil {
+		req = req.WithContext(ctx)
+	}
+	if c.closeIgnored && body == nil {
+		// unread bodies aren't drained, so the connection can't be
+		// reused either
+		req.Close = true
+	}
+
+	start = time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
 		code = -1
 	} else {
 		code = resp.StatusCode
 
-		_, berr := io.Copy(ioutil.Discard, resp.Body)
-		if berr != nil {
-			err = berr
+		var src io.Reader = resp.Body
+		var wire *countingReader
+		if c.compression != nil || c.successBytes != nil ||
+			c.goodput != nil {
+			wire = &countingReader{r: resp.Body}
+			src = wire
+		}
+		if c.compression != nil {
+			src, err = decodingReader(
+				resp.Header.Get("Content-Encoding"), wire,
+			)
+		}
+		if err == nil && (!c.ignoreBody || body != nil) {
+			code, err = c.readBody(resp, src, wire, body)
 		}
 
 		if cerr := resp.Body.Close(); cerr != nil {
 			err = cerr
 		}
+		if c.goodput != nil {
+			c.goodput.add(wire.n)
+		}
+		if err == nil && c.successBytes != nil && code/100 == 2 {
+			c.successBytes.add(httpExchangeSize(
+				req, resp, wire.n, !c.noDefaultHeaders,
+			))
+		}
+	}
+	taken := time.Since(start)
+	usTaken = durationUs(taken)
+	var expired error
+	if deadlines != nil {
+		expired = deadlines.stop()
+	}
+	if hop != nil && !hop.redirected.IsZero() {
+		hopTaken := hop.redirected.Sub(start)
+		c.httpsUpgrades.add(durationUs(hopTaken))
+	}
+	if err != nil && abortCtx.Err() == context.DeadlineExceeded {
+		code, err = -1, abortErr
+	} else if err != nil && c.interrupted != nil &&
+		c.interrupted.Err() != nil {
+		code, err = -1, errRequestCanceled
+	} else if err != nil && expired != nil {
+		code, err = -1, expired
+	}
+
+	if c.tracePhases {
+		conn := time.Duration(atomic.LoadInt64(&gotConn))
+		wrote := time.Duration(atomic.LoadInt64(&wroteRequest))
+		if conn > 0 && wrote > 0 {
+			// the response may be read before the request is written
+			// completely, e.g. if the server rejects its body early
+			if wrote > taken {
+				wrote = taken
+			}
+			if conn > wrote {
+				conn = wrote
+			}
+			// phases are differences of rounded values, so that they
+			// sum to usTaken exactly
+			phases.usConnect = durationUs(conn)
+			phases.usWrite = durationUs(wrote) - phases.usConnect
+			phases.usRead = usTaken - durationUs(wrote)
+			phases.measured = true
+		}
+	}
+	if atomic.LoadUint32(&dnsDone) == 1 {
+		phases.usDNS = durationUs(time.Duration(atomic.LoadInt64(&dnsTaken)))
+		phases.dnsMeasured = true
 	}
-	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
 
 	return
 }
 
-func headersToFastHTTPHeaders(h *headersList) *fasthttp.RequestHeader {
+// readBody reads the (decoded) body of resp from src, validating it
+// as specified by options. wire, if not nil, counts bytes as received,
+// it's always set with --decompress.
+func (c *httpClient) readBody(
+	resp *http.Response, src io.Reader, wire *countingReader, body *[]byte,
+) (code int, err error) {
+	code = resp.StatusCode
+	if c.responseReadDelay > 0 {
+		src = &pacedReader{
+			r:     ioutil.NopCloser(src),
+			size:  responseReadChunkSize,
+			delay: c.responseReadDelay,
+			done:  c.done,
+		}
+	}
+	if c.maxResponseSize > 0 {
+		// read one byte more to tell whether the limit was exceeded,
+		// the connection is closed then, since the body is not drained
+		src = io.LimitReader(src, int64(c.maxResponseSize)+1)
+	}
+	var dst io.Writer = ioutil.Discard
+	var respBody *bytes.Buffer
+	if c.grpcWeb != grpcWebNone || body != nil {
+		respBody = new(bytes.Buffer)
+		dst = respBody
+	}
+	n, err := copyBody(dst, src)
+	if err == io.ErrUnexpectedEOF && c.strictLength &&
+		resp.ContentLength > 0 {
+		return code, errContentLengthMismatch
+	}
+	if err != nil {
+		return code, err
+	}
+	if c.compression != nil {
+		c.compression.add(wire.n, n)
+	}
+	if c.maxResponseSize > 0 && uint64(n) > c.maxResponseSize {
+		err = errOversizedResponse
+	} else if c.grpcWeb != grpcWebNone {
+		code, err = c.grpcWeb.responseCode(
+			code, httpGRPCStatus(resp), respBody.Bytes(),
+		)
+	}
+	if body != nil {
+		*body = respBody.Bytes()
+	}
+	return code, err
+}
+
+var bodyCopyBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, bodyCopyBufferSize)
+		return &buf
+	},
+}
+
+// copyBody copies the body from src to dst through a pooled buffer, so
+// that memory used to read bodies that aren't kept doesn't grow with
+// their size or the number of connections reading them.
+func copyBody(dst io.Writer, src io.Reader) (int64, error) {
+	buf := bodyCopyBuffers.Get().(*[]byte)
+	defer bodyCopyBuffers.Put(buf)
+	// hide ReadFrom of dst, if any, which would bypass the buffer
+	return io.CopyBuffer(struct{ io.Writer }{dst}, src, *buf)
+}
+
+func headersToFastHTTPHeaders(
+	h *headersList, preserveCase bool,
+) *fasthttp.RequestHeader {
 	if len(*h) == 0 {
 		return nil
 	}
 	res := new(fasthttp.RequestHeader)
+	if preserveCase {
+		res.DisableNormalizing()
+	}
 	for _, header := range *h {
 		res.Set(header.key, header.value)
 	}
@@ -3,16 +3,41 @@ package main
 import (
 	"bytes"
 	"crypto/tls"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 )
 
+func TestDurationUsIsNeverNegative(t *testing.T) {
+	// Round(0) strips monotonic clock reading, so this is the same as
+	// wall clock going back by an hour after the request started
+	skewed := time.Now().Add(time.Hour).Round(0)
+	expectations := []struct {
+		in  time.Duration
+		out uint64
+	}{
+		{time.Since(skewed), 0},
+		{-time.Nanosecond, 0},
+		{0, 0},
+		{999 * time.Nanosecond, 0},
+		{1500 * time.Nanosecond, 1},
+		{2 * time.Second, 2000000},
+	}
+	for _, e := range expectations {
+		if act := durationUs(e.in); act != e.out {
+			t.Errorf("Expected %v for %v, but got %v", e.out, e.in, act)
+		}
+	}
+}
+
 func TestShouldReturnNilIfNoHeadersWhereSet(t *testing.T) {
 	h := new(headersList)
-	if headersToFastHTTPHeaders(h) != nil {
+	if headersToFastHTTPHeaders(h, false) != nil {
 		t.Fail()
 	}
 }
@@ -33,7 +58,7 @@ func TestShouldProperlyConvertToHttpHeaders(t *testing.T) {
 			t.Error(err)
 		}
 	}
-	fh := headersToFastHTTPHeaders(h)
+	fh := headersToFastHTTPHeaders(h, false)
 	{
 		e, a := []byte("application/json"), fh.Peek("Content-Type")
 		if !bytes.Equal(e, a) {
@@ -56,6 +81,17 @@ func TestShouldProperlyConvertToHttpHeaders(t *testing.T) {
 	}
 }
 
+func TestHTTPHeadersKeepNamesAsGiven(t *testing.T) {
+	h := new(headersList)
+	if err := h.Set("x-my-header: value"); err != nil {
+		t.Fatal(err)
+	}
+	nh := headersToHTTPHeaders(h)
+	if v, ok := nh["x-my-header"]; !ok || v[0] != "value" {
+		t.Errorf("Expected header name to be kept as given, but got %v", nh)
+	}
+}
+
 func TestHTTP2Client(t *testing.T) {
 	responseSize := 1024
 	response := bytes.Repeat([]byte{'a'}, responseSize)
@@ -103,7 +139,7 @@ func TestHTTP2Client(t *testing.T) {
 		bytesRead:    &bytesRead,
 		bytesWritten: &bytesWritten,
 	})
-	code, _, err := c.do()
+	code, _, _, err := c.do()
 	if err != nil {
 		t.Error(err)
 		return
@@ -163,7 +199,7 @@ func TestHTTP1Clients(t *testing.T) {
 	}
 	for _, c := range clients {
 		bytesRead, bytesWritten = 0, 0
-		code, _, err := c.do()
+		code, _, _, err := c.do()
 		if err != nil {
 			t.Error(err)
 			return
@@ -179,3 +215,128 @@ func TestHTTP1Clients(t *testing.T) {
 		}
 	}
 }
+
+func TestClientsCloseIdleConnections(t *testing.T) {
+	var newConns int64
+	s := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {},
+	))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&newConns, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	idleTimeout := 20 * time.Millisecond
+	factories := map[string]func(*clientOpts) client{
+		"fasthttp": newFastHTTPClient,
+		"net/http": newHTTPClient,
+	}
+	for name, newClient := range factories {
+		atomic.StoreInt64(&newConns, 0)
+		bytesRead, bytesWritten := int64(0), int64(0)
+		c := newClient(&clientOpts{
+			maxConns:    1,
+			timeout:     defaultTimeout,
+			idleTimeout: idleTimeout,
+
+			headers: new(headersList),
+			url:     s.URL,
+			method:  "GET",
+			body:    new(string),
+
+			bytesRead:    &bytesRead,
+			bytesWritten: &bytesWritten,
+		})
+		for i := 0; i < 2; i++ {
+			if _, _, _, err := c.do(); err != nil {
+				t.Error(err)
+			}
+			time.Sleep(idleTimeout * 10)
+		}
+		if n := atomic.LoadInt64(&newConns); n != 2 {
+			t.Errorf("%v: expected idle connection to be closed and "+
+				"reopened, but got %v connections", name, n)
+		}
+	}
+}
+
+func TestClientsBufferSizes(t *testing.T) {
+	bigHeader := strings.Repeat("x", 8*1024)
+	s := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Big", bigHeader)
+		},
+	))
+	defer s.Close()
+	bytesRead, bytesWritten := int64(0), int64(0)
+	newOpts := func(readBufferSize, writeBufferSize int) *clientOpts {
+		return &clientOpts{
+			headers:         new(headersList),
+			url:             s.URL,
+			method:          "GET",
+			body:            new(string),
+			timeout:         defaultTimeout,
+			readBufferSize:  readBufferSize,
+			writeBufferSize: writeBufferSize,
+			bytesRead:       &bytesRead,
+			bytesWritten:    &bytesWritten,
+		}
+	}
+	// fasthttp needs response headers to fit into the read buffer,
+	// which is 4KB by default
+	if _, _, _, err := newFastHTTPClient(newOpts(0, 0)).do(); err == nil {
+		t.Error("Expected headers not to fit into default read buffer")
+	}
+	clients := map[string]client{
+		"fasthttp": newFastHTTPClient(newOpts(16*1024, 16*1024)),
+		"net/http": newHTTPClient(newOpts(16, 16)),
+	}
+	for name, c := range clients {
+		code, _, _, err := c.do()
+		if err != nil || code != http.StatusOK {
+			t.Errorf("%v: expected 200, but got %v (%v)", name, code, err)
+		}
+	}
+}
+
+func TestHTTPClientLargeResponsesAllocations(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 4<<20)
+	s := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(body)
+		},
+	))
+	defer s.Close()
+	bytesRead, bytesWritten := int64(0), int64(0)
+	c := newHTTPClient(&clientOpts{
+		headers:      new(headersList),
+		url:          s.URL,
+		method:       "GET",
+		body:         new(string),
+		timeout:      defaultTimeout,
+		bytesRead:    &bytesRead,
+		bytesWritten: &bytesWritten,
+	})
+	// warm up the connection and buffers
+	if code, _, _, err := c.do(); err != nil || code != http.StatusOK {
+		t.Fatalf("Expected 200, but got %v (%v)", code, err)
+	}
+	const numReqs = 10
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < numReqs; i++ {
+		if code, _, _, err := c.do(); err != nil || code != http.StatusOK {
+			t.Fatalf("Expected 200, but got %v (%v)", code, err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	// bodies are read through a fixed-size buffer, so allocations don't
+	// depend on their size
+	perRequest := (after.TotalAlloc - before.TotalAlloc) / numReqs
+	if perRequest > uint64(len(body))/16 {
+		t.Errorf("Expected allocations to be bounded, but got %v bytes "+
+			"per request with %v bytes bodies", perRequest, len(body))
+	}
+}
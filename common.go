@@ -12,7 +12,135 @@ const (
 	rateLimitInterval = 10 * time.Millisecond
 	oneSecond         = 1 * time.Second
 
+	readyProbeInterval = 100 * time.Millisecond
+	// with --dial-retries failed dials are retried after
+	// dialRetryBackoff, doubled for each next retry up to
+	// dialRetryMaxBackoff
+	dialRetryBackoff    = 50 * time.Millisecond
+	dialRetryMaxBackoff = time.Second
+	// --recovery-probe probes the target every recoveryProbeInterval
+	// and reports latencies of each recoveryProbeStep
+	recoveryProbeInterval = 100 * time.Millisecond
+	recoveryProbeStep     = time.Second
+
+	adaptiveTimeoutInterval   = 1 * time.Second
+	adaptiveTimeoutMinSamples = 100
+	adaptiveTimeoutFloor      = 1 * time.Millisecond
+
+	// --connections-auto doubles connections every autoConnsStep
+	// while throughput grows by more than autoConnsMinGain and mean
+	// latency stays within autoConnsLatencyFactor of the best step
+	autoConnsStep          = 1 * time.Second
+	autoConnsMinGain       = 0.05
+	autoConnsLatencyFactor = 2.0
+
+	// --find-max-rps probes each rate for findMaxRPSProbe, starting
+	// at --rate or findMaxRPSStart, until the highest sustained one is
+	// known within findMaxRPSPrecision. A rate is sustained if at
+	// least findMaxRPSMinAchieved of it was achieved within the error
+	// rate and p99 latency thresholds.
+	findMaxRPSProbe       = 1 * time.Second
+	findMaxRPSStart       = 100
+	findMaxRPSPrecision   = 0.05
+	findMaxRPSMinAchieved = 0.9
+	// in percents
+	defaultMaxErrorRate = 1.0
+
+	// --target-p99 measures p99 latency every targetP99Interval and
+	// changes the rate by targetP99Gain times its relative difference
+	// from the target, but by no more than targetP99MaxStep of it. The
+	// rate is settled once p99 stays within targetP99Tolerance of the
+	// target for targetP99SettleIntervals in a row. The rate starts at
+	// --rate or targetP99Start.
+	targetP99Interval        = 500 * time.Millisecond
+	targetP99Gain            = 0.5
+	targetP99MaxStep         = 0.5
+	targetP99Tolerance       = 0.1
+	targetP99SettleIntervals = 3
+	targetP99Start           = 100
+
+	// --print-pipeline-stats samples pipelines' depth every
+	// pipelineSampleInterval
+	pipelineSampleInterval = 10 * time.Millisecond
+	// each pipelined request has its own worker, so --pipeline can't
+	// exceed maxPipeline and there can't be more than
+	// maxPipelineWorkers of them over all connections
+	maxPipeline        = 1024
+	maxPipelineWorkers = 1 << 20
+
+	// --rate-schedule adjusts the rate every rateScheduleInterval
+	rateScheduleInterval = 100 * time.Millisecond
+	// offsets are capped, so that they fit into time.Duration
+	maxRateScheduleOffset = 100 * 365 * 24 * 3600
+
+	// latencies are printed with defaultLatencyPrecision digits
+	// after the decimal point, unless --percentile-precision is set
+	defaultLatencyPrecision = 2
+	maxLatencyPrecision     = 6
+
+	// --body-dir files are kept in memory while their total size is
+	// within bodyDirBufferSize
+	bodyDirBufferSize = 64 << 20
+
+	// --body-command is run by bodyCommandRunners goroutines, which
+	// keep up to bodyCommandPoolSize bodies ready
+	bodyCommandRunners  = 4
+	bodyCommandPoolSize = 256
+
+	// weights of --method-mix add up to at most maxMethodMixWeight
+	maxMethodMixWeight = 10000
+
+	// shares of codes may differ from --expect-distribution by
+	// defaultDistributionTolerance percentage points, unless
+	// --distribution-tolerance is set
+	defaultDistributionTolerance = 5
+	// at most defaultLatencyGrace percent of requests may exceed
+	// --max-latency-p99, unless --latency-grace is set
+	defaultLatencyGrace = 1
+
+	// --trace-first writes at most traceMaxBodySize bytes of each body
+	traceMaxBodySize = 64 << 10
+
+	// requests wait for --output-raw-latencies to be written once
+	// rawLatenciesQueue of them are queued
+	rawLatenciesQueue = 1 << 16
+
+	// samples written to --csv-out are flushed every
+	// rpsCSVFlushInterval
+	rpsCSVFlushInterval = time.Second
+
+	// values of --random-header headers are defaultRandomHeaderBytes
+	// long, unless --random-header-bytes is set, and drawn from
+	// a source seeded with randomHeaderSeed
+	defaultRandomHeaderBytes = 16
+	maxRandomHeaderBytes     = 8192
+	randomHeaderSeed         = 1
+
+	// ids of --traceparent headers are drawn from a source seeded with
+	// traceparentSeed
+	traceparentSeed = 2
+
+	// --read-buffer-size and --write-buffer-size can't exceed
+	// maxBufferSize
+	maxBufferSize = 1 << 30
+
+	defaultChunkSize      = 1024
+	responseReadChunkSize = 1024
+	// response bodies are read through pooled buffers of
+	// bodyCopyBufferSize bytes
+	bodyCopyBufferSize = 32 << 10
+
+	// OAuth2 tokens are refreshed oauth2RefreshMargin before they
+	// expire (or halfway, if they're valid for less than twice that),
+	// failed attempts are retried every oauth2RetryInterval
+	oauth2RefreshMargin   = 30 * time.Second
+	oauth2RetryInterval   = 1 * time.Second
+	oauth2MaxResponseSize = 1 << 20
+
 	exitFailure = 1
+	// --exit-code-* flags can't be set above maxExitCode, higher codes
+	// are reserved by shells
+	maxExitCode = 125
 )
 
 var (
@@ -24,12 +152,20 @@ var (
 	defaultTestDuration  = 10 * time.Second
 	defaultNumberOfConns = uint64(125)
 	defaultTimeout       = 2 * time.Second
+	defaultNotifyTimeout = 5 * time.Second
+
+	defaultSlowlorisDelay = 1 * time.Second
+
+	defaultRegressionThreshold = 10.0
+
+	defaultSummaryPercentiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+	defaultLatencyPercentiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
 
 	httpMethods = []string{
 		"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS",
-		"PATCH",
+		"PATCH", "CONNECT",
 	}
-	cantHaveBody = []string{"GET", "HEAD"}
+	cantHaveBody = []string{"GET", "HEAD", "CONNECT"}
 
 	errInvalidURL = errors.New(
 		"No hostname or invalid scheme")
@@ -41,19 +177,334 @@ var (
 		"Invalid test duration(must be >= 1s)")
 	errNegativeTimeout = errors.New(
 		"Timeout can't be negative")
+	errPhaseTimeoutNotSupported = errors.New("--write-timeout and " +
+		"--read-timeout can't be used with --raw-request-file, " +
+		"--slowloris or CONNECT")
 	errBodyNotAllowed = errors.New(
-		"GET and HEAD requests cannot have body")
+		"GET, HEAD and CONNECT requests cannot have body")
 	errNoPathToCert = errors.New(
 		"No Path to TLS Client Certificate")
 	errNoPathToKey = errors.New(
 		"No Path to TLS Client Certificate Private Key")
+	errCertKeyCount = errors.New(
+		"--cert and --key must list the same number of paths")
 	errZeroRate = errors.New(
 		"Rate can't be less than 1")
+	errZeroRateBytes = errors.New(
+		"Byte rate can't be less than 1 byte per second")
+	errRateStepWithoutRate  = errors.New("--rate-step requires --rate")
+	errZeroRateStep         = errors.New("Rate step can't be less than 1")
+	errRateStepNotSupported = errors.New(
+		"--rate-step isn't supported on this platform")
+	errZeroBurst        = errors.New("Burst can't be less than 1")
+	errBurstWithoutRate = errors.New("--burst requires --rate, " +
+		"--find-max-rps, --target-p99 or --rate-schedule")
+
+	errFindMaxRPSTimed = errors.New(
+		"--find-max-rps requires a timed test (-d)")
+	errFindMaxRPSConflict = errors.New(
+		"--find-max-rps can't be used with --rate-step or --connections-auto")
+	errFindMaxRPSThresholds = errors.New(
+		"--max-p99 requires --find-max-rps")
+	errMaxErrorRate = errors.New(
+		"--max-error-rate must be between 0 and 100 percent")
+	errNegativeMaxP99 = errors.New("--max-p99 can't be negative")
+
+	errNegativeTargetP99 = errors.New("--target-p99 can't be negative")
+	errTargetP99Timed    = errors.New(
+		"--target-p99 requires a timed test (-d)")
+	errTargetP99Conflict = errors.New("--target-p99 can't be used with " +
+		"--rate-step, --find-max-rps, --rate-schedule or --connections-auto")
+
+	errRateScheduleConflict = errors.New("--rate-schedule can't be used " +
+		"with --rate, --rate-step or --find-max-rps")
+	errRateScheduleEmpty = errors.New("Rate schedule is empty")
+
+	errMethodMixWeights = errors.New(
+		"Weights of --method-mix can't add up to more than 10000")
+	errMethodMixConflict = errors.New("--method-mix can't be used with " +
+		"-m, --body-dir, --grpc-web, --scenario, --raw-request-file " +
+		"or --slowloris")
+
+	errInvalidRandomHeader = errors.New(
+		"--random-header must be a header name")
+	errRandomHeaderBytes = errors.New(
+		"--random-header-bytes must be between 1 and 8192")
+	errRandomHeaderBytesWithoutHeader = errors.New(
+		"--random-header-bytes requires --random-header")
+	errRandomHeaderConflict = errors.New("--random-header can't be " +
+		"used with --scenario, --raw-request-file or --slowloris")
+	errCompareClientsConflict = errors.New("--compare-clients can't be " +
+		"used with --raw-request-file, --slowloris, --connect-target, " +
+		"--tui, --report-template-file or formats other than plain-text")
+	errCompareClientsOutputs = errors.New("--compare-clients can't be " +
+		"used with checks of results (--compare-baseline, --min-rps, " +
+		"--expect-distribution, --max-latency-p99, --max-error-rate) or " +
+		"outputs of a single test (--trace-file, --output-raw-latencies, " +
+		"--csv-out, --per-conn-stats, --rps-histogram-file, " +
+		"--notify-url, --recovery-probe)")
+
+	errExitCode = errors.New(
+		"--exit-code-* flags must be between 1 and 125")
+
+	errStatsResetConflict = errors.New("--stats-reset-on-code can't be " +
+		"used with --scenario or --slowloris")
+
+	errTraceparentConflict = errors.New("--traceparent can't be " +
+		"used with --scenario, --raw-request-file or --slowloris")
+	errTraceparentOptions = errors.New(
+		"--traceparent-span-id and --tracestate require --traceparent")
+	errInvalidSpanID = errors.New("--traceparent-span-id must be " +
+		"16 lowercase hex digits, not all zeros")
+	errInvalidTracestate = errors.New(
+		"--tracestate can't contain line breaks")
+
+	errHeaderRotateConflict = errors.New("--header-rotate can't be " +
+		"used with --scenario, --raw-request-file or --slowloris")
+
 	errBodyProvidedTwice = errors.New("Use either --body or --body-file")
+	errBodyDirConflict   = errors.New("--body-dir can't be used with " +
+		"--body, --body-file, --stream, --grpc-web, --scenario, " +
+		"--raw-request-file or --slowloris")
+	errBodyDirEmpty = errors.New("No regular files in --body-dir")
+
+	errBodyCommandConflict = errors.New("--body-command can't be used " +
+		"with --body, --body-file, --body-dir, --stream, --grpc-web, " +
+		"--scenario, --raw-request-file or --slowloris")
+	errEmptyBodyCommand = errors.New("--body-command can't be empty")
+
+	errHeaderCasePreserveHTTP2 = errors.New(
+		"HTTP/2 header names are always lower-case, " +
+			"--header-case-preserve can't be used with --http2")
+	errPipelineNotSupported = errors.New(
+		"Pipelining is only supported by fasthttp client")
+	// errWriteTimeout and errReadTimeout are reported by net/http
+	// clients for requests that exceeded --write-timeout or
+	// --read-timeout
+	errWriteTimeout = errors.New("write timeout")
+	errReadTimeout  = errors.New("read timeout")
+
+	errPipelineStatsWithoutPipeline = errors.New(
+		"--print-pipeline-stats requires --pipeline")
+	errPipelineTooDeep = errors.New(
+		"--pipeline must be at most 1024")
+	errTooManyPipelinedRequests = errors.New(
+		"--pipeline times the number of connections must be " +
+			"at most 1048576")
+	errMaxResponseSizePipeline = errors.New(
+		"--max-response-size can't be used with --pipeline")
+	errNoDefaultHeadersPipeline = errors.New(
+		"--no-default-headers can't be used with --pipeline")
+	errConnectionsAutoPipeline = errors.New(
+		"--connections-auto can't be used with --pipeline")
+
+	errInvalidNotifyURL = errors.New(
+		"No hostname or invalid scheme in --notify-url")
+
+	errBaselineNoP99 = errors.New(
+		"Baseline has no p99 latency, was it recorded with --latencies " +
+			"and, if --summary-percentiles was set, with 99 among them?")
+	errNegativeRegressionThreshold = errors.New(
+		"Regression threshold can't be negative")
+	errNonPositiveMinRPS = errors.New("--min-rps must be positive")
+
+	errDistributionTotal = errors.New(
+		"Percentages of --expect-distribution must add up to 100")
+	errToleranceWithoutDistribution = errors.New(
+		"--distribution-tolerance requires --expect-distribution")
+	errDistributionTolerance = errors.New(
+		"--distribution-tolerance must be between 0 and 100")
+	errNonPositiveMaxLatency = errors.New(
+		"--max-latency-p99 must be positive")
+	errGraceWithoutMaxLatency = errors.New(
+		"--latency-grace requires --max-latency-p99")
+	errLatencyGrace = errors.New(
+		"--latency-grace must be at least 0 and less than 100")
+
+	errAborted = errors.New(
+		"Request aborted after exceeding --abort-slower-than")
+	errAbortNotBelowTimeout = errors.New(
+		"--abort-slower-than must be less than --timeout")
+	errOversizedResponse = errors.New(
+		"Oversized response (exceeds --max-response-size)")
+	errZeroMaxResponseSize = errors.New(
+		"Max response size can't be less than 1 byte")
+	errContentLengthMismatch = errors.New(
+		"Response body is shorter than its Content-Length")
+	errStrictContentLengthNotSupported = errors.New(
+		"--strict-content-length can't be used with --ignore-body, " +
+			"--raw-request-file, --slowloris or CONNECT")
+
+	errZeroBufferSize = errors.New(
+		"Buffer size can't be less than 1 byte")
+	errBufferSizeTooLarge = errors.New("Buffer size can't exceed 1GB")
+	errBufferSizeHTTP2    = errors.New("--read-buffer-size and " +
+		"--write-buffer-size can't be used with --http2")
+
+	errGRPCWebMethod = errors.New("gRPC-Web requires -m POST")
+	errGRPCWebStream = errors.New(
+		"gRPC-Web messages can't be streamed, use --body or --body-file")
+	errNoGRPCStatus = errors.New("No grpc-status in gRPC-Web response")
+
+	errNegativeLatencyCap       = errors.New("Latency cap can't be negative")
+	errNegativeSnapshotInterval = errors.New(
+		"Snapshot interval can't be negative")
+	errIntervalPercentilesWithoutSnapshots = errors.New(
+		"--report-interval-percentiles requires --snapshot-interval")
+	errSnapshotsWithTUI = errors.New("--snapshot-interval can't be used " +
+		"with --tui, snapshots printed would break the dashboard")
+	errLiveP99WithTUI = errors.New(
+		"--live-p99 can't be used with --tui, which already shows p99")
+	errPrintErrorsOnlyWithTUI = errors.New("--print-errors-only can't " +
+		"be used with --tui, errors printed as they occur would break " +
+		"the dashboard")
+	errLatencyPrecision = errors.New(
+		"Percentile precision can't be more than 6 digits")
+	errGraphFormat = errors.New(
+		"--graph can only be used with plain-text format")
+	errBucketsFormat = errors.New(
+		"--print-histogram-buckets can only be used with plain-text format")
+	errJSONPrettyFormat = errors.New(
+		"--json-pretty can only be used with json format")
+	errReportTemplateFormat = errors.New(
+		"--report-template-file can't be used with --format")
+	errPrintTLSNotHTTPS = errors.New(
+		"--print-tls can only be used with https URLs")
+	errPrintDNSNotSupported = errors.New("--print-dns can't be used " +
+		"with fasthttp, --raw-request-file, --slowloris or CONNECT")
+	errQueueTimeWithoutRate = errors.New("--print-queue-time requires " +
+		"--rate, --rate-bytes, --rate-schedule, --find-max-rps or " +
+		"--target-p99")
+	errHTTPSRedirectNotSupported = errors.New("--follow-https-redirect " +
+		"can't be used with fasthttp, --raw-request-file, --slowloris " +
+		"or CONNECT")
+	errHTTPSRedirectScheme = errors.New(
+		"--follow-https-redirect requires http:// URL")
+
+	errTraceFlags = errors.New(
+		"--trace-first and --trace-file must be used together")
+	errTraceNotSupported = errors.New("--trace-first can't be used " +
+		"with --raw-request-file, --slowloris or CONNECT")
+
+	errAdaptiveTimeoutFactor = errors.New(
+		"--adaptive-timeout factor must be greater than 1")
+
+	errNegativeMaxDuration   = errors.New("Max duration can't be negative")
+	errMaxDurationNotCounted = errors.New(
+		"--max-duration can only be used with -n")
+
+	errHostsNotSupported = errors.New(
+		"--hosts can't be used with --scenario or -m CONNECT")
+	errFewerConnsThanHosts = errors.New(
+		"Number of connections can't be less than number of --hosts")
+
+	errInvalidProxyURL = errors.New(
+		"--proxy must be socks5://[user:password@]host[:port]")
+	errInvalidProxyCredentials = errors.New("SOCKS5 proxy username " +
+		"and password must be from 1 to 255 bytes long")
+
+	errNotReady = errors.New(
+		"Target didn't respond with --ready-status within --wait-ready")
+	errReadyStatusWithoutWait = errors.New("--ready-status can only be " +
+		"used with --wait-ready or --recovery-probe")
+
+	errNoConnectTarget     = errors.New("-m CONNECT requires --connect-target")
+	errConnectTargetMethod = errors.New(
+		"--connect-target can only be used with -m CONNECT")
+	errInvalidConnectTarget = errors.New(
+		"Invalid --connect-target(must be host:port)")
+	errConnectNotSupported = errors.New("CONNECT can't be used with " +
+		"--pipeline, --http2, --grpc-web or --scenario")
+
+	errRawRequestConflict = errors.New("--raw-request-file can't be " +
+		"used with -m, -H, -b, -f, --stream, --pipeline, --http2, " +
+		"--grpc-web, --cache-bust, --query templates or --scenario")
+
+	errAcceptEncodingNotSupported = errors.New("--accept-encoding " +
+		"can't be used with --raw-request-file or -m CONNECT")
+	errAcceptEncodingHeader = errors.New(
+		"Accept-Encoding header can't be used with --accept-encoding")
+	errDecompressWithoutEncoding = errors.New(
+		"--decompress can only be used with --accept-encoding")
+	errSuccessfulThroughputNotSupported = errors.New(
+		"--count-only-successful-for-throughput can't be used with " +
+			"--raw-request-file, --slowloris or CONNECT")
+	errGoodputNotSupported = errors.New("--print-goodput can't be used " +
+		"with --raw-request-file, --slowloris or CONNECT")
+
+	errNegativeClientDelay = errors.New(
+		"--client-delay and --response-read-delay can't be negative")
+	errResponseReadDelayNotSupported = errors.New("--response-read-delay " +
+		"can't be used with fasthttp or --ignore-body")
+
+	errBodyHandlingConflict = errors.New(
+		"--discard-body and --ignore-body can't be used together")
+	errIgnoreBodyNotSupported = errors.New("--ignore-body can't be used " +
+		"with --grpc-web, --decompress, --max-response-size or --pipeline")
+
+	errOAuth2WithoutTokenURL = errors.New("--oauth2-client-id, " +
+		"--oauth2-client-secret and --oauth2-scope require " +
+		"--oauth2-token-url")
+	errInvalidOAuth2TokenURL = errors.New(
+		"--oauth2-token-url must be an absolute http(s) URL")
+	errOAuth2NotSupported = errors.New("--oauth2-token-url can't be " +
+		"used with --raw-request-file, --slowloris or CONNECT")
+	errOAuth2Authorization = errors.New(
+		"--oauth2-token-url can't be used with Authorization header")
+	errOAuth2NoToken = errors.New("No access_token in OAuth2 token response")
+
+	errSlowlorisConflict = errors.New("--slowloris can't be used with " +
+		"-n, -b, -f, --stream, --pipeline, --http2, --grpc-web, " +
+		"--raw-request-file, --hosts, --connections-auto, --scenario, " +
+		"--query templates or CONNECT")
+	errSlowlorisDelay = errors.New(
+		"--slowloris-delay can't be negative")
+	errSlowlorisClosed = errors.New(
+		"connection closed by server")
+	// errTestDone is returned by clients for requests interrupted by
+	// the end of the test, they aren't accounted
+	errTestDone = errors.New("test is done")
+	// errRequestCanceled is returned by clients for requests canceled
+	// on interrupt, they are counted separately
+	errRequestCanceled = errors.New("request canceled")
+
+	errChunkWithoutStream = errors.New(
+		"--chunk-delay and --chunk-size can only be used with --stream")
+	errNegativeChunkDelay = errors.New(
+		"--chunk-delay can't be negative")
+	errZeroChunkSize = errors.New(
+		"--chunk-size must be at least 1 byte")
+
+	errStreamRewind = errors.New(
+		"--stream-rewind can only be used with --stream and -f")
+
+	errEmptyScenario    = errors.New("Scenario has no steps")
+	errScenarioHost     = errors.New("Scenario steps must target the URL's host")
+	errScenarioConflict = errors.New(
+		"--scenario can't be used with --stream or --grpc-web")
+	errCaptureNoVar      = errors.New("Capture has no var name")
+	errCaptureExpression = errors.New(
+		"Capture needs either json or regex expression")
+
+	errScenarioAt = errors.New(
+		"Scenario steps' \"at\" must not be earlier than the previous one's")
+	errScenarioTimeout = errors.New(
+		"Scenario steps' \"timeout\" must be positive")
+	errScenarioTimeoutTooLong = errors.New(
+		"Scenario steps' \"timeout\" can't be longer than --timeout")
+	errStepTimeout = errors.New("Request exceeded the step's timeout")
+	errReplaySpeed = errors.New(
+		"--replay-speed must be positive and requires --scenario")
 
 	errInvalidHeaderFormat = errors.New("Invalid header format")
 	errEmptyPrintSpec      = errors.New(
 		"Empty print spec is not a valid print spec")
+	errInvalidQueryFormat = errors.New(
+		"Invalid query parameter format(must be key=value)")
+	errInvalidRawPath = errors.New("Path sent with --raw-path must " +
+		"start with / and can't have spaces, control characters or #")
+	errRawPathConflict = errors.New("--raw-path can't be used with " +
+		"--scenario, --raw-request-file, --slowloris or CONNECT")
 )
 
 func init() {
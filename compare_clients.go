@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// comparedClients are run in turn with --compare-clients.
+var comparedClients = []clientTyp{fhttp, nhttp1, nhttp2}
+
+type compareClientsError struct {
+	client clientTyp
+	err    error
+}
+
+func (e *compareClientsError) Error() string {
+	return fmt.Sprintf("can't compare with %v client: %v", e.client, e.err)
+}
+
+// clientComparison is what was measured with one of the clients,
+// latencies are in microseconds.
+type clientComparison struct {
+	client    clientTyp
+	rps       float64
+	latency   float64
+	p99       float64
+	completed uint64
+	errors    uint64
+}
+
+// compareClients runs the test described by c with each of
+// comparedClients and prints their results side by side to out.
+// Clients that are left are skipped once the test is interrupted.
+func compareClients(
+	c config, out io.Writer, interrupts <-chan os.Signal,
+) error {
+	rows := make([]clientComparison, 0, len(comparedClients))
+	interrupted := uint32(0)
+	for _, typ := range comparedClients {
+		cc := c
+		cc.compareClients = false
+		cc.clientType = typ
+		b, err := newBombardier(cc)
+		if err != nil {
+			return &compareClientsError{typ, err}
+		}
+		b.out = out
+		if c.printIntro {
+			fmt.Fprintf(out, "Using %v client\n", typ)
+		}
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-interrupts:
+				atomic.StoreUint32(&interrupted, 1)
+				b.interrupt()
+			case <-done:
+			}
+		}()
+		b.bombard()
+		close(done)
+		rows = append(rows, b.clientComparison())
+		if atomic.LoadUint32(&interrupted) == 1 {
+			break
+		}
+	}
+	c.printClientComparison(out, rows)
+	return nil
+}
+
+func (b *bombardier) clientComparison() clientComparison {
+	res := b.gatherInfo().Result
+	row := clientComparison{
+		client:    b.conf.clientType,
+		completed: b.completedRequests(),
+		errors:    b.errors.sum(),
+	}
+	if rps := res.RequestsStats(nil); rps != nil {
+		row.rps = rps.Mean
+	}
+	if lats := res.LatenciesStats([]float64{0.99}); lats != nil {
+		row.latency = lats.Mean
+		row.p99 = float64(lats.Percentiles[0.99])
+	}
+	return row
+}
+
+func (c *config) printClientComparison(
+	out io.Writer, rows []clientComparison,
+) {
+	fmt.Fprintln(out, "Client comparison:")
+	fmt.Fprintf(out, "  %-14v %10v %10v %10v %10v %8v\n", "Client",
+		"Reqs/sec", "Latency", "p99", "Completed", "Errors")
+	for _, r := range rows {
+		fmt.Fprintf(out, "  %-14v %10.2f %10v %10v %10v %8v\n", r.client,
+			r.rps, c.formatLatency(r.latency), c.formatLatency(r.p99),
+			r.completed, r.errors)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompareClients(t *testing.T) {
+	reqs := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&reqs, 1)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	buf := new(bytes.Buffer)
+	err := compareClients(config{
+		numConns:       1,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		format:         knownFormat("plain-text"),
+		compareClients: true,
+	}, buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := numReqs * uint64(len(comparedClients)); reqs != exp {
+		t.Errorf("Expected %v requests, but got %v", exp, reqs)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2+len(comparedClients) {
+		t.Fatalf("Expected a row per client, but got:\n%v", buf)
+	}
+	for i, typ := range comparedClients {
+		fields := strings.Fields(strings.TrimPrefix(
+			lines[2+i], "  "+typ.String()))
+		if !strings.HasPrefix(lines[2+i], "  "+typ.String()) ||
+			len(fields) != 5 || fields[3] != "10" || fields[4] != "0" {
+			t.Errorf("Unexpected row of %v: %q", typ, lines[2+i])
+		}
+	}
+}
+
+func TestCompareClientsChecksEachClient(t *testing.T) {
+	numReqs := uint64(10)
+	c := config{
+		numConns:       1,
+		numReqs:        &numReqs,
+		url:            "http://localhost:8080",
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		format:         knownFormat("plain-text"),
+		pipeline:       2,
+		compareClients: true,
+	}
+	err := c.checkArgs()
+	if _, ok := err.(*compareClientsError); !ok {
+		t.Errorf("Expected the error of some client, but got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -122,3 +123,79 @@ func (c *timedCompletionBarrier) completed() float64 {
 			float64(c.duration.Nanoseconds())
 	}
 }
+
+// compositeCompletionBarrier is done as soon as any of its barriers is
+// done, i.e. counting one bounded by the timed one.
+type compositeCompletionBarrier struct {
+	barriers  []completionBarrier
+	doneChan  chan struct{}
+	closeOnce sync.Once
+}
+
+func newCompositeCompletionBarrier(
+	barriers ...completionBarrier,
+) completionBarrier {
+	c := new(compositeCompletionBarrier)
+	c.barriers = barriers
+	c.doneChan = make(chan struct{})
+	for _, b := range barriers {
+		go func(b completionBarrier) {
+			select {
+			case <-b.done():
+				c.closeOnce.Do(func() {
+					close(c.doneChan)
+				})
+			case <-c.doneChan:
+			}
+		}(b)
+	}
+	return completionBarrier(c)
+}
+
+func (c *compositeCompletionBarrier) tryGrabWork() bool {
+	select {
+	case <-c.doneChan:
+		return false
+	default:
+		for _, b := range c.barriers {
+			if !b.tryGrabWork() {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (c *compositeCompletionBarrier) jobDone() {
+	for _, b := range c.barriers {
+		b.jobDone()
+	}
+}
+
+func (c *compositeCompletionBarrier) done() <-chan struct{} {
+	return c.doneChan
+}
+
+func (c *compositeCompletionBarrier) cancel() {
+	for _, b := range c.barriers {
+		b.cancel()
+	}
+	c.closeOnce.Do(func() {
+		close(c.doneChan)
+	})
+}
+
+func (c *compositeCompletionBarrier) completed() float64 {
+	select {
+	case <-c.doneChan:
+		return 1.0
+	default:
+		max := 0.0
+		for _, b := range c.barriers {
+			if completed := b.completed(); completed > max {
+				max = completed
+			}
+		}
+		return math.Min(max, 1.0)
+	}
+}
@@ -102,6 +102,64 @@ func TestCountedBarrierCancel(t *testing.T) {
 	}
 }
 
+func TestCompositeCompletionBarrierCount(t *testing.T) {
+	b := newCompositeCompletionBarrier(
+		newCountingCompletionBarrier(100),
+		newTimedCompletionBarrier(9000*time.Second),
+	)
+	jobs := 0
+	for b.tryGrabWork() {
+		jobs++
+		b.jobDone()
+	}
+	if jobs != 100 {
+		t.Errorf("Expected 100 jobs to be done, but got %v", jobs)
+	}
+	select {
+	case <-b.done():
+		if c := b.completed(); c != 1.0 {
+			t.Error(c)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Error("Barrier hanged")
+	}
+}
+
+func TestCompositeCompletionBarrierTime(t *testing.T) {
+	duration := 100 * time.Millisecond
+	b := newCompositeCompletionBarrier(
+		newCountingCompletionBarrier(math.MaxUint64),
+		newTimedCompletionBarrier(duration),
+	)
+	if c := b.completed(); c >= 1.0 {
+		t.Errorf("Expected barrier to be incomplete, but got %v", c)
+	}
+	select {
+	case <-b.done():
+		if b.tryGrabWork() {
+			t.Error("Shouldn't be able to grab work after completion")
+		}
+	case <-time.After(duration * 2):
+		t.Error("Barrier hanged")
+	}
+}
+
+func TestCompositeCompletionBarrierCancel(t *testing.T) {
+	b := newCompositeCompletionBarrier(
+		newCountingCompletionBarrier(math.MaxUint64),
+		newTimedCompletionBarrier(9000*time.Second),
+	)
+	b.cancel()
+	select {
+	case <-b.done():
+		if c := b.completed(); c != 1.0 {
+			t.Error(c)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fail()
+	}
+}
+
 func TestTimeBarrierPanicOnBadDuration(t *testing.T) {
 	defer func() {
 		r := recover()
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// completionPrediction is shown on the progress bar, it tells when a
+// counted test will be done or how many requests a timed one will have
+// completed by its end at the average rate so far. done is the share
+// of the test already done, completed is the number of requests.
+func (c *config) completionPrediction(
+	done float64, completed uint64, elapsed time.Duration, now time.Time,
+) string {
+	if elapsed <= 0 || done <= 0 || done >= 1 {
+		return ""
+	}
+	switch c.testType() {
+	case counted:
+		left := time.Duration(float64(elapsed) * (1 - done) / done)
+		return " done at ~" + now.Add(left).Format("15:04:05")
+	case timed:
+		if completed == 0 {
+			return ""
+		}
+		total := float64(completed) * float64(*c.duration) / float64(elapsed)
+		return fmt.Sprintf(" ~%.0f reqs at end", total)
+	}
+	return ""
+}
+
+// updatePostfix shows the prediction, unless the test is over, and p99
+// with --live-p99 after the progress bar.
+func (b *bombardier) updatePostfix(begin time.Time, over bool) {
+	if b.liveLatencies != nil {
+		b.updateLiveP99()
+	}
+	postfix := b.liveP99
+	if !over && !b.bar.NotPrint {
+		completed := b.completedRequests() + b.errors.sum()
+		postfix = b.conf.completionPrediction(
+			b.barrier.completed(), completed, time.Since(begin), time.Now(),
+		) + postfix
+	}
+	b.bar.Postfix(postfix)
+}
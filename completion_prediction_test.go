@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompletionPrediction(t *testing.T) {
+	numReqs := uint64(1000)
+	duration := 10 * time.Second
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	counted := &config{numReqs: &numReqs}
+	timed := &config{duration: &duration}
+	expectations := []struct {
+		conf      *config
+		done      float64
+		completed uint64
+		elapsed   time.Duration
+		out       string
+	}{
+		{counted, 0.25, 250, 30 * time.Second, " done at ~12:01:30"},
+		{counted, 0.5, 500, time.Second, " done at ~12:00:01"},
+		{counted, 0, 0, time.Second, ""},
+		{counted, 1, 1000, time.Second, ""},
+		{counted, 0.5, 500, 0, ""},
+		{timed, 0.2, 400, 2 * time.Second, " ~2000 reqs at end"},
+		{timed, 0.5, 0, 5 * time.Second, ""},
+		{timed, 1, 1000, 10 * time.Second, ""},
+	}
+	for _, e := range expectations {
+		out := e.conf.completionPrediction(e.done, e.completed, e.elapsed, now)
+		if out != e.out {
+			t.Errorf("Expected %q for %v done in %v, but got %q",
+				e.out, e.done, e.elapsed, out)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// compressionStats accounts sizes of response bodies as received and
+// after decompression with --decompress.
+type compressionStats struct {
+	compressed, decompressed int64
+}
+
+func (s *compressionStats) add(compressed, decompressed int64) {
+	atomic.AddInt64(&s.compressed, compressed)
+	atomic.AddInt64(&s.decompressed, decompressed)
+}
+
+func (s *compressionStats) load() (compressed, decompressed int64) {
+	return atomic.LoadInt64(&s.compressed), atomic.LoadInt64(&s.decompressed)
+}
+
+// decodingReader returns r decoded according to Content-Encoding,
+// bodies in other encodings are returned as is. For "deflate" zlib
+// format is expected, as per RFC 7230.
+func decodingReader(encoding string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return zlib.NewReader(r)
+	default:
+		return r, nil
+	}
+}
+
+// countingReader counts bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// fasthttpDecompressedBody returns the body of resp decoded according
+// to Content-Encoding. fasthttp, unlike net/http, never does that by
+// itself.
+func fasthttpDecompressedBody(resp *fasthttp.Response) ([]byte, error) {
+	encoding := string(resp.Header.Peek("Content-Encoding"))
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		return resp.BodyGunzip()
+	case "deflate":
+		return resp.BodyInflate()
+	default:
+		return resp.Body(), nil
+	}
+}
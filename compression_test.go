@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBombardierDecompress(t *testing.T) {
+	testAllClients(t, testBombardierDecompress)
+}
+
+func testBombardierDecompress(clientType clientTyp, t *testing.T) {
+	body := strings.Repeat("compressible ", 1000)
+	var gzipped, deflated bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, _ = io.WriteString(gw, body)
+	_ = gw.Close()
+	zw := zlib.NewWriter(&deflated)
+	_, _ = io.WriteString(zw, body)
+	_ = zw.Close()
+	accepted := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.Header.Get("Accept-Encoding") {
+			case "gzip":
+				rw.Header().Set("Content-Encoding", "gzip")
+				_, _ = rw.Write(gzipped.Bytes())
+			case "deflate":
+				rw.Header().Set("Content-Encoding", "deflate")
+				_, _ = rw.Write(deflated.Bytes())
+			default:
+				_, _ = rw.Write([]byte(body))
+				return
+			}
+			atomic.AddUint64(&accepted, 1)
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		encoding   string
+		compressed int
+	}{
+		{"gzip", gzipped.Len()},
+		{"deflate", deflated.Len()},
+		{"identity", len(body)},
+	}
+	for _, e := range expectations {
+		atomic.StoreUint64(&accepted, 0)
+		numReqs := uint64(4)
+		b, err := newBombardier(config{
+			numConns:       defaultNumberOfConns,
+			numReqs:        &numReqs,
+			url:            s.URL,
+			headers:        new(headersList),
+			timeout:        defaultTimeout,
+			method:         "GET",
+			format:         knownFormat("plain-text"),
+			clientType:     clientType,
+			acceptEncoding: e.encoding,
+			decompress:     true,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		if b.req2xx != numReqs {
+			t.Errorf("%v: expected %v 2xx, but got %v (errors: %v)",
+				e.encoding, numReqs, b.req2xx, b.errors.byFrequency())
+		}
+		if e.encoding != "identity" && accepted != numReqs {
+			t.Errorf("%v: expected %v compressed responses, but got %v",
+				e.encoding, numReqs, accepted)
+		}
+		res := b.gatherInfo().Result
+		expectedCompressed := int64(e.compressed) * int64(numReqs)
+		expectedDecompressed := int64(len(body)) * int64(numReqs)
+		if res.CompressedBytes != expectedCompressed ||
+			res.DecompressedBytes != expectedDecompressed {
+			t.Errorf("%v: expected %v bytes decompressed from %v, "+
+				"but got %v from %v", e.encoding,
+				expectedDecompressed, expectedCompressed,
+				res.DecompressedBytes, res.CompressedBytes)
+		}
+	}
+}
+
+func TestBombardierAcceptEncodingWithoutDecompress(t *testing.T) {
+	testAllClients(t, testBombardierAcceptEncodingWithoutDecompress)
+}
+
+func testBombardierAcceptEncodingWithoutDecompress(
+	clientType clientTyp, t *testing.T,
+) {
+	accepted := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Accept-Encoding") == "br" {
+				atomic.AddUint64(&accepted, 1)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(3)
+	b, err := newBombardier(config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		format:         knownFormat("plain-text"),
+		clientType:     clientType,
+		acceptEncoding: "br",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if accepted != numReqs {
+		t.Errorf("Expected %v requests with Accept-Encoding, but got %v",
+			numReqs, accepted)
+	}
+	if res := b.gatherInfo().Result; res.CompressionRatio() != 0 {
+		t.Errorf("Unexpected compression ratio %v", res.CompressionRatio())
+	}
+}
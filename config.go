@@ -2,32 +2,192 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"sort"
+	"strings"
 	"time"
 )
 
 type config struct {
 	numConns                       uint64
 	numReqs                        *uint64
-	disableKeepAlives         bool
+	disableKeepAlives              bool
 	duration                       *time.Duration
+	maxDuration                    time.Duration
 	url, method, certPath, keyPath string
+	urlTemplate                    string
+	connectTarget                  string
+	rawRequestFile                 string
+	hosts                          *hostList
+	methodMix                      *methodMix
+	proxyURL                       string
+	dialRetries                    uint64
+	alpn                           *alpnList
 	body, bodyFilePath             string
-	stream                         bool
+	bodyDir                        string
+	bodyCommand                    string
+	stream, streamRewind           bool
+	slowloris                      bool
+	slowlorisDelay                 time.Duration
+	chunkDelay                     time.Duration
+	chunkSize                      *uint64
 	headers                        *headersList
-	timeout                        time.Duration
+	// headerTemplates, if set, are headers as given, before ${VAR}
+	// expansion, shown instead of headers in the output
+	headerTemplates             *headersList
+	headerCasePreserve          bool
+	noDefaultHeaders            bool
+	oauth2TokenURL, oauth2Scope string
+	oauth2ClientID              string
+	oauth2ClientSecret          string
+	cacheBust                   bool
+	queryTemplates              *queryList
+	rawPath                     string
+	randomHeaders               *randomHeaderNames
+	randomHeaderBytes           int
+	headerRotate                *rotatedHeaders
+	traceparent                 bool
+	traceparentSpanID           string
+	tracestate                  string
+	acceptEncoding              string
+	decompress                  bool
+	successfulThroughput        bool
+	printGoodput                bool
+	grpcWeb                     grpcWebMode
+	timeout                     time.Duration
+	writeTimeout                time.Duration
+	readTimeout                 time.Duration
+	abortSlowerThan             time.Duration
+	adaptiveTimeout             float64
+	latencyCap                  time.Duration
+	idleTimeout                 time.Duration
 	// TODO(codesenberg): printLatencies should probably be
-	// re(named&maked) into printPercentiles or even let
-	// users provide their own percentiles and not just
-	// calculate for [0.5, 0.75, 0.9, 0.99]
+	// re(named&maked) into printPercentiles
 	printLatencies, insecure bool
+	printWriteRead           bool
+	printDNS                 bool
+	printQueueTime           bool
+	httpsRedirect            bool
+	latencyByCode            bool
+	discardBody, ignoreBody  bool
+	clientDelay              time.Duration
+	responseReadDelay        time.Duration
+	connectionsAuto          bool
+	printGraph               bool
+	printBuckets             bool
+	printTLS                 bool
+	connectionCount          bool
+	jsonPretty               bool
 	rate                     *uint64
+	rateStep                 *uint64
+	burst                    *uint64
+	findMaxRPS               bool
+	rateSchedule             string
+	maxErrorRate             *float64
+	maxP99                   time.Duration
+	targetP99                time.Duration
+	rateBytes                *uint64
+	maxResponseSize          *uint64
+	strictContentLength      bool
+	readBufferSize           *uint64
+	writeBufferSize          *uint64
 	clientType               clientTyp
+	pipeline                 uint64
+	printPipelineStats       bool
+	compareClients           bool
 
 	printIntro, printProgress, printResult bool
+	tui                                    bool
+	liveP99                                bool
+	printErrorsOnly                        bool
+	// snapshotInterval, if non-zero, is the interval between printing
+	// results of the test while it's running
+	snapshotInterval time.Duration
+	// intervalLatency makes snapshots report latencies of requests
+	// completed during the last interval only
+	intervalLatency bool
+
+	// summaryPercentiles, if not nil, overrides percentiles used in
+	// summary outputs (i.e. json)
+	summaryPercentiles *percentileList
+	// latencyPercentiles, if not nil, overrides percentiles printed
+	// with --latencies
+	latencyPercentiles *percentileList
+	// latencyPrecision, if not nil, is the number of digits after
+	// the decimal point in latencies printed
+	latencyPrecision *uint64
 
 	format format
+	// reportTemplateFile, if set, is the path to the template applied
+	// to reportMetrics, which is used instead of format
+	reportTemplateFile string
+
+	// expectStatus, if not nil, is the set of status codes considered
+	// successful, responses with other codes are reported as errors
+	expectStatus *statusRanges
+	// abortOnFirstError, if set, stops the test once any request fails
+	abortOnFirstError bool
+	// statsResetOnCode, if set, discards results of requests completed
+	// before the first 2xx response
+	statsResetOnCode bool
+
+	// scenario, if set, is the path to the file with requests to send
+	// instead of the one specified by url, method, headers and body
+	scenario string
+	// replaySpeed, if not nil, scales the timing of scenario steps
+	// given with "at", i.e. 2 replays them twice as fast
+	replaySpeed *float64
+
+	// perConnStats, if set, is the path to write statistics of each
+	// connection to once the test is finished
+	perConnStats string
+	// rpsHistogramFile, if set, is the path to write the histogram of
+	// requests per second to once the test is finished
+	rpsHistogramFile string
+	// traceFirst, if non-zero, is the number of requests to write with
+	// their responses to traceFile
+	traceFirst uint64
+	traceFile  string
+	// rawLatenciesFile, if set, is the path to write latencies of all
+	// requests to
+	rawLatenciesFile string
+	// rpsCSVFile, if set, is the path to write samples of requests per
+	// second to
+	rpsCSVFile string
+
+	notifyURL     string
+	notifyTimeout time.Duration
+
+	// waitReady, if non-zero, is how long to wait for the target to
+	// respond with one of readyStatus codes before starting the test
+	waitReady   time.Duration
+	readyStatus *statusRanges
+	// recoveryProbe, if non-zero, is how long to probe the target
+	// after the test
+	recoveryProbe time.Duration
+
+	compareBaseline     string
+	regressionThreshold *float64
+	// minRPS, if not nil, is the mean RPS below which the test fails
+	minRPS *float64
+	// expectDistribution, if not nil, is the share of responses with
+	// codes of each class, within distributionTolerance, without which
+	// the test fails
+	expectDistribution    *codeDistribution
+	distributionTolerance *float64
+	// maxLatencyP99, if not nil, is the latency more than latencyGrace
+	// percent of requests may not exceed without failing the test
+	maxLatencyP99 *time.Duration
+	latencyGrace  *float64
+
+	// exitCodeLatency, exitCodeRPS, exitCodeDistribution and
+	// exitCodeErrors, if not nil, are exit codes used instead of
+	// exitFailure when the corresponding check fails
+	exitCodeLatency      *uint64
+	exitCodeRPS          *uint64
+	exitCodeDistribution *uint64
+	exitCodeErrors       *uint64
 }
 
 type testTyp int
@@ -52,10 +212,62 @@ func (c *config) checkArgs() error {
 	checks := []func() error{
 		c.checkURL,
 		c.checkRate,
+		c.checkFindMaxRPS,
+		c.checkTargetP99,
 		c.checkRunParameters,
+		c.checkMaxDuration,
 		c.checkTimeoutDuration,
+		c.checkWaitReady,
 		c.checkHTTPParameters,
+		c.checkConnect,
+		c.checkRawRequest,
+		c.checkSlowloris,
+		c.checkAcceptEncoding,
+		c.checkSuccessfulThroughput,
+		c.checkGoodput,
+		c.checkBodyHandling,
+		c.checkClientDelays,
+		c.checkHosts,
+		c.checkMethodMix,
+		c.checkQueryTemplates,
+		c.checkRandomHeaders,
+		c.checkHeaderRotate,
+		c.checkTraceparent,
+		c.checkProxy,
 		c.checkCertPaths,
+		c.checkHeaderCasePreserve,
+		c.checkOAuth2,
+		c.checkPipeline,
+		c.checkMaxResponseSize,
+		c.checkStrictContentLength,
+		c.checkBufferSizes,
+		c.checkGRPCWeb,
+		c.checkLatencyCap,
+		c.checkSnapshotInterval,
+		c.checkLiveP99,
+		c.checkPrintErrorsOnly,
+		c.checkLatencyPrecision,
+		c.checkGraph,
+		c.checkJSONPretty,
+		c.checkReportTemplate,
+		c.checkPrintTLS,
+		c.checkPrintDNS,
+		c.checkPrintQueueTime,
+		c.checkHTTPSRedirect,
+		c.checkRawPath,
+		c.checkTrace,
+		c.checkScenario,
+		c.checkStreamRewind,
+		c.checkChunks,
+		c.checkNotifyURL,
+		c.checkRegressionThreshold,
+		c.checkMinRPS,
+		c.checkDistribution,
+		c.checkMaxLatency,
+		c.checkMaxErrorRate,
+		c.checkExitCodes,
+		c.checkStatsReset,
+		c.checkCompareClients,
 	}
 
 	for _, check := range checks {
@@ -67,6 +279,37 @@ func (c *config) checkArgs() error {
 	return nil
 }
 
+// checkCompareClients makes sure the test can be run with each of the
+// clients, since the request for one of them isn't enough.
+func (c *config) checkCompareClients() error {
+	if !c.compareClients {
+		return nil
+	}
+	if c.rawRequestFile != "" || c.slowloris || c.connectTarget != "" ||
+		c.tui || c.format != knownFormat("plain-text") ||
+		c.reportTemplateFile != "" {
+		return errCompareClientsConflict
+	}
+	// main skips them, and files would be created once per client
+	if c.compareBaseline != "" || c.minRPS != nil ||
+		c.expectDistribution != nil || c.maxLatencyP99 != nil ||
+		c.errorRateGate() != nil || c.traceFile != "" ||
+		c.rawLatenciesFile != "" || c.rpsCSVFile != "" || c.perConnStats != "" ||
+		c.rpsHistogramFile != "" || c.notifyURL != "" ||
+		c.recoveryProbe > 0 {
+		return errCompareClientsOutputs
+	}
+	for _, typ := range comparedClients {
+		cc := *c
+		cc.compareClients = false
+		cc.clientType = typ
+		if err := cc.checkArgs(); err != nil {
+			return &compareClientsError{typ, err}
+		}
+	}
+	return nil
+}
+
 func (c *config) checkOrSetDefaultTestType() {
 	if c.testType() == none {
 		c.duration = &defaultTestDuration
@@ -96,12 +339,79 @@ func (c *config) checkURL() error {
 }
 
 func (c *config) checkRate() error {
+	if c.rateSchedule != "" &&
+		(c.rate != nil || c.rateStep != nil || c.findMaxRPS) {
+		return errRateScheduleConflict
+	}
 	if c.rate != nil && *c.rate < 1 {
 		return errZeroRate
 	}
+	if c.rateBytes != nil && *c.rateBytes < 1 {
+		return errZeroRateBytes
+	}
+	if c.burst != nil {
+		switch {
+		case *c.burst < 1:
+			return errZeroBurst
+		case c.rate == nil && !c.findMaxRPS && c.targetP99 == 0 &&
+			c.rateSchedule == "":
+			return errBurstWithoutRate
+		}
+	}
+	if c.rateStep != nil {
+		switch {
+		case c.rate == nil:
+			return errRateStepWithoutRate
+		case *c.rateStep < 1:
+			return errZeroRateStep
+		case rateUpSignal == nil:
+			return errRateStepNotSupported
+		}
+	}
+	return nil
+}
+
+func (c *config) checkFindMaxRPS() error {
+	if !c.findMaxRPS {
+		if c.maxP99 != 0 {
+			return errFindMaxRPSThresholds
+		}
+		return nil
+	}
+	switch {
+	case c.testType() != timed:
+		return errFindMaxRPSTimed
+	case c.rateStep != nil || c.connectionsAuto:
+		return errFindMaxRPSConflict
+	case c.maxP99 < 0:
+		return errNegativeMaxP99
+	}
+	return nil
+}
+
+func (c *config) checkTargetP99() error {
+	switch {
+	case c.targetP99 == 0:
+		return nil
+	case c.targetP99 < 0:
+		return errNegativeTargetP99
+	case c.testType() != timed:
+		return errTargetP99Timed
+	case c.rateStep != nil || c.findMaxRPS || c.rateSchedule != "" ||
+		c.connectionsAuto:
+		return errTargetP99Conflict
+	}
 	return nil
 }
 
+// maxErrorRateOrDefault returns --max-error-rate in percents.
+func (c *config) maxErrorRateOrDefault() float64 {
+	if c.maxErrorRate == nil {
+		return defaultMaxErrorRate
+	}
+	return *c.maxErrorRate
+}
+
 func (c *config) checkRunParameters() error {
 	if c.numConns < uint64(1) {
 		return errInvalidNumberOfConns
@@ -115,10 +425,42 @@ func (c *config) checkRunParameters() error {
 	return nil
 }
 
+func (c *config) checkMaxDuration() error {
+	if c.maxDuration < 0 {
+		return errNegativeMaxDuration
+	}
+	if c.maxDuration > 0 && c.testType() != counted {
+		return errMaxDurationNotCounted
+	}
+	return nil
+}
+
 func (c *config) checkTimeoutDuration() error {
-	if c.timeout < 0 {
+	if c.timeout < 0 || c.abortSlowerThan < 0 || c.idleTimeout < 0 ||
+		c.writeTimeout < 0 || c.readTimeout < 0 {
 		return errNegativeTimeout
 	}
+	if (c.writeTimeout > 0 || c.readTimeout > 0) &&
+		(c.rawRequestFile != "" || c.slowloris || c.method == "CONNECT") {
+		return errPhaseTimeoutNotSupported
+	}
+	if c.abortSlowerThan > 0 && c.timeout > 0 &&
+		c.abortSlowerThan >= c.timeout {
+		return errAbortNotBelowTimeout
+	}
+	if c.adaptiveTimeout != 0 && !(c.adaptiveTimeout > 1) {
+		return errAdaptiveTimeoutFactor
+	}
+	return nil
+}
+
+func (c *config) checkWaitReady() error {
+	if c.waitReady < 0 || c.recoveryProbe < 0 {
+		return errNegativeTimeout
+	}
+	if c.readyStatus != nil && c.waitReady == 0 && c.recoveryProbe == 0 {
+		return errReadyStatusWithoutWait
+	}
 	return nil
 }
 
@@ -126,12 +468,282 @@ func (c *config) checkHTTPParameters() error {
 	if !allowedHTTPMethod(c.method) {
 		return &invalidHTTPMethodError{method: c.method}
 	}
-	if !canHaveBody(c.method) && (c.body != "" || c.bodyFilePath != "") {
+	if !c.canHaveBody() && (c.body != "" ||
+		c.bodyFilePath != "" || c.bodyDir != "" || c.bodyCommand != "") {
 		return errBodyNotAllowed
 	}
 	if c.body != "" && c.bodyFilePath != "" {
 		return errBodyProvidedTwice
 	}
+	if c.bodyDir != "" && (c.body != "" || c.bodyFilePath != "" ||
+		c.stream || c.grpcWeb != grpcWebNone || c.scenario != "" ||
+		c.rawRequestFile != "" || c.slowloris) {
+		return errBodyDirConflict
+	}
+	if c.bodyCommand != "" && (c.body != "" || c.bodyFilePath != "" ||
+		c.bodyDir != "" || c.stream || c.grpcWeb != grpcWebNone ||
+		c.scenario != "" || c.rawRequestFile != "" || c.slowloris) {
+		return errBodyCommandConflict
+	}
+	return nil
+}
+
+func (c *config) checkConnect() error {
+	if c.method != "CONNECT" {
+		if c.connectTarget != "" {
+			return errConnectTargetMethod
+		}
+		return nil
+	}
+	if c.connectTarget == "" {
+		return errNoConnectTarget
+	}
+	if _, _, err := net.SplitHostPort(c.connectTarget); err != nil {
+		return errInvalidConnectTarget
+	}
+	if c.pipeline > 0 || c.clientType == nhttp2 ||
+		c.grpcWeb != grpcWebNone || c.scenario != "" {
+		return errConnectNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkRawRequest() error {
+	if c.rawRequestFile == "" {
+		return nil
+	}
+	if c.method != "GET" || c.body != "" || c.bodyFilePath != "" ||
+		c.stream || (c.headers != nil && len(*c.headers) > 0) ||
+		c.pipeline > 0 || c.clientType == nhttp2 ||
+		c.grpcWeb != grpcWebNone || c.cacheBust || c.scenario != "" ||
+		c.queryTemplates != nil {
+		return errRawRequestConflict
+	}
+	return nil
+}
+
+func (c *config) checkSlowloris() error {
+	if !c.slowloris {
+		return nil
+	}
+	if c.testType() != timed || c.body != "" || c.bodyFilePath != "" ||
+		c.stream || c.pipeline > 0 || c.clientType == nhttp2 ||
+		c.grpcWeb != grpcWebNone || c.rawRequestFile != "" ||
+		c.hosts != nil || c.connectionsAuto || c.scenario != "" ||
+		c.method == "CONNECT" || c.queryTemplates != nil {
+		return errSlowlorisConflict
+	}
+	if c.slowlorisDelay < 0 {
+		return errSlowlorisDelay
+	}
+	return nil
+}
+
+func (c *config) slowlorisDelayOrDefault() time.Duration {
+	if c.slowlorisDelay == 0 {
+		return defaultSlowlorisDelay
+	}
+	return c.slowlorisDelay
+}
+
+func (c *config) checkSuccessfulThroughput() error {
+	// raw requests aren't parsed, so their responses can't be told
+	// apart
+	if c.successfulThroughput && (c.rawRequestFile != "" ||
+		c.slowloris || c.method == "CONNECT") {
+		return errSuccessfulThroughputNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkGoodput() error {
+	// bodies of raw responses aren't told apart from their headers
+	if c.printGoodput && (c.rawRequestFile != "" ||
+		c.slowloris || c.method == "CONNECT") {
+		return errGoodputNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkAcceptEncoding() error {
+	if c.acceptEncoding == "" {
+		if c.decompress {
+			return errDecompressWithoutEncoding
+		}
+		return nil
+	}
+	if c.rawRequestFile != "" || c.method == "CONNECT" {
+		return errAcceptEncodingNotSupported
+	}
+	if c.headers != nil && c.headers.contains("Accept-Encoding") {
+		return errAcceptEncodingHeader
+	}
+	return nil
+}
+
+func (c *config) checkBodyHandling() error {
+	if !c.ignoreBody {
+		return nil
+	}
+	if c.discardBody {
+		return errBodyHandlingConflict
+	}
+	if c.grpcWeb != grpcWebNone || c.decompress ||
+		c.maxResponseSize != nil || c.pipeline > 0 {
+		return errIgnoreBodyNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkClientDelays() error {
+	if c.clientDelay < 0 || c.responseReadDelay < 0 {
+		return errNegativeClientDelay
+	}
+	if c.responseReadDelay > 0 && (c.clientType == fhttp || c.ignoreBody) {
+		return errResponseReadDelayNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkOAuth2() error {
+	if c.oauth2TokenURL == "" {
+		if c.oauth2ClientID != "" || c.oauth2ClientSecret != "" ||
+			c.oauth2Scope != "" {
+			return errOAuth2WithoutTokenURL
+		}
+		return nil
+	}
+	u, err := url.Parse(c.oauth2TokenURL)
+	if err != nil || u.Host == "" ||
+		u.Scheme != "http" && u.Scheme != "https" {
+		return errInvalidOAuth2TokenURL
+	}
+	if c.rawRequestFile != "" || c.method == "CONNECT" || c.slowloris {
+		return errOAuth2NotSupported
+	}
+	if c.headers != nil && c.headers.contains("Authorization") {
+		return errOAuth2Authorization
+	}
+	return nil
+}
+
+func (c *config) checkQueryTemplates() error {
+	if c.queryTemplates == nil {
+		return nil
+	}
+	_, err := newQueryTemplates(c.queryTemplates)
+	return err
+}
+
+func (c *config) checkRandomHeaders() error {
+	if c.randomHeaders == nil {
+		if c.randomHeaderBytes != 0 {
+			return errRandomHeaderBytesWithoutHeader
+		}
+		return nil
+	}
+	if c.randomHeaderBytes < 0 || c.randomHeaderBytes > maxRandomHeaderBytes {
+		return errRandomHeaderBytes
+	}
+	if c.scenario != "" || c.rawRequestFile != "" || c.slowloris {
+		return errRandomHeaderConflict
+	}
+	return nil
+}
+
+// randomHeaderBytesOrDefault returns length of --random-header values.
+func (c *config) randomHeaderBytesOrDefault() int {
+	if c.randomHeaderBytes == 0 {
+		return defaultRandomHeaderBytes
+	}
+	return c.randomHeaderBytes
+}
+
+func (c *config) checkHeaderRotate() error {
+	if c.headerRotate != nil &&
+		(c.scenario != "" || c.rawRequestFile != "" || c.slowloris) {
+		return errHeaderRotateConflict
+	}
+	return nil
+}
+
+func (c *config) checkStatsReset() error {
+	if c.statsResetOnCode && (c.scenario != "" || c.slowloris) {
+		return errStatsResetConflict
+	}
+	return nil
+}
+
+func (c *config) checkTraceparent() error {
+	if !c.traceparent {
+		if c.traceparentSpanID != "" || c.tracestate != "" {
+			return errTraceparentOptions
+		}
+		return nil
+	}
+	if c.scenario != "" || c.rawRequestFile != "" || c.slowloris {
+		return errTraceparentConflict
+	}
+	if c.traceparentSpanID != "" && !validSpanID(c.traceparentSpanID) {
+		return errInvalidSpanID
+	}
+	if strings.ContainsAny(c.tracestate, "\r\n") {
+		return errInvalidTracestate
+	}
+	return nil
+}
+
+func (c *config) checkMethodMix() error {
+	if c.methodMix == nil {
+		return nil
+	}
+	if c.method != "GET" || c.bodyDir != "" || c.grpcWeb != grpcWebNone ||
+		c.scenario != "" || c.rawRequestFile != "" || c.slowloris {
+		return errMethodMixConflict
+	}
+	return nil
+}
+
+// canHaveBody tells whether requests may be sent with a body, with
+// --method-mix it's enough for one of the methods to allow it.
+func (c *config) canHaveBody() bool {
+	if c.methodMix != nil {
+		return c.methodMix.hasWriteMethod()
+	}
+	return canHaveBody(c.method)
+}
+
+func (c *config) checkHosts() error {
+	if c.hosts == nil {
+		return nil
+	}
+	if c.scenario != "" || c.method == "CONNECT" {
+		return errHostsNotSupported
+	}
+	if c.numConns < uint64(len(*c.hosts)) {
+		return errFewerConnsThanHosts
+	}
+	return nil
+}
+
+func (c *config) checkProxy() error {
+	if c.proxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.proxyURL)
+	if err != nil || (u.Scheme != "socks5" && u.Scheme != "socks5h") ||
+		u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+		return errInvalidProxyURL
+	}
+	if u.User == nil {
+		return nil
+	}
+	// RFC 1929 limits both to 255 bytes
+	password, _ := u.User.Password()
+	if len(u.User.Username()) == 0 || len(u.User.Username()) > 255 ||
+		len(password) == 0 || len(password) > 255 {
+		return errInvalidProxyCredentials
+	}
 	return nil
 }
 
@@ -140,10 +752,494 @@ func (c *config) checkCertPaths() error {
 		return errNoPathToKey
 	} else if c.certPath == "" && c.keyPath != "" {
 		return errNoPathToCert
+	} else if strings.Count(c.certPath, ",") != strings.Count(c.keyPath, ",") {
+		return errCertKeyCount
+	}
+	return nil
+}
+
+func (c *config) checkHeaderCasePreserve() error {
+	if c.headerCasePreserve && c.clientType == nhttp2 {
+		return errHeaderCasePreserveHTTP2
+	}
+	return nil
+}
+
+func (c *config) checkNotifyURL() error {
+	if c.notifyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.notifyURL)
+	if err != nil || u.Host == "" ||
+		(u.Scheme != "http" && u.Scheme != "https") {
+		return errInvalidNotifyURL
+	}
+	if c.notifyTimeout < 0 {
+		return errNegativeTimeout
+	}
+	return nil
+}
+
+func (c *config) checkRegressionThreshold() error {
+	if c.regressionThreshold != nil && *c.regressionThreshold < 0 {
+		return errNegativeRegressionThreshold
+	}
+	return nil
+}
+
+func (c *config) checkMinRPS() error {
+	if c.minRPS != nil && *c.minRPS <= 0 {
+		return errNonPositiveMinRPS
+	}
+	return nil
+}
+
+func (c *config) checkDistribution() error {
+	if c.distributionTolerance == nil {
+		return nil
+	}
+	if c.expectDistribution == nil {
+		return errToleranceWithoutDistribution
+	}
+	if !(*c.distributionTolerance >= 0 && *c.distributionTolerance <= 100) {
+		return errDistributionTolerance
 	}
 	return nil
 }
 
+func (c *config) checkMaxLatency() error {
+	if c.maxLatencyP99 == nil {
+		if c.latencyGrace != nil {
+			return errGraceWithoutMaxLatency
+		}
+		return nil
+	}
+	if *c.maxLatencyP99 <= 0 {
+		return errNonPositiveMaxLatency
+	}
+	if c.latencyGrace != nil &&
+		!(*c.latencyGrace >= 0 && *c.latencyGrace < 100) {
+		return errLatencyGrace
+	}
+	return nil
+}
+
+func (c *config) checkMaxErrorRate() error {
+	if c.maxErrorRate != nil &&
+		!(*c.maxErrorRate >= 0 && *c.maxErrorRate <= 100) {
+		return errMaxErrorRate
+	}
+	return nil
+}
+
+// errorRateGate returns --max-error-rate if it's a check of results,
+// that is, it doesn't bound the search of --find-max-rps.
+func (c *config) errorRateGate() *float64 {
+	if c.findMaxRPS {
+		return nil
+	}
+	return c.maxErrorRate
+}
+
+func (c *config) checkExitCodes() error {
+	codes := []struct {
+		code        *uint64
+		flag, gates string
+		gated       bool
+	}{
+		{
+			c.exitCodeLatency, "--exit-code-latency",
+			"--max-latency-p99 or --compare-baseline",
+			c.maxLatencyP99 != nil || c.compareBaseline != "",
+		},
+		{c.exitCodeRPS, "--exit-code-rps", "--min-rps", c.minRPS != nil},
+		{
+			c.exitCodeDistribution, "--exit-code-distribution",
+			"--expect-distribution", c.expectDistribution != nil,
+		},
+		{
+			c.exitCodeErrors, "--exit-code-errors",
+			"--max-error-rate or --abort-on-first-error",
+			c.errorRateGate() != nil || c.abortOnFirstError,
+		},
+	}
+	for _, e := range codes {
+		if e.code == nil {
+			continue
+		}
+		if *e.code < 1 || *e.code > maxExitCode {
+			return errExitCode
+		}
+		if !e.gated {
+			return &exitCodeWithoutGateError{e.flag, e.gates}
+		}
+	}
+	return nil
+}
+
+type exitCodeWithoutGateError struct {
+	flag, gates string
+}
+
+func (e *exitCodeWithoutGateError) Error() string {
+	return e.flag + " requires " + e.gates
+}
+
+// latencyGraceOrDefault returns --latency-grace in percents.
+func (c *config) latencyGraceOrDefault() float64 {
+	if c.latencyGrace == nil {
+		return defaultLatencyGrace
+	}
+	return *c.latencyGrace
+}
+
+// distributionToleranceOrDefault returns --distribution-tolerance in
+// percentage points.
+func (c *config) distributionToleranceOrDefault() float64 {
+	if c.distributionTolerance == nil {
+		return defaultDistributionTolerance
+	}
+	return *c.distributionTolerance
+}
+
+func (c *config) checkMaxResponseSize() error {
+	if c.maxResponseSize == nil {
+		return nil
+	}
+	if *c.maxResponseSize < 1 {
+		return errZeroMaxResponseSize
+	}
+	if c.pipeline > 0 {
+		return errMaxResponseSizePipeline
+	}
+	return nil
+}
+
+func (c *config) burstOrZero() uint64 {
+	if c.burst == nil {
+		return 0
+	}
+	return *c.burst
+}
+
+func (c *config) maxResponseSizeOrZero() uint64 {
+	if c.maxResponseSize == nil {
+		return 0
+	}
+	return *c.maxResponseSize
+}
+
+func (c *config) checkStrictContentLength() error {
+	if c.strictContentLength && (c.ignoreBody || c.rawRequestFile != "" ||
+		c.slowloris || c.method == "CONNECT") {
+		return errStrictContentLengthNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkBufferSizes() error {
+	for _, size := range []*uint64{c.readBufferSize, c.writeBufferSize} {
+		if size == nil {
+			continue
+		}
+		if *size < 1 {
+			return errZeroBufferSize
+		}
+		if *size > maxBufferSize {
+			return errBufferSizeTooLarge
+		}
+		// HTTP/2 connections are buffered by golang.org/x/net/http2,
+		// which doesn't allow to change buffer sizes
+		if c.clientType == nhttp2 {
+			return errBufferSizeHTTP2
+		}
+	}
+	return nil
+}
+
+// bufferSizeOrZero returns size in bytes or zero, if it wasn't set.
+func bufferSizeOrZero(size *uint64) int {
+	if size == nil {
+		return 0
+	}
+	return int(*size)
+}
+
+func (c *config) checkGRPCWeb() error {
+	if c.grpcWeb == grpcWebNone {
+		return nil
+	}
+	if c.method != "POST" {
+		return errGRPCWebMethod
+	}
+	if c.stream {
+		return errGRPCWebStream
+	}
+	return nil
+}
+
+func (c *config) checkLatencyCap() error {
+	if c.latencyCap < 0 {
+		return errNegativeLatencyCap
+	}
+	return nil
+}
+
+func (c *config) checkScenario() error {
+	if c.scenario != "" && (c.stream || c.grpcWeb != grpcWebNone) {
+		return errScenarioConflict
+	}
+	if c.replaySpeed != nil && (*c.replaySpeed <= 0 || c.scenario == "") {
+		return errReplaySpeed
+	}
+	return nil
+}
+
+func (c *config) replaySpeedOrDefault() float64 {
+	if c.replaySpeed == nil {
+		return 1
+	}
+	return *c.replaySpeed
+}
+
+func (c *config) checkStreamRewind() error {
+	if c.streamRewind && (!c.stream || c.bodyFilePath == "") {
+		return errStreamRewind
+	}
+	return nil
+}
+
+func (c *config) checkChunks() error {
+	if c.chunkDelay == 0 && c.chunkSize == nil {
+		return nil
+	}
+	if !c.stream {
+		return errChunkWithoutStream
+	}
+	if c.chunkDelay < 0 {
+		return errNegativeChunkDelay
+	}
+	if c.chunkSize != nil && *c.chunkSize < 1 {
+		return errZeroChunkSize
+	}
+	return nil
+}
+
+func (c *config) chunkSizeOrDefault() uint64 {
+	if c.chunkSize == nil {
+		return defaultChunkSize
+	}
+	return *c.chunkSize
+}
+
+func (c *config) checkSnapshotInterval() error {
+	if c.snapshotInterval < 0 {
+		return errNegativeSnapshotInterval
+	}
+	if c.intervalLatency && c.snapshotInterval == 0 {
+		return errIntervalPercentilesWithoutSnapshots
+	}
+	if c.snapshotInterval > 0 && c.tui {
+		return errSnapshotsWithTUI
+	}
+	return nil
+}
+
+func (c *config) checkLiveP99() error {
+	if c.liveP99 && c.tui {
+		return errLiveP99WithTUI
+	}
+	return nil
+}
+
+func (c *config) checkPrintErrorsOnly() error {
+	if c.printErrorsOnly && c.tui {
+		return errPrintErrorsOnlyWithTUI
+	}
+	return nil
+}
+
+func (c *config) checkLatencyPrecision() error {
+	if c.latencyPrecision != nil && *c.latencyPrecision > maxLatencyPrecision {
+		return errLatencyPrecision
+	}
+	return nil
+}
+
+func (c *config) latencyPrecisionOrDefault() int {
+	if c.latencyPrecision == nil {
+		return defaultLatencyPrecision
+	}
+	return int(*c.latencyPrecision)
+}
+
+// formatLatency formats latency in microseconds with the precision
+// requested.
+func (c *config) formatLatency(us float64) string {
+	return formatTimeUsPrec(us, c.latencyPrecisionOrDefault())
+}
+
+func (c *config) checkGraph() error {
+	if c.printGraph && c.format != knownFormat("plain-text") {
+		return errGraphFormat
+	}
+	if c.printBuckets && c.format != knownFormat("plain-text") {
+		return errBucketsFormat
+	}
+	return nil
+}
+
+func (c *config) checkJSONPretty() error {
+	if c.jsonPretty && c.format != knownFormat("json") {
+		return errJSONPrettyFormat
+	}
+	return nil
+}
+
+func (c *config) checkReportTemplate() error {
+	if c.reportTemplateFile != "" && c.format != knownFormat("plain-text") {
+		return errReportTemplateFormat
+	}
+	return nil
+}
+
+func (c *config) checkPrintTLS() error {
+	if c.printTLS && !strings.HasPrefix(c.url, "https://") {
+		return errPrintTLSNotHTTPS
+	}
+	return nil
+}
+
+func (c *config) checkTrace() error {
+	if (c.traceFirst == 0) != (c.traceFile == "") {
+		return errTraceFlags
+	}
+	if c.traceFirst > 0 && (c.rawRequestFile != "" || c.slowloris ||
+		c.method == "CONNECT") {
+		return errTraceNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkPrintDNS() error {
+	if c.printDNS && (c.clientType == fhttp || c.rawRequestFile != "" ||
+		c.slowloris || c.method == "CONNECT") {
+		return errPrintDNSNotSupported
+	}
+	return nil
+}
+
+func (c *config) checkPrintQueueTime() error {
+	if c.printQueueTime && c.rate == nil && c.rateBytes == nil &&
+		c.rateSchedule == "" && !c.findMaxRPS && c.targetP99 == 0 {
+		return errQueueTimeWithoutRate
+	}
+	return nil
+}
+
+func (c *config) checkHTTPSRedirect() error {
+	if !c.httpsRedirect {
+		return nil
+	}
+	if c.clientType == fhttp || c.rawRequestFile != "" || c.slowloris ||
+		c.method == "CONNECT" {
+		return errHTTPSRedirectNotSupported
+	}
+	if !strings.HasPrefix(c.url, "http://") {
+		return errHTTPSRedirectScheme
+	}
+	return nil
+}
+
+func (c *config) checkRawPath() error {
+	if c.rawPath == "" {
+		return nil
+	}
+	if !validRawPath(c.rawPath) {
+		return errInvalidRawPath
+	}
+	if c.scenario != "" || c.rawRequestFile != "" || c.slowloris ||
+		c.method == "CONNECT" {
+		return errRawPathConflict
+	}
+	return nil
+}
+
+// targetURL returns the URL requests are sent to, including the path
+// of --raw-path.
+func (c *config) targetURL() string {
+	if c.rawPath == "" {
+		return c.url
+	}
+	return c.url + c.rawPath
+}
+
+// displayHeaders returns headers to show in the output and reports,
+// ones with references to environment variables are shown as given.
+func (c *config) displayHeaders() *headersList {
+	if c.headerTemplates != nil {
+		return c.headerTemplates
+	}
+	return c.headers
+}
+
+// displayURL returns the URL to show in the output and reports. When
+// the URL refers to environment variables, it's shown as given, so
+// that their values (i.e. secrets) don't end up in logs.
+func (c *config) displayURL() string {
+	if c.urlTemplate != "" {
+		return c.urlTemplate
+	}
+	return c.targetURL()
+}
+
+func (c *config) checkPipeline() error {
+	if c.pipeline > 0 && c.clientType != fhttp {
+		return errPipelineNotSupported
+	}
+	if c.pipeline > maxPipeline {
+		return errPipelineTooDeep
+	}
+	if c.pipeline > 0 && c.numConns > maxPipelineWorkers/c.pipeline {
+		return errTooManyPipelinedRequests
+	}
+	if c.printPipelineStats && c.pipeline == 0 {
+		return errPipelineStatsWithoutPipeline
+	}
+	if c.noDefaultHeaders && c.pipeline > 0 {
+		// fasthttp's PipelineClient always sends its User-Agent
+		return errNoDefaultHeadersPipeline
+	}
+	if c.connectionsAuto && c.pipeline > 0 {
+		// the ramp activates connections, not pipelined workers
+		return errConnectionsAutoPipeline
+	}
+	return nil
+}
+
+// numWorkers returns the number of goroutines that should be issuing
+// requests, which with pipelining is more than the number of
+// connections.
+func (c *config) numWorkers() uint64 {
+	if c.pipeline > 0 {
+		return c.numConns * c.pipeline
+	}
+	return c.numConns
+}
+
+// phaseTimeouts returns timeouts of writing requests and reading
+// responses, --timeout unless they were set.
+func (c *config) phaseTimeouts() (write, read time.Duration) {
+	write, read = c.timeout, c.timeout
+	if c.writeTimeout > 0 {
+		write = c.writeTimeout
+	}
+	if c.readTimeout > 0 {
+		read = c.readTimeout
+	}
+	return write, read
+}
+
 func (c *config) timeoutMillis() uint64 {
 	return uint64(c.timeout.Nanoseconds() / 1000)
 }
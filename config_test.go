@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -55,6 +56,11 @@ func TestCheckArgs(t *testing.T) {
 	negativeTimeoutDuration := -1 * time.Second
 	noHeaders := new(headersList)
 	zeroRate := uint64(0)
+	negativeThreshold := -1.0
+	tooHighErrorRate := 101.0
+	tenKB := uint64(10 * 1024)
+	tooLargeBufferSize := uint64(maxBufferSize + 1)
+	minRPSCheck := 100.0
 	expectations := []struct {
 		in  config
 		out error
@@ -129,6 +135,33 @@ func TestCheckArgs(t *testing.T) {
 			},
 			errNegativeTimeout,
 		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				readTimeout: negativeTimeoutDuration,
+				method:      "GET",
+				format:      knownFormat("plain-text"),
+			},
+			errNegativeTimeout,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				writeTimeout: time.Second,
+				method:       "GET",
+				slowloris:    true,
+				format:       knownFormat("plain-text"),
+			},
+			errPhaseTimeoutNotSupported,
+		},
 		{
 			config{
 				numConns: defaultNumberOfConns,
@@ -203,6 +236,20 @@ func TestCheckArgs(t *testing.T) {
 			},
 			errNoPathToCert,
 		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				certPath: "a.pem,b.pem",
+				keyPath:  "a.key",
+				format:   knownFormat("plain-text"),
+			},
+			errCertKeyCount,
+		},
 		{
 			config{
 				numConns: defaultNumberOfConns,
@@ -217,6 +264,20 @@ func TestCheckArgs(t *testing.T) {
 			},
 			errZeroRate,
 		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				duration:  &defaultTestDuration,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				rateBytes: &zeroRate,
+				format:    knownFormat("plain-text"),
+			},
+			errZeroRateBytes,
+		},
 		{
 			config{
 				numConns:     defaultNumberOfConns,
@@ -232,15 +293,1782 @@ func TestCheckArgs(t *testing.T) {
 			},
 			errBodyProvidedTwice,
 		},
-	}
-	for _, e := range expectations {
-		if r := e.in.checkArgs(); r != e.out {
-			t.Logf("Expected (%v).checkArgs to return %v, but got %v", e.in, e.out, r)
-			t.Fail()
-		}
-		if _, r := newBombardier(e.in); r != e.out {
-			t.Logf("Expected newBombardier(%v) to return %v, but got %v", e.in, e.out, r)
-			t.Fail()
+		{
+			config{
+				numConns:           defaultNumberOfConns,
+				numReqs:            &defaultNumberOfReqs,
+				duration:           &defaultTestDuration,
+				url:                "http://localhost:8080",
+				headers:            noHeaders,
+				headerCasePreserve: true,
+				timeout:            defaultTimeout,
+				method:             "GET",
+				clientType:         nhttp2,
+				format:             knownFormat("plain-text"),
+			},
+			errHeaderCasePreserveHTTP2,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				duration:   &defaultTestDuration,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				clientType: nhttp1,
+				pipeline:   10,
+				format:     knownFormat("plain-text"),
+			},
+			errPipelineNotSupported,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				pipeline: maxPipeline + 1,
+				format:   knownFormat("plain-text"),
+			},
+			errPipelineTooDeep,
+		},
+		{
+			config{
+				numConns: maxPipelineWorkers/maxPipeline + 1,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				pipeline: maxPipeline,
+				format:   knownFormat("plain-text"),
+			},
+			errTooManyPipelinedRequests,
+		},
+		{
+			config{
+				numConns: 1 << 63,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				pipeline: 2,
+				format:   knownFormat("plain-text"),
+			},
+			errTooManyPipelinedRequests,
+		},
+		{
+			config{
+				numConns:           defaultNumberOfConns,
+				numReqs:            &defaultNumberOfReqs,
+				url:                "http://localhost:8080",
+				headers:            noHeaders,
+				timeout:            defaultTimeout,
+				method:             "GET",
+				printPipelineStats: true,
+				format:             knownFormat("plain-text"),
+			},
+			errPipelineStatsWithoutPipeline,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				duration:        &defaultTestDuration,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				abortSlowerThan: defaultTimeout,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+			},
+			errAbortNotBelowTimeout,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				duration:  &defaultTestDuration,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				format:    knownFormat("plain-text"),
+				notifyURL: "ftp://localhost/hook",
+			},
+			errInvalidNotifyURL,
+		},
+		{
+			config{
+				numConns:            defaultNumberOfConns,
+				numReqs:             &defaultNumberOfReqs,
+				duration:            &defaultTestDuration,
+				url:                 "http://localhost:8080",
+				headers:             noHeaders,
+				timeout:             defaultTimeout,
+				method:              "GET",
+				format:              knownFormat("plain-text"),
+				regressionThreshold: &negativeThreshold,
+			},
+			errNegativeRegressionThreshold,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				duration:        &defaultTestDuration,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+				maxResponseSize: &zeroRate,
+			},
+			errZeroMaxResponseSize,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				duration:        &defaultTestDuration,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+				clientType:      fhttp,
+				pipeline:        4,
+				maxResponseSize: &defaultNumberOfReqs,
+			},
+			errMaxResponseSizePipeline,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				duration:         &defaultTestDuration,
+				url:              "http://localhost:8080",
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				format:           knownFormat("plain-text"),
+				clientType:       fhttp,
+				pipeline:         4,
+				noDefaultHeaders: true,
+			},
+			errNoDefaultHeadersPipeline,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				duration:        &defaultTestDuration,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+				clientType:      fhttp,
+				pipeline:        4,
+				connectionsAuto: true,
+			},
+			errConnectionsAutoPipeline,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				duration: &defaultTestDuration,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				format:   knownFormat("plain-text"),
+				grpcWeb:  grpcWebBinary,
+			},
+			errGRPCWebMethod,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				duration: &defaultTestDuration,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "POST",
+				stream:   true,
+				format:   knownFormat("plain-text"),
+				grpcWeb:  grpcWebText,
+			},
+			errGRPCWebStream,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				duration:   &defaultTestDuration,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				latencyCap: -time.Second,
+				method:     "GET",
+				format:     knownFormat("plain-text"),
+			},
+			errNegativeLatencyCap,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              "http://localhost:8080",
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				snapshotInterval: -time.Second,
+				format:           knownFormat("plain-text"),
+			},
+			errNegativeSnapshotInterval,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				intervalLatency: true,
+				format:          knownFormat("plain-text"),
+			},
+			errIntervalPercentilesWithoutSnapshots,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				printGraph: true,
+				format:     knownFormat("json"),
+			},
+			errGraphFormat,
+		},
+		{
+			config{
+				numConns:              defaultNumberOfConns,
+				numReqs:               &defaultNumberOfReqs,
+				url:                   "http://localhost:8080",
+				headers:               noHeaders,
+				timeout:               defaultTimeout,
+				method:                "GET",
+				distributionTolerance: new(float64),
+				format:                knownFormat("plain-text"),
+			},
+			errToleranceWithoutDistribution,
+		},
+		{
+			config{
+				numConns:              defaultNumberOfConns,
+				numReqs:               &defaultNumberOfReqs,
+				url:                   "http://localhost:8080",
+				headers:               noHeaders,
+				timeout:               defaultTimeout,
+				method:                "GET",
+				expectDistribution:    &codeDistribution{2: 100},
+				distributionTolerance: &negativeThreshold,
+				format:                knownFormat("plain-text"),
+			},
+			errDistributionTolerance,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				latencyGrace: &negativeThreshold,
+				format:       knownFormat("plain-text"),
+			},
+			errGraceWithoutMaxLatency,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				maxLatencyP99: &negativeTimeoutDuration,
+				format:        knownFormat("plain-text"),
+			},
+			errNonPositiveMaxLatency,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				maxLatencyP99: &defaultTestDuration,
+				latencyGrace:  &negativeThreshold,
+				format:        knownFormat("plain-text"),
+			},
+			errLatencyGrace,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				traceFirst: 10,
+				format:     knownFormat("plain-text"),
+			},
+			errTraceFlags,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				duration:   &defaultTestDuration,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				traceFirst: 10,
+				traceFile:  "trace.txt",
+				slowloris:  true,
+				format:     knownFormat("plain-text"),
+			},
+			errTraceNotSupported,
+		},
+		{
+			config{
+				numConns:          defaultNumberOfConns,
+				numReqs:           &defaultNumberOfReqs,
+				url:               "http://localhost:8080",
+				headers:           noHeaders,
+				timeout:           defaultTimeout,
+				method:            "GET",
+				randomHeaderBytes: 8,
+				format:            knownFormat("plain-text"),
+			},
+			errRandomHeaderBytesWithoutHeader,
+		},
+		{
+			config{
+				numConns:          defaultNumberOfConns,
+				numReqs:           &defaultNumberOfReqs,
+				url:               "http://localhost:8080",
+				headers:           noHeaders,
+				timeout:           defaultTimeout,
+				method:            "GET",
+				randomHeaders:     &randomHeaderNames{"X-Fuzz"},
+				randomHeaderBytes: maxRandomHeaderBytes + 1,
+				format:            knownFormat("plain-text"),
+			},
+			errRandomHeaderBytes,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				duration:  &defaultTestDuration,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				slowloris: true,
+				headerRotate: &rotatedHeaders{
+					{name: "X-Tenant", values: []string{"a"}},
+				},
+				format: knownFormat("plain-text"),
+			},
+			errHeaderRotateConflict,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				traceparent:    true,
+				rawRequestFile: "req.txt",
+				format:         knownFormat("plain-text"),
+			},
+			errTraceparentConflict,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				duration:         &defaultTestDuration,
+				url:              "http://localhost:8080",
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				slowloris:        true,
+				statsResetOnCode: true,
+				format:           knownFormat("plain-text"),
+			},
+			errStatsResetConflict,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				printQueueTime: true,
+				format:         knownFormat("plain-text"),
+			},
+			errQueueTimeWithoutRate,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				recoveryProbe: negativeTimeoutDuration,
+				format:        knownFormat("plain-text"),
+			},
+			errNegativeTimeout,
+		},
+		{
+			config{
+				numConns:          defaultNumberOfConns,
+				numReqs:           &defaultNumberOfReqs,
+				url:               "http://localhost:8080",
+				headers:           noHeaders,
+				timeout:           defaultTimeout,
+				method:            "GET",
+				abortOnFirstError: true,
+				exitCodeErrors:    &zeroRate,
+				format:            knownFormat("plain-text"),
+			},
+			errExitCode,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				maxErrorRate: &tooHighErrorRate,
+				format:       knownFormat("plain-text"),
+			},
+			errMaxErrorRate,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				maxErrorRate: &negativeThreshold,
+				format:       knownFormat("plain-text"),
+			},
+			errMaxErrorRate,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				tracestate: "a=1",
+				format:     knownFormat("plain-text"),
+			},
+			errTraceparentOptions,
+		},
+		{
+			config{
+				numConns:          defaultNumberOfConns,
+				numReqs:           &defaultNumberOfReqs,
+				url:               "http://localhost:8080",
+				headers:           noHeaders,
+				timeout:           defaultTimeout,
+				method:            "GET",
+				traceparent:       true,
+				traceparentSpanID: "0000000000000000",
+				format:            knownFormat("plain-text"),
+			},
+			errInvalidSpanID,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				traceparent: true,
+				tracestate:  "a=1\r\nX: y",
+				format:      knownFormat("plain-text"),
+			},
+			errInvalidTracestate,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				tui:            true,
+				compareClients: true,
+				format:         knownFormat("plain-text"),
+			},
+			errCompareClientsConflict,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				minRPS:         &minRPSCheck,
+				compareClients: true,
+				format:         knownFormat("plain-text"),
+			},
+			errCompareClientsOutputs,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				rpsCSVFile:     "rps.csv",
+				compareClients: true,
+				format:         knownFormat("plain-text"),
+			},
+			errCompareClientsOutputs,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				jsonPretty: true,
+				format:     knownFormat("plain-text"),
+			},
+			errJSONPrettyFormat,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "PUT",
+				methodMix: &methodMix{{"GET", 80}, {"POST", 20}},
+				format:    knownFormat("plain-text"),
+			},
+			errMethodMixConflict,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				body:      "data",
+				methodMix: &methodMix{{"GET", 80}, {"HEAD", 20}},
+				format:    knownFormat("plain-text"),
+			},
+			errBodyNotAllowed,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				targetP99: 20 * time.Millisecond,
+				format:    knownFormat("plain-text"),
+			},
+			errTargetP99Timed,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				duration:   &defaultTestDuration,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				targetP99:  20 * time.Millisecond,
+				findMaxRPS: true,
+				format:     knownFormat("plain-text"),
+			},
+			errTargetP99Conflict,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				clientType: fhttp,
+				printDNS:   true,
+				format:     knownFormat("plain-text"),
+			},
+			errPrintDNSNotSupported,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				rawPath:  "/a b",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				format:   knownFormat("plain-text"),
+			},
+			errInvalidRawPath,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				rawPath:        "/a%2Fb",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				rawRequestFile: "request.txt",
+				format:         knownFormat("plain-text"),
+			},
+			errRawPathConflict,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				clientType:    fhttp,
+				httpsRedirect: true,
+				format:        knownFormat("plain-text"),
+			},
+			errHTTPSRedirectNotSupported,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "https://localhost:8443",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				clientType:    nhttp2,
+				httpsRedirect: true,
+				format:        knownFormat("plain-text"),
+			},
+			errHTTPSRedirectScheme,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "POST",
+				body:        "body",
+				bodyCommand: "gen",
+				format:      knownFormat("plain-text"),
+			},
+			errBodyCommandConflict,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				bodyCommand: "gen",
+				format:      knownFormat("plain-text"),
+			},
+			errBodyNotAllowed,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				rate:         &defaultNumberOfReqs,
+				rateSchedule: "schedule.txt",
+				format:       knownFormat("plain-text"),
+			},
+			errRateScheduleConflict,
+		},
+		{
+			config{
+				numConns:            defaultNumberOfConns,
+				numReqs:             &defaultNumberOfReqs,
+				url:                 "http://localhost:8080",
+				headers:             noHeaders,
+				timeout:             defaultTimeout,
+				method:              "GET",
+				ignoreBody:          true,
+				strictContentLength: true,
+				format:              knownFormat("plain-text"),
+			},
+			errStrictContentLengthNotSupported,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				proxyURL: "http://proxy:8080",
+				format:   knownFormat("plain-text"),
+			},
+			errInvalidProxyURL,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				proxyURL: "socks5://:1080",
+				format:   knownFormat("plain-text"),
+			},
+			errInvalidProxyURL,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				proxyURL: "socks5://user@proxy:1080",
+				format:   knownFormat("plain-text"),
+			},
+			errInvalidProxyCredentials,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				proxyURL: "socks5://user:" + strings.Repeat("x", 256) + "@proxy",
+				format:   knownFormat("plain-text"),
+			},
+			errInvalidProxyCredentials,
+		},
+		{
+			config{
+				numConns:             defaultNumberOfConns,
+				duration:             &defaultTestDuration,
+				url:                  "http://localhost:8080",
+				headers:              noHeaders,
+				timeout:              defaultTimeout,
+				method:               "GET",
+				slowloris:            true,
+				successfulThroughput: true,
+				format:               knownFormat("plain-text"),
+			},
+			errSuccessfulThroughputNotSupported,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				duration:     &defaultTestDuration,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				slowloris:    true,
+				printGoodput: true,
+				format:       knownFormat("plain-text"),
+			},
+			errGoodputNotSupported,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				readBufferSize: new(uint64),
+				format:         knownFormat("plain-text"),
+			},
+			errZeroBufferSize,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				writeBufferSize: &tooLargeBufferSize,
+				format:          knownFormat("plain-text"),
+			},
+			errBufferSizeTooLarge,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				readBufferSize: &tenKB,
+				clientType:     nhttp2,
+				format:         knownFormat("plain-text"),
+			},
+			errBufferSizeHTTP2,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				format:     knownFormat("plain-text"),
+				findMaxRPS: true,
+			},
+			errFindMaxRPSTimed,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				duration:        &defaultTestDuration,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+				findMaxRPS:      true,
+				connectionsAuto: true,
+			},
+			errFindMaxRPSConflict,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				duration: &defaultTestDuration,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				format:   knownFormat("plain-text"),
+				maxP99:   time.Second,
+			},
+			errFindMaxRPSThresholds,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				duration:     &defaultTestDuration,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				format:       knownFormat("plain-text"),
+				findMaxRPS:   true,
+				maxErrorRate: &tooHighErrorRate,
+			},
+			errMaxErrorRate,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				duration:   &defaultTestDuration,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				format:     knownFormat("plain-text"),
+				findMaxRPS: true,
+				maxP99:     -time.Second,
+			},
+			errNegativeMaxP99,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				minRPS:   new(float64),
+				format:   knownFormat("plain-text"),
+			},
+			errNonPositiveMinRPS,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				tui:      true,
+				liveP99:  true,
+				format:   knownFormat("plain-text"),
+			},
+			errLiveP99WithTUI,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				method:          "GET",
+				tui:             true,
+				printErrorsOnly: true,
+				format:          knownFormat("plain-text"),
+			},
+			errPrintErrorsOnlyWithTUI,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              "http://localhost:8080",
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				tui:              true,
+				snapshotInterval: time.Second,
+				format:           knownFormat("plain-text"),
+			},
+			errSnapshotsWithTUI,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				bodyDir:  "bodies",
+				format:   knownFormat("plain-text"),
+			},
+			errBodyNotAllowed,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "POST",
+				bodyDir:      "bodies",
+				bodyFilePath: "testbody.txt",
+				format:       knownFormat("plain-text"),
+			},
+			errBodyDirConflict,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "POST",
+				bodyDir:  "bodies",
+				stream:   true,
+				format:   knownFormat("plain-text"),
+			},
+			errBodyDirConflict,
+		},
+		{
+			config{
+				numConns:         defaultNumberOfConns,
+				numReqs:          &defaultNumberOfReqs,
+				url:              "http://localhost:8080",
+				headers:          noHeaders,
+				timeout:          defaultTimeout,
+				method:           "GET",
+				latencyPrecision: &defaultNumberOfReqs,
+				format:           knownFormat("plain-text"),
+			},
+			errLatencyPrecision,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				oauth2ClientID: "client",
+				format:         knownFormat("plain-text"),
+			},
+			errOAuth2WithoutTokenURL,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				oauth2TokenURL: "/token",
+				format:         knownFormat("plain-text"),
+			},
+			errInvalidOAuth2TokenURL,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				rawRequestFile: "request.txt",
+				oauth2TokenURL: "http://localhost:8081/token",
+				format:         knownFormat("plain-text"),
+			},
+			errOAuth2NotSupported,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        &headersList{{"authorization", "Basic dTpw"}},
+				timeout:        defaultTimeout,
+				method:         "GET",
+				oauth2TokenURL: "http://localhost:8081/token",
+				format:         knownFormat("plain-text"),
+			},
+			errOAuth2Authorization,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				rateStep: &defaultNumberOfReqs,
+				format:   knownFormat("plain-text"),
+			},
+			errRateStepWithoutRate,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				burst:    &defaultNumberOfReqs,
+				format:   knownFormat("plain-text"),
+			},
+			errBurstWithoutRate,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				rate:     &defaultNumberOfReqs,
+				burst:    &zeroRate,
+				format:   knownFormat("plain-text"),
+			},
+			errZeroBurst,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				rate:     &defaultNumberOfReqs,
+				rateStep: new(uint64),
+				format:   knownFormat("plain-text"),
+			},
+			errZeroRateStep,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "GET",
+				printBuckets: true,
+				format:       knownFormat("json"),
+			},
+			errBucketsFormat,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				discardBody: true,
+				ignoreBody:  true,
+				format:      knownFormat("plain-text"),
+			},
+			errBodyHandlingConflict,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				ignoreBody: true,
+				pipeline:   2,
+				format:     knownFormat("plain-text"),
+			},
+			errIgnoreBodyNotSupported,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				clientDelay: -time.Second,
+				format:      knownFormat("plain-text"),
+			},
+			errNegativeClientDelay,
+		},
+		{
+			config{
+				numConns:          defaultNumberOfConns,
+				numReqs:           &defaultNumberOfReqs,
+				url:               "http://localhost:8080",
+				headers:           noHeaders,
+				timeout:           defaultTimeout,
+				method:            "GET",
+				responseReadDelay: time.Second,
+				format:            knownFormat("plain-text"),
+			},
+			errResponseReadDelayNotSupported,
+		},
+		{
+			config{
+				numConns:          defaultNumberOfConns,
+				numReqs:           &defaultNumberOfReqs,
+				url:               "http://localhost:8080",
+				headers:           noHeaders,
+				timeout:           defaultTimeout,
+				method:            "GET",
+				responseReadDelay: time.Second,
+				ignoreBody:        true,
+				clientType:        nhttp1,
+				format:            knownFormat("plain-text"),
+			},
+			errResponseReadDelayNotSupported,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				replaySpeed: new(float64),
+				scenario:    "steps.json",
+				format:      knownFormat("plain-text"),
+			},
+			errReplaySpeed,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "GET",
+				slowloris: true,
+				format:    knownFormat("plain-text"),
+			},
+			errSlowlorisConflict,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				duration:       &defaultTestDuration,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				slowloris:      true,
+				slowlorisDelay: -time.Second,
+				format:         knownFormat("plain-text"),
+			},
+			errSlowlorisDelay,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "POST",
+				body:       "abc",
+				chunkDelay: time.Millisecond,
+				format:     knownFormat("plain-text"),
+			},
+			errChunkWithoutStream,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "POST",
+				body:       "abc",
+				stream:     true,
+				chunkDelay: -time.Millisecond,
+				format:     knownFormat("plain-text"),
+			},
+			errNegativeChunkDelay,
+		},
+		{
+			config{
+				numConns:  defaultNumberOfConns,
+				numReqs:   &defaultNumberOfReqs,
+				url:       "http://localhost:8080",
+				headers:   noHeaders,
+				timeout:   defaultTimeout,
+				method:    "POST",
+				body:      "abc",
+				stream:    true,
+				chunkSize: new(uint64),
+				format:    knownFormat("plain-text"),
+			},
+			errZeroChunkSize,
+		},
+		{
+			config{
+				numConns:           defaultNumberOfConns,
+				numReqs:            &defaultNumberOfReqs,
+				url:                "http://localhost:8080",
+				headers:            noHeaders,
+				timeout:            defaultTimeout,
+				method:             "GET",
+				format:             knownFormat("json"),
+				reportTemplateFile: "report.tmpl",
+			},
+			errReportTemplateFormat,
+		},
+		{
+			config{
+				numConns:     defaultNumberOfConns,
+				numReqs:      &defaultNumberOfReqs,
+				url:          "http://localhost:8080",
+				headers:      noHeaders,
+				timeout:      defaultTimeout,
+				method:       "POST",
+				bodyFilePath: "testbody.txt",
+				streamRewind: true,
+				format:       knownFormat("plain-text"),
+			},
+			errStreamRewind,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				printTLS: true,
+				format:   knownFormat("plain-text"),
+			},
+			errPrintTLSNotHTTPS,
+		},
+		{
+			config{
+				numConns:   defaultNumberOfConns,
+				numReqs:    &defaultNumberOfReqs,
+				url:        "http://localhost:8080",
+				headers:    noHeaders,
+				timeout:    defaultTimeout,
+				method:     "GET",
+				decompress: true,
+				format:     knownFormat("plain-text"),
+			},
+			errDecompressWithoutEncoding,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        &headersList{{"accept-encoding", "br"}},
+				timeout:        defaultTimeout,
+				method:         "GET",
+				acceptEncoding: "gzip",
+				format:         knownFormat("plain-text"),
+			},
+			errAcceptEncodingHeader,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				rawRequestFile: "/path/to/request",
+				acceptEncoding: "gzip",
+				format:         knownFormat("plain-text"),
+			},
+			errAcceptEncodingNotSupported,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				adaptiveTimeout: 0.5,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+			},
+			errAdaptiveTimeoutFactor,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "POST",
+				rawRequestFile: "/path/to/request",
+				format:         knownFormat("plain-text"),
+			},
+			errRawRequestConflict,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        &headersList{{"K", "V"}},
+				timeout:        defaultTimeout,
+				method:         "GET",
+				rawRequestFile: "/path/to/request",
+				format:         knownFormat("plain-text"),
+			},
+			errRawRequestConflict,
+		},
+		{
+			config{
+				numConns:       defaultNumberOfConns,
+				numReqs:        &defaultNumberOfReqs,
+				url:            "http://localhost:8080",
+				headers:        noHeaders,
+				timeout:        defaultTimeout,
+				method:         "GET",
+				rawRequestFile: "/path/to/request",
+				queryTemplates: &queryList{{"id", "{{ .Seq }}"}},
+				format:         knownFormat("plain-text"),
+			},
+			errRawRequestConflict,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				maxDuration: -time.Second,
+				method:      "GET",
+				format:      knownFormat("plain-text"),
+			},
+			errNegativeMaxDuration,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				method:      "GET",
+				readyStatus: &statusRanges{{200, 200}},
+				format:      knownFormat("plain-text"),
+			},
+			errReadyStatusWithoutWait,
+		},
+		{
+			config{
+				numConns: 1,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				hosts:    &hostList{"a:8080", "b:8080"},
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				format:   knownFormat("plain-text"),
+			},
+			errFewerConnsThanHosts,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				hosts:    &hostList{"a:8080"},
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				scenario: "scenario.json",
+				format:   knownFormat("plain-text"),
+			},
+			errHostsNotSupported,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "CONNECT",
+				format:   knownFormat("plain-text"),
+			},
+			errNoConnectTarget,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "GET",
+				connectTarget: "example.com:443",
+				format:        knownFormat("plain-text"),
+			},
+			errConnectTargetMethod,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "CONNECT",
+				connectTarget: "example.com",
+				format:        knownFormat("plain-text"),
+			},
+			errInvalidConnectTarget,
+		},
+		{
+			config{
+				numConns:      defaultNumberOfConns,
+				numReqs:       &defaultNumberOfReqs,
+				url:           "http://localhost:8080",
+				headers:       noHeaders,
+				timeout:       defaultTimeout,
+				method:        "CONNECT",
+				connectTarget: "example.com:443",
+				clientType:    nhttp2,
+				format:        knownFormat("plain-text"),
+			},
+			errConnectNotSupported,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				duration:    &defaultTestDuration,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				maxDuration: time.Second,
+				method:      "GET",
+				format:      knownFormat("plain-text"),
+			},
+			errMaxDurationNotCounted,
+		},
+		{
+			config{
+				numConns: defaultNumberOfConns,
+				numReqs:  &defaultNumberOfReqs,
+				duration: &defaultTestDuration,
+				url:      "http://localhost:8080",
+				headers:  noHeaders,
+				timeout:  defaultTimeout,
+				method:   "GET",
+				stream:   true,
+				scenario: "scenario.json",
+				format:   knownFormat("plain-text"),
+			},
+			errScenarioConflict,
+		},
+		{
+			config{
+				numConns:    defaultNumberOfConns,
+				numReqs:     &defaultNumberOfReqs,
+				duration:    &defaultTestDuration,
+				url:         "http://localhost:8080",
+				headers:     noHeaders,
+				timeout:     defaultTimeout,
+				idleTimeout: -time.Second,
+				method:      "GET",
+				format:      knownFormat("plain-text"),
+			},
+			errNegativeTimeout,
+		},
+		{
+			config{
+				numConns:        defaultNumberOfConns,
+				numReqs:         &defaultNumberOfReqs,
+				duration:        &defaultTestDuration,
+				url:             "http://localhost:8080",
+				headers:         noHeaders,
+				timeout:         defaultTimeout,
+				abortSlowerThan: negativeTimeoutDuration,
+				method:          "GET",
+				format:          knownFormat("plain-text"),
+			},
+			errNegativeTimeout,
+		},
+	}
+	for _, e := range expectations {
+		if r := e.in.checkArgs(); r != e.out {
+			t.Logf("Expected (%v).checkArgs to return %v, but got %v", e.in, e.out, r)
+			t.Fail()
+		}
+		if _, r := newBombardier(e.in); r != e.out {
+			t.Logf("Expected newBombardier(%v) to return %v, but got %v", e.in, e.out, r)
+			t.Fail()
+		}
+	}
+}
+
+func TestCheckArgsExitCodeWithoutGate(t *testing.T) {
+	code := uint64(3)
+	c := config{
+		numConns:    defaultNumberOfConns,
+		numReqs:     &defaultNumberOfReqs,
+		url:         "http://localhost:8080",
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		format:      knownFormat("plain-text"),
+		exitCodeRPS: &code,
+	}
+	err := c.checkArgs()
+	if err == nil || err.Error() != "--exit-code-rps requires --min-rps" {
+		t.Errorf("Expected --exit-code-rps to require --min-rps, but got %v",
+			err)
+	}
+	c.exitCodeRPS, c.exitCodeErrors = nil, &code
+	err = c.checkArgs()
+	if err == nil || err.Error() != "--exit-code-errors requires "+
+		"--max-error-rate or --abort-on-first-error" {
+		t.Errorf("Expected --exit-code-errors to require "+
+			"--max-error-rate, but got %v", err)
+	}
+	maxErrorRate := 1.0
+	c.maxErrorRate = &maxErrorRate
+	if err := c.checkArgs(); err != nil {
+		t.Errorf("Expected --exit-code-errors with --max-error-rate to "+
+			"be valid, but got %v", err)
+	}
+	// with --find-max-rps it only bounds the search
+	dur := time.Minute
+	c.numReqs, c.duration, c.findMaxRPS = nil, &dur, true
+	err = c.checkArgs()
+	if err == nil || err.Error() != "--exit-code-errors requires "+
+		"--max-error-rate or --abort-on-first-error" {
+		t.Errorf("Expected --exit-code-errors to require "+
+			"--max-error-rate without --find-max-rps, but got %v", err)
+	}
+}
+
+func TestCheckCompareClientsNamesRejectedFlags(t *testing.T) {
+	minRPS, maxErrorRate := 1.0, 1.0
+	maxLatency := time.Second
+	expectations := []struct {
+		flag string
+		set  func(c *config)
+		err  error
+	}{
+		{"--raw-request-file", func(c *config) {
+			c.rawRequestFile = "request.txt"
+		}, errCompareClientsConflict},
+		{"--slowloris", func(c *config) {
+			c.slowloris = true
+		}, errCompareClientsConflict},
+		{"--connect-target", func(c *config) {
+			c.connectTarget = "localhost:22"
+		}, errCompareClientsConflict},
+		{"--tui", func(c *config) {
+			c.tui = true
+		}, errCompareClientsConflict},
+		{"--report-template-file", func(c *config) {
+			c.reportTemplateFile = "report.tmpl"
+		}, errCompareClientsConflict},
+		{"plain-text", func(c *config) {
+			c.format = knownFormat("json")
+		}, errCompareClientsConflict},
+		{"--compare-baseline", func(c *config) {
+			c.compareBaseline = "baseline.json"
+		}, errCompareClientsOutputs},
+		{"--min-rps", func(c *config) {
+			c.minRPS = &minRPS
+		}, errCompareClientsOutputs},
+		{"--expect-distribution", func(c *config) {
+			c.expectDistribution = new(codeDistribution)
+		}, errCompareClientsOutputs},
+		{"--max-latency-p99", func(c *config) {
+			c.maxLatencyP99 = &maxLatency
+		}, errCompareClientsOutputs},
+		{"--max-error-rate", func(c *config) {
+			c.maxErrorRate = &maxErrorRate
+		}, errCompareClientsOutputs},
+		{"--trace-file", func(c *config) {
+			c.traceFile = "trace.log"
+		}, errCompareClientsOutputs},
+		{"--output-raw-latencies", func(c *config) {
+			c.rawLatenciesFile = "latencies.csv"
+		}, errCompareClientsOutputs},
+		{"--csv-out", func(c *config) {
+			c.rpsCSVFile = "rps.csv"
+		}, errCompareClientsOutputs},
+		{"--per-conn-stats", func(c *config) {
+			c.perConnStats = "conns.csv"
+		}, errCompareClientsOutputs},
+		{"--rps-histogram-file", func(c *config) {
+			c.rpsHistogramFile = "rps.txt"
+		}, errCompareClientsOutputs},
+		{"--notify-url", func(c *config) {
+			c.notifyURL = "http://localhost:9090"
+		}, errCompareClientsOutputs},
+		{"--recovery-probe", func(c *config) {
+			c.recoveryProbe = time.Second
+		}, errCompareClientsOutputs},
+	}
+	for _, e := range expectations {
+		c := config{
+			numConns:       defaultNumberOfConns,
+			numReqs:        &defaultNumberOfReqs,
+			url:            "http://localhost:8080",
+			headers:        new(headersList),
+			timeout:        defaultTimeout,
+			method:         "GET",
+			format:         knownFormat("plain-text"),
+			compareClients: true,
+		}
+		e.set(&c)
+		err := c.checkCompareClients()
+		if err != e.err {
+			t.Errorf("Expected %v to be rejected with %v, but got %v",
+				e.flag, e.err, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), e.flag) {
+			t.Errorf("Expected %q to name %v", err, e.flag)
 		}
 	}
 }
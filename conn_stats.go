@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+)
+
+var connStatsHeader = []string{
+	"conn", "requests", "errors", "latency_mean_us",
+}
+
+// connStats accumulates statistics of a single worker for
+// --per-conn-stats. Each worker keeps one request in flight, so
+// unless --pipeline is set workers correspond to connections. Only
+// the worker itself writes its stats and they're read once all of the
+// workers are done, so no synchronization is needed.
+type connStats struct {
+	reqs, errs, usSum uint64
+}
+
+// record is a no-op on nil stats, so that workers don't have to check
+// whether --per-conn-stats is set.
+func (s *connStats) record(usTaken uint64, err error) {
+	if s == nil {
+		return
+	}
+	s.reqs++
+	s.usSum += usTaken
+	if err != nil {
+		s.errs++
+	}
+}
+
+func (s *connStats) meanUs() float64 {
+	if s.reqs == 0 {
+		return 0
+	}
+	return float64(s.usSum) / float64(s.reqs)
+}
+
+func writeConnStats(out io.Writer, stats []connStats) error {
+	w := csv.NewWriter(out)
+	_ = w.Write(connStatsHeader)
+	for i := range stats {
+		s := &stats[i]
+		_ = w.Write([]string{
+			strconv.Itoa(i),
+			strconv.FormatUint(s.reqs, decBase),
+			strconv.FormatUint(s.errs, decBase),
+			strconv.FormatFloat(s.meanUs(), 'f', 2, 64),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// dumpConnStats writes --per-conn-stats to the file at path.
+func (b *bombardier) dumpConnStats(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeConnStats(f, b.connStats); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
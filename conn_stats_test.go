@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestWriteConnStats(t *testing.T) {
+	stats := make([]connStats, 2)
+	stats[0].record(100, nil)
+	stats[0].record(200, errors.New("fail"))
+	var out bytes.Buffer
+	if err := writeConnStats(&out, stats); err != nil {
+		t.Fatal(err)
+	}
+	expected := "conn,requests,errors,latency_mean_us\n" +
+		"0,2,1,150.00\n" +
+		"1,0,0,0.00\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, out.String())
+	}
+	// workers record into nil stats without --per-conn-stats
+	var none *connStats
+	none.record(100, nil)
+}
+
+func TestBombardierPerConnStats(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	f, err := ioutil.TempFile("", "conn-stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	numConns, numReqs := uint64(3), uint64(30)
+	b, e := newBombardier(config{
+		numConns:     numConns,
+		numReqs:      &numReqs,
+		url:          s.URL,
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		perConnStats: f.Name(),
+		format:       knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if err := b.dumpConnStats(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != int(numConns)+1 {
+		t.Fatalf("Expected %v rows, but got %q", numConns+1, content)
+	}
+	total := uint64(0)
+	for _, rec := range records[1:] {
+		reqs, err := strconv.ParseUint(rec[1], decBase, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += reqs
+		if rec[2] != "0" {
+			t.Errorf("Expected no errors, but got %q", rec)
+		}
+	}
+	if total != numReqs {
+		t.Errorf("Expected %v requests in total, but got %v", numReqs, total)
+	}
+}
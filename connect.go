@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/textproto"
+)
+
+// connectClient benchmarks CONNECT handling of the proxy at the
+// target URL. Each request establishes a tunnel over a new
+// connection and the time until the proxy's response is taken as
+// latency, the tunnel is closed afterwards.
+type connectClient struct {
+	*rawClient
+}
+
+func newConnectClient(opts *clientOpts) client {
+	request := connectRequest(
+		opts.connectTarget, opts.headers, opts.headerCasePreserve,
+	)
+	return client(&connectClient{newRawClient(opts, request)})
+}
+
+func connectRequest(
+	target string, headers *headersList, preserveCase bool,
+) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "CONNECT %v HTTP/1.1\r\n", target)
+	if headers == nil || !headers.contains("Host") {
+		fmt.Fprintf(&buf, "Host: %v\r\n", target)
+	}
+	if headers != nil {
+		for _, h := range *headers {
+			key := h.key
+			if !preserveCase {
+				key = textproto.CanonicalMIMEHeaderKey(key)
+			}
+			fmt.Fprintf(&buf, "%v: %v\r\n", key, h.value)
+		}
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func (c *connectClient) doRequest(r *request) (
+	code int, usTaken uint64, body []byte, err error,
+) {
+	// config guarantees that there are no scenarios with CONNECT
+	return -1, 0, nil, errConnectNotSupported
+}
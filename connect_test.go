@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectRequest(t *testing.T) {
+	expectations := []struct {
+		headers      *headersList
+		preserveCase bool
+		out          string
+	}{
+		{
+			nil, false,
+			"CONNECT example.com:443 HTTP/1.1\r\n" +
+				"Host: example.com:443\r\n\r\n",
+		},
+		{
+			&headersList{{"proxy-authorization", "Basic dTpw"}}, false,
+			"CONNECT example.com:443 HTTP/1.1\r\n" +
+				"Host: example.com:443\r\n" +
+				"Proxy-Authorization: Basic dTpw\r\n\r\n",
+		},
+		{
+			&headersList{{"host", "other:443"}, {"x-Id", "1"}}, true,
+			"CONNECT example.com:443 HTTP/1.1\r\n" +
+				"host: other:443\r\nx-Id: 1\r\n\r\n",
+		},
+	}
+	for _, e := range expectations {
+		out := string(connectRequest("example.com:443", e.headers,
+			e.preserveCase))
+		if out != e.out {
+			t.Errorf("Expected %q, but got %q", e.out, out)
+		}
+	}
+}
+
+func TestBombardierConnect(t *testing.T) {
+	tunnels := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method != "CONNECT" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.Host != "example.com:443" {
+				rw.WriteHeader(http.StatusForbidden)
+				return
+			}
+			atomic.AddUint64(&tunnels, 1)
+		}),
+	)
+	defer s.Close()
+	for _, target := range []string{"example.com:443", "other.com:443"} {
+		atomic.StoreUint64(&tunnels, 0)
+		numReqs := uint64(10)
+		b, e := newBombardier(config{
+			numConns:      defaultNumberOfConns,
+			numReqs:       &numReqs,
+			url:           s.URL,
+			headers:       new(headersList),
+			timeout:       defaultTimeout,
+			method:        "CONNECT",
+			connectTarget: target,
+			format:        knownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		if target == "example.com:443" {
+			if tunnels != numReqs || b.req2xx != numReqs {
+				t.Errorf("Expected %v tunnels, but got %v (%v 2xx)",
+					numReqs, tunnels, b.req2xx)
+			}
+		} else if b.req4xx != numReqs {
+			t.Errorf("Expected %v 4xx, but got %v", numReqs, b.req4xx)
+		}
+		if b.latencies.Count() == 0 {
+			t.Error("Tunnel setup latencies should be recorded")
+		}
+	}
+}
+
+func TestBombardierConnectAbortsSlowProxy(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(2)
+	b, e := newBombardier(config{
+		numConns:        defaultNumberOfConns,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		abortSlowerThan: 10 * time.Millisecond,
+		method:          "CONNECT",
+		connectTarget:   "example.com:443",
+		format:          knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.aborted != numReqs {
+		t.Errorf("Expected %v aborted tunnels, but got %v (errors: %v)",
+			numReqs, b.aborted, b.errors.byFrequency())
+	}
+}
+
+func TestBombardierConnectTLS(t *testing.T) {
+	s := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.Host, "example.com") {
+				rw.WriteHeader(http.StatusForbidden)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(5)
+	b, e := newBombardier(config{
+		numConns:      defaultNumberOfConns,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "CONNECT",
+		connectTarget: "example.com:443",
+		insecure:      true,
+		format:        knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBombardierConnectionCount(t *testing.T) {
+	testAllClients(t, testBombardierConnectionCount)
+}
+
+func testBombardierConnectionCount(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:        2,
+		numReqs:         &numReqs,
+		url:             s.URL,
+		headers:         new(headersList),
+		timeout:         defaultTimeout,
+		method:          "GET",
+		connectionCount: true,
+		clientType:      clientType,
+		format:          knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.ConnectionCount
+	if res == nil || res.Opened < 1 || res.Opened > 2 || res.Configured != 2 {
+		t.Errorf("Expected 1 or 2 connections opened out of 2, but got %+v",
+			res)
+	}
+}
+
+func TestBombardierConnectionCountWithoutKeepAlive(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:          2,
+		numReqs:           &numReqs,
+		url:               s.URL,
+		headers:           new(headersList),
+		timeout:           defaultTimeout,
+		method:            "GET",
+		disableKeepAlives: true,
+		connectionCount:   true,
+		clientType:        nhttp1,
+		format:            knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.ConnectionCount
+	if res == nil || res.Opened != numReqs {
+		t.Errorf("Expected a connection per request, but got %+v", res)
+	}
+}
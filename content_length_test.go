@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBombardierStrictContentLength(t *testing.T) {
+	testAllClients(t, testBombardierStrictContentLength)
+}
+
+func testBombardierStrictContentLength(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			conn, bw, err := rw.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			_, _ = bw.WriteString("HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 100\r\n\r\nshort body")
+			_ = bw.Flush()
+		}),
+	)
+	defer s.Close()
+	for _, strict := range []bool{false, true} {
+		numReqs := uint64(5)
+		b, e := newBombardier(config{
+			numConns:            1,
+			numReqs:             &numReqs,
+			url:                 s.URL,
+			headers:             new(headersList),
+			timeout:             defaultTimeout,
+			method:              "GET",
+			clientType:          clientType,
+			strictContentLength: strict,
+			format:              knownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		res := b.gatherInfo().Result
+		expected := uint64(0)
+		if strict {
+			expected = numReqs
+		}
+		if res.ContentLengthMismatches != expected {
+			t.Errorf("Expected %v mismatches with strict = %v, but got %v "+
+				"(errors: %v)", expected, strict,
+				res.ContentLengthMismatches, res.Errors)
+		}
+		if strict && (len(res.Errors) != 1 ||
+			res.Errors[0].Error != errContentLengthMismatch.Error()) {
+			t.Errorf("Expected only mismatches to be reported, but got %v",
+				res.Errors)
+		}
+	}
+}
+
+func TestBombardierStrictContentLengthMatches(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write([]byte("full body"))
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(5)
+	b, e := newBombardier(config{
+		numConns:            1,
+		numReqs:             &numReqs,
+		url:                 s.URL,
+		headers:             new(headersList),
+		timeout:             defaultTimeout,
+		method:              "GET",
+		strictContentLength: true,
+		format:              knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs || b.lengthMismatches != 0 {
+		t.Errorf("Expected %v 2xx and no mismatches, but got %v and %v "+
+			"(errors: %v)", numReqs, b.req2xx, b.lengthMismatches,
+			b.errors.byFrequency())
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+var csvHeader = []string{
+	"url", "method", "conns", "duration_seconds", "total_reqs",
+	"rps_mean", "latency_p50_us", "latency_p90_us", "latency_p99_us",
+	"2xx", "4xx", "5xx", "errors", "throughput_bytes_per_second",
+}
+
+// csvReporter writes a header and a single row with the key metrics,
+// so that results of successive runs are easy to put together.
+type csvReporter struct{}
+
+func (c *csvReporter) report(out io.Writer, info internal.TestInfo) error {
+	r := info.Result
+	var rpsMean float64
+	if rps := r.RequestsStats(nil); rps != nil {
+		rpsMean = rps.Mean
+	}
+	var p50, p90, p99 uint64
+	if lats := r.LatenciesStats([]float64{0.5, 0.9, 0.99}); lats != nil {
+		p50, p90, p99 = lats.Percentiles[0.5], lats.Percentiles[0.9],
+			lats.Percentiles[0.99]
+	}
+	var errs uint64
+	for _, e := range r.Errors {
+		errs += e.Count
+	}
+	total := r.Req1XX + r.Req2XX + r.Req3XX + r.Req4XX + r.Req5XX + r.Others
+	w := csv.NewWriter(out)
+	_ = w.Write(csvHeader)
+	_ = w.Write([]string{
+		info.Spec.URL,
+		info.Spec.Method,
+		strconv.FormatUint(info.Spec.NumberOfConnections, decBase),
+		strconv.FormatFloat(r.TimeTaken.Seconds(), 'f', -1, 64),
+		strconv.FormatUint(total, decBase),
+		strconv.FormatFloat(rpsMean, 'f', 2, 64),
+		strconv.FormatUint(p50, decBase),
+		strconv.FormatUint(p90, decBase),
+		strconv.FormatUint(p99, decBase),
+		strconv.FormatUint(r.Req2XX, decBase),
+		strconv.FormatUint(r.Req4XX, decBase),
+		strconv.FormatUint(r.Req5XX, decBase),
+		strconv.FormatUint(errs, decBase),
+		strconv.FormatFloat(r.Throughput(), 'f', 2, 64),
+	})
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestCSVReporter(t *testing.T) {
+	r, err := (&bombardier{}).prepareReporter(formatFromString("csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := r.report(out, testInfo()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv %q: %v", out, err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected header and a single row, but got %v", records)
+	}
+	row := make(map[string]string)
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	expected := map[string]string{
+		"url":                         "http://localhost:8080",
+		"method":                      "GET",
+		"conns":                       "5",
+		"duration_seconds":            "1",
+		"total_reqs":                  "10",
+		"rps_mean":                    "10.00",
+		"latency_p99_us":              "1000",
+		"2xx":                         "10",
+		"errors":                      "0",
+		"throughput_bytes_per_second": "1024.00",
+	}
+	for column, value := range expected {
+		if row[column] != value {
+			t.Errorf("Expected %v to be %q, but got %q",
+				column, value, row[column])
+		}
+	}
+}
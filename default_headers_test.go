@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// headerNamesServer records names of headers of requests it receives,
+// as they arrive on the wire, answering each with an empty 200.
+type headerNamesServer struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func newHeaderNamesServer(t *testing.T) *headerNamesServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &headerNamesServer{ln: ln, names: make(map[string]bool)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *headerNamesServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := textproto.NewReader(bufio.NewReader(conn))
+	for {
+		// request line
+		if _, err := r.ReadLine(); err != nil {
+			return
+		}
+		for {
+			line, err := r.ReadLine()
+			if err != nil {
+				return
+			}
+			if line == "" {
+				break
+			}
+			s.mu.Lock()
+			s.names[strings.SplitN(line, ":", 2)[0]] = true
+			s.mu.Unlock()
+		}
+		_, err := conn.Write([]byte(
+			"HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n",
+		))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *headerNamesServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []string
+	for name := range s.names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestBombardierNoDefaultHeaders(t *testing.T) {
+	testAllClients(t, testBombardierNoDefaultHeaders)
+}
+
+func testBombardierNoDefaultHeaders(clientType clientTyp, t *testing.T) {
+	for _, noDefaults := range []bool{false, true} {
+		s := newHeaderNamesServer(t)
+		numReqs := uint64(5)
+		b, e := newBombardier(config{
+			numConns:         1,
+			numReqs:          &numReqs,
+			url:              "http://" + s.ln.Addr().String(),
+			headers:          &headersList{{"X-Test", "1"}},
+			timeout:          defaultTimeout,
+			method:           "GET",
+			clientType:       clientType,
+			format:           knownFormat("plain-text"),
+			noDefaultHeaders: noDefaults,
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		s.ln.Close()
+		if b.req2xx != numReqs {
+			t.Fatalf("Expected %v 2xx, but got %v (errors: %v)",
+				numReqs, b.req2xx, b.errors.byFrequency())
+		}
+		names := s.received()
+		if noDefaults {
+			if exp := []string{"Host", "X-Test"}; !reflect.DeepEqual(names, exp) {
+				t.Errorf("Expected only %v, but got %v", exp, names)
+			}
+		} else if i := sort.SearchStrings(names, "User-Agent"); i == len(names) ||
+			names[i] != "User-Agent" {
+			t.Errorf("Expected default User-Agent, but got %v", names)
+		}
+	}
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"context"
 	"net"
+	"net/url"
 	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+
+	"golang.org/x/net/proxy"
 )
 
 type countingConn struct {
@@ -31,14 +36,46 @@ func (cc *countingConn) Write(b []byte) (n int, err error) {
 	return
 }
 
+// newDialer returns the dialer to establish connections with: through
+// the SOCKS5 proxy at proxyURL, authenticating with its username and
+// password, if any, or directly, if proxyURL is empty.
+func newDialer(proxyURL string) (proxy.ContextDialer, error) {
+	if proxyURL == "" {
+		return &net.Dialer{}, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		// proxyURL guaranteed to be valid at this point
+		panic(err)
+	}
+	d, err := proxy.FromURL(u, &net.Dialer{})
+	if err != nil {
+		return nil, err
+	}
+	// SOCKS5 dialer forwards contexts to the dialer it's given
+	return d.(proxy.ContextDialer), nil
+}
+
+// fasthttpDialFunc and httpDialContextFunc return dial functions that
+// count bytes read and written over connections they establish and,
+// if opened isn't nil, the connections themselves.
 var fasthttpDialFunc = func(
-	bytesRead, bytesWritten *int64,
+	dialer proxy.ContextDialer, bytesRead, bytesWritten *int64,
+	opened *uint64,
 ) func(string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
 	return func(address string) (net.Conn, error) {
-		conn, err := net.Dial("tcp", address)
+		conn, err := dialer.DialContext(
+			context.Background(), "tcp", address,
+		)
 		if err != nil {
 			return nil, err
 		}
+		if opened != nil {
+			atomic.AddUint64(opened, 1)
+		}
 
 		wrappedConn := &countingConn{
 			Conn:         conn,
@@ -51,14 +88,20 @@ var fasthttpDialFunc = func(
 }
 
 var httpDialContextFunc = func(
-	bytesRead, bytesWritten *int64,
+	dialer proxy.ContextDialer, bytesRead, bytesWritten *int64,
+	opened *uint64,
 ) func(context.Context, string, string) (net.Conn, error) {
-	dialer := &net.Dialer{}
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
 		conn, err := dialer.DialContext(ctx, network, address)
 		if err != nil {
 			return nil, err
 		}
+		if opened != nil {
+			atomic.AddUint64(opened, 1)
+		}
 
 		wrappedConn := &countingConn{
 			Conn:         conn,
@@ -69,3 +112,45 @@ var httpDialContextFunc = func(
 		return wrappedConn, nil
 	}
 }
+
+// retryingDialer implements --dial-retries: failed dials are retried up
+// to retries times, waiting dialRetryBackoff before the first retry
+// and twice as long before each next one, up to dialRetryMaxBackoff.
+// Retries and dials that failed after all of them are counted.
+type retryingDialer struct {
+	dialer  proxy.ContextDialer
+	retries uint64
+
+	// accessed atomically
+	retried, failed uint64
+}
+
+func (d *retryingDialer) DialContext(
+	ctx context.Context, network, address string,
+) (net.Conn, error) {
+	backoff := dialRetryBackoff
+	for attempt := uint64(0); ; attempt++ {
+		conn, err := d.dialer.DialContext(ctx, network, address)
+		if err == nil || ctx.Err() != nil {
+			return conn, err
+		}
+		if attempt == d.retries {
+			atomic.AddUint64(&d.failed, 1)
+			return nil, err
+		}
+		if !sleepOrDone(backoff, ctx.Done()) {
+			return nil, ctx.Err()
+		}
+		atomic.AddUint64(&d.retried, 1)
+		if backoff *= 2; backoff > dialRetryMaxBackoff {
+			backoff = dialRetryMaxBackoff
+		}
+	}
+}
+
+func (d *retryingDialer) result() *internal.DialRetriesResult {
+	return &internal.DialRetriesResult{
+		Retries: atomic.LoadUint64(&d.retried),
+		Failed:  atomic.LoadUint64(&d.failed),
+	}
+}
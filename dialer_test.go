@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// flakyDialer fails the first fail dials.
+type flakyDialer struct {
+	fail, dials int
+}
+
+func (d *flakyDialer) DialContext(
+	ctx context.Context, network, address string,
+) (net.Conn, error) {
+	d.dials++
+	if d.dials <= d.fail {
+		return nil, errors.New("connection refused")
+	}
+	c, _ := net.Pipe()
+	return c, nil
+}
+
+func TestRetryingDialer(t *testing.T) {
+	expectations := []struct {
+		fail            int
+		retries         uint64
+		ok              bool
+		retried, failed uint64
+	}{
+		{0, 3, true, 0, 0},
+		{2, 3, true, 2, 0},
+		{3, 3, true, 3, 0},
+		{4, 3, false, 3, 1},
+		{1, 0, false, 0, 1},
+	}
+	for _, e := range expectations {
+		fd := &flakyDialer{fail: e.fail}
+		d := &retryingDialer{dialer: fd, retries: e.retries}
+		conn, err := d.DialContext(context.Background(), "tcp", "x:80")
+		if (err == nil) != e.ok {
+			t.Errorf("Expected dial with %v failures and %v retries to "+
+				"succeed: %v, but got %v", e.fail, e.retries, e.ok, err)
+		}
+		if conn != nil {
+			_ = conn.Close()
+		}
+		res := d.result()
+		if res.Retries != e.retried || res.Failed != e.failed {
+			t.Errorf("Expected %v retries and %v failed, but got %+v",
+				e.retried, e.failed, res)
+		}
+	}
+}
+
+func TestRetryingDialerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := &retryingDialer{dialer: &flakyDialer{fail: 10}, retries: 5}
+	if _, err := d.DialContext(ctx, "tcp", "x:80"); err == nil {
+		t.Error("Expected canceled dial to fail")
+	}
+	if res := d.result(); res.Retries != 0 || res.Failed != 0 {
+		t.Errorf("Expected no retries of canceled dial, but got %+v", res)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// codeDistribution holds percentages of responses expected in each
+// class of codes with --expect-distribution, indexed as codeClasses and
+// specified as comma-separated list of class:percent on the command
+// line. Classes that aren't listed are expected to get no responses.
+type codeDistribution [len(codeClasses)]float64
+
+func (d *codeDistribution) String() string {
+	var entries []string
+	for j := range d {
+		i := (j + 1) % len(d)
+		if p := d[i]; p > 0 {
+			entries = append(entries, codeClasses[i]+":"+
+				strconv.FormatFloat(p, 'f', -1, 64))
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+func (d *codeDistribution) Set(value string) error {
+	var res codeDistribution
+	var seen [len(res)]bool
+	total := 0.0
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return &invalidDistributionError{entry}
+		}
+		class := codeClassIndex(strings.ToLower(parts[0]))
+		p, err := strconv.ParseFloat(parts[1], 64)
+		if class < 0 || seen[class] || err != nil || !(p >= 0 && p <= 100) {
+			return &invalidDistributionError{entry}
+		}
+		seen[class] = true
+		res[class] = p
+		total += p
+	}
+	if math.Abs(total-100) > 1e-9 {
+		return errDistributionTotal
+	}
+	*d = res
+	return nil
+}
+
+func codeClassIndex(class string) int {
+	for i, c := range codeClasses {
+		if c == class {
+			return i
+		}
+	}
+	return -1
+}
+
+type invalidDistributionError struct {
+	entry string
+}
+
+func (i *invalidDistributionError) Error() string {
+	return fmt.Sprintf("%q is not a valid distribution entry "+
+		"(must be class:percent, i.e. 2xx:90, with each class once)",
+		i.entry)
+}
+
+// checkDistribution reports whether shares of responses in each class
+// of codes are within --distribution-tolerance percentage points of
+// --expect-distribution.
+func (b *bombardier) checkDistribution(out io.Writer) bool {
+	expected := *b.conf.expectDistribution
+	tolerance := b.conf.distributionToleranceOrDefault()
+	counts := [len(expected)]uint64{
+		b.others, b.req1xx, b.req2xx, b.req3xx, b.req4xx, b.req5xx,
+	}
+	total := uint64(0)
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		fmt.Fprintln(out,
+			"FAILED: no responses to check --expect-distribution")
+		return false
+	}
+	passed := true
+	for j := range counts {
+		i := (j + 1) % len(counts)
+		observed := 100 * float64(counts[i]) / float64(total)
+		if math.Abs(observed-expected[i]) > tolerance {
+			fmt.Fprintf(out, "FAILED: %v made up %.2f%% of responses, "+
+				"%.2f%% ± %.2f expected\n",
+				codeClasses[i], observed, expected[i], tolerance)
+			passed = false
+		}
+	}
+	if passed {
+		fmt.Fprintf(out, "PASSED: codes distributed as expected "+
+			"(± %.2f)\n", tolerance)
+	}
+	return passed
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCodeDistributionSet(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out string
+		err error
+	}{
+		{"2xx:100", "2xx:100", nil},
+		{"5xx:10, 2XX:89.5,others:0.5", "2xx:89.5,5xx:10,others:0.5", nil},
+		{"2xx", "", &invalidDistributionError{"2xx"}},
+		{"6xx:100", "", &invalidDistributionError{"6xx:100"}},
+		{"2xx:101", "", &invalidDistributionError{"2xx:101"}},
+		{"2xx:-1,5xx:101", "", &invalidDistributionError{"2xx:-1"}},
+		{"2xx:50,2xx:50", "", &invalidDistributionError{"2xx:50"}},
+		{"2xx:90,5xx:5", "", errDistributionTotal},
+	}
+	for _, e := range expectations {
+		var d codeDistribution
+		err := d.Set(e.in)
+		if e.err != nil {
+			if err == nil || err.Error() != e.err.Error() {
+				t.Errorf("Expected %q for %q, but got %v", e.err, e.in, err)
+			}
+			continue
+		}
+		if err != nil || d.String() != e.out {
+			t.Errorf("Expected %v for %q, but got %v (%v)",
+				e.out, e.in, d.String(), err)
+		}
+	}
+}
+
+func TestBombardierExpectDistribution(t *testing.T) {
+	var seq uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&seq, 1)%10 == 0 {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		distribution codeDistribution
+		tolerance    float64
+		passed       bool
+		output       string
+	}{
+		{codeDistribution{2: 90, 5: 10}, 0, true, "PASSED: codes distributed"},
+		{codeDistribution{2: 95, 5: 5}, 5, true, "PASSED: codes distributed"},
+		{
+			codeDistribution{2: 95, 5: 5}, 1, false,
+			"FAILED: 5xx made up 10.00% of responses, 5.00% ± 1.00 expected",
+		},
+		{
+			codeDistribution{2: 100}, 1, false,
+			"FAILED: 2xx made up 90.00% of responses",
+		},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(100)
+		distribution, tolerance := e.distribution, e.tolerance
+		b, err := newBombardier(config{
+			numConns:              defaultNumberOfConns,
+			numReqs:               &numReqs,
+			url:                   s.URL,
+			headers:               new(headersList),
+			timeout:               defaultTimeout,
+			method:                "GET",
+			format:                knownFormat("plain-text"),
+			expectDistribution:    &distribution,
+			distributionTolerance: &tolerance,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if passed := b.gatesExitCode() == 0; passed != e.passed {
+			t.Errorf("Expected gates to pass: %v, but got %v\n%s",
+				e.passed, passed, out)
+		}
+		if !strings.Contains(out.String(), e.output) {
+			t.Errorf("Expected %q in output:\n%s", e.output, out)
+		}
+	}
+}
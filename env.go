@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envVarRef matches ${NAME} references, other uses of $ (i.e. OData's
+// $filter in URLs) are left as they are.
+var envVarRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+type missingEnvVarError struct {
+	name string
+}
+
+func (m *missingEnvVarError) Error() string {
+	return fmt.Sprintf("Environment variable %v is not set", m.name)
+}
+
+// expandEnv replaces ${VAR} in s with values of corresponding
+// environment variables. Unlike os.ExpandEnv, it fails if any of them
+// is not set.
+func expandEnv(s string) (string, error) {
+	var missing error
+	res := envVarRef.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[len("${") : len(ref)-len("}")]
+		val, ok := os.LookupEnv(name)
+		if !ok && missing == nil {
+			missing = &missingEnvVarError{name}
+		}
+		return val
+	})
+	return res, missing
+}
+
+// unexpandedURL returns rawURL with ${VAR} references left as they
+// are, but with the default scheme and --query parameters added, to
+// be shown instead of the expanded URL.
+func unexpandedURL(rawURL string, params *queryList) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	return withRawQueryParams(rawURL, params)
+}
+
+// unexpandedHeaders returns headers as given, if expanding ${VAR}
+// references changed any of their values, to be shown instead of the
+// expanded ones, nil otherwise.
+func unexpandedHeaders(given, expanded *headersList) *headersList {
+	for i := range *given {
+		if (*given)[i].value != (*expanded)[i].value {
+			return given
+		}
+	}
+	return nil
+}
+
+func expandEnvInHeaders(h *headersList) (*headersList, error) {
+	var res headersList
+	for _, hdr := range *h {
+		value, err := expandEnv(hdr.value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, header{hdr.key, value})
+	}
+	return &res, nil
+}
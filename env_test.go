@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("BOMBARDIER_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("BOMBARDIER_TEST_TOKEN")
+	os.Unsetenv("BOMBARDIER_TEST_MISSING")
+	expectations := []struct {
+		in, out string
+		missing string
+	}{
+		{"Bearer ${BOMBARDIER_TEST_TOKEN}", "Bearer s3cr3t", ""},
+		{"$BOMBARDIER_TEST_TOKEN", "$BOMBARDIER_TEST_TOKEN", ""},
+		{"/items?$filter=price gt 5", "/items?$filter=price gt 5", ""},
+		{"$1, 100$ and $", "$1, 100$ and $", ""},
+		{"${not a name}", "${not a name}", ""},
+		{"$${BOMBARDIER_TEST_TOKEN}", "$s3cr3t", ""},
+		{"no variables", "no variables", ""},
+		{"${BOMBARDIER_TEST_MISSING}", "", "BOMBARDIER_TEST_MISSING"},
+	}
+	for _, e := range expectations {
+		out, err := expandEnv(e.in)
+		if e.missing != "" {
+			merr, ok := err.(*missingEnvVarError)
+			if !ok || merr.name != e.missing {
+				t.Errorf("Expected %v to be reported missing, but got %v",
+					e.missing, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if out != e.out {
+			t.Errorf("Expected %q, but got %q", e.out, out)
+		}
+	}
+}
+
+func TestArgsParsingEnvExpansion(t *testing.T) {
+	os.Setenv("BOMBARDIER_TEST_HOST", "somehost.somedomain")
+	defer os.Unsetenv("BOMBARDIER_TEST_HOST")
+	os.Setenv("BOMBARDIER_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("BOMBARDIER_TEST_TOKEN")
+	os.Unsetenv("BOMBARDIER_TEST_MISSING")
+
+	c, err := newKingpinParser().parse([]string{
+		programName,
+		"-H", "Authorization: Bearer ${BOMBARDIER_TEST_TOKEN}",
+		"https://${BOMBARDIER_TEST_HOST}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.url != "https://somehost.somedomain:443" {
+		t.Errorf("URL wasn't expanded: %v", c.url)
+	}
+	if c.displayURL() != "https://${BOMBARDIER_TEST_HOST}" {
+		t.Errorf("URL should be shown as given: %v", c.displayURL())
+	}
+	if v := (*c.headers)[0].value; v != "Bearer s3cr3t" {
+		t.Errorf("Header wasn't expanded: %v", v)
+	}
+
+	_, err = newKingpinParser().parse([]string{
+		programName,
+		"-H", "Authorization: Bearer ${BOMBARDIER_TEST_MISSING}",
+		"https://somehost.somedomain",
+	})
+	if merr, ok := err.(*missingEnvVarError); !ok ||
+		merr.name != "BOMBARDIER_TEST_MISSING" {
+		t.Errorf("Expected missing variable error, but got %v", err)
+	}
+
+	c, err = newKingpinParser().parse([]string{
+		programName,
+		"-H", "X-Price: $5",
+		"https://somehost.somedomain/items?$filter=price%20gt%205",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.url != "https://somehost.somedomain:443/items?$filter=price%20gt%205" {
+		t.Errorf("URL shouldn't be expanded: %v", c.url)
+	}
+	if c.displayURL() != c.url {
+		t.Errorf("Expected %v to be shown, but got %v",
+			c.url, c.displayURL())
+	}
+	if v := (*c.headers)[0].value; v != "$5" {
+		t.Errorf("Header shouldn't be expanded: %v", v)
+	}
+	if c.displayHeaders() != c.headers {
+		t.Errorf("Expected headers to be shown as sent, but got %v",
+			*c.displayHeaders())
+	}
+
+	c, err = newKingpinParser().parse([]string{
+		programName,
+		"--no-env-expand",
+		"-H", "X-Price: ${BOMBARDIER_TEST_TOKEN}",
+		"https://somehost.somedomain",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := (*c.headers)[0].value; v != "${BOMBARDIER_TEST_TOKEN}" {
+		t.Errorf("Header shouldn't be expanded: %v", v)
+	}
+
+	c, err = newKingpinParser().parse([]string{
+		programName,
+		"--oauth2-token-url", "https://auth.somedomain/token",
+		"--oauth2-client-id", "${BOMBARDIER_TEST_HOST}",
+		"--oauth2-client-secret", "${BOMBARDIER_TEST_TOKEN}",
+		"https://somehost.somedomain",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.oauth2ClientID != "somehost.somedomain" ||
+		c.oauth2ClientSecret != "s3cr3t" {
+		t.Errorf("OAuth2 credentials weren't expanded: %v, %v",
+			c.oauth2ClientID, c.oauth2ClientSecret)
+	}
+}
+
+func TestBombardierHidesExpandedURL(t *testing.T) {
+	os.Setenv("BOMBARDIER_TEST_PASS", "pa55w0rd")
+	defer os.Unsetenv("BOMBARDIER_TEST_PASS")
+	os.Setenv("BOMBARDIER_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("BOMBARDIER_TEST_TOKEN")
+
+	var gotToken, gotAuth string
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			gotToken = r.URL.Query().Get("token")
+			gotAuth = r.Header.Get("Authorization")
+		}),
+	)
+	defer s.Close()
+	c, err := newKingpinParser().parse([]string{
+		programName,
+		"-n", "1",
+		"-c", "1",
+		"-o", "json",
+		"--query", "q=1",
+		"-H", "Authorization: Bearer ${BOMBARDIER_TEST_TOKEN}",
+		strings.Replace(s.URL, "://", "://user:${BOMBARDIER_TEST_PASS}@", 1) +
+			"/?token=${BOMBARDIER_TEST_TOKEN}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newBombardier(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printIntro()
+	b.bombard()
+	b.printStats()
+	if gotToken != "s3cr3t" || gotAuth != "Bearer s3cr3t" {
+		t.Errorf("Expected expanded URL and headers to be sent, "+
+			"but got %q, %q", gotToken, gotAuth)
+	}
+	for _, secret := range []string{"pa55w0rd", "s3cr3t"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("Output contains %q: %v", secret, out)
+		}
+	}
+	if !strings.Contains(out.String(), "${BOMBARDIER_TEST_TOKEN}&q=1") {
+		t.Errorf("Expected the URL to be shown as given: %v", out)
+	}
+	if !strings.Contains(out.String(), "Bearer ${BOMBARDIER_TEST_TOKEN}") {
+		t.Errorf("Expected headers to be shown as given: %v", out)
+	}
+}
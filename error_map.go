@@ -1,10 +1,16 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
 	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
 )
 
 type errorMap struct {
@@ -18,7 +24,9 @@ func newErrorMap() *errorMap {
 	return em
 }
 
-func (e *errorMap) add(err error) {
+// add records the error and reports whether it's the first
+// occurrence of it.
+func (e *errorMap) add(err error) (first bool) {
 	s := err.Error()
 	e.mu.RLock()
 	c, ok := e.m[s]
@@ -29,10 +37,12 @@ func (e *errorMap) add(err error) {
 		if !ok {
 			c = new(uint64)
 			e.m[s] = c
+			first = true
 		}
 		e.mu.Unlock()
 	}
 	atomic.AddUint64(c, 1)
+	return first
 }
 
 func (e *errorMap) get(err error) uint64 {
@@ -90,3 +100,27 @@ func (e *errorMap) byFrequency() errorsByFrequency {
 	sort.Sort(byFreq)
 	return byFreq
 }
+
+// isConnectionError tells whether err happened while establishing
+// a connection, i.e. dialing or performing TLS handshake, rather than
+// while sending a request or receiving a response.
+func isConnectionError(err error) bool {
+	if err == fasthttp.ErrDialTimeout {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" ||
+		opErr.Op == "remote error" || opErr.Op == "socks connect") {
+		// TLS alerts are reported as "remote error", failures to
+		// connect through SOCKS5 proxy as "socks connect"
+		return true
+	}
+	var (
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &recordErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -1,18 +1,28 @@
 package main
 
 import (
+	"crypto/x509"
 	"errors"
+	"net"
+	"net/url"
 	"reflect"
 	"testing"
+
+	"github.com/valyala/fasthttp"
 )
 
 func TestErrorMapAdd(t *testing.T) {
 	m := newErrorMap()
 	err := errors.New("add")
-	m.add(err)
+	if !m.add(err) {
+		t.Error("First occurrence should be reported")
+	}
 	if c := m.get(err); c != 1 {
 		t.Error(c)
 	}
+	if m.add(err) {
+		t.Error("Only first occurrence should be reported")
+	}
 }
 
 func TestErrorMapGet(t *testing.T) {
@@ -78,3 +88,29 @@ func BenchmarkErrorMapGet(b *testing.B) {
 		}
 	})
 }
+
+func TestIsConnectionError(t *testing.T) {
+	expectations := []struct {
+		err      error
+		expected bool
+	}{
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, true},
+		{&url.Error{
+			Op:  "Get",
+			Err: &net.OpError{Op: "dial", Err: errors.New("refused")},
+		}, true},
+		{&net.OpError{Op: "remote error", Err: errors.New("tls")}, true},
+		{x509.UnknownAuthorityError{}, true},
+		{fasthttp.ErrDialTimeout, true},
+		{&net.OpError{Op: "read", Err: errors.New("reset")}, false},
+		{errAborted, false},
+		{&unexpectedStatusError{500}, false},
+		{errors.New("EOF"), false},
+	}
+	for _, e := range expectations {
+		if got := isConnectionError(e.err); got != e.expected {
+			t.Errorf("Expected isConnectionError(%v) to be %v",
+				e.err, e.expected)
+		}
+	}
+}
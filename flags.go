@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -66,3 +69,97 @@ func (n *nullableString) Set(value string) error {
 	*n.val = value
 	return nil
 }
+
+type nullableSize struct {
+	val *uint64
+}
+
+func (n *nullableSize) String() string {
+	if n.val == nil {
+		return nilStr
+	}
+	return strconv.FormatUint(*n.val, 10)
+}
+
+func (n *nullableSize) Set(value string) error {
+	res, err := parseSize(value)
+	if err != nil {
+		return err
+	}
+	n.val = new(uint64)
+	*n.val = res
+	return nil
+}
+
+// parseSize parses sizes like "512", "512B", "64KB" or "1.5MB" into
+// number of bytes. Suffixes are the same that formatBinary produces
+// and are case-insensitive.
+func parseSize(value string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := float64(1)
+	for i := len(binaryUnits.units) - 1; i >= 0; i-- {
+		unit := binaryUnits.units[i]
+		if strings.HasSuffix(s, unit) {
+			s = s[:len(s)-len(unit)]
+			multiplier = math.Pow(float64(binaryUnits.scale), float64(i+1))
+			break
+		}
+	}
+	if multiplier == 1 {
+		s = strings.TrimSuffix(s, "B")
+	}
+	res, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || res < 0 || math.IsInf(res, 0) || math.IsNaN(res) {
+		return 0, fmt.Errorf("%q is not a valid size", value)
+	}
+	// float64(math.MaxUint64) is 2^64, which uint64 can't hold
+	size := res * multiplier
+	if size >= float64(math.MaxUint64) {
+		return 0, fmt.Errorf("%q is too large a size", value)
+	}
+	return uint64(size), nil
+}
+
+type nullableFloat64 struct {
+	val *float64
+}
+
+func (n *nullableFloat64) String() string {
+	if n.val == nil {
+		return nilStr
+	}
+	return strconv.FormatFloat(*n.val, 'g', -1, 64)
+}
+
+func (n *nullableFloat64) Set(value string) error {
+	res, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	n.val = new(float64)
+	*n.val = res
+	return nil
+}
+
+// nullablePercent is a number of percents, optionally followed by
+// "%", i.e. "1%".
+type nullablePercent struct {
+	val *float64
+}
+
+func (n *nullablePercent) String() string {
+	if n.val == nil {
+		return nilStr
+	}
+	return strconv.FormatFloat(*n.val, 'g', -1, 64) + "%"
+}
+
+func (n *nullablePercent) Set(value string) error {
+	res, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return err
+	}
+	n.val = new(float64)
+	*n.val = res
+	return nil
+}
@@ -78,3 +78,84 @@ func TestNullableStringConversionToString(t *testing.T) {
 		t.Errorf("Expected %q, but got %q", someVal, act)
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out uint64
+		ok  bool
+	}{
+		{"0", 0, true},
+		{"512", 512, true},
+		{"512B", 512, true},
+		{"1KB", 1024, true},
+		{"1kb", 1024, true},
+		{"1.5MB", 1536 * 1024, true},
+		{"2GB", 2 * 1024 * 1024 * 1024, true},
+		{"", 0, false},
+		{"KB", 0, false},
+		{"-1KB", 0, false},
+		{"ten", 0, false},
+		{"16383PB", 16383 << 50, true},
+		{"16384PB", 0, false},
+		{"99999999999TB", 0, false},
+		{"1e30", 0, false},
+	}
+	for _, e := range expectations {
+		act, err := parseSize(e.in)
+		if (err == nil) != e.ok {
+			t.Errorf("For %q, expected ok = %v, but got %v", e.in, e.ok, err)
+			continue
+		}
+		if act != e.out {
+			t.Errorf("For %q, expected %v, but got %v", e.in, e.out, act)
+		}
+	}
+}
+
+func TestNullableSizeConversionToString(t *testing.T) {
+	ns := new(nullableSize)
+	if act := ns.String(); act != nilStr {
+		t.Error("Unset nullableSize should convert to \"nil\"")
+	}
+	if err := ns.Set("1KB"); err != nil {
+		t.Error(err)
+	}
+	if act := ns.String(); act != "1024" {
+		t.Errorf("Expected 1024, but got %q", act)
+	}
+}
+
+func TestNullableFloat64(t *testing.T) {
+	n := &nullableFloat64{}
+	if s := n.String(); s != "nil" {
+		t.Errorf("Expected \"nil\", but got %v", s)
+	}
+	if err := n.Set("ten"); err == nil {
+		t.Error("Should fail on non-numeric values")
+	}
+	if err := n.Set("12.5"); err != nil || *n.val != 12.5 {
+		t.Errorf("Expected 12.5, but got %v(%v)", n.val, err)
+	}
+	if s := n.String(); s != "12.5" {
+		t.Errorf("Expected 12.5, but got %v", s)
+	}
+}
+
+func TestNullablePercent(t *testing.T) {
+	n := &nullablePercent{}
+	if s := n.String(); s != "nil" {
+		t.Errorf("Expected \"nil\", but got %v", s)
+	}
+	if err := n.Set("ten%"); err == nil {
+		t.Error("Should fail on non-numeric values")
+	}
+	for _, value := range []string{"0.5", "0.5%"} {
+		if err := n.Set(value); err != nil || *n.val != 0.5 {
+			t.Errorf("Expected 0.5, but got %v(%v)", n.val, err)
+		}
+	}
+	if s := n.String(); s != "0.5%" {
+		t.Errorf("Expected 0.5%%, but got %v", s)
+	}
+}
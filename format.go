@@ -46,10 +46,16 @@ func formatBinary(n float64) string {
 }
 
 func formatTimeUs(n float64) string {
+	return formatTimeUsPrec(n, defaultLatencyPrecision)
+}
+
+// formatTimeUsPrec is like formatTimeUs, but with prec digits after
+// the decimal point.
+func formatTimeUsPrec(n float64, prec int) string {
 	units := timeUnitsUs
 	if n >= 1000000.0 {
 		n /= 1000000.0
 		units = timeUnitsS
 	}
-	return formatUnits(n, units, 2)
+	return formatUnits(n, units, prec)
 }
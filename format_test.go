@@ -63,3 +63,23 @@ func TestShouldFormatUs(t *testing.T) {
 		}
 	}
 }
+
+func TestShouldFormatUsWithPrecision(t *testing.T) {
+	expectations := []struct {
+		in   float64
+		prec int
+		out  string
+	}{
+		{22.2225, 0, "22us"},
+		{22.2225, 3, "22.223us"},
+		{1234.5, 3, "1.234ms"},
+		{1234.5, 4, "1.2345ms"},
+		{90 * 60 * M, 1, "1.5h"},
+	}
+	for _, e := range expectations {
+		actual := formatTimeUsPrec(e.in, e.prec)
+		if e.out != actual {
+			t.Errorf("Expected \"%v\", but got \"%v\"", e.out, actual)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// gatesExitCode runs post-test checks (i.e. comparison with baseline),
+// printing their results to errOut, so that they don't mix with
+// results in machine-readable formats. It returns the exit code of the first
+// one that failed, as set with --exit-code-* flags, or zero if all of
+// them passed. Errors are checked last, so that a test stopped by
+// --abort-on-first-error doesn't hide failures of other checks.
+func (b *bombardier) gatesExitCode() int {
+	out := b.errOut
+	code := 0
+	check := func(passed bool, exitCode *uint64) {
+		if !passed && code == 0 {
+			code = exitCodeOrFailure(exitCode)
+		}
+	}
+	if b.baseline != nil {
+		check(b.compareWithBaseline(out), b.conf.exitCodeLatency)
+	}
+	if b.conf.minRPS != nil {
+		check(b.checkMinRPS(out), b.conf.exitCodeRPS)
+	}
+	if b.conf.expectDistribution != nil {
+		check(b.checkDistribution(out), b.conf.exitCodeDistribution)
+	}
+	if b.conf.maxLatencyP99 != nil {
+		check(b.checkMaxLatency(out), b.conf.exitCodeLatency)
+	}
+	if b.conf.errorRateGate() != nil || b.conf.abortOnFirstError {
+		check(b.checkErrors(out), b.conf.exitCodeErrors)
+	}
+	return code
+}
+
+// exitCodeOrFailure returns the exit code set with one of --exit-code-*
+// flags, exitFailure if it wasn't.
+func exitCodeOrFailure(code *uint64) int {
+	if code == nil {
+		return exitFailure
+	}
+	return int(*code)
+}
+
+// checkMinRPS reports whether mean RPS, the same one printed in the
+// results, is at least --min-rps.
+func (b *bombardier) checkMinRPS(out io.Writer) bool {
+	required := *b.conf.minRPS
+	rps := b.gatherInfo().Result.RequestsStats(nil)
+	if rps == nil {
+		fmt.Fprintln(out, "FAILED: not enough data to check --min-rps")
+		return false
+	}
+	if rps.Mean < required {
+		fmt.Fprintf(out, "FAILED: achieved %.2f rps < %.2f required\n",
+			rps.Mean, required)
+		return false
+	}
+	fmt.Fprintf(out, "PASSED: achieved %.2f rps >= %.2f required\n",
+		rps.Mean, required)
+	return true
+}
+
+// checkMaxLatency reports whether at most --latency-grace percent of
+// requests exceeded --max-latency-p99, i.e. whether latency at the
+// percentile the grace leaves is within it.
+func (b *bombardier) checkMaxLatency(out io.Writer) bool {
+	limit := *b.conf.maxLatencyP99
+	grace := b.conf.latencyGraceOrDefault()
+	pc := (100 - grace) / 100
+	lats := b.gatherInfo().Result.LatenciesStats([]float64{pc})
+	if lats == nil {
+		fmt.Fprintln(out,
+			"FAILED: not enough data to check --max-latency-p99")
+		return false
+	}
+	name := "p" + strconv.FormatFloat(100-grace, 'f', -1, 64)
+	latency := float64(lats.Percentiles[pc])
+	if latency > float64(limit.Nanoseconds()/1000) {
+		fmt.Fprintf(out, "FAILED: %v latency %v > %v (more than %v%% "+
+			"of requests exceeded it)\n", name,
+			b.conf.formatLatency(latency), limit, grace)
+		return false
+	}
+	fmt.Fprintf(out, "PASSED: %v latency %v <= %v (at most %v%% of "+
+		"requests exceeded it)\n", name,
+		b.conf.formatLatency(latency), limit, grace)
+	return true
+}
+
+// checkErrors reports whether the test wasn't stopped by
+// --abort-on-first-error and at most --max-error-rate percent of
+// requests failed.
+func (b *bombardier) checkErrors(out io.Writer) bool {
+	res := b.gatherInfo().Result
+	if fe := res.FirstError; fe != nil {
+		fmt.Fprintf(out, "FAILED: the test was stopped by request %v "+
+			"failing with %v\n", fe.Request, fe.Error)
+		return false
+	}
+	if b.conf.errorRateGate() == nil {
+		return true
+	}
+	limit := *b.conf.errorRateGate()
+	total := res.Req1XX + res.Req2XX + res.Req3XX + res.Req4XX +
+		res.Req5XX + res.Others
+	if total == 0 {
+		fmt.Fprintln(out, "FAILED: not enough data to check --max-error-rate")
+		return false
+	}
+	errs := res.ConnectionErrors + res.RequestErrors
+	percent := float64(errs) / float64(total) * 100
+	if percent > limit {
+		fmt.Fprintf(out, "FAILED: %.2f%% of requests failed > %v%% "+
+			"allowed\n", percent, limit)
+		return false
+	}
+	fmt.Fprintf(out, "PASSED: %.2f%% of requests failed <= %v%% "+
+		"allowed\n", percent, limit)
+	return true
+}
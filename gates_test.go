@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBombardierMinRPS(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		minRPS float64
+		passed bool
+		output string
+	}{
+		{1, true, "PASSED: achieved "},
+		{1e12, false, " rps < 1000000000000.00 required"},
+	}
+	for _, e := range expectations {
+		minRPS := e.minRPS
+		duration := time.Second
+		b, err := newBombardier(config{
+			numConns: defaultNumberOfConns,
+			duration: &duration,
+			url:      s.URL,
+			headers:  new(headersList),
+			timeout:  defaultTimeout,
+			method:   "GET",
+			format:   knownFormat("plain-text"),
+			minRPS:   &minRPS,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if passed := b.gatesExitCode() == 0; passed != e.passed {
+			t.Errorf("Expected gates to pass: %v, but got %v\n%s",
+				e.passed, passed, out)
+		}
+		if !strings.Contains(out.String(), e.output) {
+			t.Errorf("Expected %q in output:\n%s", e.output, out)
+		}
+	}
+}
+
+func TestBombardierMaxLatency(t *testing.T) {
+	var served uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			// every tenth request is slow
+			if atomic.AddUint64(&served, 1)%10 == 0 {
+				time.Sleep(50 * time.Millisecond)
+			}
+		}),
+	)
+	defer s.Close()
+	limit, grace := 25*time.Millisecond, 15.0
+	expectations := []struct {
+		grace  *float64
+		passed bool
+		output string
+	}{
+		{nil, false, "FAILED: p99 latency "},
+		{&grace, true, "PASSED: p85 latency "},
+	}
+	for _, e := range expectations {
+		atomic.StoreUint64(&served, 0)
+		numReqs := uint64(100)
+		b, err := newBombardier(config{
+			numConns:      1,
+			numReqs:       &numReqs,
+			url:           s.URL,
+			headers:       new(headersList),
+			timeout:       defaultTimeout,
+			method:        "GET",
+			format:        knownFormat("plain-text"),
+			maxLatencyP99: &limit,
+			latencyGrace:  e.grace,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if passed := b.gatesExitCode() == 0; passed != e.passed {
+			t.Errorf("Expected gates to pass: %v, but got %v\n%s",
+				e.passed, passed, out)
+		}
+		if !strings.Contains(out.String(), e.output) {
+			t.Errorf("Expected %q in output:\n%s", e.output, out)
+		}
+	}
+}
+
+func TestBombardierGatesExitCode(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	highRPS, lowRPS := 1e12, 1.0
+	limit := time.Nanosecond
+	rpsCode, latencyCode := uint64(3), uint64(4)
+	expectations := []struct {
+		minRPS      float64
+		rpsCode     *uint64
+		latencyCode *uint64
+		code        int
+	}{
+		{highRPS, &rpsCode, &latencyCode, 3},
+		{highRPS, nil, &latencyCode, exitFailure},
+		{lowRPS, &rpsCode, &latencyCode, 4},
+		{lowRPS, &rpsCode, nil, exitFailure},
+	}
+	for _, e := range expectations {
+		minRPS := e.minRPS
+		numReqs := uint64(10)
+		b, err := newBombardier(config{
+			numConns:        1,
+			numReqs:         &numReqs,
+			url:             s.URL,
+			headers:         new(headersList),
+			timeout:         defaultTimeout,
+			method:          "GET",
+			format:          knownFormat("plain-text"),
+			minRPS:          &minRPS,
+			maxLatencyP99:   &limit,
+			exitCodeRPS:     e.rpsCode,
+			exitCodeLatency: e.latencyCode,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if code := b.gatesExitCode(); code != e.code {
+			t.Errorf("Expected exit code %v, but got %v\n%s",
+				e.code, code, out)
+		}
+	}
+}
+
+func TestBombardierMaxErrorRate(t *testing.T) {
+	var served uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			// every fourth request fails
+			if atomic.AddUint64(&served, 1)%4 == 0 {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		maxErrorRate float64
+		passed       bool
+		output       string
+	}{
+		{10, false, "FAILED: 25.00% of requests failed > 10% allowed"},
+		{30, true, "PASSED: 25.00% of requests failed <= 30% allowed"},
+	}
+	for _, e := range expectations {
+		atomic.StoreUint64(&served, 0)
+		maxErrorRate := e.maxErrorRate
+		numReqs := uint64(100)
+		expectStatus := statusRanges{{200, 299}}
+		b, err := newBombardier(config{
+			numConns:     1,
+			numReqs:      &numReqs,
+			url:          s.URL,
+			headers:      new(headersList),
+			timeout:      defaultTimeout,
+			method:       "GET",
+			format:       knownFormat("plain-text"),
+			expectStatus: &expectStatus,
+			maxErrorRate: &maxErrorRate,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if passed := b.gatesExitCode() == 0; passed != e.passed {
+			t.Errorf("Expected gates to pass: %v, but got %v\n%s",
+				e.passed, passed, out)
+		}
+		if !strings.Contains(out.String(), e.output) {
+			t.Errorf("Expected %q in output:\n%s", e.output, out)
+		}
+	}
+}
+
+func TestBombardierGatesFirstErrorDoesntMaskOthers(t *testing.T) {
+	var served uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&served, 1) > 5 {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	limit := time.Nanosecond
+	latencyCode, errorsCode := uint64(3), uint64(4)
+	expectations := []struct {
+		maxLatencyP99 *time.Duration
+		code          int
+	}{
+		{&limit, 3},
+		{nil, 4},
+	}
+	for _, e := range expectations {
+		atomic.StoreUint64(&served, 0)
+		duration := time.Hour
+		expectStatus := statusRanges{{200, 299}}
+		c := config{
+			numConns:          1,
+			duration:          &duration,
+			url:               s.URL,
+			headers:           new(headersList),
+			timeout:           defaultTimeout,
+			method:            "GET",
+			format:            knownFormat("plain-text"),
+			expectStatus:      &expectStatus,
+			abortOnFirstError: true,
+			maxLatencyP99:     e.maxLatencyP99,
+			exitCodeErrors:    &errorsCode,
+		}
+		if e.maxLatencyP99 != nil {
+			c.exitCodeLatency = &latencyCode
+		}
+		b, err := newBombardier(c)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		b.errOut = out
+		if code := b.gatesExitCode(); code != e.code {
+			t.Errorf("Expected exit code %v, but got %v\n%s",
+				e.code, code, out)
+		}
+		msg := "FAILED: the test was stopped by request 6 failing"
+		if !strings.Contains(out.String(), msg) {
+			t.Errorf("Expected %q in output:\n%s", msg, out)
+		}
+	}
+}
+
+func TestBombardierGatesKeepJSONOutputValid(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	minRPS, maxErrorRate := 1.0, 50.0
+	limit := time.Minute
+	numReqs := uint64(20)
+	b, err := newBombardier(config{
+		numConns:      1,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		printResult:   true,
+		format:        knownFormat("json"),
+		minRPS:        &minRPS,
+		maxLatencyP99: &limit,
+		maxErrorRate:  &maxErrorRate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	b.out, b.errOut = out, errOut
+	b.printStats()
+	if code := b.gatesExitCode(); code != 0 {
+		t.Errorf("Expected gates to pass, but got %v\n%s", code, errOut)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Errorf("Expected valid json, but got %v:\n%s", err, out)
+	}
+	if n := strings.Count(errOut.String(), "PASSED: "); n != 3 {
+		t.Errorf("Expected results of 3 gates, but got:\n%s", errOut)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// goodputStats accounts sizes of response bodies as received for
+// --print-goodput, excluding headers and the framing of chunked
+// responses. Bodies that weren't read, i.e. with --ignore-body, aren't
+// accounted.
+type goodputStats struct {
+	bodyRead int64
+}
+
+func (g *goodputStats) add(n int64) {
+	atomic.AddInt64(&g.bodyRead, n)
+}
+
+// result relates sizes of bodies to bytes read during the test, the
+// rest of which is protocol overhead: headers, framing and TLS.
+func (g *goodputStats) result(r internal.Results) *internal.GoodputResult {
+	res := &internal.GoodputResult{BodyBytes: atomic.LoadInt64(&g.bodyRead)}
+	if r.TimeTaken > 0 {
+		res.Goodput = float64(res.BodyBytes) / r.TimeTaken.Seconds()
+	}
+	if r.BytesRead > 0 {
+		res.Share = 100 * float64(res.BodyBytes) / float64(r.BytesRead)
+	}
+	return res
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBombardierGoodput(t *testing.T) {
+	testAllClients(t, testBombardierGoodput)
+}
+
+func testBombardierGoodput(clientType clientTyp, t *testing.T) {
+	body := strings.Repeat("x", 1024)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("X-Padding", strings.Repeat("y", 512))
+			_, _ = rw.Write([]byte(body))
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:     1,
+		numReqs:      &numReqs,
+		url:          s.URL,
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		clientType:   clientType,
+		format:       knownFormat("plain-text"),
+		printGoodput: true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result
+	if res.Goodput == nil {
+		t.Fatal("Expected goodput to be reported")
+	}
+	if exp := int64(numReqs) * int64(len(body)); res.Goodput.BodyBytes != exp {
+		t.Errorf("Expected %v bytes of bodies, but got %v",
+			exp, res.Goodput.BodyBytes)
+	}
+	if res.Goodput.BodyBytes >= res.BytesRead {
+		t.Errorf("Expected headers to be excluded, but got %v of %v bytes",
+			res.Goodput.BodyBytes, res.BytesRead)
+	}
+	if res.Goodput.Share <= 0 || res.Goodput.Share >= 100 {
+		t.Errorf("Expected share between 0 and 100, but got %v",
+			res.Goodput.Share)
+	}
+	if res.Goodput.Goodput <= 0 || res.Goodput.Goodput >= res.Throughput() {
+		t.Errorf("Expected goodput below throughput of %v, but got %v",
+			res.Throughput(), res.Goodput.Goodput)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codesenberg/bombardier/internal"
+
+	"github.com/cheggaaa/pb"
+)
+
+const (
+	graphBuckets      = 20
+	defaultGraphWidth = 80
+	// "  <= 123.45ms |" before and " 100.00%" after the bar
+	graphLabelsWidth = 17 + 8
+)
+
+// graphWidth returns width of the terminal or the default one if
+// the output isn't a terminal.
+func graphWidth() int {
+	if isTerminal(os.Stdout) {
+		if w, err := pb.GetTerminalWidth(); err == nil && w > 0 {
+			return w
+		}
+	}
+	return defaultGraphWidth
+}
+
+// printLatencyGraph renders latencies as a histogram with linear
+// buckets between the minimal and the maximal latency. Bars are
+// scaled to fit width, percents on the right are cumulative.
+func printLatencyGraph(
+	out io.Writer, h internal.ReadonlyUint64Histogram, width int,
+) {
+	min, max, total := ^uint64(0), uint64(0), uint64(0)
+	h.VisitAll(func(k, v uint64) bool {
+		if k < min {
+			min = k
+		}
+		if k > max {
+			max = k
+		}
+		total += v
+		return true
+	})
+	if total == 0 {
+		fmt.Fprintln(out, "  There wasn't enough data to draw latency graph.")
+		return
+	}
+	step := (max - min + graphBuckets) / graphBuckets
+	var counts [graphBuckets]uint64
+	h.VisitAll(func(k, v uint64) bool {
+		counts[(k-min)/step] += v
+		return true
+	})
+	highest := uint64(0)
+	for _, c := range counts {
+		if c > highest {
+			highest = c
+		}
+	}
+	barWidth := width - graphLabelsWidth
+	if barWidth < 1 {
+		barWidth = 1
+	}
+	fmt.Fprintln(out, "  Latency Graph")
+	cumulative := uint64(0)
+	for i, c := range counts {
+		cumulative += c
+		upper := min + uint64(i+1)*step - 1
+		bar := strings.Repeat("#", int(c*uint64(barWidth)/highest))
+		fmt.Fprintf(out, "  <= %10v |%-*v %6.2f%%\n",
+			formatTimeUs(float64(upper)), barWidth, bar,
+			float64(cumulative)/float64(total)*100)
+		if cumulative == total {
+			break
+		}
+	}
+}
+
+// printHistogramBuckets dumps latencies as they're stored in h, one
+// line per distinct latency in microseconds, in ascending order,
+// with the number of requests and the cumulative one.
+func printHistogramBuckets(out io.Writer, h internal.ReadonlyUint64Histogram) {
+	type bucket struct{ us, count uint64 }
+	var buckets []bucket
+	h.VisitAll(func(k, v uint64) bool {
+		buckets = append(buckets, bucket{k, v})
+		return true
+	})
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].us < buckets[j].us
+	})
+	fmt.Fprintf(out, "%-14v %14v %14v\n", "latency_us", "count", "cumulative")
+	cumulative := uint64(0)
+	for _, b := range buckets {
+		cumulative += b.count
+		fmt.Fprintf(out, "%-14v %14v %14v\n", b.us, b.count, cumulative)
+	}
+}
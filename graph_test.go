@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+func TestPrintLatencyGraph(t *testing.T) {
+	h := uhist.Default()
+	for i := 0; i < 3; i++ {
+		h.Increment(1000)
+	}
+	h.Increment(1010)
+	h.Increment(1039)
+	var out bytes.Buffer
+	printLatencyGraph(&out, h, 40)
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	// 20 buckets of 2us from 1ms
+	expected := map[int]string{
+		0: "  Latency Graph",
+		1: "  <=     1.00ms |###############  60.00%",
+		2: "  <=     1.00ms |                 60.00%",
+		6: "  <=     1.01ms |#####            80.00%",
+	}
+	if len(lines) != graphBuckets+1 {
+		t.Fatalf("Expected %v lines, but got %v:\n%v",
+			graphBuckets+1, len(lines), out.String())
+	}
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Errorf("Expected line %q, but got %q", e, lines[i])
+		}
+	}
+	last := lines[len(lines)-1]
+	if !strings.HasSuffix(last, "100.00%") {
+		t.Errorf("Expected last line to end with 100.00%%, but got %q", last)
+	}
+	for _, l := range lines[1:] {
+		if len(l) != 40 {
+			t.Errorf("Expected line %q to be 40 characters wide", l)
+		}
+	}
+}
+
+func TestPrintLatencyGraphNoData(t *testing.T) {
+	var out bytes.Buffer
+	printLatencyGraph(&out, uhist.Default(), defaultGraphWidth)
+	if !strings.Contains(out.String(), "There wasn't enough data") {
+		t.Errorf("Unexpected output for empty histogram: %q", out.String())
+	}
+}
+
+func TestPrintHistogramBuckets(t *testing.T) {
+	h := uhist.Default()
+	h.Add(1500, 2)
+	h.Increment(20)
+	h.Increment(300)
+	var out bytes.Buffer
+	printHistogramBuckets(&out, h)
+	expected := "" +
+		"latency_us              count     cumulative\n" +
+		"20                          1              1\n" +
+		"300                         1              2\n" +
+		"1500                        2              4\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expected, out.String())
+	}
+}
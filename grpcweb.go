@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type grpcWebMode int
+
+const (
+	grpcWebNone grpcWebMode = iota
+	grpcWebBinary
+	grpcWebText
+)
+
+const (
+	grpcWebFrameHeaderLen = 5
+	grpcWebTrailerFlag    = 0x80
+	grpcStatusHeader      = "grpc-status"
+)
+
+// grpcStatusToHTTP maps gRPC status codes to the closest HTTP status
+// codes, so that they could be accounted in the code counters.
+var grpcStatusToHTTP = map[int]int{
+	0:  http.StatusOK,
+	1:  499, // Canceled, client closed request
+	2:  http.StatusInternalServerError,
+	3:  http.StatusBadRequest,
+	4:  http.StatusGatewayTimeout,
+	5:  http.StatusNotFound,
+	6:  http.StatusConflict,
+	7:  http.StatusForbidden,
+	8:  http.StatusTooManyRequests,
+	9:  http.StatusBadRequest,
+	10: http.StatusConflict,
+	11: http.StatusBadRequest,
+	12: http.StatusNotImplemented,
+	13: http.StatusInternalServerError,
+	14: http.StatusServiceUnavailable,
+	15: http.StatusInternalServerError,
+	16: http.StatusUnauthorized,
+}
+
+func grpcWebModeFromString(mode string) grpcWebMode {
+	switch mode {
+	case "binary":
+		return grpcWebBinary
+	case "text":
+		return grpcWebText
+	}
+	return grpcWebNone
+}
+
+func (m grpcWebMode) String() string {
+	switch m {
+	case grpcWebBinary:
+		return "binary"
+	case grpcWebText:
+		return "text"
+	}
+	return "none"
+}
+
+func (m grpcWebMode) contentType() string {
+	if m == grpcWebText {
+		return "application/grpc-web-text+proto"
+	}
+	return "application/grpc-web+proto"
+}
+
+// frame wraps a serialized message into a gRPC-Web data frame.
+func (m grpcWebMode) frame(message []byte) string {
+	framed := make([]byte, grpcWebFrameHeaderLen+len(message))
+	binary.BigEndian.PutUint32(framed[1:], uint32(len(message)))
+	copy(framed[grpcWebFrameHeaderLen:], message)
+	if m == grpcWebText {
+		return base64.StdEncoding.EncodeToString(framed)
+	}
+	return string(framed)
+}
+
+// responseCode translates gRPC status of the response, taken either
+// from the header or the trailer frame in the body, into HTTP status
+// code. Responses with non-200 HTTP status are left as is.
+func (m grpcWebMode) responseCode(
+	httpCode int, status string, body []byte,
+) (int, error) {
+	if httpCode != http.StatusOK {
+		return httpCode, nil
+	}
+	if status == "" {
+		if m == grpcWebText {
+			decoded, err := decodeGRPCWebText(body)
+			if err != nil {
+				return httpCode, err
+			}
+			body = decoded
+		}
+		status = grpcWebTrailerStatus(body)
+	}
+	if status == "" {
+		return httpCode, errNoGRPCStatus
+	}
+	grpcCode, err := strconv.Atoi(status)
+	if err != nil {
+		return httpCode, fmt.Errorf("invalid grpc-status %q", status)
+	}
+	if code, ok := grpcStatusToHTTP[grpcCode]; ok {
+		return code, nil
+	}
+	return http.StatusInternalServerError, nil
+}
+
+// decodeGRPCWebText decodes body of grpc-web-text response, which
+// may consist of several separately padded base64 chunks.
+func decodeGRPCWebText(body []byte) ([]byte, error) {
+	res := new(bytes.Buffer)
+	for len(body) > 0 {
+		end := bytes.IndexByte(body, '=')
+		if end == -1 {
+			end = len(body)
+		} else {
+			for end < len(body) && body[end] == '=' {
+				end++
+			}
+		}
+		chunk := make([]byte, base64.StdEncoding.DecodedLen(end))
+		n, err := base64.StdEncoding.Decode(chunk, body[:end])
+		if err != nil {
+			return nil, err
+		}
+		res.Write(chunk[:n])
+		body = body[end:]
+	}
+	return res.Bytes(), nil
+}
+
+// grpcWebTrailerStatus looks for grpc-status in the trailer frame.
+func grpcWebTrailerStatus(body []byte) string {
+	for len(body) >= grpcWebFrameHeaderLen {
+		flag := body[0]
+		length := int(binary.BigEndian.Uint32(body[1:grpcWebFrameHeaderLen]))
+		body = body[grpcWebFrameHeaderLen:]
+		if length > len(body) {
+			return ""
+		}
+		if flag&grpcWebTrailerFlag != 0 {
+			for _, line := range strings.Split(string(body[:length]), "\r\n") {
+				kv := strings.SplitN(line, ":", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				key := strings.TrimSpace(kv[0])
+				if strings.EqualFold(key, grpcStatusHeader) {
+					return strings.TrimSpace(kv[1])
+				}
+			}
+		}
+		body = body[length:]
+	}
+	return ""
+}
+
+// grpcWebHeaders returns headers with the ones required by gRPC-Web
+// added, unless they were already specified.
+func grpcWebHeaders(h *headersList, m grpcWebMode) *headersList {
+	res := append(headersList{}, *h...)
+	required := []header{
+		{"Content-Type", m.contentType()},
+		{"X-Grpc-Web", "1"},
+	}
+	for _, r := range required {
+		found := false
+		for _, hdr := range *h {
+			if strings.EqualFold(hdr.key, r.key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, r)
+		}
+	}
+	return &res
+}
+
+func fasthttpGRPCStatus(resp *fasthttp.Response) string {
+	var status string
+	resp.Header.VisitAll(func(key, value []byte) {
+		if strings.EqualFold(string(key), grpcStatusHeader) {
+			status = string(value)
+		}
+	})
+	return status
+}
+
+func httpGRPCStatus(resp *http.Response) string {
+	if status := resp.Header.Get(grpcStatusHeader); status != "" {
+		return status
+	}
+	return resp.Trailer.Get(grpcStatusHeader)
+}
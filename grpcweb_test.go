@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func grpcWebTrailerFrame(trailer string) []byte {
+	frame := make([]byte, grpcWebFrameHeaderLen+len(trailer))
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(trailer)))
+	copy(frame[grpcWebFrameHeaderLen:], trailer)
+	return frame
+}
+
+func TestGRPCWebFrame(t *testing.T) {
+	framed := grpcWebBinary.frame([]byte("msg"))
+	if e := "\x00\x00\x00\x00\x03msg"; framed != e {
+		t.Errorf("Expected %q, but got %q", e, framed)
+	}
+	text := grpcWebText.frame([]byte("msg"))
+	if e := base64.StdEncoding.EncodeToString([]byte(framed)); text != e {
+		t.Errorf("Expected %q, but got %q", e, text)
+	}
+}
+
+func TestGRPCWebResponseCode(t *testing.T) {
+	data := string(grpcWebBinary.frame([]byte("response")))
+	trailer := string(grpcWebTrailerFrame(
+		"grpc-status: 5\r\ngrpc-message: not found\r\n",
+	))
+	expectations := []struct {
+		mode     grpcWebMode
+		httpCode int
+		status   string
+		body     string
+		code     int
+		err      bool
+	}{
+		{grpcWebBinary, 200, "0", "", 200, false},
+		{grpcWebBinary, 200, "14", "", 503, false},
+		{grpcWebBinary, 200, "42", "", 500, false},
+		{grpcWebBinary, 502, "", "", 502, false},
+		{grpcWebBinary, 200, "", data + trailer, 404, false},
+		{
+			grpcWebText, 200, "",
+			base64.StdEncoding.EncodeToString([]byte(data)) +
+				base64.StdEncoding.EncodeToString([]byte(trailer)),
+			404, false,
+		},
+		{grpcWebBinary, 200, "", data, 200, true},
+		{grpcWebBinary, 200, "OK", "", 200, true},
+	}
+	for i, e := range expectations {
+		code, err := e.mode.responseCode(e.httpCode, e.status, []byte(e.body))
+		if code != e.code {
+			t.Errorf("%v: expected code %v, but got %v", i, e.code, code)
+		}
+		if (err != nil) != e.err {
+			t.Errorf("%v: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestBombardierGRPCWeb(t *testing.T) {
+	testAllClients(t, testBombardierGRPCWeb)
+}
+
+func testBombardierGRPCWeb(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ct := r.Header.Get("Content-Type")
+			if ct != grpcWebBinary.contentType() {
+				t.Errorf("Unexpected content type %q", ct)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != grpcWebBinary.frame([]byte("request")) {
+				t.Errorf("Unexpected body %q", body)
+			}
+			rw.Header().Set("Content-Type", grpcWebBinary.contentType())
+			_, _ = rw.Write(grpcWebTrailerFrame("grpc-status:7\r\n"))
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "request",
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+		grpcWeb:    grpcWebBinary,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req4xx != numReqs {
+		t.Errorf("Expected %v 4xx, but got %v (2xx - %v), errors: %v",
+			numReqs, b.req4xx, b.req2xx, b.errors.byFrequency())
+	}
+}
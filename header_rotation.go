@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// rotatedHeader is a header specified with --header-rotate, which
+// values are sent in turn, one per request.
+type rotatedHeader struct {
+	name   string
+	values []string
+}
+
+// rotatedHeaders are headers specified with --header-rotate, which is
+// repeatable, as "name: value1,value2,...".
+type rotatedHeaders []rotatedHeader
+
+func (r *rotatedHeaders) String() string {
+	entries := make([]string, 0, len(*r))
+	for _, h := range *r {
+		entries = append(entries, h.name+": "+strings.Join(h.values, ","))
+	}
+	return strings.Join(entries, "; ")
+}
+
+func (r *rotatedHeaders) IsCumulative() bool {
+	return true
+}
+
+func (r *rotatedHeaders) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return &invalidRotatedHeaderError{value}
+	}
+	name := strings.TrimSpace(parts[0])
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return &invalidRotatedHeaderError{value}
+	}
+	values := strings.Split(parts[1], ",")
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || strings.ContainsAny(v, "\r\n") {
+			return &invalidRotatedHeaderError{value}
+		}
+		values[i] = v
+	}
+	*r = append(*r, rotatedHeader{name: name, values: values})
+	return nil
+}
+
+type invalidRotatedHeaderError struct {
+	value string
+}
+
+func (i *invalidRotatedHeaderError) Error() string {
+	return fmt.Sprintf("%q is not a valid --header-rotate "+
+		"(must be name: value1,value2,..., with non-empty values)",
+		i.value)
+}
+
+// headerRotation provides values of --header-rotate headers, the n-th
+// request gets the n-th value of each of them, wrapping around, in the
+// order requests happen to be made.
+type headerRotation struct {
+	headers []rotatedHeader
+	next    uint64
+}
+
+func newHeaderRotation(headers []rotatedHeader) *headerRotation {
+	return &headerRotation{headers: headers}
+}
+
+// set calls set with each of the headers and its value for the next
+// request.
+func (r *headerRotation) set(set func(name, value string)) {
+	n := atomic.AddUint64(&r.next, 1) - 1
+	for _, h := range r.headers {
+		set(h.name, h.values[n%uint64(len(h.values))])
+	}
+}
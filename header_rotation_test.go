@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRotatedHeadersSet(t *testing.T) {
+	var headers rotatedHeaders
+	for _, value := range []string{"X-Tenant: a, b,c", "X-Region:eu"} {
+		if err := headers.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := rotatedHeaders{
+		{name: "X-Tenant", values: []string{"a", "b", "c"}},
+		{name: "X-Region", values: []string{"eu"}},
+	}
+	if !reflect.DeepEqual(headers, exp) {
+		t.Errorf("Expected %v, but got %v", exp, headers)
+	}
+	if s := headers.String(); s != "X-Tenant: a,b,c; X-Region: eu" {
+		t.Errorf("Unexpected string: %q", s)
+	}
+	for _, value := range []string{
+		"", "X-Tenant", "X-Tenant:", ": a,b", "X Tenant: a", "X-Tenant: a,,b",
+	} {
+		err := headers.Set(value)
+		if _, ok := err.(*invalidRotatedHeaderError); !ok {
+			t.Errorf("Expected invalidRotatedHeaderError for %q, but got %v",
+				value, err)
+		}
+	}
+}
+
+func TestHeaderRotationValues(t *testing.T) {
+	r := newHeaderRotation(rotatedHeaders{
+		{name: "A", values: []string{"1", "2", "3"}},
+		{name: "B", values: []string{"x", "y"}},
+	})
+	var res []string
+	for i := 0; i < 6; i++ {
+		r.set(func(name, value string) {
+			res = append(res, name+value)
+		})
+	}
+	exp := []string{
+		"A1", "Bx", "A2", "By", "A3", "Bx",
+		"A1", "By", "A2", "Bx", "A3", "By",
+	}
+	if !reflect.DeepEqual(res, exp) {
+		t.Errorf("Expected %v, but got %v", exp, res)
+	}
+}
+
+func TestBombardierHeaderRotation(t *testing.T) {
+	testAllClients(t, testBombardierHeaderRotation)
+}
+
+func testBombardierHeaderRotation(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]int)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if vs := r.Header["X-Tenant"]; len(vs) != 1 {
+				t.Errorf("Expected a single X-Tenant, but got %v", vs)
+			}
+			mu.Lock()
+			seen[r.Header.Get("X-Tenant")]++
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(30)
+	b, e := newBombardier(config{
+		numConns: 3,
+		numReqs:  &numReqs,
+		url:      s.URL,
+		headers:  &headersList{{"X-Tenant", "default"}},
+		timeout:  defaultTimeout,
+		method:   "GET",
+		headerRotate: &rotatedHeaders{
+			{name: "X-Tenant", values: []string{"a", "b", "c"}},
+		},
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Fatalf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	exp := map[string]int{"a": 10, "b": 10, "c": 10}
+	if !reflect.DeepEqual(seen, exp) {
+		t.Errorf("Expected %v, but got %v", exp, seen)
+	}
+}
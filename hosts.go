@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// hostList is a list of hosts (with optional ports) to spread
+// connections across, specified as comma-separated list on the
+// command line. Hosts may be prefixed with http:// or https:// to be
+// reached with that scheme instead of the one of the URL, so that
+// plain and TLS targets can be mixed in one test.
+type hostList []string
+
+func (h *hostList) String() string {
+	return strings.Join(*h, ",")
+}
+
+func (h *hostList) Set(value string) error {
+	res := hostList{}
+	for _, host := range strings.Split(value, ",") {
+		host = strings.TrimSpace(host)
+		scheme, authority := splitHostScheme(host)
+		u, err := url.Parse("//" + authority)
+		if authority == "" || err != nil || u.Host != authority ||
+			(scheme != "" && scheme != "http" && scheme != "https") {
+			return &invalidHostError{host}
+		}
+		res = append(res, host)
+	}
+	*h = res
+	return nil
+}
+
+type invalidHostError struct {
+	host string
+}
+
+func (i *invalidHostError) Error() string {
+	return fmt.Sprintf("%q is not a valid host"+
+		"(must be [http://|https://]host[:port])", i.host)
+}
+
+// splitHostScheme splits one of --hosts into its scheme, empty if
+// there's none, and the host with optional port.
+func splitHostScheme(host string) (scheme, authority string) {
+	i := strings.Index(host, "://")
+	if i < 0 {
+		return "", host
+	}
+	return strings.ToLower(host[:i]), host[i+len("://"):]
+}
+
+// hostStats is the client for one of --hosts and HTTP codes of
+// responses it received, indexed by class with others at 0. scheme is
+// only set if --hosts mixed http:// and https:// hosts.
+type hostStats struct {
+	host   string
+	client client
+	codes  [6]uint64
+	scheme *schemeStats
+}
+
+// schemeStats holds HTTP codes, indexed as in hostStats, and latencies
+// of requests sent to all of --hosts with the scheme.
+type schemeStats struct {
+	scheme    string
+	codes     [6]uint64
+	latencies *uhist.Histogram
+}
+
+func codeClass(code int) int {
+	class := code / 100
+	if class < 1 || class > 5 {
+		class = 0
+	}
+	return class
+}
+
+func (h *hostStats) record(code int, usTaken uint64) {
+	class := codeClass(code)
+	atomic.AddUint64(&h.codes[class], 1)
+	if h.scheme != nil {
+		atomic.AddUint64(&h.scheme.codes[class], 1)
+		h.scheme.latencies.Increment(usTaken)
+	}
+}
+
+// withHost returns rawURL pointing to host instead, with the scheme of
+// host if it has one.
+func withHost(rawURL, host string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// rawURL guaranteed to be valid at this point
+		panic(err)
+	}
+	scheme, authority := splitHostScheme(host)
+	if scheme != "" {
+		u.Scheme = scheme
+	}
+	u.Host = authority
+	return u.String()
+}
+
+// hostSchemes returns the schemes hosts are reached with, in order of
+// their first appearance, url is the one used for hosts without it.
+func hostSchemes(rawURL string, hosts hostList) []string {
+	var res []string
+	for _, host := range hosts {
+		u, err := url.Parse(withHost(rawURL, host))
+		if err != nil {
+			panic(err)
+		}
+		known := false
+		for _, s := range res {
+			known = known || s == u.Scheme
+		}
+		if !known {
+			res = append(res, u.Scheme)
+		}
+	}
+	return res
+}
+
+// newHostClients creates a client with its own connection pool for
+// each of hosts, connections are split evenly between them. Each
+// client connects with TLS or not depending on the scheme of its
+// host. If both schemes are used, stats of each are returned too.
+func newHostClients(
+	clientType clientTyp, cc *clientOpts, hosts hostList,
+) ([]*hostStats, []*schemeStats) {
+	var schemes []*schemeStats
+	if names := hostSchemes(cc.url, hosts); len(names) > 1 {
+		for _, name := range names {
+			schemes = append(schemes, &schemeStats{
+				scheme:    name,
+				latencies: uhist.Default(),
+			})
+		}
+	}
+	perHost := (cc.maxConns + uint64(len(hosts)) - 1) / uint64(len(hosts))
+	res := make([]*hostStats, 0, len(hosts))
+	for _, host := range hosts {
+		opts := *cc
+		opts.url = withHost(cc.url, host)
+		opts.maxConns = perHost
+		h := &hostStats{
+			host:   host,
+			client: makeHTTPClient(clientType, &opts),
+		}
+		for _, s := range schemes {
+			if strings.HasPrefix(opts.url, s.scheme+"://") {
+				h.scheme = s
+			}
+		}
+		res = append(res, h)
+	}
+	return res, schemes
+}
+
+func (b *bombardier) hostResults() []internal.HostResult {
+	res := make([]internal.HostResult, 0, len(b.hosts))
+	for _, h := range b.hosts {
+		res = append(res, internal.HostResult{
+			Host:   h.host,
+			Req1XX: atomic.LoadUint64(&h.codes[1]),
+			Req2XX: atomic.LoadUint64(&h.codes[2]),
+			Req3XX: atomic.LoadUint64(&h.codes[3]),
+			Req4XX: atomic.LoadUint64(&h.codes[4]),
+			Req5XX: atomic.LoadUint64(&h.codes[5]),
+			Others: atomic.LoadUint64(&h.codes[0]),
+		})
+	}
+	return res
+}
+
+func (b *bombardier) schemeResults() []internal.SchemeResult {
+	res := make([]internal.SchemeResult, 0, len(b.schemes))
+	for _, s := range b.schemes {
+		res = append(res, internal.SchemeResult{
+			Scheme:    s.scheme,
+			Req1XX:    atomic.LoadUint64(&s.codes[1]),
+			Req2XX:    atomic.LoadUint64(&s.codes[2]),
+			Req3XX:    atomic.LoadUint64(&s.codes[3]),
+			Req4XX:    atomic.LoadUint64(&s.codes[4]),
+			Req5XX:    atomic.LoadUint64(&s.codes[5]),
+			Others:    atomic.LoadUint64(&s.codes[0]),
+			Latencies: s.latencies,
+		})
+	}
+	return res
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHostListSet(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out hostList
+		err error
+	}{
+		{"a", hostList{"a"}, nil},
+		{"a:8080, b:8081,c", hostList{"a:8080", "b:8081", "c"}, nil},
+		{"a,,b", nil, &invalidHostError{""}},
+		{"http://a, https://b:8443", hostList{"http://a", "https://b:8443"}, nil},
+		{"ftp://a", nil, &invalidHostError{"ftp://a"}},
+		{"https://", nil, &invalidHostError{"https://"}},
+		{"a/path", nil, &invalidHostError{"a/path"}},
+		{"https://a/path", nil, &invalidHostError{"https://a/path"}},
+	}
+	for _, e := range expectations {
+		var h hostList
+		err := h.Set(e.in)
+		if e.err != nil {
+			if err == nil || err.Error() != e.err.Error() {
+				t.Errorf("Expected %q for %q, but got %v", e.err, e.in, err)
+			}
+			continue
+		}
+		if err != nil || h.String() != e.out.String() {
+			t.Errorf("Expected %v for %q, but got %v (%v)",
+				e.out.String(), e.in, h.String(), err)
+		}
+	}
+}
+
+func TestBombardierHosts(t *testing.T) {
+	testAllClients(t, testBombardierHosts)
+}
+
+func testBombardierHosts(clientType clientTyp, t *testing.T) {
+	var received [2]uint64
+	servers := make([]*httptest.Server, 0, len(received))
+	hosts := hostList{}
+	for i := range received {
+		i := i
+		s := httptest.NewServer(
+			http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/path" {
+					rw.WriteHeader(http.StatusNotFound)
+				}
+				if i == 1 {
+					rw.WriteHeader(http.StatusServiceUnavailable)
+				}
+				atomic.AddUint64(&received[i], 1)
+			}),
+		)
+		defer s.Close()
+		servers = append(servers, s)
+		u, _ := url.Parse(s.URL)
+		hosts = append(hosts, u.Host)
+	}
+	numReqs := uint64(200)
+	b, e := newBombardier(config{
+		numConns:   4,
+		numReqs:    &numReqs,
+		url:        "http://unused.example:9999/path",
+		hosts:      &hosts,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if received[0] == 0 || received[1] == 0 ||
+		received[0]+received[1] != numReqs {
+		t.Errorf("Expected %v requests spread across hosts, but got %v",
+			numReqs, received)
+	}
+	results := b.gatherInfo().Result.Hosts
+	if len(results) != 2 {
+		t.Fatalf("Expected results for 2 hosts, but got %v", results)
+	}
+	if results[0].Host != hosts[0] || results[0].Req2XX != received[0] ||
+		results[1].Host != hosts[1] || results[1].Req5XX != received[1] {
+		t.Errorf("Unexpected results by host %+v, received %v",
+			results, received)
+	}
+	if b.req2xx != received[0] || b.req5xx != received[1] {
+		t.Errorf("Expected totals to include all hosts, got %v 2xx, %v 5xx",
+			b.req2xx, b.req5xx)
+	}
+	var out strings.Builder
+	if err := b.reporter.report(&out, b.gatherInfo()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), hosts[1]+": 1xx - 0") {
+		t.Errorf("Expected codes by host in output, but got %q", out.String())
+	}
+}
+
+func TestWithHost(t *testing.T) {
+	expectations := []struct {
+		host string
+		out  string
+	}{
+		{"b:8080", "https://b:8080/path?q"},
+		{"http://b:8080", "http://b:8080/path?q"},
+		{"HTTPS://b", "https://b/path?q"},
+	}
+	for _, e := range expectations {
+		if out := withHost("https://a:443/path?q", e.host); out != e.out {
+			t.Errorf("Expected %q for %q, but got %q", e.out, e.host, out)
+		}
+	}
+}
+
+func TestBombardierMixedSchemeHosts(t *testing.T) {
+	testAllClients(t, testBombardierMixedSchemeHosts)
+}
+
+func testBombardierMixedSchemeHosts(clientType clientTyp, t *testing.T) {
+	var received [2]uint64
+	plain := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			atomic.AddUint64(&received[0], 1)
+		}),
+	)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil {
+				rw.WriteHeader(http.StatusBadRequest)
+			}
+			atomic.AddUint64(&received[1], 1)
+		}),
+	)
+	defer secure.Close()
+	hosts := hostList{plain.URL, secure.URL}
+	numReqs := uint64(100)
+	b, e := newBombardier(config{
+		numConns:   4,
+		numReqs:    &numReqs,
+		url:        "http://unused.example:9999/",
+		hosts:      &hosts,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+		insecure:   true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if received[0] == 0 || received[1] == 0 {
+		t.Errorf("Expected requests to both schemes, but got %v", received)
+	}
+	results := b.gatherInfo().Result.Schemes
+	if len(results) != 2 {
+		t.Fatalf("Expected results for 2 schemes, but got %v", results)
+	}
+	if results[0].Scheme != "http" || results[0].Req2XX != received[0] ||
+		results[1].Scheme != "https" || results[1].Req2XX != received[1] {
+		t.Errorf("Unexpected results by scheme %+v, received %v",
+			results, received)
+	}
+	if results[1].LatenciesStats(nil) == nil {
+		t.Error("Expected latencies of https requests")
+	}
+	var out strings.Builder
+	if err := b.reporter.report(&out, b.gatherInfo()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "HTTP codes by scheme:") {
+		t.Errorf("Expected codes by scheme in output, but got %q",
+			out.String())
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// httpsUpgrades counts requests to http:// URL redirected to https://
+// with --follow-https-redirect and latencies of the redirect hops,
+// from sending the request till the redirect was received.
+type httpsUpgrades struct {
+	upgraded  uint64
+	latencies *uhist.Histogram
+}
+
+func newHTTPSUpgrades() *httpsUpgrades {
+	return &httpsUpgrades{latencies: uhist.Default()}
+}
+
+func (u *httpsUpgrades) add(usTaken uint64) {
+	atomic.AddUint64(&u.upgraded, 1)
+	u.latencies.Increment(usTaken)
+}
+
+func (u *httpsUpgrades) result() *internal.HTTPSUpgradeResult {
+	return &internal.HTTPSUpgradeResult{
+		Upgraded:          atomic.LoadUint64(&u.upgraded),
+		RedirectLatencies: u.latencies,
+	}
+}
+
+// upgradeHop is passed with contexts of requests to tell fire when the
+// redirect to https:// was received.
+type upgradeHop struct {
+	redirected time.Time
+}
+
+type upgradeHopKey struct{}
+
+// checkHTTPSRedirect follows the first redirect of a request, if it
+// goes from http:// to https://, and returns the redirect response
+// otherwise.
+func checkHTTPSRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) != 1 || via[0].URL.Scheme != "http" ||
+		req.URL.Scheme != "https" {
+		return http.ErrUseLastResponse
+	}
+	if hop, ok := req.Context().Value(upgradeHopKey{}).(*upgradeHop); ok {
+		hop.redirected = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBombardierHTTPSUpgrade(t *testing.T) {
+	for _, clientType := range []clientTyp{nhttp1, nhttp2} {
+		testBombardierHTTPSUpgrade(clientType, t)
+	}
+}
+
+func testBombardierHTTPSUpgrade(clientType clientTyp, t *testing.T) {
+	secure := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/loop" {
+				http.Redirect(rw, r, "/", http.StatusFound)
+			}
+		}),
+	)
+	defer secure.Close()
+	plain := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/":
+				http.Redirect(rw, r, secure.URL+"/",
+					http.StatusMovedPermanently)
+			case "/loop":
+				http.Redirect(rw, r, secure.URL+"/loop",
+					http.StatusMovedPermanently)
+			case "/plain":
+				http.Redirect(rw, r, "/", http.StatusFound)
+			}
+		}),
+	)
+	defer plain.Close()
+	expectations := []struct {
+		path     string
+		upgraded uint64
+		req2xx   uint64
+	}{
+		{"/", 10, 10},
+		// only the first redirect is followed
+		{"/loop", 10, 0},
+		{"/plain", 0, 0},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(10)
+		b, err := newBombardier(config{
+			numConns:      2,
+			numReqs:       &numReqs,
+			url:           plain.URL + e.path,
+			headers:       new(headersList),
+			timeout:       defaultTimeout,
+			method:        "GET",
+			insecure:      true,
+			httpsRedirect: true,
+			clientType:    clientType,
+			format:        knownFormat("plain-text"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		b.bombard()
+		if b.req2xx != e.req2xx || b.req3xx != numReqs-e.req2xx {
+			t.Errorf("%v: expected %v 2xx and the rest 3xx, but got %v 2xx, "+
+				"%v 3xx (errors: %v)", e.path, e.req2xx, b.req2xx, b.req3xx,
+				b.errors.byFrequency())
+		}
+		res := b.gatherInfo().Result.HTTPSUpgrade
+		if res.Upgraded != e.upgraded {
+			t.Errorf("%v: expected %v upgraded, but got %v",
+				e.path, e.upgraded, res.Upgraded)
+		}
+		stats := res.LatenciesStats(nil)
+		if (stats != nil) != (e.upgraded > 0) {
+			t.Errorf("%v: unexpected latencies of redirects: %+v",
+				e.path, stats)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBombardierIgnoreBody(t *testing.T) {
+	testAllClients(t, testBombardierIgnoreBody)
+}
+
+func testBombardierIgnoreBody(clientType clientTyp, t *testing.T) {
+	body := strings.Repeat("x", 1<<20)
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write([]byte(body))
+		}),
+	)
+	conns := uint64(0)
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddUint64(&conns, 1)
+		}
+	}
+	if clientType == nhttp2 {
+		s.EnableHTTP2 = true
+		s.StartTLS()
+	} else {
+		s.Start()
+	}
+	defer s.Close()
+	numReqs := uint64(5)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		ignoreBody: true,
+		insecure:   true,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	// HTTP/2 flow control window lets the server send bodies anyway
+	read := atomic.LoadInt64(&b.bytesRead)
+	if clientType != nhttp2 && read >= int64(len(body))*int64(numReqs) {
+		t.Errorf("Expected bodies to be left unread, but %v bytes were read",
+			read)
+	}
+	opened := atomic.LoadUint64(&conns)
+	if clientType == nhttp2 && opened != 1 {
+		t.Errorf("Expected HTTP/2 connection to be reused, but %v opened",
+			opened)
+	}
+	if clientType != nhttp2 && opened != numReqs {
+		t.Errorf("Expected a connection per request, but %v opened", opened)
+	}
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"sort"
+	"sync"
+)
+
+// sortedUint64s is a histogram with its keys in ascending order,
+// cumulative[i] being the number of values up to keys[i] inclusive.
+type sortedUint64s struct {
+	keys       []uint64
+	cumulative []uint64
+	sum        uint64
+}
+
+func newSortedUint64s(h ReadonlyUint64Histogram) *sortedUint64s {
+	type pair struct{ k, v uint64 }
+	pairs := make([]pair, 0, h.Count())
+	h.VisitAll(func(f uint64, c uint64) bool {
+		pairs = append(pairs, pair{f, c})
+		return true
+	})
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].k < pairs[j].k
+	})
+	s := &sortedUint64s{
+		keys:       make([]uint64, len(pairs)),
+		cumulative: make([]uint64, len(pairs)),
+	}
+	total := uint64(0)
+	for i, p := range pairs {
+		total += p.v
+		s.keys[i], s.cumulative[i] = p.k, total
+		s.sum += p.k * p.v
+	}
+	return s
+}
+
+func (s *sortedUint64s) count() uint64 {
+	if len(s.cumulative) == 0 {
+		return 0
+	}
+	return s.cumulative[len(s.cumulative)-1]
+}
+
+// rank returns the smallest key with at least n values up to it.
+func (s *sortedUint64s) rank(n uint64) uint64 {
+	i := sort.Search(len(s.cumulative), func(i int) bool {
+		return s.cumulative[i] >= n
+	})
+	return s.keys[i]
+}
+
+// totalCount returns the number of values in h, Count of uhist
+// histograms is the number of distinct keys instead.
+func totalCount(h ReadonlyUint64Histogram) uint64 {
+	total := uint64(0)
+	h.VisitAll(func(_ uint64, c uint64) bool {
+		total += c
+		return true
+	})
+	return total
+}
+
+// SortedUint64Histogram wraps a histogram, caching its keys in order
+// with cumulative counts, so statistics can be calculated repeatedly
+// (i.e. for every template using them or on every refresh of live
+// output) without sorting the keys each time. The cache is rebuilt
+// once the wrapped histogram has more values.
+type SortedUint64Histogram struct {
+	ReadonlyUint64Histogram
+
+	mu     sync.Mutex
+	sorted *sortedUint64s
+}
+
+// NewSortedUint64Histogram wraps h.
+func NewSortedUint64Histogram(
+	h ReadonlyUint64Histogram,
+) *SortedUint64Histogram {
+	return &SortedUint64Histogram{ReadonlyUint64Histogram: h}
+}
+
+func (h *SortedUint64Histogram) sortedValues() *sortedUint64s {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// histograms only grow, so the same number of values means the
+	// same values
+	if h.sorted == nil ||
+		h.sorted.count() != totalCount(h.ReadonlyUint64Histogram) {
+		h.sorted = newSortedUint64s(h.ReadonlyUint64Histogram)
+	}
+	return h.sorted
+}
+
+func sortedValues(h ReadonlyUint64Histogram) *sortedUint64s {
+	if s, ok := h.(*SortedUint64Histogram); ok {
+		return s.sortedValues()
+	}
+	return newSortedUint64s(h)
+}
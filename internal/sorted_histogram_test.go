@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"testing"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+func TestSortedUint64HistogramRebuildsOnNewValues(t *testing.T) {
+	h := uhist.Default()
+	for _, v := range []uint64{10, 20, 30} {
+		h.Increment(v)
+	}
+	sorted := NewSortedUint64Histogram(h)
+	before := latenciesStats(sorted, []float64{0.5})
+	if before == nil || before.Percentiles[0.5] != 20 {
+		t.Fatalf("Expected median of 20, but got %+v", before)
+	}
+	// no new keys, only more values of an existing one
+	for i := 0; i < 10; i++ {
+		h.Increment(30)
+	}
+	after := latenciesStats(sorted, []float64{0.5})
+	if after == nil || after.Percentiles[0.5] != 30 {
+		t.Errorf("Expected median of 30 after new values, but got %+v",
+			after)
+	}
+	if c := sorted.sortedValues().count(); c != 13 {
+		t.Errorf("Expected 13 values, but got %v", c)
+	}
+}
@@ -37,11 +37,15 @@ type Spec struct {
 	CertPath string
 	KeyPath  string
 
-	Stream     bool
-	Timeout    time.Duration
-	ClientType ClientType
-
-	Rate *uint64
+	Stream          bool
+	Timeout         time.Duration
+	AbortSlowerThan time.Duration
+	LatencyCap      time.Duration
+	ClientType      ClientType
+	Pipeline        uint64
+
+	Rate      *uint64
+	RateBytes *uint64
 }
 
 // IsTimedTest tells if the test was limited by time.
@@ -78,13 +82,361 @@ type Results struct {
 	BytesRead, BytesWritten int64
 	TimeTaken               time.Duration
 
+	// Sizes of response bodies as received and after decompression,
+	// only filled when responses were decompressed.
+	CompressedBytes, DecompressedBytes int64
+
+	// SuccessfulOnly tells whether throughput is calculated from sizes
+	// of requests with 2xx responses only, which are filled then.
+	SuccessfulOnly                              bool
+	SuccessfulBytesRead, SuccessfulBytesWritten int64
+
 	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
 	Others                                 uint64
 
+	// Aborted requests are those that exceeded --abort-slower-than,
+	// they are also counted in Others.
+	Aborted uint64
+	// LatencyCapped is the number of requests which latency exceeded
+	// --latency-cap and was recorded as the cap.
+	LatencyCapped uint64
+	// ContentLengthMismatches is the number of responses with bodies
+	// shorter than their Content-Length, only counted with
+	// --strict-content-length.
+	ContentLengthMismatches uint64
+	// Canceled is the number of requests in flight canceled when the
+	// test was interrupted (net/http only), they aren't counted as
+	// errors or anywhere else.
+	Canceled uint64
+	// ConnectionErrors is the number of requests that failed to
+	// establish a connection (i.e. because of dial or TLS handshake
+	// errors), RequestErrors is the number of the ones that failed
+	// otherwise, including aborted ones.
+	ConnectionErrors, RequestErrors uint64
+
 	Errors []ErrorWithCount
 
 	Latencies ReadonlyUint64Histogram
 	Requests  ReadonlyFloat64Histogram
+
+	// Only filled when time spent obtaining connections, writing
+	// requests and waiting for and reading responses were measured
+	// separately.
+	ConnectLatencies ReadonlyUint64Histogram
+	WriteLatencies   ReadonlyUint64Histogram
+	ReadLatencies    ReadonlyUint64Histogram
+	// Only filled when DNS lookups were measured (--print-dns), one
+	// per new connection to a host name.
+	DNSLatencies ReadonlyUint64Histogram
+	// Only filled when --print-queue-time is set, time each request
+	// waited for the rate limiter.
+	QueueTimes ReadonlyUint64Histogram
+
+	// Only filled when the test was performed with --latency-by-code,
+	// classes without responses are omitted.
+	LatenciesByCode []CodeLatencies
+
+	// Only filled when the test was performed with --scenario, in
+	// order of steps.
+	Steps []StepResult
+
+	// Only filled when load was spread across --hosts.
+	Hosts []HostResult
+	// Only filled when --hosts mixed http:// and https:// hosts, in
+	// order of their first appearance.
+	Schemes []SchemeResult
+
+	// Only filled when requests were spread across methods with
+	// --method-mix, in its order.
+	Methods []MethodResult
+
+	// Only filled when the test was performed with --connections-auto
+	// and lasted long enough to measure at least one step.
+	ConnectionsAuto *ConnectionsAutoResult
+
+	// Only filled when the test was performed with --find-max-rps and
+	// some rate was sustained.
+	MaxRPS *MaxRPSResult
+
+	// Only filled when the test was performed with --target-p99.
+	TargetP99 *TargetP99Result
+
+	// Only filled when the test was performed with --print-tls.
+	TLS *TLSResult
+
+	// Only filled when the test was performed with --connection-count.
+	ConnectionCount *ConnectionCountResult
+
+	// Only filled when --dial-retries is set.
+	DialRetries *DialRetriesResult
+
+	// Only filled when --follow-https-redirect is set.
+	HTTPSUpgrade *HTTPSUpgradeResult
+
+	// Only filled when --print-pipeline-stats is set.
+	Pipeline *PipelineResult
+
+	// Only filled when --print-goodput is set.
+	Goodput *GoodputResult
+
+	// Only filled when the test was stopped by a failed request with
+	// --abort-on-first-error.
+	FirstError *FirstErrorResult
+
+	// Only filled when bodies were sent with --chunk-delay or
+	// --chunk-size.
+	PacedUploads *PacedUploadsResult
+
+	// Only filled when the test was performed with --slowloris.
+	Slowloris *SlowlorisResult
+
+	// Only filled when bodies were sent from --body-dir, files that
+	// caused no errors are omitted.
+	BodyFileErrors []BodyFileErrors
+
+	// Only filled when bodies were generated with --body-command.
+	BodyCommand *BodyCommandResult
+
+	// Only filled when --stats-reset-on-code is set.
+	StatsReset *StatsResetResult
+}
+
+// StatsResetResult describes the warm-up discarded with
+// --stats-reset-on-code. FirstSuccess is the time from the start of
+// the test till the first 2xx response, if Reached, and Discarded is
+// the number of requests completed before it.
+type StatsResetResult struct {
+	Reached      bool
+	FirstSuccess time.Duration
+	Discarded    uint64
+}
+
+// BodyCommandResult describes runs of --body-command. Waits is the
+// number of requests that found no body ready and had to wait for
+// the command, i.e. were held back by it.
+type BodyCommandResult struct {
+	Generated, Failed, Waits uint64
+}
+
+// BodyFileErrors is the number of failed requests sent with the body
+// from File. Requests fail with errors or unexpected status codes,
+// which, unless --expect-status is given, are 4xx and 5xx.
+type BodyFileErrors struct {
+	File   string
+	Errors uint64
+}
+
+// SlowlorisResult describes connections held open with --slowloris.
+type SlowlorisResult struct {
+	// Opened is the number of connections established, Refused is
+	// the number of those that couldn't be and ClosedByServer is the
+	// number of the ones the server closed or responded to before
+	// the end of the test
+	Opened, Refused, ClosedByServer uint64
+	// MaxHeld is the most connections open at once
+	MaxHeld uint64
+	// HeldAtFirstRefusal is the number of connections that were open
+	// when a connection was refused for the first time, it's only
+	// meaningful if Refused is non-zero
+	HeldAtFirstRefusal uint64
+}
+
+// PacedUploadsResult tells how many of the paced request bodies were
+// sent completely before the request finished, the rest were
+// interrupted by the server responding or closing the connection
+// early, or by an error.
+type PacedUploadsResult struct {
+	Completed, Interrupted uint64
+}
+
+// TLSResult describes the first TLS connection of the test.
+type TLSResult struct {
+	Version, CipherSuite string
+	// NegotiatedProtocol is empty if no protocol was negotiated
+	// with ALPN
+	NegotiatedProtocol string
+	// Verified is false if verification of the server's certificate
+	// was skipped with --insecure
+	Verified bool
+}
+
+// ConnectionCountResult compares the number of connections opened
+// during the test with the number of connections it was configured
+// to use. They differ if connections were closed and reopened, i.e.
+// with --disableKeepAlives.
+type ConnectionCountResult struct {
+	Opened, Configured uint64
+}
+
+// DialRetriesResult describes retries of failed dials with
+// --dial-retries. Failed is the number of dials that still failed
+// after all retries.
+type DialRetriesResult struct {
+	Retries, Failed uint64
+}
+
+// HTTPSUpgradeResult describes requests redirected from http:// to
+// https://, RedirectLatencies are those of the redirect hops alone.
+type HTTPSUpgradeResult struct {
+	Upgraded uint64
+
+	RedirectLatencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs the same calculations as
+// Results.LatenciesStats on latencies of the redirect hops.
+func (u HTTPSUpgradeResult) LatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return latenciesStats(u.RedirectLatencies, percentiles)
+}
+
+// PipelineResult describes how requests were pipelined over
+// connections. Depths are shares (in percents) of samples with each
+// number of requests in flight per connection, in increasing order of
+// depth.
+type PipelineResult struct {
+	Requests, Connections uint64
+	RequestsPerConnection float64
+	Depths                []PipelineDepth
+}
+
+// PipelineDepth is a share of samples with the given depth.
+type PipelineDepth struct {
+	Depth uint64
+	Share float64
+}
+
+// GoodputResult describes the part of bytes read that were response
+// bodies. Goodput is in bytes per second and Share is in percents of
+// all bytes read.
+type GoodputResult struct {
+	BodyBytes int64
+	Goodput   float64
+	Share     float64
+}
+
+// FirstErrorResult describes the request that stopped the test.
+// Request is its number in order of completion, approximately, as
+// requests complete concurrently.
+type FirstErrorResult struct {
+	Request uint64
+	Error   string
+}
+
+// ConnectionsAutoResult describes the number of connections found
+// with --connections-auto.
+type ConnectionsAutoResult struct {
+	Connections       uint64
+	RequestsPerSecond float64
+	// MeanLatency is in microseconds
+	MeanLatency float64
+	// Settled is false if the test ended before throughput plateaued,
+	// in which case the best number of connections so far is reported.
+	Settled bool
+}
+
+// MaxRPSResult describes the highest rate found with --find-max-rps
+// and what was measured while probing it.
+type MaxRPSResult struct {
+	Rate              uint64
+	RequestsPerSecond float64
+	// P99Latency is in microseconds, estimated within 1/16 of it
+	P99Latency uint64
+	// ErrorRate is in percents
+	ErrorRate float64
+	// Settled is false if the test ended before the search did, in
+	// which case the highest rate sustained so far is reported.
+	Settled bool
+}
+
+// TargetP99Result describes the rate --target-p99 arrived at. Target
+// and P99Latency (the last one measured, estimated within 1/16 of it)
+// are in microseconds.
+type TargetP99Result struct {
+	Target, Rate, P99Latency uint64
+	// Settled is false if p99 didn't stay close to the target before
+	// the end of the test, Rate is the last one used then, otherwise
+	// it's the mean of the rates used since p99 got there.
+	Settled bool
+}
+
+// HostResult holds HTTP codes of responses received from one of the
+// hosts.
+type HostResult struct {
+	Host string
+
+	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
+	Others                                 uint64
+}
+
+// SchemeResult holds HTTP codes of responses received from hosts with
+// one of the schemes and latencies of their requests.
+type SchemeResult struct {
+	Scheme string
+
+	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
+	Others                                 uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs the same calculations as
+// Results.LatenciesStats on latencies of the scheme.
+func (s SchemeResult) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return latenciesStats(s.Latencies, percentiles)
+}
+
+// MethodResult holds HTTP codes of responses to requests with one of
+// the methods.
+type MethodResult struct {
+	Method string
+
+	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
+	Others                                 uint64
+}
+
+// CodeLatencies holds latencies of responses with status codes of
+// some class, i.e. "2xx" (or "others").
+type CodeLatencies struct {
+	Class string
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// LatenciesStats performs the same calculations as
+// Results.LatenciesStats on latencies of the class.
+func (c CodeLatencies) LatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return latenciesStats(c.Latencies, percentiles)
+}
+
+// StepResult holds results of a single scenario step.
+type StepResult struct {
+	Name string
+
+	Requests, Errors uint64
+	// Timeouts are errors of requests that exceeded the step's
+	// timeout
+	Timeouts uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// PassRate returns the percentage of requests of the step that
+// didn't fail, i.e. got one of the expected status codes.
+func (s StepResult) PassRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Requests-s.Errors) / float64(s.Requests) * 100
+}
+
+// LatenciesStats performs the same calculations as
+// Results.LatenciesStats on latencies of the step.
+func (s StepResult) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return latenciesStats(s.Latencies, percentiles)
 }
 
 // ReadonlyUint64Histogram is a readonly histogram with uint64 keys
@@ -102,11 +454,24 @@ type ReadonlyFloat64Histogram interface {
 }
 
 // Throughput returns total throughput (read + write) in bytes per
-// second
+// second, of requests with 2xx responses only if SuccessfulOnly is set
 func (r Results) Throughput() float64 {
+	if r.SuccessfulOnly {
+		return float64(r.SuccessfulBytesRead+r.SuccessfulBytesWritten) /
+			r.TimeTaken.Seconds()
+	}
 	return float64(r.BytesRead+r.BytesWritten) / r.TimeTaken.Seconds()
 }
 
+// CompressionRatio is the size of decompressed response bodies
+// divided by their size as received.
+func (r Results) CompressionRatio() float64 {
+	if r.CompressedBytes == 0 {
+		return 0
+	}
+	return float64(r.DecompressedBytes) / float64(r.CompressedBytes)
+}
+
 // LatenciesStats contains statistical information about latencies.
 type LatenciesStats struct {
 	// These are in microseconds
@@ -121,30 +486,65 @@ type LatenciesStats struct {
 // LatenciesStats performs various statistical calculations on
 // latencies.
 func (r Results) LatenciesStats(percentiles []float64) *LatenciesStats {
-	h := r.Latencies
-	sum := uint64(0)
-	count := uint64(0)
-	max := uint64(0)
-	pairs := make([]struct{ k, v uint64 }, 0, h.Count())
+	return latenciesStats(r.Latencies, percentiles)
+}
+
+// ConnectLatenciesStats performs the same calculations as
+// LatenciesStats on time spent obtaining connections.
+func (r Results) ConnectLatenciesStats(
+	percentiles []float64,
+) *LatenciesStats {
+	return latenciesStats(r.ConnectLatencies, percentiles)
+}
+
+// WriteLatenciesStats performs the same calculations as LatenciesStats
+// on time spent writing requests.
+func (r Results) WriteLatenciesStats(percentiles []float64) *LatenciesStats {
+	return latenciesStats(r.WriteLatencies, percentiles)
+}
+
+// ReadLatenciesStats performs the same calculations as LatenciesStats
+// on time spent waiting for and reading responses.
+func (r Results) ReadLatenciesStats(percentiles []float64) *LatenciesStats {
+	return latenciesStats(r.ReadLatencies, percentiles)
+}
 
+// DNSLookups returns the number of DNS lookups made, lookups that took
+// the same time share a key of DNSLatencies.
+func (r Results) DNSLookups() uint64 {
+	if r.DNSLatencies == nil {
+		return 0
+	}
+	return totalCount(r.DNSLatencies)
+}
+
+// DNSLatenciesStats performs the same calculations as LatenciesStats
+// on DNS lookups.
+func (r Results) DNSLatenciesStats(percentiles []float64) *LatenciesStats {
+	return latenciesStats(r.DNSLatencies, percentiles)
+}
+
+// QueueTimesStats performs the same calculations as LatenciesStats
+// on time requests waited for the rate limiter.
+func (r Results) QueueTimesStats(percentiles []float64) *LatenciesStats {
+	return latenciesStats(r.QueueTimes, percentiles)
+}
+
+func latenciesStats(
+	h ReadonlyUint64Histogram, percentiles []float64,
+) *LatenciesStats {
+	if h == nil {
+		return nil
+	}
 	// Gather all the data
-	h.VisitAll(func(f uint64, c uint64) bool {
-		if f > max {
-			max = f
-		}
-		sum += f * c
-		count += c
-		pairs = append(pairs, struct{ k, v uint64 }{f, c})
-		return true
-	})
+	sorted := sortedValues(h)
+	count := sorted.count()
 	if count < 1 {
 		return nil
 	}
+	max := sorted.keys[len(sorted.keys)-1]
 
 	// Calculate percentiles
-	sort.Slice(pairs, func(i, j int) bool {
-		return pairs[i].k < pairs[j].k
-	})
 	percentilesMap := map[float64]uint64{}
 	for _, pc := range percentiles {
 		if _, calculated := percentilesMap[pc]; calculated {
@@ -155,23 +555,15 @@ func (r Results) LatenciesStats(percentiles []float64) *LatenciesStats {
 			continue
 		}
 		rank := uint64(pc*float64(count) + 0.5)
-		total := uint64(0)
-		for _, p := range pairs {
-			total += p.v
-			if total >= rank {
-				percentilesMap[pc] = p.k
-				break
-			}
-		}
+		percentilesMap[pc] = sorted.rank(rank)
 	}
 
 	// Calculate mean and standard deviation
-	mean := float64(sum) / float64(count)
+	mean := float64(sorted.sum) / float64(count)
 	sumOfSquares := float64(0)
-	h.VisitAll(func(f uint64, c uint64) bool {
+	for _, f := range sorted.keys {
 		sumOfSquares += math.Pow(float64(f)-mean, 2)
-		return true
-	})
+	}
 	stddev := 0.0
 	if count > 2 {
 		stddev = math.Sqrt(sumOfSquares / float64(count))
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// intervalLatencies accumulates latencies of requests completed since
+// the last snapshot for --report-interval-percentiles, percentiles are
+// estimated as with --live-p99.
+type intervalLatencies struct {
+	// holds *latencyWindow, which is replaced on every snapshot
+	window atomic.Value
+	// last is when the current interval began, relative to the start
+	// of the test, it's only accessed by the snapshotter
+	last time.Duration
+}
+
+func newIntervalLatencies() *intervalLatencies {
+	l := new(intervalLatencies)
+	l.window.Store(new(latencyWindow))
+	return l
+}
+
+func (l *intervalLatencies) record(usTaken uint64) {
+	w := l.window.Load().(*latencyWindow)
+	atomic.AddUint64(&w.reqs, 1)
+	w.latencies.record(usTaken)
+}
+
+// next returns the interval that ended at elapsed and its duration,
+// and starts a new one.
+func (l *intervalLatencies) next(
+	elapsed time.Duration,
+) (*latencyWindow, time.Duration) {
+	w := l.window.Load().(*latencyWindow)
+	l.window.Store(new(latencyWindow))
+	interval := elapsed - l.last
+	l.last = elapsed
+	return w, interval
+}
+
+// printIntervalLatencies prints a line with the number of requests of
+// the last interval and their p50 and p99 latencies, as json object
+// with json format.
+func (b *bombardier) printIntervalLatencies(elapsed time.Duration) {
+	w, interval := b.intervalLatencies.next(elapsed)
+	reqs := atomic.LoadUint64(&w.reqs)
+	rps := 0.0
+	if interval > 0 {
+		rps = float64(reqs) / interval.Seconds()
+	}
+	p50, _ := w.latencies.percentile(0.5)
+	p99, _ := w.latencies.percentile(0.99)
+
+	if !b.bar.NotPrint {
+		fmt.Fprint(b.out, clearLine)
+	}
+	if b.conf.format == knownFormat("json") {
+		fmt.Fprintf(b.out, `{"elapsedSeconds":%v,"intervalSeconds":%v,`+
+			`"requests":%v,"rps":%v`,
+			elapsed.Seconds(), interval.Seconds(), reqs, rps)
+		if reqs > 0 {
+			fmt.Fprintf(b.out, `,"latency":{"p50":%v,"p99":%v}`, p50, p99)
+		}
+		fmt.Fprintln(b.out, "}")
+		return
+	}
+	fmt.Fprintf(b.out, "After %v: ", elapsed.Round(time.Second))
+	if reqs == 0 {
+		fmt.Fprintf(b.out, "no requests completed in the last %v\n",
+			interval.Round(time.Millisecond))
+		return
+	}
+	fmt.Fprintf(b.out, "%v requests (%.2f/s), p50 %v, p99 %v\n",
+		reqs, rps, b.conf.formatLatency(float64(p50)),
+		b.conf.formatLatency(float64(p99)))
+}
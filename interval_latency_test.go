@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntervalLatenciesNext(t *testing.T) {
+	l := newIntervalLatencies()
+	for _, us := range []uint64{100, 200, 300} {
+		l.record(us)
+	}
+	w, interval := l.next(time.Second)
+	if w.reqs != 3 || interval != time.Second {
+		t.Errorf("Expected 3 requests over 1s, but got %v over %v",
+			w.reqs, interval)
+	}
+	if p50, _ := w.latencies.percentile(0.5); p50 < 200 || p50 > 215 {
+		t.Errorf("Expected p50 of about 200us, but got %v", p50)
+	}
+	w, interval = l.next(1500 * time.Millisecond)
+	if w.reqs != 0 || interval != 500*time.Millisecond {
+		t.Errorf("Expected no requests over 500ms, but got %v over %v",
+			w.reqs, interval)
+	}
+}
+
+func TestBombardierIntervalPercentiles(t *testing.T) {
+	var served uint64
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			// latency degrades halfway through the test
+			if atomic.AddUint64(&served, 1) <= 15 {
+				time.Sleep(5 * time.Millisecond)
+			} else {
+				time.Sleep(40 * time.Millisecond)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(30)
+	b, e := newBombardier(config{
+		numConns:         1,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		format:           knownFormat("json"),
+		snapshotInterval: 100 * time.Millisecond,
+		intervalLatency:  true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	out := new(bytes.Buffer)
+	b.out = out
+	b.bombard()
+	type interval struct {
+		Requests uint64
+		Latency  *struct{ P50, P99 uint64 }
+	}
+	var intervals []interval
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var i interval
+		if err := json.Unmarshal([]byte(line), &i); err != nil {
+			t.Fatalf("Expected json lines, but got %q: %v", out, err)
+		}
+		intervals = append(intervals, i)
+	}
+	if len(intervals) < 2 {
+		t.Fatalf("Expected at least 2 intervals, but got %q", out)
+	}
+	first, last := intervals[0], intervals[len(intervals)-1]
+	if first.Latency == nil || first.Latency.P50 > 20000 {
+		t.Errorf("Expected p50 of fast requests first, but got %q", out)
+	}
+	if last.Latency == nil || last.Latency.P50 < 30000 {
+		t.Errorf("Expected p50 of slow requests last, but got %q", out)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/codesenberg/bombardier/internal"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// codeClasses are names of classes of status codes, indexed by the
+// first digit of the code, with 0 standing for all other codes.
+var codeClasses = [6]string{"others", "1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// codeLatencies holds latencies for each class of status codes, if
+// --latency-by-code is set.
+type codeLatencies [len(codeClasses)]*uhist.Histogram
+
+func newCodeLatencies() *codeLatencies {
+	var l codeLatencies
+	for i := range l {
+		l[i] = uhist.Default()
+	}
+	return &l
+}
+
+func (l *codeLatencies) record(code int, usTaken uint64) {
+	class := code / 100
+	if class < 1 || class > 5 {
+		class = 0
+	}
+	l[class].Increment(usTaken)
+}
+
+// results returns latencies of classes with any responses, from 1xx
+// to 5xx followed by others.
+func (l *codeLatencies) results() []internal.CodeLatencies {
+	var res []internal.CodeLatencies
+	for i := range l {
+		class := (i + 1) % len(l)
+		if l[class].Count() == 0 {
+			continue
+		}
+		res = append(res, internal.CodeLatencies{
+			Class:     codeClasses[class],
+			Latencies: l[class],
+		})
+	}
+	return res
+}
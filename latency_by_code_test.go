@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCodeLatencies(t *testing.T) {
+	l := newCodeLatencies()
+	l.record(200, 10)
+	l.record(204, 30)
+	l.record(503, 100)
+	l.record(-1, 1000)
+	res := l.results()
+	classes := make([]string, 0, len(res))
+	for _, r := range res {
+		classes = append(classes, r.Class)
+	}
+	if got := strings.Join(classes, ","); got != "2xx,5xx,others" {
+		t.Errorf("Expected classes 2xx,5xx,others, but got %v", got)
+	}
+	if stats := res[0].LatenciesStats(nil); stats == nil ||
+		stats.Mean != 20 || stats.Max != 30 {
+		t.Errorf("Unexpected 2xx latencies: %+v", stats)
+	}
+}
+
+func TestBombardierLatencyByCode(t *testing.T) {
+	counter := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&counter, 1)%2 == 0 {
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	c := config{
+		numConns:      1,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		latencyByCode: true,
+		format:        knownFormat("json"),
+	}
+	b, e := newBombardier(c)
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+	var parsed struct {
+		Result struct {
+			LatencyByCode map[string]struct {
+				Max         float64           `json:"max"`
+				Percentiles map[string]uint64 `json:"percentiles"`
+			} `json:"latencyByCode"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("invalid json %q: %v", out, err)
+	}
+	byCode := parsed.Result.LatencyByCode
+	if len(byCode) != 2 {
+		t.Fatalf("Expected 2xx and 4xx, but got %v", byCode)
+	}
+	for _, class := range []string{"2xx", "4xx"} {
+		l, ok := byCode[class]
+		if !ok || l.Max == 0 {
+			t.Errorf("Expected latencies for %v, but got %+v", class, l)
+			continue
+		}
+		if _, ok := l.Percentiles["99"]; !ok {
+			t.Errorf("Expected p99 for %v, but got %v", class, l.Percentiles)
+		}
+	}
+
+	c.format = knownFormat("plain-text")
+	b, e = newBombardier(c)
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	out.Reset()
+	b.redirectOutputTo(out)
+	b.printStats()
+	if !strings.Contains(out.String(), "Latency by code:\n    2xx: mean ") ||
+		!strings.Contains(out.String(), "\n    4xx: mean ") {
+		t.Errorf("Unexpected plain-text output %q", out.String())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/codesenberg/bombardier/internal"
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+func TestSortedLatenciesStats(t *testing.T) {
+	percentiles := []float64{0, 0.01, 0.5, 0.75, 0.9, 0.99, 0.999, 1}
+	h := uhist.Default()
+	sorted := internal.NewSortedUint64Histogram(h)
+	if stats := (internal.Results{Latencies: sorted}).
+		LatenciesStats(percentiles); stats != nil {
+		t.Errorf("Expected no stats for empty histogram, but got %+v", stats)
+	}
+	rnd := rand.New(rand.NewSource(42))
+	for pass := 0; pass < 3; pass++ {
+		for i := 0; i < 1000; i++ {
+			h.Increment(uint64(rnd.Intn(100000)))
+		}
+		expected := internal.Results{Latencies: h}.LatenciesStats(percentiles)
+		// computed repeatedly with the same data and once more is added
+		for i := 0; i < 2; i++ {
+			got := internal.Results{Latencies: sorted}.
+				LatenciesStats(percentiles)
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Expected %+v, but got %+v", expected, got)
+			}
+		}
+	}
+}
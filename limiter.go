@@ -3,6 +3,7 @@ package main
 import (
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/ratelimit"
@@ -26,17 +27,158 @@ func (n *nooplimiter) pace(<-chan struct{}) token {
 }
 
 type bucketlimiter struct {
-	limiter   *ratelimit.Bucket
+	// limiter holds *rateBucket, which is replaced when the rate is
+	// changed
+	limiter   atomic.Value
 	timerPool *sync.Pool
+
+	// burst, if set, is the capacity of buckets, unless they refill
+	// more tokens at once
+	burst uint64
+
+	// rate is guarded by mu, which also serializes its changes
+	mu   sync.Mutex
+	rate uint64
+}
+
+// rateBucket is a bucket of tokens for some rate, changed is closed
+// once it's replaced, so that requests waiting for tokens from it
+// are paced with the new one.
+type rateBucket struct {
+	*ratelimit.Bucket
+	changed chan struct{}
+}
+
+func newBucketLimiter(rate, burst uint64) *bucketlimiter {
+	b := &bucketlimiter{
+		timerPool: &sync.Pool{
+			New: func() interface{} {
+				return time.NewTimer(math.MaxInt64)
+			},
+		},
+		burst: burst,
+		rate:  rate,
+	}
+	b.limiter.Store(newRateBucket(rate, burst))
+	return b
+}
+
+func newRateBucket(rate, burst uint64) *rateBucket {
+	fillInterval, quantum := estimate(rate, rateLimitInterval)
+	return newBucketWithQuantum(fillInterval, quantum, burst)
 }
 
-func newBucketLimiter(rate uint64) limiter {
+// newReplacementBucket returns a bucket for the rate changed during
+// the test. It starts empty, so rates without a short exact interval
+// (i.e. prime ones, which would be filled once a second) are
+// approximated in quanta of about rateLimitInterval, otherwise each
+// change would hold requests back for up to a second.
+func newReplacementBucket(rate, burst uint64) *rateBucket {
 	fillInterval, quantum := estimate(rate, rateLimitInterval)
-	return &bucketlimiter{
+	if fillInterval > rateLimitInterval {
+		quantum = rate * uint64(rateLimitInterval) / uint64(time.Second)
+		if quantum < 1 {
+			quantum = 1
+		}
+		fillInterval = time.Duration(quantum * uint64(time.Second) / rate)
+	}
+	return newBucketWithQuantum(fillInterval, quantum, burst)
+}
+
+func newBucketWithQuantum(
+	fillInterval time.Duration, quantum, burst uint64,
+) *rateBucket {
+	capacity := quantum
+	if burst > capacity {
+		capacity = burst
+	}
+	return &rateBucket{
 		ratelimit.NewBucketWithQuantum(
-			fillInterval, int64(quantum), int64(quantum),
+			fillInterval, int64(capacity), int64(quantum),
 		),
-		&sync.Pool{
+		make(chan struct{}),
+	}
+}
+
+// adjustRate changes the rate by delta requests per second, though
+// not below 1, and returns the new rate.
+func (b *bucketlimiter) adjustRate(delta int64) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case delta >= 0:
+		b.rate += uint64(delta)
+	case uint64(-delta) < b.rate:
+		b.rate -= uint64(-delta)
+	default:
+		b.rate = 1
+	}
+	b.replaceBucket()
+	return b.rate
+}
+
+// setRate changes the rate to the given one, though not below 1.
+func (b *bucketlimiter) setRate(rate uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rate < 1 {
+		rate = 1
+	}
+	b.rate = rate
+	b.replaceBucket()
+}
+
+// replaceBucket switches to a bucket for the current rate, it must be
+// called with mu held.
+func (b *bucketlimiter) replaceBucket() {
+	// new bucket is full, drain it to avoid a burst of requests
+	bucket := newReplacementBucket(b.rate, b.burst)
+	bucket.TakeAvailable(bucket.Capacity())
+	old := b.limiter.Load().(*rateBucket)
+	b.limiter.Store(bucket)
+	close(old.changed)
+}
+
+func (b *bucketlimiter) pace(done <-chan struct{}) (res token) {
+	bucket := b.limiter.Load().(*rateBucket)
+	wd := bucket.Take(1)
+	if wd <= 0 {
+		return cont
+	}
+
+	timer := b.timerPool.Get().(*time.Timer)
+	timer.Reset(wd)
+	select {
+	case <-timer.C:
+		res = cont
+	case <-done:
+		res = brk
+	case <-bucket.changed:
+		if !timer.Stop() {
+			<-timer.C
+		}
+		b.timerPool.Put(timer)
+		return b.pace(done)
+	}
+	b.timerPool.Put(timer)
+	return
+}
+
+type byteslimiter struct {
+	rate                    uint64
+	bytesRead, bytesWritten *int64
+
+	startOnce sync.Once
+	start     time.Time
+	timerPool *sync.Pool
+}
+
+func newBytesLimiter(rate uint64, bytesRead, bytesWritten *int64) limiter {
+	return &byteslimiter{
+		rate:         rate,
+		bytesRead:    bytesRead,
+		bytesWritten: bytesWritten,
+		timerPool: &sync.Pool{
 			New: func() interface{} {
 				return time.NewTimer(math.MaxInt64)
 			},
@@ -44,8 +186,19 @@ func newBucketLimiter(rate uint64) limiter {
 	}
 }
 
-func (b *bucketlimiter) pace(done <-chan struct{}) (res token) {
-	wd := b.limiter.Take(1)
+func (b *byteslimiter) pace(done <-chan struct{}) (res token) {
+	b.startOnce.Do(func() {
+		b.start = time.Now()
+	})
+	// Size of the next request isn't known in advance, so instead of
+	// reserving bytes we wait until everything transferred so far fits
+	// into the budget.
+	transferred := atomic.LoadInt64(b.bytesRead) +
+		atomic.LoadInt64(b.bytesWritten)
+	allowedAt := time.Duration(
+		float64(transferred) / float64(b.rate) * float64(time.Second),
+	)
+	wd := allowedAt - time.Since(b.start)
 	if wd <= 0 {
 		return cont
 	}
@@ -56,8 +209,23 @@ func (b *bucketlimiter) pace(done <-chan struct{}) (res token) {
 	case <-timer.C:
 		res = cont
 	case <-done:
+		if !timer.Stop() {
+			<-timer.C
+		}
 		res = brk
 	}
 	b.timerPool.Put(timer)
 	return
 }
+
+// compositeLimiter paces with each of its limiters in turn.
+type compositeLimiter []limiter
+
+func (c compositeLimiter) pace(done <-chan struct{}) token {
+	for _, l := range c {
+		if l.pace(done) == brk {
+			return brk
+		}
+	}
+	return cont
+}
@@ -54,7 +54,7 @@ func TestBucketLimiterCounterBarrierCombination(t *testing.T) {
 		go func() {
 			defer expWg.Done()
 			b := newCountingCompletionBarrier(exp.count)
-			lim := newBucketLimiter(exp.rate)
+			lim := newBucketLimiter(exp.rate, 0)
 			counter := uint64(0)
 			numParties := 10
 			var wg sync.WaitGroup
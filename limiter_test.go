@@ -75,7 +75,7 @@ func TestBucketLimiterLowRates(t *testing.T) {
 		exp := expectations[i]
 		go func() {
 			defer lwg.Done()
-			lim := newBucketLimiter(exp.rate)
+			lim := newBucketLimiter(exp.rate, 0)
 			done := make(chan struct{})
 			counter := uint64(0)
 			waitChan := make(chan struct{})
@@ -119,7 +119,7 @@ func TestBucketLimiterHighRates(t *testing.T) {
 	}
 	for i := range expectations {
 		exp := expectations[i]
-		lim := newBucketLimiter(exp.rate)
+		lim := newBucketLimiter(exp.rate, 0)
 		counter := uint64(0)
 		done := make(chan struct{})
 		waitChan := make(chan struct{})
@@ -147,8 +147,119 @@ func TestBucketLimiterHighRates(t *testing.T) {
 	}
 }
 
+func TestBucketLimiterAdjustRate(t *testing.T) {
+	lim := newBucketLimiter(100, 0)
+	expectations := []struct {
+		delta    int64
+		expected uint64
+	}{
+		{50, 150},
+		{-100, 50},
+		{-50, 1},
+		{-10, 1},
+		{99, 100},
+	}
+	for _, e := range expectations {
+		if got := lim.adjustRate(e.delta); got != e.expected {
+			t.Errorf("Expected rate %v after %v, but got %v",
+				e.expected, e.delta, got)
+		}
+	}
+	// the new rate is in effect right away
+	lim.adjustRate(maxRps)
+	done := make(chan struct{})
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		lim.pace(done)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("Expected 1000 requests to be paced quickly, but took %v",
+			took)
+	}
+}
+
+func TestBucketLimiterSetRate(t *testing.T) {
+	lim := newBucketLimiter(1, 0)
+	lim.setRate(0)
+	if lim.rate != 1 {
+		t.Errorf("Expected rate not to go below 1, but got %v", lim.rate)
+	}
+	// the new rate is in effect right away
+	lim.setRate(maxRps)
+	done := make(chan struct{})
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		lim.pace(done)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("Expected 1000 requests to be paced quickly, but took %v",
+			took)
+	}
+}
+
+func TestBucketLimiterPrimeRates(t *testing.T) {
+	// --rate is filled once a second in a single quantum, as it was
+	lim := newBucketLimiter(997, 0)
+	bucket := lim.limiter.Load().(*rateBucket)
+	if capacity := bucket.Capacity(); capacity != 997 {
+		t.Errorf("Expected capacity 997, but got %v", capacity)
+	}
+	// but a changed rate is approximated, since the new bucket starts
+	// empty and would hold requests back for a second
+	lim.setRate(997)
+	bucket = lim.limiter.Load().(*rateBucket)
+	if capacity := bucket.Capacity(); capacity != 9 {
+		t.Errorf("Expected capacity 9, but got %v", capacity)
+	}
+	done := make(chan struct{})
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		lim.pace(done)
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("Expected 20 requests to be paced evenly, but took %v",
+			took)
+	}
+}
+
+func TestBucketLimiterBurst(t *testing.T) {
+	expectations := []struct {
+		burst     uint64
+		immediate int
+	}{
+		{0, 1},
+		{1, 1},
+		{5, 5},
+	}
+	for _, e := range expectations {
+		lim := newBucketLimiter(10, e.burst)
+		done := make(chan struct{})
+		start := time.Now()
+		for i := 0; i < e.immediate; i++ {
+			lim.pace(done)
+		}
+		if took := time.Since(start); took > 50*time.Millisecond {
+			t.Errorf("Expected %v requests to fire at once with burst %v, "+
+				"but took %v", e.immediate, e.burst, took)
+		}
+		lim.pace(done)
+		if took := time.Since(start); took < 50*time.Millisecond {
+			t.Errorf("Expected request %v to wait with burst %v, "+
+				"but took %v", e.immediate+1, e.burst, took)
+		}
+	}
+	// the burst is kept when the rate is changed, but the new bucket
+	// starts empty
+	lim := newBucketLimiter(10, 5)
+	lim.setRate(20)
+	bucket := lim.limiter.Load().(*rateBucket)
+	if capacity := bucket.Capacity(); capacity != 5 {
+		t.Errorf("Expected capacity 5, but got %v", capacity)
+	}
+}
+
 func BenchmarkBucketLimiter(bm *testing.B) {
-	lim := newBucketLimiter(maxRps)
+	lim := newBucketLimiter(maxRps, 0)
 	done := make(chan struct{})
 	bm.SetParallelism(int(defaultNumberOfConns) / runtime.NumCPU())
 	bm.ResetTimer()
@@ -158,3 +269,49 @@ func BenchmarkBucketLimiter(bm *testing.B) {
 		}
 	})
 }
+
+func TestBytesLimiter(t *testing.T) {
+	rate := uint64(100000)
+	requestSize := int64(1000)
+	duration := 500 * time.Millisecond
+	bytesRead, bytesWritten := int64(0), int64(0)
+	lim := newBytesLimiter(rate, &bytesRead, &bytesWritten)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(int(defaultNumberOfConns))
+	for i := uint64(0); i < defaultNumberOfConns; i++ {
+		go func() {
+			defer wg.Done()
+			for lim.pace(done) == cont {
+				atomic.AddInt64(&bytesRead, requestSize/2)
+				atomic.AddInt64(&bytesWritten, requestSize/2)
+			}
+		}()
+	}
+	time.Sleep(duration)
+	close(done)
+	wg.Wait()
+	expected := float64(rate) * duration.Seconds()
+	// Every connection is allowed to overshoot by one request
+	slack := float64(defaultNumberOfConns) * float64(requestSize)
+	actual := float64(bytesRead + bytesWritten)
+	if actual < expected*0.9 || actual > expected*1.1+slack {
+		t.Error(expected, actual)
+	}
+}
+
+func TestCompositeLimiter(t *testing.T) {
+	done := make(chan struct{})
+	lim := compositeLimiter{&nooplimiter{}, &nooplimiter{}}
+	if lim.pace(done) != cont {
+		t.Error("composite of nooplimiters should return cont")
+	}
+	bytesRead, bytesWritten := int64(1000000), int64(0)
+	lim = compositeLimiter{
+		&nooplimiter{}, newBytesLimiter(1, &bytesRead, &bytesWritten),
+	}
+	close(done)
+	if lim.pace(done) != brk {
+		t.Error("composite limiter should return brk when done")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	// latencies are bucketed by their liveSubBucketBits most
+	// significant bits, so estimates are within 1/16 of actual values
+	liveSubBucketBits = 4
+	liveSubBuckets    = 1 << liveSubBucketBits
+	liveBuckets       = (64 - liveSubBucketBits + 1) * liveSubBuckets
+)
+
+// liveLatencies is a coarse latency histogram for --live-p99. Unlike
+// uhist.Histogram it has a fixed number of buckets, so percentiles
+// can be estimated on every refresh of the progress bar without
+// sorting all latencies recorded so far.
+type liveLatencies struct {
+	// accessed atomically
+	buckets [liveBuckets]uint64
+}
+
+func liveBucket(us uint64) int {
+	if us < liveSubBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(us) - liveSubBucketBits - 1
+	return (shift+1)*liveSubBuckets + int(us>>uint(shift)) - liveSubBuckets
+}
+
+// liveBucketMax returns the largest latency that falls into bucket i.
+func liveBucketMax(i int) uint64 {
+	if i < liveSubBuckets {
+		return uint64(i)
+	}
+	shift := uint(i/liveSubBuckets - 1)
+	mantissa := uint64(i%liveSubBuckets + liveSubBuckets)
+	return (mantissa+1)<<shift - 1
+}
+
+func (l *liveLatencies) record(us uint64) {
+	atomic.AddUint64(&l.buckets[liveBucket(us)], 1)
+}
+
+// percentile estimates p-th (0 < p <= 1) percentile of latencies
+// recorded so far, false is returned if there are none.
+func (l *liveLatencies) percentile(p float64) (uint64, bool) {
+	var counts [liveBuckets]uint64
+	total := uint64(0)
+	for i := range l.buckets {
+		counts[i] = atomic.LoadUint64(&l.buckets[i])
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0, false
+	}
+	rank := uint64(p*float64(total) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	seen := uint64(0)
+	for i, count := range counts {
+		seen += count
+		if seen >= rank {
+			return liveBucketMax(i), true
+		}
+	}
+	return liveBucketMax(liveBuckets - 1), true
+}
+
+// updateLiveP99 updates the current p99 latency shown on the progress
+// bar, the last one is kept while no requests complete.
+func (b *bombardier) updateLiveP99() {
+	if p99, ok := b.liveLatencies.percentile(0.99); ok {
+		b.liveP99 = " p99: " + b.conf.formatLatency(float64(p99))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLiveBucket(t *testing.T) {
+	values := []uint64{
+		0, 1, 15, 16, 17, 31, 32, 33, 1000, 123456, 1 << 40,
+		math.MaxUint64,
+	}
+	for _, us := range values {
+		i := liveBucket(us)
+		if i < 0 || i >= liveBuckets {
+			t.Fatalf("Bucket of %v is out of range: %v", us, i)
+		}
+		max := liveBucketMax(i)
+		if max < us {
+			t.Errorf("Bucket of %v has smaller max %v", us, max)
+		}
+		if us > 0 && float64(max-us)/float64(us) > 1.0/liveSubBuckets {
+			t.Errorf("Bucket max %v is too far from %v", max, us)
+		}
+		if i > 0 && liveBucketMax(i-1) >= us {
+			t.Errorf("Previous bucket of %v has max %v", us, liveBucketMax(i-1))
+		}
+	}
+}
+
+func TestLiveLatenciesPercentile(t *testing.T) {
+	l := new(liveLatencies)
+	if _, ok := l.percentile(0.99); ok {
+		t.Error("Expected no percentile without latencies")
+	}
+	for i := 0; i < 990; i++ {
+		l.record(10)
+	}
+	for i := 0; i < 10; i++ {
+		l.record(100000)
+	}
+	if p, ok := l.percentile(0.99); !ok || p != 10 {
+		t.Errorf("Expected p99 of 10us, but got %v", p)
+	}
+	l.record(100000)
+	if p, ok := l.percentile(0.99); !ok || p < 100000 || p > 106250 {
+		t.Errorf("Expected p99 of about 100000us, but got %v", p)
+	}
+}
+
+func TestBombardierLiveP99(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:      1,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		liveP99:       true,
+		printProgress: true,
+		format:        knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.errOut = ioutil.Discard
+	b.bombard()
+	if !strings.Contains(out.String(), " p99: ") {
+		t.Errorf("Expected p99 on the progress bar, but got %q", out)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// rateSearch implements --find-max-rps. Every findMaxRPSProbe it
+// measures the rate currently allowed by the limiter and picks the
+// next one: doubling it until some rate isn't sustained, then
+// bisecting between the highest sustained rate and the lowest failed
+// one until they are within findMaxRPSPrecision of each other. The
+// highest sustained rate is kept for the rest of the test.
+type rateSearch struct {
+	// maxErrorRate is a fraction of requests, maxP99Us is zero if p99
+	// latency isn't limited
+	maxErrorRate float64
+	maxP99Us     uint64
+
+	// holds *rateProbe, which is replaced once it's measured
+	probe atomic.Value
+
+	// mu guards the rest
+	mu      sync.Mutex
+	rate    uint64
+	best    *rateProbeResult
+	failed  uint64
+	settled bool
+}
+
+// rateProbe accumulates requests sent while probing a rate.
+type rateProbe struct {
+	// accessed atomically
+	reqs, errs uint64
+	latencies  liveLatencies
+}
+
+// rateProbeResult is what was measured with some rate.
+type rateProbeResult struct {
+	rate      uint64
+	rps       float64
+	p99Us     uint64
+	errorRate float64
+}
+
+func newRateSearch(c *config, rate uint64) *rateSearch {
+	s := &rateSearch{
+		maxErrorRate: c.maxErrorRateOrDefault() / 100,
+		maxP99Us:     uint64(c.maxP99 / time.Microsecond),
+		rate:         rate,
+	}
+	s.probe.Store(new(rateProbe))
+	return s
+}
+
+func (s *rateSearch) record(usTaken uint64) {
+	p := s.probe.Load().(*rateProbe)
+	atomic.AddUint64(&p.reqs, 1)
+	p.latencies.record(usTaken)
+}
+
+func (s *rateSearch) recordError() {
+	p := s.probe.Load().(*rateProbe)
+	atomic.AddUint64(&p.errs, 1)
+}
+
+// measure returns results of the probe that lasted elapsed and
+// starts a new one.
+func (s *rateSearch) measure(elapsed time.Duration) rateProbeResult {
+	p := s.probe.Load().(*rateProbe)
+	s.probe.Store(new(rateProbe))
+	s.mu.Lock()
+	res := rateProbeResult{rate: s.rate}
+	s.mu.Unlock()
+	reqs, errs := atomic.LoadUint64(&p.reqs), atomic.LoadUint64(&p.errs)
+	if reqs == 0 {
+		return res
+	}
+	res.rps = float64(reqs) / elapsed.Seconds()
+	res.p99Us, _ = p.latencies.percentile(0.99)
+	res.errorRate = float64(errs) / float64(reqs)
+	return res
+}
+
+// sustained tells whether the rate was achieved within thresholds.
+func (s *rateSearch) sustained(r rateProbeResult) bool {
+	return r.rps >= float64(r.rate)*findMaxRPSMinAchieved &&
+		r.errorRate <= s.maxErrorRate &&
+		(s.maxP99Us == 0 || r.p99Us <= s.maxP99Us)
+}
+
+// advance accounts r and returns the rate to use from now on and
+// whether the search is over.
+func (s *rateSearch) advance(r rateProbeResult) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sustained(r) {
+		if s.best == nil || r.rate > s.best.rate {
+			s.best = &r
+		}
+	} else if s.failed == 0 || r.rate < s.failed {
+		s.failed = r.rate
+	}
+	switch {
+	case s.failed == 0:
+		s.rate *= 2
+	case s.best == nil:
+		if s.failed <= 1 {
+			// not even a request per second is sustained
+			s.settled = true
+			return 1, true
+		}
+		s.rate = s.failed / 2
+	case s.failed <= s.best.rate+1 ||
+		float64(s.failed) <= float64(s.best.rate)*(1+findMaxRPSPrecision):
+		s.settled = true
+		s.rate = s.best.rate
+		return s.rate, true
+	default:
+		s.rate = (s.best.rate + s.failed) / 2
+	}
+	return s.rate, false
+}
+
+// searchMaxRPS probes rates until the highest sustained one is found
+// or the test is done.
+func (b *bombardier) searchMaxRPS() {
+	ticker := time.NewTicker(findMaxRPSProbe)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	probeStart := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		res := b.rateSearch.measure(time.Since(probeStart))
+		probeStart = time.Now()
+		next, settled := b.rateSearch.advance(res)
+		b.rateLimiter.setRate(next)
+		if settled {
+			return
+		}
+	}
+}
+
+func (s *rateSearch) result() *internal.MaxRPSResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.best == nil {
+		return nil
+	}
+	return &internal.MaxRPSResult{
+		Rate:              s.best.rate,
+		RequestsPerSecond: s.best.rps,
+		P99Latency:        s.best.p99Us,
+		ErrorRate:         s.best.errorRate * 100,
+		Settled:           s.settled,
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateSearchAdvance(t *testing.T) {
+	type advance struct {
+		probe    rateProbeResult
+		next     uint64
+		settled  bool
+		bestRate uint64
+	}
+	maxP99 := 10 * time.Millisecond
+	expectations := []struct {
+		name   string
+		maxP99 time.Duration
+		start  uint64
+		steps  []advance
+	}{
+		{
+			"bisect", 0, 100,
+			[]advance{
+				{rateProbeResult{100, 100, 1000, 0}, 200, false, 100},
+				{rateProbeResult{200, 199, 1000, 0}, 400, false, 200},
+				{rateProbeResult{400, 300, 1000, 0}, 300, false, 200},
+				{rateProbeResult{300, 298, 1000, 0}, 350, false, 300},
+				{rateProbeResult{350, 340, 1000, 0.02}, 325, false, 300},
+				{rateProbeResult{325, 320, 1000, 0}, 337, false, 325},
+				{rateProbeResult{337, 335, 1000, 0}, 337, true, 337},
+			},
+		},
+		{
+			"latency", maxP99, 100,
+			[]advance{
+				{rateProbeResult{100, 100, 1000, 0}, 200, false, 100},
+				{rateProbeResult{200, 200, 20000, 0}, 150, false, 100},
+				{rateProbeResult{150, 150, 20000, 0}, 125, false, 100},
+				{rateProbeResult{125, 125, 20000, 0}, 112, false, 100},
+				{rateProbeResult{112, 112, 20000, 0}, 106, false, 100},
+				{rateProbeResult{106, 106, 20000, 0}, 103, false, 100},
+				{rateProbeResult{103, 103, 20000, 0}, 100, true, 100},
+			},
+		},
+		{
+			"nothing sustained", 0, 4,
+			[]advance{
+				{rateProbeResult{4, 0, 0, 0}, 2, false, 0},
+				{rateProbeResult{2, 2, 1000, 1}, 1, false, 0},
+				{rateProbeResult{1, 1, 1000, 1}, 1, true, 0},
+			},
+		},
+	}
+	for _, e := range expectations {
+		s := newRateSearch(&config{maxP99: e.maxP99}, e.start)
+		for i, a := range e.steps {
+			next, settled := s.advance(a.probe)
+			best := uint64(0)
+			if s.best != nil {
+				best = s.best.rate
+			}
+			if next != a.next || settled != a.settled || best != a.bestRate {
+				t.Errorf("%v, step %v: expected %v, %v (best %v), "+
+					"but got %v, %v (best %v)", e.name, i+1,
+					a.next, a.settled, a.bestRate, next, settled, best)
+			}
+		}
+	}
+}
+
+func TestRateSearchMeasure(t *testing.T) {
+	s := newRateSearch(&config{}, 100)
+	for i := 0; i < 100; i++ {
+		s.record(1000)
+	}
+	for i := 0; i < 20; i++ {
+		s.recordError()
+	}
+	res := s.measure(2 * time.Second)
+	if res.rate != 100 || res.rps != 50 || res.errorRate != 0.2 ||
+		res.p99Us < 1000 || res.p99Us > liveBucketMax(liveBucket(1000)) {
+		t.Errorf("Unexpected probe results: %+v", res)
+	}
+	// the next probe starts from scratch
+	if res := s.measure(time.Second); res.rps != 0 || res.errorRate != 0 {
+		t.Errorf("Expected an empty probe, but got %+v", res)
+	}
+}
+
+func TestBombardierFindMaxRPS(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+		}),
+	)
+	defer s.Close()
+	duration := 3*findMaxRPSProbe + findMaxRPSProbe/2
+	rate := uint64(20)
+	b, e := newBombardier(config{
+		numConns:   1,
+		duration:   &duration,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		rate:       &rate,
+		findMaxRPS: true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	// a single connection can't do more than ~100 reqs/sec, so 20 and
+	// 40 are sustained, but the search doesn't go past 160
+	res := b.gatherInfo().Result.MaxRPS
+	if res == nil || res.Rate < 40 || res.Rate > 80 ||
+		res.RequestsPerSecond == 0 || res.P99Latency == 0 {
+		t.Errorf("Unexpected result of --find-max-rps: %+v", res)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// methodMix is a list of methods with weights to spread requests
+// across, specified as comma-separated list of method:weight on the
+// command line.
+type methodMix []mixedMethod
+
+type mixedMethod struct {
+	method string
+	weight uint64
+}
+
+func (m *methodMix) String() string {
+	entries := make([]string, 0, len(*m))
+	for _, mm := range *m {
+		entries = append(entries, fmt.Sprintf("%v:%v", mm.method, mm.weight))
+	}
+	return strings.Join(entries, ",")
+}
+
+func (m *methodMix) Set(value string) error {
+	res := methodMix{}
+	total := uint64(0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		sep := strings.LastIndexByte(entry, ':')
+		if sep < 0 {
+			return &invalidMethodMixError{entry}
+		}
+		method := entry[:sep]
+		weight, err := strconv.ParseUint(entry[sep+1:], 10, 64)
+		if err != nil || weight == 0 || weight > maxMethodMixWeight ||
+			!allowedHTTPMethod(method) || method == "CONNECT" {
+			return &invalidMethodMixError{entry}
+		}
+		for _, mm := range res {
+			if mm.method == method {
+				return &invalidMethodMixError{entry}
+			}
+		}
+		total += weight
+		res = append(res, mixedMethod{method, weight})
+	}
+	if total > maxMethodMixWeight {
+		return errMethodMixWeights
+	}
+	*m = res
+	return nil
+}
+
+type invalidMethodMixError struct {
+	entry string
+}
+
+func (i *invalidMethodMixError) Error() string {
+	return fmt.Sprintf("%q is not a valid method mix entry "+
+		"(must be METHOD:weight, with each method once)", i.entry)
+}
+
+// hasWriteMethod tells whether requests with any of the methods are
+// sent with the body.
+func (m methodMix) hasWriteMethod() bool {
+	for _, mm := range m {
+		if isWriteMethod(mm.method) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWriteMethod tells whether requests with the method are sent with
+// the body with --method-mix.
+func isWriteMethod(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH":
+		return true
+	}
+	return false
+}
+
+// methodPicker spreads requests across methods of --method-mix in
+// proportion to their weights, interleaving them evenly, and counts
+// HTTP codes of responses for each method.
+type methodPicker struct {
+	methods []*methodStats
+	// order is a cycle of indices of methods, each one appears as many
+	// times as its weight
+	order []int
+	next  uint64
+}
+
+// methodStats holds HTTP codes of responses to requests with the
+// method, indexed by class with others at 0.
+type methodStats struct {
+	method string
+	// write is set if requests are sent with the body
+	write bool
+	codes [6]uint64
+}
+
+func newMethodPicker(mix methodMix) *methodPicker {
+	p := &methodPicker{}
+	total := int64(0)
+	for _, mm := range mix {
+		p.methods = append(p.methods, &methodStats{
+			method: mm.method,
+			write:  isWriteMethod(mm.method),
+		})
+		total += int64(mm.weight)
+	}
+	// smooth weighted round-robin, so that i.e. GET:3,POST:1 makes
+	// every fourth request a POST rather than sending three GETs
+	// after each other and then a POST
+	current := make([]int64, len(mix))
+	for len(p.order) < int(total) {
+		best := 0
+		for i, mm := range mix {
+			current[i] += int64(mm.weight)
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		p.order = append(p.order, best)
+	}
+	return p
+}
+
+// pick returns the method to send the next request with.
+func (p *methodPicker) pick() *methodStats {
+	n := atomic.AddUint64(&p.next, 1) - 1
+	return p.methods[p.order[n%uint64(len(p.order))]]
+}
+
+// done accounts the outcome of the request sent with the method.
+// Requests cut short by the end of the test or canceled on interrupt
+// aren't accounted, like elsewhere.
+func (m *methodStats) done(code int, err error) {
+	if err == errTestDone || err == errRequestCanceled {
+		return
+	}
+	class := code / 100
+	if class < 1 || class > 5 {
+		class = 0
+	}
+	atomic.AddUint64(&m.codes[class], 1)
+}
+
+func (p *methodPicker) results() []internal.MethodResult {
+	res := make([]internal.MethodResult, 0, len(p.methods))
+	for _, m := range p.methods {
+		res = append(res, internal.MethodResult{
+			Method: m.method,
+			Req1XX: atomic.LoadUint64(&m.codes[1]),
+			Req2XX: atomic.LoadUint64(&m.codes[2]),
+			Req3XX: atomic.LoadUint64(&m.codes[3]),
+			Req4XX: atomic.LoadUint64(&m.codes[4]),
+			Req5XX: atomic.LoadUint64(&m.codes[5]),
+			Others: atomic.LoadUint64(&m.codes[0]),
+		})
+	}
+	return res
+}
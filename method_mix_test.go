@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+func TestMethodMixSet(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out string
+		err error
+	}{
+		{"GET:1", "GET:1", nil},
+		{"GET:80, POST:20", "GET:80,POST:20", nil},
+		{"GET", "", &invalidMethodMixError{"GET"}},
+		{"GET:0", "", &invalidMethodMixError{"GET:0"}},
+		{"GET:-1", "", &invalidMethodMixError{"GET:-1"}},
+		{"FETCH:1", "", &invalidMethodMixError{"FETCH:1"}},
+		{"CONNECT:1", "", &invalidMethodMixError{"CONNECT:1"}},
+		{"GET:1,GET:2", "", &invalidMethodMixError{"GET:2"}},
+		{"GET:1,", "", &invalidMethodMixError{""}},
+		{"GET:5000,POST:5001", "", errMethodMixWeights},
+	}
+	for _, e := range expectations {
+		var m methodMix
+		err := m.Set(e.in)
+		if e.err != nil {
+			if err == nil || err.Error() != e.err.Error() {
+				t.Errorf("Expected %q for %q, but got %v", e.err, e.in, err)
+			}
+			continue
+		}
+		if err != nil || m.String() != e.out {
+			t.Errorf("Expected %v for %q, but got %v (%v)",
+				e.out, e.in, m.String(), err)
+		}
+	}
+}
+
+func TestMethodPickerOrder(t *testing.T) {
+	p := newMethodPicker(methodMix{{"GET", 3}, {"POST", 1}, {"PUT", 1}})
+	var got []string
+	for i := 0; i < 10; i++ {
+		got = append(got, p.pick().method)
+	}
+	expected := []string{
+		"GET", "POST", "GET", "PUT", "GET",
+		"GET", "POST", "GET", "PUT", "GET",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, but got %v", expected, got)
+	}
+}
+
+func TestBombardierMethodMix(t *testing.T) {
+	testAllClients(t, testBombardierMethodMix)
+}
+
+func testBombardierMethodMix(clientType clientTyp, t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies = make(map[string][]string)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			bodies[r.Method] = append(bodies[r.Method], string(body))
+			mu.Unlock()
+			if r.Method == "POST" {
+				rw.WriteHeader(http.StatusCreated)
+				return
+			}
+			rw.WriteHeader(http.StatusNotFound)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(8)
+	b, e := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		body:       "data",
+		methodMix:  &methodMix{{"GET", 3}, {"POST", 1}},
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if len(bodies["GET"]) != 6 || len(bodies["POST"]) != 2 {
+		t.Fatalf("Expected 6 GETs and 2 POSTs, but got %v", bodies)
+	}
+	for _, body := range bodies["GET"] {
+		if body != "" {
+			t.Errorf("Expected GETs to be sent without body, but got %q",
+				body)
+		}
+	}
+	for _, body := range bodies["POST"] {
+		if body != "data" {
+			t.Errorf("Expected POSTs to be sent with body, but got %q",
+				body)
+		}
+	}
+	expected := []internal.MethodResult{
+		{Method: "GET", Req4XX: 6},
+		{Method: "POST", Req2XX: 2},
+	}
+	if got := b.gatherInfo().Result.Methods; !reflect.DeepEqual(
+		got, expected,
+	) {
+		t.Errorf("Expected %+v, but got %+v", expected, got)
+	}
+}
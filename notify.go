@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// notify POSTs results of the test, formatted as json, to the
+// --notify-url.
+func (b *bombardier) notify() error {
+	body := new(bytes.Buffer)
+	if err := b.notifyReporter.report(body, b.gatherInfo()); err != nil {
+		return err
+	}
+	timeout := b.conf.notifyTimeout
+	if timeout == 0 {
+		timeout = defaultNotifyTimeout
+	}
+	cl := &http.Client{Timeout: timeout}
+	resp, err := cl.Post(b.conf.notifyURL, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBombardierNotify(t *testing.T) {
+	target := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer target.Close()
+	received := make(chan map[string]interface{}, 1)
+	webhook := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("unexpected content type %q", ct)
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var parsed map[string]interface{}
+			if err := json.Unmarshal(body, &parsed); err != nil {
+				t.Errorf("invalid json %q: %v", body, err)
+			}
+			received <- parsed
+		}),
+	)
+	defer webhook.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:  defaultNumberOfConns,
+		numReqs:   &numReqs,
+		url:       target.URL,
+		headers:   new(headersList),
+		timeout:   defaultTimeout,
+		method:    "GET",
+		format:    knownFormat("plain-text"),
+		notifyURL: webhook.URL,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if err := b.notify(); err != nil {
+		t.Error(err)
+		return
+	}
+	result := <-received
+	if _, ok := result["result"]; !ok {
+		t.Errorf("no result in %v", result)
+	}
+}
+
+func TestBombardierNotifyFailure(t *testing.T) {
+	webhook := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}),
+	)
+	defer webhook.Close()
+	numReqs := uint64(1)
+	b, e := newBombardier(config{
+		numConns:  defaultNumberOfConns,
+		numReqs:   &numReqs,
+		url:       webhook.URL,
+		headers:   new(headersList),
+		timeout:   defaultTimeout,
+		method:    "GET",
+		format:    knownFormat("plain-text"),
+		notifyURL: webhook.URL,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if err := b.notify(); err == nil {
+		t.Error("expected notify to fail")
+	}
+}
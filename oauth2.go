@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// oauth2Token obtains bearer tokens with OAuth2 client credentials
+// grant (RFC 6749, section 4.4) and keeps the current one, which
+// clients send in Authorization header.
+type oauth2Token struct {
+	tokenURL         string
+	clientID, secret string
+	scope            string
+	client           *http.Client
+
+	// header holds "Bearer <token>"
+	header atomic.Value
+	// expiresIn is how long the last token obtained is valid, zero
+	// if it doesn't expire
+	expiresIn time.Duration
+}
+
+func newOAuth2Token(c *config) *oauth2Token {
+	return &oauth2Token{
+		tokenURL: c.oauth2TokenURL,
+		clientID: c.oauth2ClientID,
+		secret:   c.oauth2ClientSecret,
+		scope:    c.oauth2Scope,
+		client: &http.Client{
+			Timeout: c.timeout,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: c.insecure,
+				},
+			},
+		},
+	}
+}
+
+// authorization returns value of Authorization header to send.
+func (t *oauth2Token) authorization() string {
+	return t.header.Load().(string)
+}
+
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is in seconds, zero if the server didn't specify it
+	ExpiresIn int64 `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type oauth2Error struct {
+	status      int
+	code, descr string
+}
+
+func (e *oauth2Error) Error() string {
+	msg := fmt.Sprintf("Failed to obtain OAuth2 token (status %v)",
+		e.status)
+	if e.code != "" {
+		msg += ": " + e.code
+	}
+	if e.descr != "" {
+		msg += " (" + e.descr + ")"
+	}
+	return msg
+}
+
+// fetch obtains a new token.
+func (t *oauth2Token) fetch() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if t.scope != "" {
+		form.Set("scope", t.scope)
+	}
+	req, err := http.NewRequest(
+		"POST", t.tokenURL, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// RFC 6749, section 2.3.1
+	req.SetBasicAuth(
+		url.QueryEscape(t.clientID), url.QueryEscape(t.secret),
+	)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to obtain OAuth2 token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(
+		io.LimitReader(resp.Body, oauth2MaxResponseSize),
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to obtain OAuth2 token: %v", err)
+	}
+	var tr oauth2TokenResponse
+	jerr := json.Unmarshal(body, &tr)
+	if resp.StatusCode/100 != 2 {
+		return &oauth2Error{
+			resp.StatusCode, tr.Error, tr.ErrorDescription,
+		}
+	}
+	switch {
+	case jerr != nil:
+		return fmt.Errorf("Failed to parse OAuth2 token response: %v",
+			jerr)
+	case tr.AccessToken == "":
+		return errOAuth2NoToken
+	case tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer"):
+		return fmt.Errorf("Unsupported OAuth2 token type %q",
+			tr.TokenType)
+	}
+	t.header.Store("Bearer " + tr.AccessToken)
+	t.expiresIn = time.Duration(tr.ExpiresIn) * time.Second
+	return nil
+}
+
+// oauth2RefreshIn returns how long to wait before refreshing a token
+// valid for expiresIn.
+func oauth2RefreshIn(expiresIn time.Duration) time.Duration {
+	if expiresIn <= 2*oauth2RefreshMargin {
+		return expiresIn / 2
+	}
+	return expiresIn - oauth2RefreshMargin
+}
+
+// refreshOAuth2Token obtains new tokens before the current ones
+// expire until the test is done. Failed attempts are retried every
+// oauth2RetryInterval and if the token expires nevertheless, the test
+// is stopped.
+func (b *bombardier) refreshOAuth2Token() {
+	done := b.barrier.done()
+	for b.oauth2.expiresIn > 0 {
+		expiresAt := time.Now().Add(b.oauth2.expiresIn)
+		if !sleepOrDone(oauth2RefreshIn(b.oauth2.expiresIn), done) {
+			return
+		}
+		for {
+			err := b.oauth2.fetch()
+			if err == nil {
+				break
+			}
+			wait := oauth2RetryInterval
+			if left := time.Until(expiresAt); left < wait {
+				wait = left
+			}
+			if wait <= 0 {
+				atomic.StoreUint32(&b.oauth2Failed, 1)
+				fmt.Fprintf(b.errOut, "%v, stopping the test\n", err)
+				b.barrier.cancel()
+				return
+			}
+			if !sleepOrDone(wait, done) {
+				return
+			}
+		}
+	}
+}
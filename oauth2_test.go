@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// oauth2TestServer issues tokens "t1", "t2", etc. valid for
+// expiresIn seconds, requests after the first failAfter ones fail,
+// unless it's negative.
+type oauth2TestServer struct {
+	*httptest.Server
+	issued    int64
+	failAfter int64
+}
+
+func newOAuth2TestServer(
+	t *testing.T, expiresIn int, failAfter int64,
+) *oauth2TestServer {
+	s := &oauth2TestServer{failAfter: failAfter}
+	s.Server = httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			id, secret, ok := r.BasicAuth()
+			if r.Method != "POST" || !ok || id != "client" ||
+				secret != "s3cr3t" ||
+				r.PostFormValue("grant_type") != "client_credentials" ||
+				r.PostFormValue("scope") != "read write" {
+				t.Errorf("Unexpected token request: %v %v %q",
+					r.Method, r.Header, r.PostForm)
+			}
+			n := atomic.AddInt64(&s.issued, 1)
+			rw.Header().Set("Content-Type", "application/json")
+			if s.failAfter >= 0 && n > s.failAfter {
+				rw.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(rw, `{"error":"invalid_client",`+
+					`"error_description":"client disabled"}`)
+				return
+			}
+			fmt.Fprintf(rw, `{"access_token":"t%v","token_type":"Bearer"`+
+				`,"expires_in":%v}`, n, expiresIn)
+		}),
+	)
+	return s
+}
+
+func oauth2TestConfig(
+	tokenURL, url string, duration time.Duration, clientType clientTyp,
+) config {
+	return config{
+		numConns:           1,
+		duration:           &duration,
+		url:                url,
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		clientType:         clientType,
+		oauth2TokenURL:     tokenURL,
+		oauth2ClientID:     "client",
+		oauth2ClientSecret: "s3cr3t",
+		oauth2Scope:        "read write",
+		format:             knownFormat("plain-text"),
+	}
+}
+
+func TestBombardierOAuth2(t *testing.T) {
+	testAllClients(t, testBombardierOAuth2)
+}
+
+func testBombardierOAuth2(clientType clientTyp, t *testing.T) {
+	tokens := newOAuth2TestServer(t, 0, -1)
+	defer tokens.Close()
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen[r.Header.Get("Authorization")] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	c := oauth2TestConfig(tokens.URL, s.URL, 0, clientType)
+	c.duration, c.numReqs = nil, &numReqs
+	b, e := newBombardier(c)
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	if len(seen) != 1 || !seen["Bearer t1"] {
+		t.Errorf("Expected only \"Bearer t1\" to be sent, but got %v", seen)
+	}
+}
+
+func TestBombardierOAuth2Refresh(t *testing.T) {
+	tokens := newOAuth2TestServer(t, 1, -1)
+	defer tokens.Close()
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen[r.Header.Get("Authorization")] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	b, e := newBombardier(oauth2TestConfig(
+		tokens.URL, s.URL, 2*time.Second, nhttp1,
+	))
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	// token valid for 1s is refreshed every 500ms
+	if !seen["Bearer t1"] || !seen["Bearer t2"] || !seen["Bearer t3"] {
+		t.Errorf("Expected refreshed tokens to be sent, but got %v", seen)
+	}
+	if atomic.LoadUint32(&b.oauth2Failed) != 0 {
+		t.Error("Refreshing token shouldn't fail")
+	}
+}
+
+func TestBombardierOAuth2RefreshFailure(t *testing.T) {
+	tokens := newOAuth2TestServer(t, 1, 1)
+	defer tokens.Close()
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	b, e := newBombardier(oauth2TestConfig(
+		tokens.URL, s.URL, 10*time.Second, nhttp1,
+	))
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	errOut := new(strings.Builder)
+	b.errOut = errOut
+	start := time.Now()
+	b.bombard()
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("Expected test to be stopped once token expired, "+
+			"but it took %v", took)
+	}
+	if atomic.LoadUint32(&b.oauth2Failed) != 1 {
+		t.Error("Expected refreshing token to fail")
+	}
+	expected := "Failed to obtain OAuth2 token (status 401): " +
+		"invalid_client (client disabled), stopping the test"
+	if !strings.Contains(errOut.String(), expected) {
+		t.Errorf("Expected %q, but got %q", expected, errOut)
+	}
+}
+
+func TestBombardierOAuth2FetchFailure(t *testing.T) {
+	tokens := newOAuth2TestServer(t, 0, 0)
+	defer tokens.Close()
+	_, e := newBombardier(oauth2TestConfig(
+		tokens.URL, "http://localhost:8080", time.Second, fhttp,
+	))
+	expected := "Failed to obtain OAuth2 token (status 401): " +
+		"invalid_client (client disabled)"
+	if e == nil || e.Error() != expected {
+		t.Errorf("Expected %q, but got %v", expected, e)
+	}
+}
+
+func TestOAuth2RefreshIn(t *testing.T) {
+	expectations := []struct {
+		expiresIn, expected time.Duration
+	}{
+		{time.Hour, time.Hour - oauth2RefreshMargin},
+		{2 * oauth2RefreshMargin, oauth2RefreshMargin},
+		{time.Second, 500 * time.Millisecond},
+	}
+	for _, e := range expectations {
+		if got := oauth2RefreshIn(e.expiresIn); got != e.expected {
+			t.Errorf("Expected token valid for %v to be refreshed in %v, "+
+				"but got %v", e.expiresIn, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// pacedUploads counts bodies sent with --chunk-delay (or --chunk-size)
+// by whether they were read to the end before being closed, that is
+// whether the server waited for the whole slow upload.
+type pacedUploads struct {
+	completed, interrupted uint64
+}
+
+func (p *pacedUploads) result() *internal.PacedUploadsResult {
+	return &internal.PacedUploadsResult{
+		Completed:   atomic.LoadUint64(&p.completed),
+		Interrupted: atomic.LoadUint64(&p.interrupted),
+	}
+}
+
+// pacedBodyProducer wraps bodies produced by bsp into pacedReaders.
+func pacedBodyProducer(
+	bsp bodyStreamProducer, size uint64, delay time.Duration,
+	stats *pacedUploads,
+) bodyStreamProducer {
+	return func() (io.ReadCloser, error) {
+		body, err := bsp()
+		if err != nil {
+			return nil, err
+		}
+		return &pacedReader{
+			r:     body,
+			size:  int(size),
+			delay: delay,
+			stats: stats,
+		}, nil
+	}
+}
+
+// pacedReader returns at most size bytes of the underlying body at a
+// time and waits for delay before each chunk but the first one. Both
+// fasthttp and net/http send each read of a streamed body as a
+// separate chunk and flush it right away.
+type pacedReader struct {
+	r     io.ReadCloser
+	size  int
+	delay time.Duration
+	// stats, if set, are updated once the reader is closed
+	stats *pacedUploads
+	// done, if set, interrupts waiting, Read returns errTestDone then
+	done <-chan struct{}
+
+	// bytes left in the current chunk
+	left    int
+	started bool
+
+	// net/http may close bodies while they are being read from
+	// another goroutine, so eof is accessed atomically
+	eof       uint32
+	closeOnce sync.Once
+}
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	if p.left == 0 {
+		if p.started && p.delay > 0 && !sleepOrDone(p.delay, p.done) {
+			return 0, errTestDone
+		}
+		p.started = true
+		p.left = p.size
+	}
+	if len(b) > p.left {
+		b = b[:p.left]
+	}
+	n, err := p.r.Read(b)
+	p.left -= n
+	if err == io.EOF {
+		atomic.StoreUint32(&p.eof, 1)
+	}
+	return n, err
+}
+
+func (p *pacedReader) Close() error {
+	p.closeOnce.Do(func() {
+		if p.stats == nil {
+			return
+		}
+		if atomic.LoadUint32(&p.eof) == 1 {
+			atomic.AddUint64(&p.stats.completed, 1)
+		} else {
+			atomic.AddUint64(&p.stats.interrupted, 1)
+		}
+	})
+	return p.r.Close()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacedReader(t *testing.T) {
+	stats := new(pacedUploads)
+	bsp := pacedBodyProducer(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("abcdefgh")), nil
+	}, 3, 10*time.Millisecond, stats)
+	body, err := bsp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []string
+	buf := make([]byte, 64)
+	start := time.Now()
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			chunks = append(chunks, string(buf[:n]))
+		}
+		if err != nil {
+			break
+		}
+	}
+	elapsed := time.Since(start)
+	_ = body.Close()
+	_ = body.Close()
+	if got := strings.Join(chunks, ","); got != "abc,def,gh" {
+		t.Errorf("Expected chunks \"abc,def,gh\", but got %q", got)
+	}
+	if elapsed < 20*time.Millisecond {
+		t.Errorf("Expected at least 20ms between chunks, took %v", elapsed)
+	}
+
+	body, err = bsp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = body.Read(buf)
+	_ = body.Close()
+	r := stats.result()
+	if r.Completed != 1 || r.Interrupted != 1 {
+		t.Errorf("Expected 1 completed and 1 interrupted upload, but got %+v",
+			*r)
+	}
+}
+
+func TestBombardierPacedUploads(t *testing.T) {
+	testAllClients(t, testBombardierPacedUploads)
+}
+
+func testBombardierPacedUploads(clientType clientTyp, t *testing.T) {
+	requestBody := strings.Repeat("x", 10)
+	matched := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err == nil && string(body) == requestBody {
+				atomic.AddUint64(&matched, 1)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(5)
+	chunkSize := uint64(4)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       requestBody,
+		stream:     true,
+		chunkDelay: 20 * time.Millisecond,
+		chunkSize:  &chunkSize,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if matched != numReqs {
+		t.Errorf("Expected %v requests with the whole body, but got %v",
+			numReqs, matched)
+	}
+	info := b.gatherInfo()
+	// 3 chunks with 2 delays between them
+	minUs := float64(40 * time.Millisecond / time.Microsecond)
+	if lats := info.Result.LatenciesStats(nil); lats == nil ||
+		lats.Mean < minUs {
+		t.Errorf("Expected uploads to take at least 40ms, but got %+v", lats)
+	}
+	r := info.Result.PacedUploads
+	if r == nil || r.Completed != numReqs {
+		t.Errorf("Expected %v completed uploads, but got %+v", numReqs, r)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// percentileList holds percentiles as fractions in (0, 1], sorted
+// and without duplicates. On the command line they're specified as
+// comma-separated percents, i.e. "50,99,99.9".
+type percentileList []float64
+
+func (p *percentileList) String() string {
+	parts := make([]string, 0, len(*p))
+	for _, pc := range *p {
+		parts = append(parts, strconv.FormatFloat(pc*100, 'g', 6, 64))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *percentileList) Set(value string) error {
+	res := percentileList{}
+	seen := make(map[float64]bool)
+	for _, part := range strings.Split(value, ",") {
+		pc, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || !(pc > 0 && pc <= 100) {
+			return fmt.Errorf(
+				"%q is not a valid percentile(must be in (0, 100])", part,
+			)
+		}
+		if seen[pc] {
+			continue
+		}
+		seen[pc] = true
+		res = append(res, pc/100)
+	}
+	sort.Float64s(res)
+	*p = res
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentileListParsing(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out percentileList
+		ok  bool
+	}{
+		{"50", percentileList{0.5}, true},
+		{"99,50", percentileList{0.5, 0.99}, true},
+		{"50, 99, 99.9", percentileList{0.5, 0.99, 0.999}, true},
+		{"99,99", percentileList{0.99}, true},
+		{"100", percentileList{1}, true},
+		{"", nil, false},
+		{"0", nil, false},
+		{"101", nil, false},
+		{"50,", nil, false},
+		{"p99", nil, false},
+	}
+	for _, e := range expectations {
+		var act percentileList
+		err := act.Set(e.in)
+		if (err == nil) != e.ok {
+			t.Errorf("For %q, expected ok = %v, but got %v", e.in, e.ok, err)
+			continue
+		}
+		if e.ok && !percentilesAlmostEqual(act, e.out) {
+			t.Errorf("For %q, expected %v, but got %v", e.in, e.out, act)
+		}
+	}
+}
+
+func percentilesAlmostEqual(a, b percentileList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPercentileListConversionToString(t *testing.T) {
+	p := percentileList{0.5, 0.99, 0.999}
+	if act, exp := p.String(), "50,99,99.9"; act != exp {
+		t.Errorf("Expected %q, but got %q", exp, act)
+	}
+}
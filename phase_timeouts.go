@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// phaseDeadlines enforces --write-timeout and --read-timeout on
+// requests of net/http clients, which only have the overall timeout.
+// The request is canceled if it wasn't written within write or its
+// response wasn't read within read after that.
+type phaseDeadlines struct {
+	read   time.Duration
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	phase   int
+	expired error
+	stopped bool
+}
+
+func newPhaseDeadlines(
+	write, read time.Duration, cancel context.CancelFunc,
+) *phaseDeadlines {
+	d := &phaseDeadlines{read: read, cancel: cancel}
+	d.mu.Lock()
+	d.arm(write, errWriteTimeout)
+	d.mu.Unlock()
+	return d
+}
+
+// arm starts the next phase, which expires with err after timeout,
+// never if it's zero.
+func (d *phaseDeadlines) arm(timeout time.Duration, err error) {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.phase++
+	if timeout <= 0 {
+		d.timer = nil
+		return
+	}
+	phase := d.phase
+	d.timer = time.AfterFunc(timeout, func() { d.expire(phase, err) })
+}
+
+func (d *phaseDeadlines) expire(phase int, err error) {
+	d.mu.Lock()
+	// the timer of the previous phase may fire after it's stopped
+	if d.stopped || d.expired != nil || phase != d.phase {
+		d.mu.Unlock()
+		return
+	}
+	d.expired = err
+	d.mu.Unlock()
+	d.cancel()
+}
+
+// wrote is called once the request was written.
+func (d *phaseDeadlines) wrote() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped || d.expired != nil {
+		return
+	}
+	d.arm(d.read, errReadTimeout)
+}
+
+// stop is called once the response was read, it returns the error of
+// the phase that expired, if any.
+func (d *phaseDeadlines) stop() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	return d.expired
+}
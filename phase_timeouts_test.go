@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBombardierReadTimeout(t *testing.T) {
+	testAllClients(t, testBombardierReadTimeout)
+}
+
+func testBombardierReadTimeout(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(300 * time.Millisecond)
+			} else {
+				time.Sleep(100 * time.Millisecond)
+			}
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		path        string
+		timeout     time.Duration
+		readTimeout time.Duration
+		req2xx      uint64
+	}{
+		// --read-timeout overrides --timeout either way
+		{"/", 20 * time.Millisecond, time.Second, 2},
+		{"/slow", time.Second, 50 * time.Millisecond, 0},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(2)
+		b, err := newBombardier(config{
+			numConns:    1,
+			numReqs:     &numReqs,
+			url:         s.URL + e.path,
+			headers:     new(headersList),
+			timeout:     e.timeout,
+			readTimeout: e.readTimeout,
+			method:      "GET",
+			clientType:  clientType,
+			format:      knownFormat("plain-text"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		b.bombard()
+		if b.req2xx != e.req2xx {
+			t.Errorf("%v: expected %v 2xx, but got %v (errors: %v)",
+				e.path, e.req2xx, b.req2xx, b.errors.byFrequency())
+		}
+		if e.req2xx > 0 || clientType == fhttp {
+			continue
+		}
+		for _, err := range b.errors.byFrequency() {
+			if err.error != errReadTimeout.Error() {
+				t.Errorf("Expected %q, but got %q", errReadTimeout, err.error)
+			}
+		}
+	}
+}
+
+func TestBombardierWriteTimeout(t *testing.T) {
+	testAllClients(t, testBombardierWriteTimeout)
+}
+
+func testBombardierWriteTimeout(clientType clientTyp, t *testing.T) {
+	// accepts connections, but never reads from them
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+		}()
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+	numReqs := uint64(1)
+	body := strings.Repeat("x", 64<<20)
+	start := time.Now()
+	b, err := newBombardier(config{
+		numConns:     1,
+		numReqs:      &numReqs,
+		url:          "http://" + l.Addr().String(),
+		headers:      new(headersList),
+		timeout:      10 * time.Second,
+		writeTimeout: 100 * time.Millisecond,
+		method:       "POST",
+		body:         body,
+		clientType:   clientType,
+		format:       knownFormat("plain-text"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	if taken := time.Since(start); taken > 5*time.Second {
+		t.Errorf("Expected the request to time out early, but it took %v",
+			taken)
+	}
+	errs := b.errors.byFrequency()
+	if len(errs) != 1 {
+		t.Fatalf("Expected a single error, but got %v", errs)
+	}
+	if clientType != fhttp && errs[0].error != errWriteTimeout.Error() {
+		t.Errorf("Expected %q, but got %q", errWriteTimeout, errs[0].error)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// pipelineStats samples the depth of pipelines, the number of
+// requests in flight per open connection, every pipelineSampleInterval
+// for --print-pipeline-stats. Requests queued by the pipeline client
+// but not written yet count as in flight.
+type pipelineStats struct {
+	open int64
+
+	mu sync.Mutex
+	// pending has a function returning the number of requests in
+	// flight for each of the clients, with --hosts there's one per host
+	pending []func() int
+	// depths holds the number of samples of each depth, depths above
+	// --pipeline are counted as --pipeline
+	depths []uint64
+}
+
+func newPipelineStats(depth uint64) *pipelineStats {
+	return &pipelineStats{depths: make([]uint64, depth+1)}
+}
+
+// track adds pending requests of a client to the samples.
+func (p *pipelineStats) track(pending func() int) {
+	p.mu.Lock()
+	p.pending = append(p.pending, pending)
+	p.mu.Unlock()
+}
+
+// dial wraps dial, keeping track of connections still open.
+func (p *pipelineStats) dial(
+	dial func(string) (net.Conn, error),
+) func(string) (net.Conn, error) {
+	return func(address string) (net.Conn, error) {
+		conn, err := dial(address)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&p.open, 1)
+		return &pipelinedConn{Conn: conn, open: &p.open}, nil
+	}
+}
+
+type pipelinedConn struct {
+	net.Conn
+	open *int64
+	once sync.Once
+}
+
+func (c *pipelinedConn) Close() error {
+	c.once.Do(func() { atomic.AddInt64(c.open, -1) })
+	return c.Conn.Close()
+}
+
+// sample records the current depth, rounded to the nearest integer,
+// unless there are no open connections.
+func (p *pipelineStats) sample() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	open := atomic.LoadInt64(&p.open)
+	if open <= 0 {
+		return
+	}
+	pending := int64(0)
+	for _, f := range p.pending {
+		pending += int64(f())
+	}
+	depth := (pending + open/2) / open
+	if last := int64(len(p.depths) - 1); depth > last {
+		depth = last
+	}
+	p.depths[depth]++
+}
+
+// results returns the share of samples of each depth that was
+// sampled at least once, requests and connections are those completed
+// and opened during the test.
+func (p *pipelineStats) results(
+	requests, connections uint64,
+) *internal.PipelineResult {
+	res := &internal.PipelineResult{
+		Requests:    requests,
+		Connections: connections,
+	}
+	if connections > 0 {
+		res.RequestsPerConnection = float64(requests) / float64(connections)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total := uint64(0)
+	for _, n := range p.depths {
+		total += n
+	}
+	for depth, n := range p.depths {
+		if n == 0 {
+			continue
+		}
+		res.Depths = append(res.Depths, internal.PipelineDepth{
+			Depth: uint64(depth),
+			Share: 100 * float64(n) / float64(total),
+		})
+	}
+	return res
+}
+
+// samplePipelineDepth samples --print-pipeline-stats until the test is
+// done.
+func (b *bombardier) samplePipelineDepth() {
+	ticker := time.NewTicker(pipelineSampleInterval)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	for {
+		select {
+		case <-ticker.C:
+			b.pipelineStats.sample()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+func TestPipelineStatsResults(t *testing.T) {
+	p := newPipelineStats(4)
+	pending := 0
+	p.track(func() int { return pending })
+	p.sample()
+	dial := p.dial(func(string) (net.Conn, error) {
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	})
+	var conns []net.Conn
+	for i := 0; i < 2; i++ {
+		c, err := dial("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+	for _, n := range []int{3, 4, 4, 20} {
+		pending = n
+		p.sample()
+	}
+	_ = conns[0].Close()
+	_ = conns[0].Close()
+	pending = 1
+	p.sample()
+	expected := &internal.PipelineResult{
+		Requests:              30,
+		Connections:           2,
+		RequestsPerConnection: 15,
+		Depths: []internal.PipelineDepth{
+			{Depth: 1, Share: 20},
+			{Depth: 2, Share: 60},
+			{Depth: 4, Share: 20},
+		},
+	}
+	if got := p.results(30, 2); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, got)
+	}
+}
+
+func TestBombardierPipelineStats(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(200)
+	b, e := newBombardier(config{
+		numConns:           2,
+		numReqs:            &numReqs,
+		url:                s.URL,
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		pipeline:           4,
+		printPipelineStats: true,
+		clientType:         fhttp,
+		format:             knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.Pipeline
+	if res == nil {
+		t.Fatal("Expected pipeline stats")
+	}
+	if res.Requests != numReqs || res.Connections == 0 ||
+		res.Connections > 2 {
+		t.Errorf("Expected %v requests over at most 2 connections, "+
+			"but got %+v", numReqs, res)
+	}
+	for _, d := range res.Depths {
+		if d.Depth > 4 {
+			t.Errorf("Expected depths up to 4, but got %+v", res.Depths)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+)
+
+const clearLine = "\r\033[K"
+
+// queueError schedules a newly seen error to be printed with
+// --print-errors-only. Errors are printed by barUpdater, so that they
+// don't interleave with the progress bar.
+func (b *bombardier) queueError(err error) {
+	b.pendingErrorsMu.Lock()
+	b.pendingErrors = append(b.pendingErrors, err.Error())
+	b.pendingErrorsMu.Unlock()
+}
+
+func (b *bombardier) flushErrors() {
+	b.pendingErrorsMu.Lock()
+	pending := b.pendingErrors
+	b.pendingErrors = nil
+	b.pendingErrorsMu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	if !b.bar.NotPrint {
+		fmt.Fprint(b.out, clearLine)
+	}
+	for _, e := range pending {
+		fmt.Fprintf(b.errOut, "Error: %v\n", e)
+	}
+}
+
+// printErrorCounts prints the summary of errors, it is printed instead
+// of the results with --print-errors-only.
+func (b *bombardier) printErrorCounts() {
+	errs := b.errors.byFrequency()
+	if len(errs) == 0 {
+		fmt.Fprintln(b.errOut, "No errors")
+		return
+	}
+	fmt.Fprintln(b.errOut, "Errors:")
+	for _, e := range errs {
+		fmt.Fprintf(b.errOut, "  %10v - %v\n", e.count, e.error)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const cacheBustParam = "_cb"
+
+type queryParam struct {
+	key, value string
+}
+
+type queryList []queryParam
+
+func (q *queryList) String() string {
+	return fmt.Sprint(*q)
+}
+
+func (q *queryList) IsCumulative() bool {
+	return true
+}
+
+func (q *queryList) Set(value string) error {
+	res := strings.SplitN(value, "=", 2)
+	if len(res) != 2 || res[0] == "" {
+		return errInvalidQueryFormat
+	}
+	*q = append(*q, queryParam{res[0], res[1]})
+	return nil
+}
+
+// splitQueryTemplates separates params, whose values use Go's
+// text/template syntax, from static ones.
+func splitQueryTemplates(params *queryList) (static, templated *queryList) {
+	if params == nil {
+		return nil, nil
+	}
+	static = new(queryList)
+	for _, p := range *params {
+		if !strings.Contains(p.value, "{{") {
+			*static = append(*static, p)
+			continue
+		}
+		if templated == nil {
+			templated = new(queryList)
+		}
+		*templated = append(*templated, p)
+	}
+	return static, templated
+}
+
+// queryTemplateData is what --query templates are executed with.
+type queryTemplateData struct {
+	// Seq is the number of the request, starting at 1
+	Seq uint64
+}
+
+var queryTemplateFuncs = template.FuncMap{
+	"UUIDV4": uuid.NewV4,
+}
+
+type queryTemplateError struct {
+	key string
+	err error
+}
+
+func (q *queryTemplateError) Error() string {
+	return fmt.Sprintf("Invalid template of query parameter %v: %v",
+		q.key, q.err)
+}
+
+type queryTemplate struct {
+	key   string
+	value *template.Template
+}
+
+// queryTemplates render values of --query parameters anew for each
+// request.
+type queryTemplates struct {
+	params []queryTemplate
+	seq    uint64
+}
+
+func newQueryTemplates(params *queryList) (*queryTemplates, error) {
+	q := new(queryTemplates)
+	for _, p := range *params {
+		t, err := template.New(p.key).
+			Funcs(queryTemplateFuncs).
+			Parse(p.value)
+		if err == nil {
+			// references to unknown fields only fail on execution
+			err = t.Execute(ioutil.Discard, queryTemplateData{})
+		}
+		if err != nil {
+			return nil, &queryTemplateError{p.key, err}
+		}
+		q.params = append(q.params, queryTemplate{p.key, t})
+	}
+	return q, nil
+}
+
+// next returns the query parameters for the next request.
+func (q *queryTemplates) next() string {
+	data := queryTemplateData{Seq: atomic.AddUint64(&q.seq, 1)}
+	var query string
+	var value strings.Builder
+	for _, p := range q.params {
+		value.Reset()
+		// templates were executed once in newQueryTemplates, so they
+		// don't fail
+		_ = p.value.Execute(&value, data)
+		query = appendQuery(
+			query, url.QueryEscape(p.key)+"="+url.QueryEscape(value.String()),
+		)
+	}
+	return query
+}
+
+// perRequestQuery returns query parameters that differ between
+// requests, if any: rendered --query templates and the --cache-bust
+// one.
+func perRequestQuery(q *queryTemplates, cb *cacheBuster) string {
+	var query string
+	if q != nil {
+		query = q.next()
+	}
+	if cb != nil {
+		query = appendQuery(query, cb.next())
+	}
+	return query
+}
+
+// withQueryParams appends params to the query of rawURL, leaving
+// existing parameters intact.
+func withQueryParams(rawURL string, params *queryList) (string, error) {
+	if params == nil || len(*params) == 0 {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.RawQuery
+	for _, p := range *params {
+		query = appendQuery(
+			query, url.QueryEscape(p.key)+"="+url.QueryEscape(p.value),
+		)
+	}
+	u.RawQuery = query
+	return u.String(), nil
+}
+
+// withRawQueryParams appends params to the query of rawPath, which
+// is sent as is with --raw-path.
+func withRawQueryParams(rawPath string, params *queryList) string {
+	if params == nil {
+		return rawPath
+	}
+	for _, p := range *params {
+		sep := "&"
+		if !strings.Contains(rawPath, "?") {
+			sep = "?"
+		} else if strings.HasSuffix(rawPath, "?") {
+			sep = ""
+		}
+		rawPath += sep + url.QueryEscape(p.key) + "=" +
+			url.QueryEscape(p.value)
+	}
+	return rawPath
+}
+
+func appendQuery(query, param string) string {
+	if query == "" {
+		return param
+	}
+	return query + "&" + param
+}
+
+// cacheBuster produces a unique query parameter for each request.
+type cacheBuster struct {
+	seq uint64
+}
+
+func (cb *cacheBuster) next() string {
+	seq := atomic.AddUint64(&cb.seq, 1)
+	return cacheBustParam + "=" + strconv.FormatUint(seq, decBase)
+}
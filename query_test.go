@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestQueryListParsing(t *testing.T) {
+	q := new(queryList)
+	for _, invalid := range []string{"", "novalue", "=value"} {
+		if err := q.Set(invalid); err != errInvalidQueryFormat {
+			t.Errorf("Expected %v for %q, but got %v",
+				errInvalidQueryFormat, invalid, err)
+		}
+	}
+	if err := q.Set("key=a=b"); err != nil {
+		t.Error(err)
+	}
+	if err := q.Set("empty="); err != nil {
+		t.Error(err)
+	}
+	expected := queryList{{"key", "a=b"}, {"empty", ""}}
+	if len(*q) != len(expected) {
+		t.Fatalf("Expected %v, but got %v", expected, *q)
+	}
+	for i := range expected {
+		if (*q)[i] != expected[i] {
+			t.Errorf("Expected %v, but got %v", expected[i], (*q)[i])
+		}
+	}
+}
+
+func TestWithQueryParams(t *testing.T) {
+	expectations := []struct {
+		in     string
+		params *queryList
+		out    string
+	}{
+		{"http://localhost", nil, "http://localhost"},
+		{"http://localhost", &queryList{}, "http://localhost"},
+		{
+			"http://localhost/path",
+			&queryList{{"a", "1"}, {"b", "x y"}},
+			"http://localhost/path?a=1&b=x+y",
+		},
+		{
+			"http://localhost/?z=0",
+			&queryList{{"a", "&"}},
+			"http://localhost/?z=0&a=%26",
+		},
+	}
+	for _, e := range expectations {
+		out, err := withQueryParams(e.in, e.params)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if out != e.out {
+			t.Errorf("Expected %q, but got %q", e.out, out)
+		}
+	}
+}
+
+func TestCacheBuster(t *testing.T) {
+	cb := new(cacheBuster)
+	if p := cb.next(); p != "_cb=1" {
+		t.Errorf("Expected _cb=1, but got %v", p)
+	}
+	if p := cb.next(); p != "_cb=2" {
+		t.Errorf("Expected _cb=2, but got %v", p)
+	}
+}
+
+func TestSplitQueryTemplates(t *testing.T) {
+	static, templated := splitQueryTemplates(&queryList{
+		{"a", "1"}, {"id", "{{ .Seq }}"}, {"b", "{x}"},
+	})
+	if len(*static) != 2 || (*static)[0].key != "a" ||
+		(*static)[1].key != "b" {
+		t.Errorf("Unexpected static parameters: %v", *static)
+	}
+	if templated == nil || len(*templated) != 1 ||
+		(*templated)[0].key != "id" {
+		t.Errorf("Unexpected templated parameters: %v", templated)
+	}
+	_, templated = splitQueryTemplates(&queryList{{"a", "1"}})
+	if templated != nil {
+		t.Errorf("Expected no templated parameters, but got %v", *templated)
+	}
+}
+
+func TestQueryTemplates(t *testing.T) {
+	q, err := newQueryTemplates(&queryList{
+		{"n", "{{ .Seq }}"}, {"id", "{{ UUIDV4 }}"}, {"s", "{{ .Seq }}&x"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seq := 1; seq <= 2; seq++ {
+		params := strings.Split(q.next(), "&")
+		if len(params) != 3 {
+			t.Fatalf("Expected 3 parameters, but got %v", params)
+		}
+		if expected := "n=" + fmt.Sprint(seq); params[0] != expected {
+			t.Errorf("Expected %v, but got %v", expected, params[0])
+		}
+		id := strings.TrimPrefix(params[1], "id=")
+		if _, err := uuid.FromString(id); err != nil {
+			t.Errorf("Expected a UUID, but got %v: %v", params[1], err)
+		}
+		if expected := "s=" + fmt.Sprint(seq) + "%26x"; params[2] != expected {
+			t.Errorf("Expected %v, but got %v", expected, params[2])
+		}
+	}
+}
+
+func TestInvalidQueryTemplates(t *testing.T) {
+	for _, invalid := range []string{
+		"{{ .Seq", "{{ .NoSuchField }}", "{{ NoSuchFunc }}",
+	} {
+		_, err := newQueryTemplates(&queryList{{"id", invalid}})
+		if qerr, ok := err.(*queryTemplateError); !ok || qerr.key != "id" {
+			t.Errorf("Expected template error for %q, but got %v",
+				invalid, err)
+		}
+	}
+}
+
+func TestPerRequestQuery(t *testing.T) {
+	if q := perRequestQuery(nil, nil); q != "" {
+		t.Errorf("Expected no parameters, but got %v", q)
+	}
+	qt, err := newQueryTemplates(&queryList{{"n", "{{ .Seq }}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := perRequestQuery(qt, new(cacheBuster)); q != "n=1&_cb=1" {
+		t.Errorf("Expected n=1&_cb=1, but got %v", q)
+	}
+}
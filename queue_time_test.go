@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBombardierQueueTime(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	rate := uint64(50)
+	b, e := newBombardier(config{
+		numConns:       1,
+		numReqs:        &numReqs,
+		rate:           &rate,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		format:         knownFormat("plain-text"),
+		printResult:    true,
+		printQueueTime: true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result
+	stats := res.QueueTimesStats([]float64{0.5})
+	if stats == nil {
+		t.Fatal("Expected queue time to be reported")
+	}
+	// the single connection waits for ~20ms before most of requests
+	if p50 := stats.Percentiles[0.5]; p50 < uint64(5*time.Millisecond/
+		time.Microsecond) {
+		t.Errorf("Expected requests to wait for the limiter, p50 is %vus",
+			p50)
+	}
+	if sum := res.LatenciesStats(nil); sum.Mean >= stats.Mean {
+		t.Errorf("Expected queue time to be left out of latency: "+
+			"%v >= %v", sum.Mean, stats.Mean)
+	}
+	out := new(bytes.Buffer)
+	b.out = out
+	b.printStats()
+	if !strings.Contains(out.String(), "  Queue time ") {
+		t.Errorf("Expected queue time in results:\n%s", out)
+	}
+}
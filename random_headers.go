@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// randomHeaderNames are names of headers specified with
+// --random-header, which is repeatable.
+type randomHeaderNames []string
+
+func (r *randomHeaderNames) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *randomHeaderNames) IsCumulative() bool {
+	return true
+}
+
+func (r *randomHeaderNames) Set(value string) error {
+	if value == "" || strings.ContainsAny(value, ": \t\r\n") {
+		return errInvalidRandomHeader
+	}
+	*r = append(*r, value)
+	return nil
+}
+
+const randomHeaderChars = "0123456789" +
+	"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// randomHeaders provides values of --random-header headers, each
+// request gets its own. Values are drawn from a source seeded with
+// randomHeaderSeed, so that runs send the same sequence of them.
+type randomHeaders struct {
+	names []string
+	size  int
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newRandomHeaders(names []string, size int) *randomHeaders {
+	return &randomHeaders{
+		names: names,
+		size:  size,
+		rnd:   rand.New(rand.NewSource(randomHeaderSeed)),
+	}
+}
+
+// set calls set with each of the headers and a new value for it.
+func (r *randomHeaders) set(set func(name, value string)) {
+	value := make([]byte, r.size)
+	for _, name := range r.names {
+		r.mu.Lock()
+		for i := range value {
+			value[i] = randomHeaderChars[r.rnd.Intn(len(randomHeaderChars))]
+		}
+		r.mu.Unlock()
+		set(name, string(value))
+	}
+}
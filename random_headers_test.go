@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRandomHeaderNamesSet(t *testing.T) {
+	var names randomHeaderNames
+	for _, name := range []string{"X-Cache-Key", "x-fuzz"} {
+		if err := names.Set(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := names.String(); s != "X-Cache-Key,x-fuzz" {
+		t.Errorf("Expected \"X-Cache-Key,x-fuzz\", but got %q", s)
+	}
+	for _, name := range []string{"", "X-Key: 1", "X Key"} {
+		if err := names.Set(name); err != errInvalidRandomHeader {
+			t.Errorf("Expected %v for %q, but got %v",
+				errInvalidRandomHeader, name, err)
+		}
+	}
+}
+
+func TestRandomHeadersValues(t *testing.T) {
+	values := func() []string {
+		r := newRandomHeaders([]string{"A", "B"}, 12)
+		var res []string
+		for i := 0; i < 5; i++ {
+			r.set(func(name, value string) {
+				res = append(res, value)
+			})
+		}
+		return res
+	}
+	first, second := values(), values()
+	seen := make(map[string]bool)
+	for i, v := range first {
+		if len(v) != 12 || strings.Trim(v, randomHeaderChars) != "" {
+			t.Errorf("Expected 12 alphanumeric characters, but got %q", v)
+		}
+		if seen[v] {
+			t.Errorf("Expected unique values, but got %q twice", v)
+		}
+		seen[v] = true
+		if second[i] != v {
+			t.Errorf("Expected the same sequence of values, "+
+				"but got %v and %v", first, second)
+			break
+		}
+	}
+}
+
+func TestBombardierRandomHeaders(t *testing.T) {
+	testAllClients(t, testBombardierRandomHeaders)
+}
+
+func testBombardierRandomHeaders(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			v := r.Header.Get("X-Random")
+			if len(v) != 8 || r.Header.Get("X-Fixed") != "1" {
+				t.Errorf("Unexpected headers: %v", r.Header)
+			}
+			mu.Lock()
+			seen[v] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:          2,
+		numReqs:           &numReqs,
+		url:               s.URL,
+		headers:           &headersList{{"X-Fixed", "1"}},
+		timeout:           defaultTimeout,
+		method:            "GET",
+		randomHeaders:     &randomHeaderNames{"X-Random"},
+		randomHeaderBytes: 8,
+		clientType:        clientType,
+		format:            knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Fatalf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	if len(seen) != int(numReqs) {
+		t.Errorf("Expected %v distinct values, but got %v", numReqs, seen)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateSchedulePoint is a line of the file passed with --rate-schedule.
+type rateSchedulePoint struct {
+	offset time.Duration
+	rate   uint64
+}
+
+// rateSchedule is a curve of the target rate over the test, points
+// are ordered by offset. The rate is interpolated linearly between
+// them and held before the first and after the last one.
+type rateSchedule []rateSchedulePoint
+
+type rateScheduleError struct {
+	line int
+	err  error
+}
+
+func (e *rateScheduleError) Error() string {
+	return fmt.Sprintf("Rate schedule line %v: %v", e.line, e.err)
+}
+
+func loadRateSchedule(path string) (rateSchedule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRateSchedule(f)
+}
+
+// parseRateSchedule reads lines of "offset_seconds rate", empty lines
+// and ones starting with # are skipped. Offsets must increase.
+func parseRateSchedule(r io.Reader) (rateSchedule, error) {
+	var s rateSchedule
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p, err := parseRateSchedulePoint(text)
+		if err == nil && len(s) > 0 && p.offset <= s[len(s)-1].offset {
+			err = fmt.Errorf("offset %v isn't after the previous one (%v)",
+				p.offset, s[len(s)-1].offset)
+		}
+		if err != nil {
+			return nil, &rateScheduleError{line, err}
+		}
+		s = append(s, p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(s) == 0 {
+		return nil, errRateScheduleEmpty
+	}
+	return s, nil
+}
+
+func parseRateSchedulePoint(text string) (rateSchedulePoint, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return rateSchedulePoint{}, fmt.Errorf(
+			"expected \"offset_seconds rate\", but got %q", text)
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || secs < 0 || secs > maxRateScheduleOffset {
+		return rateSchedulePoint{}, fmt.Errorf("invalid offset %q",
+			fields[0])
+	}
+	rate, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || rate < 1 {
+		return rateSchedulePoint{}, fmt.Errorf("invalid rate %q", fields[1])
+	}
+	return rateSchedulePoint{
+		offset: time.Duration(secs * float64(time.Second)),
+		rate:   rate,
+	}, nil
+}
+
+// rateAt returns the target rate at elapsed since the start of the
+// test.
+func (s rateSchedule) rateAt(elapsed time.Duration) uint64 {
+	if elapsed <= s[0].offset {
+		return s[0].rate
+	}
+	for i := 1; i < len(s); i++ {
+		if elapsed >= s[i].offset {
+			continue
+		}
+		prev, next := s[i-1], s[i]
+		frac := float64(elapsed-prev.offset) /
+			float64(next.offset-prev.offset)
+		return uint64(float64(prev.rate) +
+			frac*(float64(next.rate)-float64(prev.rate)) + 0.5)
+	}
+	return s[len(s)-1].rate
+}
+
+func (s rateSchedule) print(out io.Writer) {
+	fmt.Fprintln(out, "Rate schedule:")
+	for _, p := range s {
+		fmt.Fprintf(out, "  %10v %10v reqs/sec\n", p.offset, p.rate)
+	}
+}
+
+// followRateSchedule adjusts the rate according to the schedule every
+// rateScheduleInterval until the test is done.
+func (b *bombardier) followRateSchedule(start time.Time) {
+	ticker := time.NewTicker(rateScheduleInterval)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	current := b.rateSchedule[0].rate
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if rate := b.rateSchedule.rateAt(time.Since(start)); rate != current {
+			b.rateLimiter.setRate(rate)
+			current = rate
+		}
+	}
+}
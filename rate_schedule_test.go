@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRateSchedule(t *testing.T) {
+	expectations := []struct {
+		in       string
+		expected rateSchedule
+		err      string
+	}{
+		{
+			"0 100\n# ramp up\n\n  60 500\r\n90.5\t500\n",
+			rateSchedule{
+				{0, 100},
+				{time.Minute, 500},
+				{90*time.Second + 500*time.Millisecond, 500},
+			},
+			"",
+		},
+		{"", nil, errRateScheduleEmpty.Error()},
+		{"# nothing\n", nil, errRateScheduleEmpty.Error()},
+		{
+			"0 100\n10 200\n10 300\n", nil,
+			"Rate schedule line 3: offset 10s isn't after the " +
+				"previous one (10s)",
+		},
+		{
+			"0 100\n10 200 300\n", nil,
+			"Rate schedule line 2: expected \"offset_seconds rate\", " +
+				"but got \"10 200 300\"",
+		},
+		{"-1 100\n", nil, "Rate schedule line 1: invalid offset \"-1\""},
+		{"1e100 100\n", nil, "Rate schedule line 1: invalid offset \"1e100\""},
+		{"0 0\n", nil, "Rate schedule line 1: invalid rate \"0\""},
+		{"0 1.5\n", nil, "Rate schedule line 1: invalid rate \"1.5\""},
+	}
+	for _, e := range expectations {
+		s, err := parseRateSchedule(strings.NewReader(e.in))
+		if e.err != "" {
+			if err == nil || err.Error() != e.err {
+				t.Errorf("Expected %q for %q, but got %v", e.err, e.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", e.in, err)
+		} else if !reflect.DeepEqual(s, e.expected) {
+			t.Errorf("Expected %v for %q, but got %v", e.expected, e.in, s)
+		}
+	}
+}
+
+func TestRateScheduleRateAt(t *testing.T) {
+	s := rateSchedule{
+		{10 * time.Second, 100},
+		{20 * time.Second, 200},
+		{30 * time.Second, 50},
+	}
+	expectations := []struct {
+		elapsed  time.Duration
+		expected uint64
+	}{
+		{0, 100},
+		{10 * time.Second, 100},
+		{15 * time.Second, 150},
+		{20 * time.Second, 200},
+		{26 * time.Second, 110},
+		{30 * time.Second, 50},
+		{time.Hour, 50},
+	}
+	for _, e := range expectations {
+		if got := s.rateAt(e.elapsed); got != e.expected {
+			t.Errorf("Expected %v at %v, but got %v",
+				e.expected, e.elapsed, got)
+		}
+	}
+}
+
+func TestBombardierRateSchedule(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	f, err := ioutil.TempFile("", "rate-schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("0 10\n0.5 50\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	duration := time.Second
+	b, e := newBombardier(config{
+		numConns:     1,
+		duration:     &duration,
+		url:          s.URL,
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		rateSchedule: f.Name(),
+		printIntro:   true,
+		format:       knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	out := new(strings.Builder)
+	b.out = out
+	b.bombard()
+	b.rateLimiter.mu.Lock()
+	rate := b.rateLimiter.rate
+	b.rateLimiter.mu.Unlock()
+	if rate != 50 {
+		t.Errorf("Expected rate to end up at 50, but got %v", rate)
+	}
+	// 10 to 50 over the first half, 50 after that
+	if b.req2xx < 20 || b.req2xx > 45 {
+		t.Errorf("Expected about 32 requests, but got %v", b.req2xx)
+	}
+	expected := "Rate schedule:\n" +
+		"          0s         10 reqs/sec\n" +
+		"       500ms         50 reqs/sec\n"
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected intro to contain %q, but got %q",
+			expected, out.String())
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// handleRateSignals changes --rate by --rate-step on each of the
+// rateUpSignal and rateDownSignal received from c, until the test is
+// done. The new rate is printed each time.
+func (b *bombardier) handleRateSignals(c <-chan os.Signal) {
+	done := b.barrier.done()
+	step := int64(*b.conf.rateStep)
+	for {
+		var s os.Signal
+		select {
+		case <-done:
+			return
+		case s = <-c:
+		}
+		delta := step
+		if s == rateDownSignal {
+			delta = -step
+		}
+		rate := b.rateLimiter.adjustRate(delta)
+		fmt.Fprintf(b.errOut, "Rate changed to %v reqs/sec\n", rate)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBombardierRateSignals(t *testing.T) {
+	if rateUpSignal == nil {
+		t.Skip("rate signals aren't supported on this platform")
+	}
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	duration := time.Second
+	rate, step := uint64(10), uint64(5)
+	b, e := newBombardier(config{
+		numConns: defaultNumberOfConns,
+		duration: &duration,
+		url:      s.URL,
+		headers:  new(headersList),
+		timeout:  defaultTimeout,
+		method:   "GET",
+		rate:     &rate,
+		rateStep: &step,
+		format:   knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	errOut := new(bytes.Buffer)
+	b.errOut = errOut
+	c := make(chan os.Signal)
+	handled := make(chan struct{})
+	go func() {
+		b.handleRateSignals(c)
+		close(handled)
+	}()
+	c <- rateUpSignal
+	c <- rateUpSignal
+	c <- rateDownSignal
+	b.bombard()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("Signals are still handled after the test")
+	}
+	expected := []string{
+		"Rate changed to 15 reqs/sec",
+		"Rate changed to 20 reqs/sec",
+		"Rate changed to 15 reqs/sec",
+	}
+	lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%v\nGot:\n%v",
+			strings.Join(expected, "\n"), errOut)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var rateUpSignal, rateDownSignal os.Signal = syscall.SIGUSR1, syscall.SIGUSR2
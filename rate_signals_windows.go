@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// There are no SIGUSR1 and SIGUSR2 on Windows, so --rate-step isn't
+// supported there.
+var rateUpSignal, rateDownSignal os.Signal
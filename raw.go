@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// rawClient writes the same request bytes over a new connection for
+// each request and reads only the status line and headers of the
+// response. The connection is TLS if the target URL is https and
+// plain TCP otherwise, the request itself is sent as is.
+type rawClient struct {
+	addr      string
+	tlsConfig *tls.Config
+	request   []byte
+
+	timeout, abortAfter time.Duration
+	adaptive            *adaptiveTimeout
+
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func newRawClient(opts *clientOpts, request []byte) *rawClient {
+	c := new(rawClient)
+	u, err := url.Parse(opts.url)
+	if err != nil {
+		// opts.url guaranteed to be valid at this point
+		panic(err)
+	}
+	c.addr = u.Host
+	if u.Scheme == "https" {
+		c.tlsConfig = opts.tlsConfig.Clone()
+		if c.tlsConfig.ServerName == "" {
+			c.tlsConfig.ServerName = u.Hostname()
+		}
+	}
+	c.request = request
+	c.timeout, c.abortAfter = opts.timeout, opts.abortAfter
+	c.adaptive = opts.adaptiveTimeout
+	c.dial = httpDialContextFunc(
+		opts.dialer, opts.bytesRead, opts.bytesWritten, opts.connsOpened,
+	)
+	return c
+}
+
+func (c *rawClient) do() (
+	code int, usTaken uint64, phases phaseTimings, err error,
+) {
+	start := time.Now()
+	code, err = c.send(start)
+	usTaken = durationUs(time.Since(start))
+	return
+}
+
+func (c *rawClient) doRequest(r *request) (
+	code int, usTaken uint64, body []byte, err error,
+) {
+	// config guarantees that there are no scenarios with raw requests
+	return -1, 0, nil, errRawRequestConflict
+}
+
+func (c *rawClient) send(start time.Time) (int, error) {
+	timeout, aborting := c.timeout, false
+	abortAfter := c.adaptive.limit(c.abortAfter)
+	if abortAfter > 0 && (timeout == 0 || abortAfter < timeout) {
+		timeout, aborting = abortAfter, true
+	}
+	ctx := context.Background()
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = start.Add(timeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	conn, err := c.dial(ctx, "tcp", c.addr)
+	if err != nil {
+		return c.failure(err, aborting)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return c.failure(err, aborting)
+	}
+	if c.tlsConfig != nil {
+		conn = tls.Client(conn, c.tlsConfig)
+	}
+	if _, err := conn.Write(c.request); err != nil {
+		return c.failure(err, aborting)
+	}
+	// the body isn't read, so it doesn't matter what the method was
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return c.failure(err, aborting)
+	}
+	return resp.StatusCode, nil
+}
+
+func (c *rawClient) failure(err error, aborting bool) (int, error) {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() && aborting {
+		return -1, errAborted
+	}
+	return -1, err
+}
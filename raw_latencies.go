@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rawSample is a single request written with --output-raw-latencies,
+// completed is the time since the start of the test it completed at
+// and code is zero if it failed without a response.
+type rawSample struct {
+	completed time.Duration
+	usTaken   uint64
+	code      int
+}
+
+// rawLatencies writes every request's latency to
+// --output-raw-latencies. Samples are sent over a channel to a single
+// goroutine that writes them through a buffer, requests wait for it
+// once rawLatenciesQueue samples are queued. Files with .csv extension
+// are written as CSV with completed_us,latency_us,code rows, others
+// get rawSampleSize bytes long little-endian records of the same
+// fields: uint64, uint64 and uint16.
+type rawLatencies struct {
+	begin   time.Time
+	csv     bool
+	samples chan rawSample
+	done    chan error
+}
+
+const rawSampleSize = 8 + 8 + 2
+
+const rawLatenciesCSVHeader = "completed_us,latency_us,code\n"
+
+func newRawLatencies(path string) (*rawLatencies, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &rawLatencies{
+		begin:   time.Now(),
+		csv:     strings.EqualFold(filepath.Ext(path), ".csv"),
+		samples: make(chan rawSample, rawLatenciesQueue),
+		done:    make(chan error, 1),
+	}
+	go r.write(f)
+	return r, nil
+}
+
+func (r *rawLatencies) record(code int, usTaken uint64) {
+	r.samples <- rawSample{time.Since(r.begin), usTaken, code}
+}
+
+func (r *rawLatencies) write(f *os.File) {
+	out := bufio.NewWriter(f)
+	var err error
+	if r.csv {
+		_, err = out.WriteString(rawLatenciesCSVHeader)
+	}
+	var line []byte
+	var record [rawSampleSize]byte
+	for s := range r.samples {
+		if err != nil {
+			// drain the rest, so that requests don't wait for it
+			continue
+		}
+		completed := uint64(s.completed / time.Microsecond)
+		if r.csv {
+			line = strconv.AppendUint(line[:0], completed, decBase)
+			line = append(line, ',')
+			line = strconv.AppendUint(line, s.usTaken, decBase)
+			line = append(line, ',')
+			line = strconv.AppendInt(line, int64(s.code), decBase)
+			line = append(line, '\n')
+			_, err = out.Write(line)
+		} else {
+			binary.LittleEndian.PutUint64(record[0:], completed)
+			binary.LittleEndian.PutUint64(record[8:], s.usTaken)
+			binary.LittleEndian.PutUint16(record[16:], uint16(s.code))
+			_, err = out.Write(record[:])
+		}
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	r.done <- err
+}
+
+// close writes the samples left and closes the file, it must be called
+// once all requests completed.
+func (r *rawLatencies) close() error {
+	close(r.samples)
+	return <-r.done
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBombardierRawLatencies(t *testing.T) {
+	testAllClients(t, testBombardierRawLatencies)
+}
+
+func testBombardierRawLatencies(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusAccepted)
+		}),
+	)
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "bombardier-raw-latencies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	numReqs := uint64(20)
+	for _, name := range []string{"latencies.csv", "latencies.bin"} {
+		path := filepath.Join(dir, name)
+		b, e := newBombardier(config{
+			numConns:         2,
+			numReqs:          &numReqs,
+			url:              s.URL,
+			headers:          new(headersList),
+			timeout:          defaultTimeout,
+			method:           "GET",
+			clientType:       clientType,
+			format:           knownFormat("plain-text"),
+			rawLatenciesFile: path,
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		if err := b.rawLatencies.close(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var codes []uint64
+		if strings.HasSuffix(name, ".csv") {
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if lines[0]+"\n" != rawLatenciesCSVHeader {
+				t.Errorf("Expected CSV header, but got %q", lines[0])
+			}
+			for _, line := range lines[1:] {
+				fields := strings.Split(line, ",")
+				if len(fields) != 3 {
+					t.Fatalf("Unexpected line %q", line)
+				}
+				code, err := strconv.ParseUint(fields[2], 10, 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+				codes = append(codes, code)
+			}
+		} else {
+			if len(data)%rawSampleSize != 0 {
+				t.Fatalf("Expected records of %v bytes, but got %v bytes",
+					rawSampleSize, len(data))
+			}
+			for i := 0; i < len(data); i += rawSampleSize {
+				codes = append(codes,
+					uint64(binary.LittleEndian.Uint16(data[i+16:])))
+			}
+		}
+		if uint64(len(codes)) != numReqs {
+			t.Errorf("Expected %v samples in %v, but got %v",
+				numReqs, name, len(codes))
+		}
+		for _, code := range codes {
+			if code != http.StatusAccepted {
+				t.Errorf("Expected code %v, but got %v",
+					http.StatusAccepted, code)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// splitRawPath splits raw URL into the part before the path, which is
+// parsed as usual, and the path with query, which is sent exactly as
+// given with --raw-path. The latter isn't required to be a valid URL
+// path, i.e. it may have invalid percent-encodings.
+func splitRawPath(raw string) (base, rawPath string) {
+	start := 0
+	if i := strings.Index(raw, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	i := strings.IndexAny(raw[start:], "/?")
+	if i < 0 {
+		return raw, ""
+	}
+	return raw[:start+i], raw[start+i:]
+}
+
+// validRawPath reports whether path can be sent in the request line,
+// as is.
+func validRawPath(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] <= ' ' || path[i] == 0x7f || path[i] == '#' {
+			return false
+		}
+	}
+	return true
+}
+
+// withRawPath returns a copy of u to be sent with rawPath instead of
+// its path and query by net/http.
+func withRawPath(u *url.URL, rawPath string) *url.URL {
+	res := *u
+	res.Path, res.RawPath = "", ""
+	path, query := rawPath, ""
+	if i := strings.IndexByte(rawPath, '?'); i >= 0 {
+		path, query = rawPath[:i], rawPath[i+1:]
+		res.ForceQuery = query == ""
+	}
+	res.Opaque, res.RawQuery = path, query
+	if strings.HasPrefix(path, "//") {
+		// it would be taken for the authority otherwise, so the
+		// request is sent in absolute form
+		res.Opaque = "//" + u.Host + path
+	}
+	return &res
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSplitRawPath(t *testing.T) {
+	expectations := []struct {
+		in, base, rawPath string
+	}{
+		{"http://localhost:8080/a%2Fb/%zz?x=%2F", "http://localhost:8080",
+			"/a%2Fb/%zz?x=%2F"},
+		{"localhost/%41", "localhost", "/%41"},
+		{"https://host?q", "https://host", "?q"},
+		{"https://host", "https://host", ""},
+	}
+	for _, e := range expectations {
+		base, rawPath := splitRawPath(e.in)
+		if base != e.base || rawPath != e.rawPath {
+			t.Errorf("Expected %q and %q for %q, but got %q and %q",
+				e.base, e.rawPath, e.in, base, rawPath)
+		}
+	}
+}
+
+func TestBombardierRawPath(t *testing.T) {
+	testAllClients(t, testBombardierRawPath)
+}
+
+func testBombardierRawPath(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		uris []string
+	)
+	// unlike net/http, fasthttp server accepts invalid percent-encodings
+	server := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			mu.Lock()
+			uris = append(uris, string(ctx.RequestURI()))
+			mu.Unlock()
+		},
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	defer ln.Close()
+	for _, rawPath := range []string{
+		"/bucket/a%2Fb/%zz%2f?x=%2F&y",
+		"/%41%7E?",
+		"//double//slash",
+	} {
+		uris = nil
+		numReqs := uint64(2)
+		b, e := newBombardier(config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        "http://" + ln.Addr().String(),
+			rawPath:    rawPath,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			clientType: clientType,
+			format:     knownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		if b.req2xx != numReqs {
+			t.Errorf("%v: expected %v 2xx, but got %v (errors: %v)",
+				rawPath, numReqs, b.req2xx, b.errors.byFrequency())
+		}
+		expected := rawPath
+		if clientType != fhttp && rawPath[1] == '/' {
+			// net/http sends it in absolute form
+			expected = "http://" + ln.Addr().String() + rawPath
+		}
+		mu.Lock()
+		for _, uri := range uris {
+			if uri != expected {
+				t.Errorf("Expected %q, but got %q", expected, uri)
+			}
+		}
+		mu.Unlock()
+	}
+}
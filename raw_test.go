@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func writeRawRequest(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestBombardierRawRequest(t *testing.T) {
+	received := uint64(0)
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "PATCH" || r.URL.Path != "/raw" ||
+			r.Header.Get("x-odd") != "1" || string(body) != "abc" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		atomic.AddUint64(&received, 1)
+		rw.WriteHeader(http.StatusAccepted)
+	})
+	path := writeRawRequest(t, "PATCH /raw HTTP/1.1\r\n"+
+		"Host: localhost\r\nx-odd: 1\r\nContent-Length: 3\r\n\r\nabc")
+	defer os.Remove(path)
+	servers := map[string]*httptest.Server{
+		"plain": httptest.NewServer(handler),
+		"tls":   httptest.NewTLSServer(handler),
+	}
+	for name, s := range servers {
+		atomic.StoreUint64(&received, 0)
+		numReqs := uint64(10)
+		b, e := newBombardier(config{
+			numConns:       defaultNumberOfConns,
+			numReqs:        &numReqs,
+			url:            s.URL,
+			headers:        new(headersList),
+			timeout:        defaultTimeout,
+			method:         "GET",
+			rawRequestFile: path,
+			insecure:       true,
+			format:         knownFormat("plain-text"),
+		})
+		if e != nil {
+			t.Error(e)
+			s.Close()
+			continue
+		}
+		b.disableOutput()
+		b.bombard()
+		s.Close()
+		if received != numReqs || b.req2xx != numReqs {
+			t.Errorf("%v: expected %v requests, but got %v (%v 2xx, %v)",
+				name, numReqs, received, b.req2xx, b.errors.byFrequency())
+		}
+		if b.latencies.Count() == 0 {
+			t.Errorf("%v: latencies should be recorded", name)
+		}
+	}
+}
+
+func TestBombardierRawRequestMalformed(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	path := writeRawRequest(t, "GET / HTTP/1.1\r\nno colon here\r\n\r\n")
+	defer os.Remove(path)
+	numReqs := uint64(3)
+	b, e := newBombardier(config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		rawRequestFile: path,
+		format:         knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req4xx != numReqs {
+		t.Errorf("Expected %v 4xx, but got %v (errors: %v)",
+			numReqs, b.req4xx, b.errors.byFrequency())
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// waitReady probes the target with GET requests until it responds with
+// one of --ready-status codes (2xx by default) or --wait-ready elapses.
+// It returns how long it waited. It's called before the bombardier is
+// created, since timed tests start counting at that point.
+func waitReady(c config) (time.Duration, error) {
+	cl, err := newProbeClient(c)
+	if err != nil {
+		return 0, err
+	}
+	ready := c.readyStatusOrDefault()
+	start := time.Now()
+	deadline := start.Add(c.waitReady)
+	for {
+		if probe(cl, c, &ready) {
+			return time.Since(start), nil
+		}
+		if time.Now().Add(readyProbeInterval).After(deadline) {
+			return time.Since(start), errNotReady
+		}
+		time.Sleep(readyProbeInterval)
+	}
+}
+
+// newProbeClient returns the client probes are sent with, it doesn't
+// follow redirects.
+func newProbeClient(c config) (*http.Client, error) {
+	tlsConfig, err := generateTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   c.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
+
+// readyStatusOrDefault returns --ready-status codes, 2xx if not set.
+func (c *config) readyStatusOrDefault() statusRanges {
+	if c.readyStatus == nil {
+		return statusRanges{{200, 299}}
+	}
+	return *c.readyStatus
+}
+
+func probe(cl *http.Client, c config, ready *statusRanges) bool {
+	code, err := probeStatus(context.Background(), cl, c)
+	return err == nil && ready.contains(code)
+}
+
+// probeStatus sends a GET request to the target and returns the code
+// it responded with.
+func probeStatus(
+	ctx context.Context, cl *http.Client, c config,
+) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.headers != nil {
+		for _, h := range *c.headers {
+			if strings.EqualFold(h.key, "Host") {
+				req.Host = h.value
+			} else {
+				req.Header.Set(h.key, h.value)
+			}
+		}
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitReady(t *testing.T) {
+	probes := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Probe") != "1" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if atomic.AddUint64(&probes, 1) < 3 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(1)
+	c := config{
+		numConns:  1,
+		numReqs:   &numReqs,
+		url:       s.URL,
+		headers:   &headersList{{"X-Probe", "1"}},
+		timeout:   defaultTimeout,
+		method:    "GET",
+		format:    knownFormat("plain-text"),
+		waitReady: 5 * time.Second,
+	}
+	if err := c.checkArgs(); err != nil {
+		t.Fatal(err)
+	}
+	waited, err := waitReady(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probes != 3 {
+		t.Errorf("Expected 3 probes, but got %v", probes)
+	}
+	if waited < 2*readyProbeInterval {
+		t.Errorf("Expected to wait at least %v, but waited %v",
+			2*readyProbeInterval, waited)
+	}
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(1)
+	readyStatus := statusRanges{{204, 204}}
+	wait := 3 * readyProbeInterval
+	c := config{
+		numConns:    1,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		format:      knownFormat("plain-text"),
+		waitReady:   wait,
+		readyStatus: &readyStatus,
+	}
+	if err := c.checkArgs(); err != nil {
+		t.Fatal(err)
+	}
+	waited, err := waitReady(c)
+	if err != errNotReady {
+		t.Errorf("Expected %v, but got %v", errNotReady, err)
+	}
+	if waited > wait+readyProbeInterval {
+		t.Errorf("Expected to give up after %v, but waited %v",
+			wait, waited)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// recoveryStep accumulates --recovery-probe probes sent during one
+// recoveryProbeStep, latencies are in microseconds and only cover
+// probes that succeeded.
+type recoveryStep struct {
+	probes, failed uint64
+	usSum, usMax   uint64
+}
+
+func (s *recoveryStep) meanUs() float64 {
+	succeeded := s.probes - s.failed
+	if succeeded == 0 {
+		return 0
+	}
+	return float64(s.usSum) / float64(succeeded)
+}
+
+// probeRecovery probes the target every recoveryProbeInterval for
+// --recovery-probe once the test is over and prints latencies of each
+// recoveryProbeStep to out. With --compare-baseline it also tells when
+// the target recovered, i.e. mean latency of successful probes within
+// a step was first back within the mean of the baseline with none of
+// them failing. Probing stops early if the test was interrupted.
+func (b *bombardier) probeRecovery(out io.Writer) error {
+	cl, err := newProbeClient(b.conf)
+	if err != nil {
+		return err
+	}
+	ready := b.conf.readyStatusOrDefault()
+	latencies := uhist.Default()
+	steps := make([]recoveryStep,
+		(b.conf.recoveryProbe+recoveryProbeStep-1)/recoveryProbeStep)
+	ctx := b.requestsCtx
+	start := time.Now()
+	for next := start; ctx.Err() == nil; {
+		elapsed := time.Since(start)
+		if elapsed >= b.conf.recoveryProbe {
+			break
+		}
+		sent := time.Now()
+		code, err := probeStatus(ctx, cl, b.conf)
+		if ctx.Err() != nil {
+			break
+		}
+		usTaken := durationUs(time.Since(sent))
+		step := &steps[elapsed/recoveryProbeStep]
+		step.probes++
+		if err != nil || !ready.contains(code) {
+			step.failed++
+		} else {
+			latencies.Increment(usTaken)
+			step.usSum += usTaken
+			if usTaken > step.usMax {
+				step.usMax = usTaken
+			}
+		}
+		next = next.Add(recoveryProbeInterval)
+		if !sleepOrDone(time.Until(next), ctx.Done()) {
+			break
+		}
+	}
+	b.printRecovery(out, steps, latencies)
+	return nil
+}
+
+func (b *bombardier) printRecovery(
+	out io.Writer, steps []recoveryStep, latencies *uhist.Histogram,
+) {
+	fmt.Fprintln(out, "Recovery probes:")
+	recovered := -1
+	for i, s := range steps {
+		if s.probes == 0 {
+			break
+		}
+		fmt.Fprintf(out, "  %6v %4v probe(s), %v failed",
+			time.Duration(i+1)*recoveryProbeStep, s.probes, s.failed)
+		if s.failed < s.probes {
+			fmt.Fprintf(out, ", mean %v, max %v",
+				b.conf.formatLatency(s.meanUs()),
+				b.conf.formatLatency(float64(s.usMax)))
+		}
+		fmt.Fprintln(out)
+		if recovered < 0 && b.baseline != nil && s.failed == 0 &&
+			s.meanUs() <= b.baseline.Result.Latency.Mean {
+			recovered = i
+		}
+	}
+	res := internal.Results{
+		Latencies: internal.NewSortedUint64Histogram(latencies),
+	}
+	if lats := res.LatenciesStats([]float64{0.5, 0.99}); lats != nil {
+		fmt.Fprintf(out, "  Successful probes: p50 %v, p99 %v\n",
+			b.conf.formatLatency(float64(lats.Percentiles[0.5])),
+			b.conf.formatLatency(float64(lats.Percentiles[0.99])))
+	}
+	if b.baseline == nil {
+		return
+	}
+	baseline := b.conf.formatLatency(b.baseline.Result.Latency.Mean)
+	if recovered < 0 {
+		fmt.Fprintf(out, "  Not recovered to baseline latency %v within "+
+			"%v\n", baseline, b.conf.recoveryProbe)
+		return
+	}
+	fmt.Fprintf(out, "  Recovered to baseline latency %v within %v\n",
+		baseline, time.Duration(recovered+1)*recoveryProbeStep)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBombardierProbeRecovery(t *testing.T) {
+	var failing uint32
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.LoadUint32(&failing) == 1 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}),
+	)
+	defer s.Close()
+	expectations := []struct {
+		failing uint32
+		output  []string
+	}{
+		{0, []string{
+			"Recovery probes:\n      1s ",
+			" 0 failed, mean ",
+			"  Successful probes: p50 ",
+			"  Recovered to baseline latency 1.00s within 1s\n",
+		}},
+		{1, []string{
+			"Recovery probes:\n      1s ",
+			"  Not recovered to baseline latency 1.00s within 1s\n",
+		}},
+	}
+	for _, e := range expectations {
+		atomic.StoreUint32(&failing, e.failing)
+		numReqs := uint64(1)
+		b, err := newBombardier(config{
+			numConns:      1,
+			numReqs:       &numReqs,
+			url:           s.URL,
+			headers:       new(headersList),
+			timeout:       defaultTimeout,
+			method:        "GET",
+			format:        knownFormat("plain-text"),
+			recoveryProbe: time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.baseline = new(baseline)
+		b.baseline.Result.Latency.Mean = 1e6
+		b.disableOutput()
+		b.bombard()
+		out := new(bytes.Buffer)
+		if err := b.probeRecovery(out); err != nil {
+			t.Fatal(err)
+		}
+		for _, exp := range e.output {
+			if !strings.Contains(out.String(), exp) {
+				t.Errorf("Expected %q in output:\n%s", exp, out)
+			}
+		}
+	}
+}
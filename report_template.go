@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"text/template"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// reportMetrics is what templates passed with --report-template-file
+// are applied to. Unlike internal.TestInfo it holds plain precomputed
+// values, so that templates don't have to call methods to get them.
+type reportMetrics struct {
+	Spec     internal.Spec
+	Duration time.Duration
+
+	RPS     reportRPS
+	Latency reportLatency
+	Codes   reportCodes
+	// Errors is the total number of errors, ErrorsList has them
+	// grouped by message, in order of frequency
+	Errors     uint64
+	ErrorsList []internal.ErrorWithCount
+
+	// Throughput is in bytes per second
+	Throughput float64
+}
+
+type reportRPS struct {
+	Mean, Stddev, Max float64
+}
+
+// reportLatency is in microseconds.
+type reportLatency struct {
+	Mean, Stddev, Max       float64
+	P50, P75, P90, P95, P99 uint64
+}
+
+type reportCodes struct {
+	C1xx, C2xx, C3xx, C4xx, C5xx uint64
+	Others                       uint64
+}
+
+var reportPercentiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+func newReportMetrics(info internal.TestInfo) reportMetrics {
+	r := info.Result
+	m := reportMetrics{
+		Spec:     info.Spec,
+		Duration: r.TimeTaken,
+		Codes: reportCodes{
+			C1xx:   r.Req1XX,
+			C2xx:   r.Req2XX,
+			C3xx:   r.Req3XX,
+			C4xx:   r.Req4XX,
+			C5xx:   r.Req5XX,
+			Others: r.Others,
+		},
+		ErrorsList: r.Errors,
+		Throughput: r.Throughput(),
+	}
+	if rps := r.RequestsStats(nil); rps != nil {
+		m.RPS = reportRPS{rps.Mean, rps.Stddev, rps.Max}
+	}
+	if lats := r.LatenciesStats(reportPercentiles); lats != nil {
+		p := lats.Percentiles
+		m.Latency = reportLatency{
+			Mean:   lats.Mean,
+			Stddev: lats.Stddev,
+			Max:    lats.Max,
+			P50:    p[0.5],
+			P75:    p[0.75],
+			P90:    p[0.9],
+			P95:    p[0.95],
+			P99:    p[0.99],
+		}
+	}
+	for _, e := range r.Errors {
+		m.Errors += e.Count
+	}
+	return m
+}
+
+// reportTemplateReporter applies a user-defined template to
+// reportMetrics.
+type reportTemplateReporter struct {
+	template *template.Template
+}
+
+func (t *reportTemplateReporter) report(
+	out io.Writer, info internal.TestInfo,
+) error {
+	return t.template.Execute(out, newReportMetrics(info))
+}
+
+func (b *bombardier) prepareReportTemplate(path string) (reporter, error) {
+	templateBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := b.parseTemplate(templateBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &reportTemplateReporter{t}, nil
+}
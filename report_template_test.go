@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/codesenberg/bombardier/internal"
+	fhist "github.com/codesenberg/concurrent/float64/histogram"
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+func writeReportTemplate(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "bombardier-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestReportTemplate(t *testing.T) {
+	path := writeReportTemplate(t, "{{ .Spec.Method }} "+
+		"{{ printf \"%.0f\" .RPS.Mean }} {{ .Latency.P99 }} "+
+		"{{ .Codes.C2xx }} {{ .Errors }} "+
+		"{{ range .ErrorsList }}{{ .Error }}:{{ .Count }}{{ end }}")
+	defer os.Remove(path)
+	b := &bombardier{}
+	r, err := b.prepareReportTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := testInfo()
+	info.Result.Errors = []internal.ErrorWithCount{
+		{Error: "timeout", Count: 2},
+		{Error: "reset", Count: 1},
+	}
+	out := new(bytes.Buffer)
+	if err := r.report(out, info); err != nil {
+		t.Fatal(err)
+	}
+	expected := "GET 10 1000 10 3 timeout:2reset:1"
+	if s := out.String(); s != expected {
+		t.Errorf("Expected %q, but got %q", expected, s)
+	}
+}
+
+func TestReportTemplateNoData(t *testing.T) {
+	path := writeReportTemplate(t, "{{ .RPS.Mean }} {{ .Latency.P50 }}")
+	defer os.Remove(path)
+	b := &bombardier{}
+	r, err := b.prepareReportTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	info := internal.TestInfo{Result: internal.Results{
+		Latencies: uhist.Default(),
+		Requests:  fhist.Default(),
+	}}
+	if err := r.report(out, info); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); s != "0 0" {
+		t.Errorf("Expected \"0 0\", but got %q", s)
+	}
+}
+
+func TestReportTemplateValidatedAtStartup(t *testing.T) {
+	path := writeReportTemplate(t, "{{ .RPS.Mean ")
+	defer os.Remove(path)
+	_, err := newBombardier(config{
+		numConns:           defaultNumberOfConns,
+		numReqs:            &defaultNumberOfReqs,
+		url:                "http://localhost:8080",
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		format:             knownFormat("plain-text"),
+		reportTemplateFile: path,
+	})
+	if err == nil {
+		t.Error("Should fail on templates that can't be parsed")
+	}
+	_, err = newBombardier(config{
+		numConns:           defaultNumberOfConns,
+		numReqs:            &defaultNumberOfReqs,
+		url:                "http://localhost:8080",
+		headers:            new(headersList),
+		timeout:            defaultTimeout,
+		method:             "GET",
+		format:             knownFormat("plain-text"),
+		reportTemplateFile: "/does/not/exist",
+	})
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) {
+		t.Errorf("Expected *os.PathError, but got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"text/template"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// reporter writes results of the test in some format.
+type reporter interface {
+	report(out io.Writer, info internal.TestInfo) error
+}
+
+// builtinReporters are known formats that aren't template-based.
+var builtinReporters = map[string]func() reporter{
+	"csv": func() reporter { return &csvReporter{} },
+}
+
+// templateReporter formats results using text/template, it backs both
+// known formats and user-defined templates.
+type templateReporter struct {
+	template *template.Template
+}
+
+func (t *templateReporter) report(
+	out io.Writer, info internal.TestInfo,
+) error {
+	return t.template.Execute(out, info)
+}
+
+// prettyJSONReporter indents the output of the json reporter.
+type prettyJSONReporter struct {
+	reporter reporter
+}
+
+func (p *prettyJSONReporter) report(
+	out io.Writer, info internal.TestInfo,
+) error {
+	var compact bytes.Buffer
+	if err := p.reporter.report(&compact, info); err != nil {
+		return err
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, compact.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	_, err := pretty.WriteTo(out)
+	return err
+}
+
+func (b *bombardier) prepareReporter(f format) (reporter, error) {
+	switch f := f.(type) {
+	case knownFormat:
+		if newReporter, ok := builtinReporters[string(f)]; ok {
+			return newReporter(), nil
+		}
+		return b.newTemplateReporter(f.template())
+	case userDefinedTemplate:
+		templateBytes, err := ioutil.ReadFile(string(f))
+		if err != nil {
+			return nil, err
+		}
+		return b.newTemplateReporter(templateBytes)
+	default:
+		panic("format can't be nil at this point, this is a bug")
+	}
+}
+
+func (b *bombardier) newTemplateReporter(
+	templateBytes []byte,
+) (reporter, error) {
+	t, err := b.parseTemplate(templateBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &templateReporter{t}, nil
+}
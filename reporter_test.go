@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+	fhist "github.com/codesenberg/concurrent/float64/histogram"
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+func testInfo() internal.TestInfo {
+	latencies, requests := uhist.Default(), fhist.Default()
+	latencies.Add(1000, 10)
+	requests.Increment(10)
+	return internal.TestInfo{
+		Spec: internal.Spec{
+			NumberOfConnections: 5,
+			NumberOfRequests:    10,
+			Method:              "GET",
+			URL:                 "http://localhost:8080",
+			Timeout:             defaultTimeout,
+		},
+		Result: internal.Results{
+			BytesRead: 1024,
+			TimeTaken: time.Second,
+			Req2XX:    10,
+			Latencies: latencies,
+			Requests:  requests,
+		},
+	}
+}
+
+func TestTemplateReporters(t *testing.T) {
+	b := &bombardier{}
+	r, err := b.prepareReporter(knownFormat("json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := r.report(out, testInfo()); err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Result struct {
+			Req2xx uint64 `json:"req2xx"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("invalid json %q: %v", out, err)
+	}
+	if parsed.Result.Req2xx != 10 {
+		t.Errorf("Expected 10 2xx, but got %v", parsed.Result.Req2xx)
+	}
+
+	f, err := ioutil.TempFile("", "bombardier-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString("{{ .Spec.Method }} {{ .Result.Req2XX }}")
+	f.Close()
+	r, err = b.prepareReporter(userDefinedTemplate(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := r.report(out, testInfo()); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); s != "GET 10" {
+		t.Errorf("Expected \"GET 10\", but got %q", s)
+	}
+}
+
+func TestPrettyJSONReporter(t *testing.T) {
+	b := &bombardier{}
+	compact, err := b.prepareReporter(knownFormat("json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := compact.report(out, testInfo()); err != nil {
+		t.Fatal(err)
+	}
+	expected := new(bytes.Buffer)
+	if err := json.Indent(expected, out.Bytes(), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	r := &prettyJSONReporter{compact}
+	if err := r.report(out, testInfo()); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected.String() {
+		t.Errorf("Expected %q, but got %q", expected, out)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("{\n  \"spec\": {")) {
+		t.Errorf("Expected indented json, but got %q", out)
+	}
+	if !json.Valid(out.Bytes()) {
+		t.Errorf("invalid json %q", out)
+	}
+}
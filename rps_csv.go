@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"time"
+)
+
+// rpsCSV appends every sample of requests per second, as recorded by
+// rateMeter, to --csv-out with the wall-clock time it was taken at.
+// Rows are flushed every rpsCSVFlushInterval, so that a killed test still
+// leaves the samples taken till then. Samples are only recorded from
+// rateMeter, so there's no locking.
+type rpsCSV struct {
+	file      *os.File
+	out       *bufio.Writer
+	lastFlush time.Time
+	err       error
+}
+
+const rpsCSVHeader = "timestamp,rps\n"
+
+func newRPSCSV(path string) (*rpsCSV, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &rpsCSV{file: f, out: bufio.NewWriter(f), lastFlush: time.Now()}
+	if _, err := c.out.WriteString(rpsCSVHeader); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := c.out.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *rpsCSV) record(at time.Time, rps float64) {
+	if c.err != nil {
+		return
+	}
+	row := at.AppendFormat(make([]byte, 0, 64), time.RFC3339Nano)
+	row = append(row, ',')
+	row = strconv.AppendFloat(row, rps, 'f', 2, 64)
+	row = append(row, '\n')
+	if _, c.err = c.out.Write(row); c.err != nil {
+		return
+	}
+	if at.Sub(c.lastFlush) >= rpsCSVFlushInterval {
+		c.lastFlush = at
+		c.err = c.out.Flush()
+	}
+}
+
+// close flushes the rows left and closes the file, it returns the
+// first error writing them, if any.
+func (c *rpsCSV) close() error {
+	if c.err == nil {
+		c.err = c.out.Flush()
+	}
+	err := c.file.Close()
+	if c.err != nil {
+		return c.err
+	}
+	return err
+}
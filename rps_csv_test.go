@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBombardierRPSCSV(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "bombardier-rps-csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rps.csv")
+	numReqs := uint64(1000)
+	b, e := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+		rpsCSVFile: path,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != rpsCSVHeader {
+		t.Errorf("Expected only the header before the test, but got %q",
+			data)
+	}
+	b.disableOutput()
+	b.bombard()
+	if err := b.rpsCSV.close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if lines[0]+"\n" != rpsCSVHeader {
+		t.Errorf("Expected CSV header, but got %q", lines[0])
+	}
+	if len(lines) < 2 {
+		t.Errorf("Expected samples, but got %q", data)
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 {
+			t.Fatalf("Unexpected line %q", line)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+			t.Error(err)
+		}
+		if _, err := strconv.ParseFloat(fields[1], 64); err != nil {
+			t.Error(err)
+		}
+	}
+}
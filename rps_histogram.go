@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	fhist "github.com/codesenberg/concurrent/float64/histogram"
+)
+
+var rpsHistogramHeader = []string{"rps", "samples"}
+
+// rpsHistogramRow is the number of times requests per second were
+// measured to be RPS, rows are written by --rps-histogram-file.
+type rpsHistogramRow struct {
+	RPS     float64 `json:"rps"`
+	Samples uint64  `json:"samples"`
+}
+
+// rpsHistogramRows returns rows of h ordered by rps.
+func rpsHistogramRows(h *fhist.Histogram) []rpsHistogramRow {
+	rows := make([]rpsHistogramRow, 0)
+	h.VisitAll(func(rps float64, count uint64) bool {
+		rows = append(rows, rpsHistogramRow{rps, count})
+		return true
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].RPS < rows[j].RPS
+	})
+	return rows
+}
+
+func writeRPSHistogram(
+	out io.Writer, rows []rpsHistogramRow, asJSON bool,
+) error {
+	if asJSON {
+		return json.NewEncoder(out).Encode(rows)
+	}
+	w := csv.NewWriter(out)
+	_ = w.Write(rpsHistogramHeader)
+	for _, r := range rows {
+		_ = w.Write([]string{
+			strconv.FormatFloat(r.RPS, 'f', 2, 64),
+			strconv.FormatUint(r.Samples, decBase),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// dumpRPSHistogram writes --rps-histogram-file to the file at path,
+// as JSON if its name ends with .json and as CSV otherwise.
+func (b *bombardier) dumpRPSHistogram(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	asJSON := strings.HasSuffix(strings.ToLower(path), ".json")
+	if err := writeRPSHistogram(
+		f, rpsHistogramRows(b.requests), asJSON,
+	); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fhist "github.com/codesenberg/concurrent/float64/histogram"
+)
+
+func TestWriteRPSHistogram(t *testing.T) {
+	h := fhist.Default()
+	h.Increment(250.5)
+	h.Increment(100)
+	h.Increment(250.5)
+	rows := rpsHistogramRows(h)
+	var out bytes.Buffer
+	if err := writeRPSHistogram(&out, rows, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := "rps,samples\n" +
+		"100.00,1\n" +
+		"250.50,2\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, out.String())
+	}
+	out.Reset()
+	if err := writeRPSHistogram(&out, rows, true); err != nil {
+		t.Fatal(err)
+	}
+	expected = `[{"rps":100,"samples":1},{"rps":250.5,"samples":2}]` + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, out.String())
+	}
+	out.Reset()
+	if err := writeRPSHistogram(
+		&out, rpsHistogramRows(fhist.Default()), true,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "[]\n" {
+		t.Errorf("Expected empty list, but got %q", out.String())
+	}
+}
+
+func TestBombardierRPSHistogramFile(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "rps-histogram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rps.JSON")
+	numReqs := uint64(100)
+	b, e := newBombardier(config{
+		numConns:         2,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		rpsHistogramFile: path,
+		format:           knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if err := b.dumpRPSHistogram(path); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []rpsHistogramRow
+	if err := json.Unmarshal(content, &rows); err != nil {
+		t.Fatalf("Expected JSON, but got %q: %v", content, err)
+	}
+	samples := uint64(0)
+	for i, r := range rows {
+		if i > 0 && r.RPS <= rows[i-1].RPS {
+			t.Errorf("Expected rows ordered by rps, but got %q", content)
+		}
+		samples += r.Samples
+	}
+	if samples != b.requests.Count() {
+		t.Errorf("Expected %v samples, but got %v",
+			b.requests.Count(), samples)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+)
+
+// scenarioSpec is the format of the file passed with --scenario.
+type scenarioSpec struct {
+	Steps []struct {
+		Name    string   `json:"name"`
+		Method  string   `json:"method"`
+		URL     string   `json:"url"`
+		Headers []string `json:"headers"`
+		Body    string   `json:"body"`
+
+		Capture []captureSpec `json:"capture"`
+		Use     []string      `json:"use"`
+
+		// At is when the step was sent originally, relative to the
+		// start of the pass, i.e. "1.5s"
+		At string `json:"at"`
+		// Timeout of the step's requests, i.e. "100ms"
+		Timeout string `json:"timeout"`
+		// ExpectStatus replaces --expect-status for the step's
+		// requests, i.e. "201" or "200,3xx"
+		ExpectStatus string `json:"expectStatus"`
+	} `json:"steps"`
+}
+
+type scenarioStep struct {
+	name string
+	req  *request
+
+	// uses are names of variables substituted in URL, headers and
+	// body, rawURL is the URL as specified in the scenario
+	uses     []string
+	rawURL   string
+	captures []*capture
+
+	// wait is the time between the previous step and this one in
+	// the original traffic, it's scaled by --replay-speed
+	wait time.Duration
+
+	// expectStatus, if not nil, replaces --expect-status
+	expectStatus *statusRanges
+
+	latencies        *uhist.Histogram
+	requests, errors uint64
+	// requests that exceeded req.timeout
+	timeouts uint64
+}
+
+// scenario is an ordered list of requests each worker sends in turn,
+// starting over after the last one.
+type scenario struct {
+	base  *url.URL
+	steps []*scenarioStep
+}
+
+// scenarioIteration is the state of a worker going through the
+// scenario. Captured variables are reset when it starts over.
+type scenarioIteration struct {
+	next int
+	vars map[string]string
+}
+
+type scenarioStepError struct {
+	step int
+	err  error
+}
+
+func (s *scenarioStepError) Error() string {
+	return fmt.Sprintf("Scenario step %v: %v", s.step, s.err)
+}
+
+// loadScenario reads the scenario from path. URLs of steps are
+// resolved against target and headers are added to (or override)
+// the common ones.
+func loadScenario(
+	path, target string, common *headersList,
+) (*scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec scenarioSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("can't parse scenario %q: %v", path, err)
+	}
+	if len(spec.Steps) == 0 {
+		return nil, errEmptyScenario
+	}
+	base, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	s := &scenario{base: base}
+	captured := make(map[string]bool)
+	var prevAt time.Duration
+	for i, ss := range spec.Steps {
+		step := &scenarioStep{
+			name:      ss.Name,
+			req:       &request{method: ss.Method, body: ss.Body},
+			latencies: uhist.Default(),
+		}
+		if step.name == "" {
+			step.name = fmt.Sprintf("step %v", i+1)
+		}
+		if step.req.method == "" {
+			step.req.method = "GET"
+		}
+		if err := checkScenarioStep(step.req, base, ss.URL); err != nil {
+			return nil, &scenarioStepError{i + 1, err}
+		}
+		headers, err := mergeHeaders(common, ss.Headers)
+		if err != nil {
+			return nil, &scenarioStepError{i + 1, err}
+		}
+		step.req.headers = headers
+		for _, name := range ss.Use {
+			if !captured[name] {
+				return nil, &scenarioStepError{
+					i + 1, &undefinedVariableError{name},
+				}
+			}
+		}
+		step.uses, step.rawURL = ss.Use, ss.URL
+		for _, cs := range ss.Capture {
+			c, err := newCapture(cs)
+			if err != nil {
+				return nil, &scenarioStepError{i + 1, err}
+			}
+			step.captures = append(step.captures, c)
+			captured[c.name] = true
+		}
+		step.req.readBody = len(step.captures) > 0
+		if ss.At != "" {
+			at, err := time.ParseDuration(ss.At)
+			if err != nil {
+				return nil, &scenarioStepError{i + 1, err}
+			}
+			if at < prevAt {
+				return nil, &scenarioStepError{i + 1, errScenarioAt}
+			}
+			if i > 0 {
+				step.wait = at - prevAt
+			}
+			prevAt = at
+		}
+		if ss.Timeout != "" {
+			timeout, err := time.ParseDuration(ss.Timeout)
+			if err != nil {
+				return nil, &scenarioStepError{i + 1, err}
+			}
+			if timeout <= 0 {
+				return nil, &scenarioStepError{i + 1, errScenarioTimeout}
+			}
+			step.req.timeout = timeout
+		}
+		if ss.ExpectStatus != "" {
+			step.expectStatus = new(statusRanges)
+			if err := step.expectStatus.Set(ss.ExpectStatus); err != nil {
+				return nil, &scenarioStepError{i + 1, err}
+			}
+		}
+		s.steps = append(s.steps, step)
+	}
+	return s, nil
+}
+
+// checkTimeouts tells whether timeouts of all the steps are within
+// the one of the clients, which would cut longer requests short.
+func (s *scenario) checkTimeouts(clientTimeout time.Duration) error {
+	for i, step := range s.steps {
+		if step.req.timeout > clientTimeout {
+			return &scenarioStepError{i + 1, errScenarioTimeoutTooLong}
+		}
+	}
+	return nil
+}
+
+func checkScenarioStep(r *request, base *url.URL, rawURL string) error {
+	if !allowedHTTPMethod(r.method) || r.method == "CONNECT" {
+		return &invalidHTTPMethodError{method: r.method}
+	}
+	if !canHaveBody(r.method) && r.body != "" {
+		return errBodyNotAllowed
+	}
+	u, err := resolveStepURL(base, rawURL)
+	if err != nil {
+		return err
+	}
+	r.url = u
+	return nil
+}
+
+func resolveStepURL(base *url.URL, rawURL string) (*url.URL, error) {
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return nil, errScenarioHost
+	}
+	return u, nil
+}
+
+// mergeHeaders returns common headers followed by the ones in
+// "K: V" format, the latter replace common headers with the same name.
+func mergeHeaders(common *headersList, extra []string) (*headersList, error) {
+	var own headersList
+	for _, h := range extra {
+		if err := own.Set(h); err != nil {
+			return nil, err
+		}
+	}
+	var res headersList
+	if common != nil {
+		for _, h := range *common {
+			if !own.contains(h.key) {
+				res = append(res, h)
+			}
+		}
+	}
+	res = append(res, own...)
+	return &res, nil
+}
+
+func (h headersList) contains(key string) bool {
+	for _, header := range h {
+		if strings.EqualFold(header.key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare returns the request of the step with variables substituted.
+func (s *scenario) prepare(
+	step *scenarioStep, vars map[string]string,
+) (*request, error) {
+	if len(step.uses) == 0 {
+		return step.req, nil
+	}
+	for _, name := range step.uses {
+		if _, ok := vars[name]; !ok {
+			return nil, &uncapturedVariableError{name}
+		}
+	}
+	req := *step.req
+	u, err := resolveStepURL(
+		s.base, substituteURL(step.rawURL, step.uses, vars),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.url = u
+	headers := make(headersList, 0, len(*step.req.headers))
+	for _, h := range *step.req.headers {
+		headers = append(headers, header{
+			h.key, substitute(h.value, step.uses, vars),
+		})
+	}
+	req.headers = &headers
+	req.body = substitute(step.req.body, step.uses, vars)
+	return &req, nil
+}
+
+// performScenarioStep sends the next step of the scenario and
+// captures variables from its response.
+func (b *bombardier) performScenarioStep(
+	it *scenarioIteration, conn *connStats,
+) {
+	if it.next == 0 || it.vars == nil {
+		it.vars = make(map[string]string)
+	}
+	step := b.scenario.steps[it.next]
+	if step.wait > 0 && !b.replayWait(step.wait) {
+		// the test was done while waiting, nothing was sent
+		return
+	}
+	it.next = (it.next + 1) % len(b.scenario.steps)
+	atomic.AddUint64(&step.requests, 1)
+
+	req, err := b.scenario.prepare(step, it.vars)
+	if err != nil {
+		// nothing was sent, so there is no code or latency to record
+		atomic.AddUint64(&step.errors, 1)
+		conn.record(0, b.recordError(-1, err))
+		return
+	}
+	code, usTaken, body, err := b.client.doRequest(req)
+	if err == errTestDone {
+		// the request was cut short by the end of the test
+		return
+	}
+	if err == errRequestCanceled {
+		atomic.AddUint64(&b.canceled, 1)
+		return
+	}
+	if err == nil {
+		for _, c := range step.captures {
+			v, ok := c.extract(body)
+			if !ok {
+				err = &captureError{c.name}
+				break
+			}
+			it.vars[c.name] = v
+		}
+	}
+	if err == errStepTimeout {
+		atomic.AddUint64(&step.timeouts, 1)
+	}
+	expectStatus := b.conf.expectStatus
+	if step.expectStatus != nil {
+		expectStatus = step.expectStatus
+	}
+	err = b.recordStatusError(code, err, expectStatus)
+	if err != nil {
+		atomic.AddUint64(&step.errors, 1)
+	}
+	b.writeStatistics(code, usTaken, phaseTimings{})
+	conn.record(usTaken, err)
+	step.latencies.Increment(usTaken)
+}
+
+// replayWait sleeps for wait scaled by --replay-speed and returns
+// false if the test was done before that.
+func (b *bombardier) replayWait(wait time.Duration) bool {
+	return sleepOrDone(
+		time.Duration(float64(wait)/b.conf.replaySpeedOrDefault()),
+		b.barrier.done(),
+	)
+}
+
+func (s *scenario) results() []internal.StepResult {
+	res := make([]internal.StepResult, 0, len(s.steps))
+	for _, step := range s.steps {
+		res = append(res, internal.StepResult{
+			Name:      step.name,
+			Requests:  atomic.LoadUint64(&step.requests),
+			Errors:    atomic.LoadUint64(&step.errors),
+			Timeouts:  atomic.LoadUint64(&step.timeouts),
+			Latencies: step.latencies,
+		})
+	}
+	return res
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeScenario(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "scenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoadScenario(t *testing.T) {
+	path := writeScenario(t, `{"steps":[
+		{"name":"login","method":"POST","url":"/login","body":"u=1",
+			"headers":["X-Step: login"]},
+		{"url":"items?page=2"}
+	]}`)
+	defer os.Remove(path)
+	common := &headersList{{"X-Step", "common"}, {"Accept", "*/*"}}
+	s, err := loadScenario(path, "http://localhost:8080/api/", common)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.steps) != 2 {
+		t.Fatalf("Expected 2 steps, but got %v", len(s.steps))
+	}
+	login, items := s.steps[0], s.steps[1]
+	if login.name != "login" || login.req.method != "POST" ||
+		login.req.body != "u=1" ||
+		login.req.url.String() != "http://localhost:8080/login" {
+		t.Errorf("Unexpected first step: %+v %+v", login, login.req)
+	}
+	expectedHeaders := headersList{{"Accept", "*/*"}, {"X-Step", "login"}}
+	if login.req.headers.String() != expectedHeaders.String() {
+		t.Errorf("Expected headers %v, but got %v",
+			expectedHeaders.String(), login.req.headers.String())
+	}
+	if items.name != "step 2" || items.req.method != "GET" ||
+		items.req.url.String() != "http://localhost:8080/api/items?page=2" {
+		t.Errorf("Unexpected second step: %+v %+v", items, items.req)
+	}
+	if items.req.headers.String() != common.String() {
+		t.Errorf("Expected headers %v, but got %v",
+			common.String(), items.req.headers.String())
+	}
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	expectations := []struct {
+		content string
+		err     error
+	}{
+		{`{"steps":[]}`, errEmptyScenario},
+		{
+			`{"steps":[{"url":"http://otherhost/"}]}`,
+			&scenarioStepError{1, errScenarioHost},
+		},
+		{
+			`{"steps":[{"url":"/"},{"method":"GET","body":"b"}]}`,
+			&scenarioStepError{2, errBodyNotAllowed},
+		},
+		{
+			`{"steps":[{"method":"FETCH"}]}`,
+			&scenarioStepError{1, &invalidHTTPMethodError{"FETCH"}},
+		},
+		{
+			`{"steps":[{"headers":["no colon"]}]}`,
+			&scenarioStepError{1, errInvalidHeaderFormat},
+		},
+		{
+			`{"steps":[{"use":["token"]},` +
+				`{"capture":[{"var":"token","json":"$.token"}]}]}`,
+			&scenarioStepError{1, &undefinedVariableError{"token"}},
+		},
+		{
+			`{"steps":[{"capture":[{"var":"token"}]}]}`,
+			&scenarioStepError{1, errCaptureExpression},
+		},
+		{
+			`{"steps":[{"at":"1s"},{"at":"500ms"}]}`,
+			&scenarioStepError{2, errScenarioAt},
+		},
+		{
+			`{"steps":[{"url":"/"},{"timeout":"0s"}]}`,
+			&scenarioStepError{2, errScenarioTimeout},
+		},
+		{
+			`{"steps":[{"expectStatus":"2xx,700"}]}`,
+			&scenarioStepError{1, fmt.Errorf(
+				"%q is not a valid status code, class(i.e. 2xx) or range",
+				"700",
+			)},
+		},
+	}
+	for _, e := range expectations {
+		path := writeScenario(t, e.content)
+		_, err := loadScenario(path, "http://localhost:8080", nil)
+		os.Remove(path)
+		if err == nil || err.Error() != e.err.Error() {
+			t.Errorf("Expected %q for %v, but got %v", e.err, e.content, err)
+		}
+	}
+}
+
+func TestBombardierScenario(t *testing.T) {
+	testAllClients(t, testBombardierScenario)
+}
+
+func testBombardierScenario(clientType clientTyp, t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			received = append(received, r.Method+" "+r.URL.RequestURI()+
+				" "+r.Header.Get("X-Step")+" "+string(body))
+			mu.Unlock()
+			if r.URL.Path == "/fail" {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer s.Close()
+	path := writeScenario(t, `{"steps":[
+		{"name":"login","method":"POST","url":"/login","body":"u=1"},
+		{"name":"fetch","url":"/items","headers":["X-Step: fetch"]},
+		{"name":"fail","method":"DELETE","url":"/fail"}
+	]}`)
+	defer os.Remove(path)
+	numReqs := uint64(6)
+	expectStatus := statusRanges{{200, 299}}
+	b, e := newBombardier(config{
+		numConns:     1,
+		numReqs:      &numReqs,
+		url:          s.URL,
+		headers:      &headersList{{"X-Step", "common"}},
+		timeout:      defaultTimeout,
+		method:       "GET",
+		format:       knownFormat("plain-text"),
+		clientType:   clientType,
+		expectStatus: &expectStatus,
+		scenario:     path,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := []string{
+		"POST /login common u=1",
+		"GET /items fetch ",
+		"DELETE /fail common ",
+	}
+	if len(received) != len(expected)*2 {
+		t.Fatalf("Expected %v requests, but got %v",
+			len(expected)*2, received)
+	}
+	for i, r := range received {
+		if r != expected[i%len(expected)] {
+			t.Errorf("Expected request %q, but got %q",
+				expected[i%len(expected)], r)
+		}
+	}
+	steps := b.gatherInfo().Result.Steps
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %v steps, but got %v", len(expected), steps)
+	}
+	for i, step := range steps {
+		expectedErrors := uint64(0)
+		if step.Name == "fail" {
+			expectedErrors = 2
+		}
+		if step.Requests != 2 || step.Errors != expectedErrors ||
+			step.LatenciesStats(nil) == nil {
+			t.Errorf("Unexpected results of step %v: %+v", i+1, step)
+		}
+	}
+	if b.req2xx != 4 || b.req5xx != 2 {
+		t.Errorf("Expected 4 2xx and 2 5xx, but got %v and %v",
+			b.req2xx, b.req5xx)
+	}
+}
+
+func TestBombardierScenarioExpectStatus(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/items":
+				rw.WriteHeader(http.StatusCreated)
+			case "/missing":
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer s.Close()
+	path := writeScenario(t, `{"steps":[
+		{"name":"create","method":"POST","url":"/items","expectStatus":"201"},
+		{"name":"health","url":"/health"},
+		{"name":"missing","url":"/missing","expectStatus":"200,3xx"},
+		{"name":"gone","url":"/missing","expectStatus":"404-410"}
+	]}`)
+	defer os.Remove(path)
+	numReqs := uint64(8)
+	expectStatus := statusRanges{{200, 200}}
+	b, e := newBombardier(config{
+		numConns:     1,
+		numReqs:      &numReqs,
+		url:          s.URL,
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		format:       knownFormat("plain-text"),
+		expectStatus: &expectStatus,
+		scenario:     path,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := map[string]float64{
+		"create": 100, "health": 100, "missing": 0, "gone": 100,
+	}
+	for _, step := range b.gatherInfo().Result.Steps {
+		if step.Requests != 2 || step.PassRate() != expected[step.Name] {
+			t.Errorf("Expected pass rate %v%% of 2 requests for %v, "+
+				"but got %v%% of %v", expected[step.Name], step.Name,
+				step.PassRate(), step.Requests)
+		}
+	}
+	if errs := b.errors.byFrequency(); len(errs) != 1 ||
+		errs[0].error != (&unexpectedStatusError{404}).Error() ||
+		errs[0].count != 2 {
+		t.Errorf("Expected only 2 errors of 404 code, but got %v", errs)
+	}
+}
+
+func TestBombardierScenarioCaptures(t *testing.T) {
+	testAllClients(t, testBombardierScenarioCaptures)
+}
+
+func testBombardierScenarioCaptures(clientType clientTyp, t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			received = append(received, r.URL.RequestURI()+" "+
+				r.Header.Get("Authorization")+" "+string(body))
+			mu.Unlock()
+			if r.URL.Path == "/login" {
+				_, _ = rw.Write([]byte(`{"data":{"token":"abc"}}`))
+			}
+		}),
+	)
+	defer s.Close()
+	path := writeScenario(t, `{"steps":[
+		{"name":"login","method":"POST","url":"/login",
+			"capture":[{"var":"token","json":"$.data.token"}]},
+		{"name":"fetch","method":"PUT","url":"/items/{{token}}",
+			"headers":["Authorization: Bearer {{token}}"],
+			"body":"t={{token}}","use":["token"],
+			"capture":[{"var":"missing","regex":"id=(\\d+)"}]},
+		{"name":"delete","method":"DELETE","url":"/items?id={{missing}}",
+			"use":["missing"]}
+	]}`)
+	defer os.Remove(path)
+	numReqs := uint64(6)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+		scenario:   path,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := []string{
+		"/login  ",
+		"/items/abc Bearer abc t=abc",
+	}
+	if len(received) != len(expected)*2 {
+		t.Fatalf("Expected %v requests, but got %v",
+			len(expected)*2, received)
+	}
+	for i, r := range received {
+		if r != expected[i%len(expected)] {
+			t.Errorf("Expected request %q, but got %q",
+				expected[i%len(expected)], r)
+		}
+	}
+	errs := b.errors.byFrequency()
+	expectedErrors := []string{
+		(&captureError{"missing"}).Error(),
+		(&uncapturedVariableError{"missing"}).Error(),
+	}
+	if len(errs) != 2 {
+		t.Fatalf("Expected errors %v, but got %v", expectedErrors, errs)
+	}
+	for _, ewc := range errs {
+		if ewc.count != 2 || (ewc.error != expectedErrors[0] &&
+			ewc.error != expectedErrors[1]) {
+			t.Errorf("Unexpected error %v", ewc)
+		}
+	}
+	stepErrors := []uint64{0, 2, 2}
+	for i, step := range b.gatherInfo().Result.Steps {
+		if step.Requests != 2 || step.Errors != stepErrors[i] {
+			t.Errorf("Unexpected results of step %v: %+v", i+1, step)
+		}
+	}
+}
+
+func TestBombardierScenarioEscapesURLValues(t *testing.T) {
+	testAllClients(t, testBombardierScenarioEscapesURLValues)
+}
+
+func testBombardierScenarioEscapesURLValues(
+	clientType clientTyp, t *testing.T,
+) {
+	const value = "a b&c?d#e/f"
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/login" {
+				_, _ = rw.Write([]byte(`{"name":"` + value + `"}`))
+				return
+			}
+			mu.Lock()
+			received = append(received,
+				r.URL.EscapedPath()+" "+r.URL.Query().Get("name"))
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	path := writeScenario(t, `{"steps":[
+		{"url":"/login","capture":[{"var":"name","json":"$.name"}]},
+		{"url":"/users/{{name}}?name={{name}}","use":["name"]}
+	]}`)
+	defer os.Remove(path)
+	numReqs := uint64(2)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+		scenario:   path,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := "/users/a%20b&c%3Fd%23e%2Ff " + value
+	if len(received) != 1 || received[0] != expected {
+		t.Errorf("Expected request %q, but got %q", expected, received)
+	}
+}
+
+func TestLoadScenarioTiming(t *testing.T) {
+	path := writeScenario(t, `{"steps":[
+		{"at":"1s"},{"at":"1.5s"},{},{"at":"3s"}
+	]}`)
+	defer os.Remove(path)
+	s, err := loadScenario(path, "http://localhost:8080", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Duration{
+		0, 500 * time.Millisecond, 0, 1500 * time.Millisecond,
+	}
+	for i, step := range s.steps {
+		if step.wait != expected[i] {
+			t.Errorf("Expected step %v to wait %v, but got %v",
+				i+1, expected[i], step.wait)
+		}
+	}
+	path = writeScenario(t, `{"steps":[{"at":"soon"}]}`)
+	defer os.Remove(path)
+	if _, err := loadScenario(path, "http://localhost:8080", nil); err == nil {
+		t.Error("Should fail on invalid \"at\"")
+	}
+}
+
+func TestBombardierScenarioReplaySpeed(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	path := writeScenario(t, `{"steps":[
+		{"url":"/first","at":"0s"},{"url":"/second","at":"400ms"}
+	]}`)
+	defer os.Remove(path)
+	numReqs := uint64(2)
+	speed := 2.0
+	b, e := newBombardier(config{
+		numConns:    1,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		format:      knownFormat("plain-text"),
+		scenario:    path,
+		replaySpeed: &speed,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if len(times) != 2 {
+		t.Fatalf("Expected 2 requests, but got %v", len(times))
+	}
+	gap := times[1].Sub(times[0])
+	if gap < 180*time.Millisecond || gap >= 400*time.Millisecond {
+		t.Errorf("Expected about 200ms between steps, but got %v", gap)
+	}
+}
+
+func TestBombardierScenarioTimeouts(t *testing.T) {
+	testAllClients(t, testBombardierScenarioTimeouts)
+}
+
+func testBombardierScenarioTimeouts(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/report" {
+				time.Sleep(200 * time.Millisecond)
+			}
+		}),
+	)
+	defer s.Close()
+	path := writeScenario(t, `{"steps":[
+		{"name":"health","url":"/health","timeout":"100ms"},
+		{"name":"report","url":"/report","timeout":"1s"},
+		{"name":"report-short","url":"/report","timeout":"50ms"}
+	]}`)
+	defer os.Remove(path)
+	// fasthttp leaves aborted requests to complete in the background,
+	// holding the only connection, so a single pass is made
+	numReqs := uint64(3)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+		scenario:   path,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	expected := map[string]uint64{"health": 0, "report": 0, "report-short": 1}
+	for _, step := range b.gatherInfo().Result.Steps {
+		if step.Timeouts != expected[step.Name] ||
+			step.Errors != expected[step.Name] {
+			t.Errorf("Expected %v timeouts for %v, but got %+v",
+				expected[step.Name], step.Name, step)
+		}
+	}
+	if errs := b.errors.byFrequency(); len(errs) != 1 ||
+		errs[0].error != errStepTimeout.Error() {
+		t.Errorf("Expected only %q errors, but got %v", errStepTimeout, errs)
+	}
+}
+
+func TestBombardierScenarioTimeoutTooLong(t *testing.T) {
+	path := writeScenario(t, `{"steps":[{"url":"/"},{"timeout":"1m"}]}`)
+	defer os.Remove(path)
+	numReqs := uint64(1)
+	_, e := newBombardier(config{
+		numConns: 1,
+		numReqs:  &numReqs,
+		url:      "http://localhost:8080",
+		headers:  new(headersList),
+		timeout:  defaultTimeout,
+		method:   "GET",
+		format:   knownFormat("plain-text"),
+		scenario: path,
+	})
+	expected := &scenarioStepError{2, errScenarioTimeoutTooLong}
+	if e == nil || e.Error() != expected.Error() {
+		t.Errorf("Expected %q, but got %v", expected, e)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// slowlorisFiller is the header line trickled, over and over, once
+// the request line and headers were sent, so that the request never
+// completes.
+var slowlorisFiller = []byte("X-Slowloris: 1\r\n")
+
+// slowlorisClient implements --slowloris, it's meant for testing how
+// servers one's responsible for cope with connection exhaustion. Each
+// request opens a new connection and sends the request line and
+// headers, byte by byte with --slowloris-delay between bytes, never
+// completing the request, for as long as the server keeps the
+// connection open or until the test is done. The time the connection
+// was held is taken as latency.
+type slowlorisClient struct {
+	*rawClient
+	delay time.Duration
+	done  <-chan struct{}
+	stats *slowlorisStats
+}
+
+func newSlowlorisClient(
+	opts *clientOpts, delay time.Duration, done <-chan struct{},
+	stats *slowlorisStats,
+) client {
+	u, err := url.Parse(opts.url)
+	if err != nil {
+		// opts.url guaranteed to be valid at this point
+		panic(err)
+	}
+	request := slowlorisRequest(
+		opts.method, u, opts.headers, opts.headerCasePreserve,
+	)
+	return client(&slowlorisClient{
+		rawClient: newRawClient(opts, request),
+		delay:     delay,
+		done:      done,
+		stats:     stats,
+	})
+}
+
+// slowlorisRequest returns the request line and headers without the
+// empty line that ends them.
+func slowlorisRequest(
+	method string, u *url.URL, headers *headersList, preserveCase bool,
+) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v %v HTTP/1.1\r\n", method, u.RequestURI())
+	if headers == nil || !headers.contains("Host") {
+		fmt.Fprintf(&buf, "Host: %v\r\n", u.Host)
+	}
+	if headers != nil {
+		for _, h := range *headers {
+			key := h.key
+			if !preserveCase {
+				key = textproto.CanonicalMIMEHeaderKey(key)
+			}
+			fmt.Fprintf(&buf, "%v: %v\r\n", key, h.value)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (c *slowlorisClient) do() (
+	code int, usTaken uint64, phases phaseTimings, err error,
+) {
+	start := time.Now()
+	code, err = c.hold()
+	usTaken = durationUs(time.Since(start))
+	return
+}
+
+func (c *slowlorisClient) doRequest(r *request) (
+	code int, usTaken uint64, body []byte, err error,
+) {
+	// config guarantees that there are no scenarios with --slowloris
+	return -1, 0, nil, errSlowlorisConflict
+}
+
+// hold returns the status code of the response, if the server sent
+// one before closing the connection, errSlowlorisClosed if it didn't
+// and errTestDone if the connection was held until the test was done.
+func (c *slowlorisClient) hold() (int, error) {
+	conn, err := c.connect()
+	if err != nil {
+		c.stats.refused()
+		return -1, err
+	}
+	c.stats.opened()
+	defer c.stats.released()
+
+	var (
+		resp    *http.Response
+		readErr error
+		closed  = make(chan struct{})
+	)
+	go func() {
+		resp, readErr = http.ReadResponse(bufio.NewReader(conn), nil)
+		close(closed)
+	}()
+	ticker := time.NewTicker(c.delay)
+	defer ticker.Stop()
+trickle:
+	for i := 0; ; i++ {
+		if _, err := conn.Write(c.nextByte(i)); err != nil {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-closed:
+			break trickle
+		case <-c.done:
+			_ = conn.Close()
+			<-closed
+			return -1, errTestDone
+		}
+	}
+	_ = conn.Close()
+	<-closed
+	c.stats.closedByServer()
+	if readErr != nil {
+		return -1, errSlowlorisClosed
+	}
+	return resp.StatusCode, nil
+}
+
+func (c *slowlorisClient) nextByte(i int) []byte {
+	if i < len(c.request) {
+		return c.request[i : i+1]
+	}
+	i = (i - len(c.request)) % len(slowlorisFiller)
+	return slowlorisFiller[i : i+1]
+}
+
+// connect dials and, for https, performs the handshake, so that
+// handshakes that fail count as refused connections.
+func (c *slowlorisClient) connect() (net.Conn, error) {
+	ctx := context.Background()
+	var deadline time.Time
+	if c.timeout > 0 {
+		deadline = time.Now().Add(c.timeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	conn, err := c.dial(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.tlsConfig == nil {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, c.tlsConfig)
+	if err := tlsConn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err := tlsConn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// slowlorisStats tracks connections opened with --slowloris. All of
+// the fields are accessed atomically.
+type slowlorisStats struct {
+	open, refuse, closed uint64
+	// held is the number of connections open right now and maxHeld
+	// is the most of them open at once
+	held, maxHeld uint64
+	// heldAtRefusal is the number of connections that were held when
+	// a connection was refused for the first time, refusedOnce is
+	// set to 1 when that happens
+	heldAtRefusal uint64
+	refusedOnce   uint32
+}
+
+func (s *slowlorisStats) opened() {
+	atomic.AddUint64(&s.open, 1)
+	held := atomic.AddUint64(&s.held, 1)
+	for {
+		max := atomic.LoadUint64(&s.maxHeld)
+		if held <= max ||
+			atomic.CompareAndSwapUint64(&s.maxHeld, max, held) {
+			return
+		}
+	}
+}
+
+func (s *slowlorisStats) released() {
+	atomic.AddUint64(&s.held, ^uint64(0))
+}
+
+func (s *slowlorisStats) refused() {
+	atomic.AddUint64(&s.refuse, 1)
+	if atomic.CompareAndSwapUint32(&s.refusedOnce, 0, 1) {
+		atomic.StoreUint64(&s.heldAtRefusal, atomic.LoadUint64(&s.held))
+	}
+}
+
+func (s *slowlorisStats) closedByServer() {
+	atomic.AddUint64(&s.closed, 1)
+}
+
+func (s *slowlorisStats) result() *internal.SlowlorisResult {
+	return &internal.SlowlorisResult{
+		Opened:             atomic.LoadUint64(&s.open),
+		Refused:            atomic.LoadUint64(&s.refuse),
+		ClosedByServer:     atomic.LoadUint64(&s.closed),
+		MaxHeld:            atomic.LoadUint64(&s.maxHeld),
+		HeldAtFirstRefusal: atomic.LoadUint64(&s.heldAtRefusal),
+	}
+}
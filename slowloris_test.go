@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func slowlorisConfig(target string) config {
+	duration := 1 * time.Second
+	return config{
+		numConns:       2,
+		duration:       &duration,
+		url:            target,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		slowloris:      true,
+		slowlorisDelay: 5 * time.Millisecond,
+		format:         knownFormat("plain-text"),
+	}
+}
+
+func TestSlowlorisRequest(t *testing.T) {
+	u, err := url.Parse("http://localhost:8080/a?b=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := &headersList{{"x-custom", "1"}}
+	got := string(slowlorisRequest("POST", u, headers, false))
+	expected := "POST /a?b=c HTTP/1.1\r\nHost: localhost:8080\r\n" +
+		"X-Custom: 1\r\n"
+	if got != expected {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+}
+
+func TestSlowlorisClosedByServer(t *testing.T) {
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			t.Error("Requests should never complete")
+		}),
+	)
+	s.Config.ReadHeaderTimeout = 50 * time.Millisecond
+	s.Start()
+	defer s.Close()
+	b, e := newBombardier(slowlorisConfig(s.URL))
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	r := b.gatherInfo().Result.Slowloris
+	if r == nil || r.Opened < 2 || r.ClosedByServer < 2 || r.Refused != 0 ||
+		r.MaxHeld != 2 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+	if b.latencies.Count() == 0 {
+		t.Error("Latencies should be recorded")
+	}
+}
+
+func TestSlowlorisHeldUntilDone(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	b, e := newBombardier(slowlorisConfig(s.URL))
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	r := b.gatherInfo().Result.Slowloris
+	if r == nil || r.Opened != 2 || r.ClosedByServer != 0 || r.MaxHeld != 2 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+	if b.reqs != 0 || len(b.errors.byFrequency()) != 0 {
+		t.Errorf("Held connections shouldn't be accounted, got %v, %v",
+			b.reqs, b.errors.byFrequency())
+	}
+}
+
+func TestSlowlorisRefused(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	target := s.URL
+	s.Close()
+	b, e := newBombardier(slowlorisConfig(target))
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	r := b.gatherInfo().Result.Slowloris
+	if r == nil || r.Opened != 0 || r.Refused == 0 ||
+		r.HeldAtFirstRefusal != 0 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+	var out strings.Builder
+	b.redirectOutputTo(&out)
+	b.printStats()
+	if !strings.Contains(out.String(), "0 held when first refused") {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
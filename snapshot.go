@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// snapshotter prints cumulative results of the test every
+// --snapshot-interval until the test is done.
+func (b *bombardier) snapshotter(begin time.Time) {
+	ticker := time.NewTicker(b.conf.snapshotInterval)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	for {
+		select {
+		case <-ticker.C:
+			b.printSnapshot(time.Since(begin))
+		case <-done:
+			b.doneChan <- struct{}{}
+			return
+		}
+	}
+}
+
+func (b *bombardier) printSnapshot(elapsed time.Duration) {
+	if b.intervalLatencies != nil {
+		b.printIntervalLatencies(elapsed)
+		return
+	}
+	info := b.gatherInfo()
+	info.Result.TimeTaken = elapsed
+
+	if !b.bar.NotPrint {
+		fmt.Fprint(b.out, clearLine)
+	}
+	if b.conf.format == knownFormat("plain-text") {
+		fmt.Fprintf(b.out, "Snapshot after %v:\n", elapsed.Round(time.Second))
+	}
+	if err := b.reporter.report(b.out, info); err != nil {
+		fmt.Fprintln(b.errOut, err)
+	}
+	fmt.Fprintln(b.out)
+}
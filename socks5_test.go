@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// socks5TestServer is a SOCKS5 proxy (RFC 1928) that only supports
+// CONNECT and requires username and password authentication (RFC
+// 1929) with "user" and "s3cr3t".
+type socks5TestServer struct {
+	ln net.Listener
+	// tunnels established, accessed atomically
+	tunnels uint64
+}
+
+func newSOCKS5TestServer(t *testing.T) *socks5TestServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socks5TestServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socks5TestServer) url(userinfo string) string {
+	return "socks5://" + userinfo + s.ln.Addr().String()
+}
+
+func (s *socks5TestServer) serve(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 256)
+	// greeting: version, number of methods and methods
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{5, 2}); err != nil {
+		return
+	}
+	user, ok := readSOCKS5Field(conn, 1)
+	password, pok := readSOCKS5Field(conn, 0)
+	if !ok || !pok {
+		return
+	}
+	if user != "user" || password != "s3cr3t" {
+		_, _ = conn.Write([]byte{1, 1})
+		return
+	}
+	if _, err := conn.Write([]byte{1, 0}); err != nil {
+		return
+	}
+	// request: version, command, reserved and address type
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil || buf[1] != 1 {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+		host = net.IP(buf[:4]).String()
+	case 3:
+		if host, ok = readSOCKS5Field(conn, 0); !ok {
+			return
+		}
+	default:
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	port := strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2])))
+	target, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+	atomic.AddUint64(&s.tunnels, 1)
+	go func() {
+		_, _ = io.Copy(target, conn)
+		_ = target.Close()
+	}()
+	_, _ = io.Copy(conn, target)
+}
+
+// readSOCKS5Field reads a length-prefixed field, skipping skip bytes
+// before the length.
+func readSOCKS5Field(r io.Reader, skip int) (string, bool) {
+	buf := make([]byte, 256)
+	if _, err := io.ReadFull(r, buf[:skip+1]); err != nil {
+		return "", false
+	}
+	n := buf[skip]
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return "", false
+	}
+	return string(buf[:n]), true
+}
+
+func TestBombardierSOCKS5Proxy(t *testing.T) {
+	testAllClients(t, testBombardierSOCKS5Proxy)
+}
+
+func testBombardierSOCKS5Proxy(clientType clientTyp, t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	proxy := newSOCKS5TestServer(t)
+	defer proxy.ln.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: clientType,
+		proxyURL:   proxy.url("user:s3cr3t@"),
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	if tunnels := atomic.LoadUint64(&proxy.tunnels); tunnels == 0 {
+		t.Error("Expected connections to go through the proxy")
+	}
+}
+
+func TestBombardierSOCKS5ProxyAuthFailure(t *testing.T) {
+	proxy := newSOCKS5TestServer(t)
+	defer proxy.ln.Close()
+	numReqs := uint64(3)
+	b, e := newBombardier(config{
+		numConns: 1,
+		numReqs:  &numReqs,
+		url:      "http://localhost:8080",
+		headers:  new(headersList),
+		timeout:  defaultTimeout,
+		method:   "GET",
+		proxyURL: proxy.url("user:wrong@"),
+		format:   knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result
+	if res.ConnectionErrors != numReqs {
+		t.Errorf("Expected %v connection errors, but got %v (errors: %v)",
+			numReqs, res.ConnectionErrors, res.Errors)
+	}
+	for _, e := range res.Errors {
+		if !strings.Contains(e.Error, "authentication failed") {
+			t.Errorf("Expected authentication to fail, but got %q", e.Error)
+		}
+	}
+}
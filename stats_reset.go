@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// statsReset implements --stats-reset-on-code. Results of requests
+// completed before the first 2xx response are discarded, so that the
+// test is measured once the server is ready rather than after a fixed
+// warm-up.
+type statsReset struct {
+	// begin is the start of the test
+	begin time.Time
+
+	once sync.Once
+	// passed is set to 1 once the first 2xx response was seen, after
+	// reached is set
+	passed    uint32
+	reached   time.Duration
+	discarded uint64
+}
+
+// measured reports whether the outcome of a request should be
+// accounted, reset is called once, when the first 2xx is seen.
+func (s *statsReset) measured(code int, err error, reset func()) bool {
+	if s.done() {
+		return true
+	}
+	if err != nil || code/100 != 2 {
+		atomic.AddUint64(&s.discarded, 1)
+		return false
+	}
+	s.once.Do(func() {
+		s.reached = time.Since(s.begin)
+		reset()
+		atomic.StoreUint32(&s.passed, 1)
+	})
+	return true
+}
+
+func (s *statsReset) done() bool {
+	return atomic.LoadUint32(&s.passed) == 1
+}
+
+func (s *statsReset) result() *internal.StatsResetResult {
+	res := &internal.StatsResetResult{
+		Discarded: atomic.LoadUint64(&s.discarded),
+	}
+	if s.done() {
+		res.Reached, res.FirstSuccess = true, s.reached
+	}
+	return res
+}
+
+// resetStats marks the start of measurements with
+// --stats-reset-on-code, dropping bytes transferred and requests
+// counted for the rate so far.
+func (b *bombardier) resetStats() {
+	atomic.StoreInt64(&b.bytesRead, 0)
+	atomic.StoreInt64(&b.bytesWritten, 0)
+	b.rpl.Lock()
+	b.reqs = 0
+	b.start = time.Now()
+	b.rpl.Unlock()
+}
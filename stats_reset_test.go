@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBombardierStatsReset(t *testing.T) {
+	testAllClients(t, testBombardierStatsReset)
+}
+
+func testBombardierStatsReset(clientType clientTyp, t *testing.T) {
+	reqs := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&reqs, 1) <= 5 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:         1,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		clientType:       clientType,
+		format:           knownFormat("plain-text"),
+		statsResetOnCode: true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req5xx != 0 || b.req2xx != 15 {
+		t.Errorf("Expected 15 2xx and no 5xx, but got %v and %v",
+			b.req2xx, b.req5xx)
+	}
+	if sum := b.errors.sum(); sum != 0 {
+		t.Errorf("Expected no errors, but got %v", sum)
+	}
+	res := b.gatherInfo().Result.StatsReset
+	if res == nil || !res.Reached || res.Discarded != 5 ||
+		res.FirstSuccess <= 0 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestBombardierStatsResetWithoutSuccess(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(10)
+	b, e := newBombardier(config{
+		numConns:         1,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		format:           knownFormat("plain-text"),
+		statsResetOnCode: true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req5xx != 0 {
+		t.Errorf("Expected no 5xx, but got %v", b.req5xx)
+	}
+	res := b.gatherInfo().Result.StatsReset
+	if res == nil || res.Reached || res.Discarded != numReqs {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestBombardierStatsResetCountsTowardsNumReqs(t *testing.T) {
+	reqs := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddUint64(&reqs, 1) <= 30 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(100)
+	b, e := newBombardier(config{
+		numConns:         4,
+		numReqs:          &numReqs,
+		url:              s.URL,
+		headers:          new(headersList),
+		timeout:          defaultTimeout,
+		method:           "GET",
+		format:           knownFormat("plain-text"),
+		statsResetOnCode: true,
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if sent := atomic.LoadUint64(&reqs); sent != numReqs {
+		t.Errorf("Expected %v requests to be sent, but got %v",
+			numReqs, sent)
+	}
+	res := b.gatherInfo().Result
+	measured := res.Req1XX + res.Req2XX + res.Req3XX + res.Req4XX +
+		res.Req5XX + res.Others
+	if res.StatsReset == nil || res.StatsReset.Discarded == 0 ||
+		measured+res.StatsReset.Discarded != numReqs {
+		t.Errorf("Expected discarded and measured requests to add up "+
+			"to %v, but got %v measured, %+v", numReqs, measured,
+			res.StatsReset)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type statusRange struct {
+	from, to int
+}
+
+// statusRanges is a set of status codes that are considered successful.
+// On the command line it's specified as comma-separated codes, classes
+// or ranges, i.e. "200,3xx,400-404".
+type statusRanges []statusRange
+
+func (s *statusRanges) String() string {
+	parts := make([]string, 0, len(*s))
+	for _, r := range *s {
+		if r.from == r.to {
+			parts = append(parts, strconv.Itoa(r.from))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v-%v", r.from, r.to))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *statusRanges) Set(value string) error {
+	res := statusRanges{}
+	for _, part := range strings.Split(value, ",") {
+		r, err := parseStatusRange(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		res = append(res, r)
+	}
+	*s = res
+	return nil
+}
+
+func parseStatusRange(spec string) (statusRange, error) {
+	invalid := fmt.Errorf(
+		"%q is not a valid status code, class(i.e. 2xx) or range", spec,
+	)
+	var r statusRange
+	var err error
+	switch {
+	case len(spec) == 3 && strings.ToLower(spec[1:]) == "xx":
+		class, cerr := strconv.Atoi(spec[:1])
+		r, err = statusRange{class * 100, class*100 + 99}, cerr
+	case strings.Contains(spec, "-"):
+		bounds := strings.SplitN(spec, "-", 2)
+		r.from, err = strconv.Atoi(bounds[0])
+		if err == nil {
+			r.to, err = strconv.Atoi(bounds[1])
+		}
+	default:
+		r.from, err = strconv.Atoi(spec)
+		r.to = r.from
+	}
+	if err != nil || r.from < 100 || r.to > 599 || r.from > r.to {
+		return statusRange{}, invalid
+	}
+	return r, nil
+}
+
+func (s *statusRanges) contains(code int) bool {
+	for _, r := range *s {
+		if code >= r.from && code <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+type unexpectedStatusError struct {
+	code int
+}
+
+func (u *unexpectedStatusError) Error() string {
+	return fmt.Sprintf("Unexpected status code %v", u.code)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestStatusRangesParsing(t *testing.T) {
+	s := new(statusRanges)
+	for _, invalid := range []string{
+		"", "abc", "99", "600", "6xx", "300-200", "200-", "2xx,", "x2xx",
+	} {
+		if err := s.Set(invalid); err == nil {
+			t.Errorf("Should fail on %q", invalid)
+		}
+	}
+	if err := s.Set("200, 3XX,400-404"); err != nil {
+		t.Fatal(err)
+	}
+	if str, e := s.String(), "200,300-399,400-404"; str != e {
+		t.Errorf("Expected %q, but got %q", e, str)
+	}
+	expectations := []struct {
+		code     int
+		contains bool
+	}{
+		{200, true},
+		{201, false},
+		{300, true},
+		{399, true},
+		{404, true},
+		{405, false},
+		{-1, false},
+	}
+	for _, e := range expectations {
+		if c := s.contains(e.code); c != e.contains {
+			t.Errorf("Expected contains(%v) to be %v, but got %v",
+				e.code, e.contains, c)
+		}
+	}
+}
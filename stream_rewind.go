@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// rewindingBodyProducer returns a producer of --stream bodies that
+// reads the regular file at path over one handle opened once, instead
+// of opening it for each request. Every body is a separate section
+// reader starting at offset 0, so no seeks are involved and bodies
+// may be read concurrently. Files which aren't regular (i.e. pipes)
+// can't be reread that way, so they're reopened for each request.
+func rewindingBodyProducer(path string) (bodyStreamProducer, error) {
+	reopen := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		_ = f.Close()
+		return reopen, nil
+	}
+	// the handle stays open until the process exits
+	size := fi.Size()
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(io.NewSectionReader(f, 0, size)), nil
+	}, nil
+}
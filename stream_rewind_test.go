@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRewindingBodyProducer(t *testing.T) {
+	bodyPath := "testbody.txt"
+	expected, err := ioutil.ReadFile(bodyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bsp, err := rewindingBodyProducer(bodyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		body, err := bsp()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := body.Close(); err != nil {
+			t.Error(err)
+		}
+		if string(got) != string(expected) {
+			t.Errorf("Expected %q, but got %q", expected, got)
+		}
+	}
+	if _, err := rewindingBodyProducer("doesnotexist.txt"); err == nil {
+		t.Error("Should fail for files that don't exist")
+	}
+}
+
+func TestBombardierStreamRewind(t *testing.T) {
+	testAllClients(t, testBombardierStreamRewind)
+}
+
+func testBombardierStreamRewind(clientType clientTyp, t *testing.T) {
+	bodyPath := "testbody.txt"
+	requestBody, err := ioutil.ReadFile(bodyPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	matched := uint64(0)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err == nil && string(body) == string(requestBody) {
+				atomic.AddUint64(&matched, 1)
+			}
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(50)
+	b, e := newBombardier(config{
+		numConns:     defaultNumberOfConns,
+		numReqs:      &numReqs,
+		url:          s.URL,
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "POST",
+		bodyFilePath: bodyPath,
+		stream:       true,
+		streamRewind: true,
+		clientType:   clientType,
+		format:       knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	if matched != numReqs {
+		t.Errorf("Expected %v requests with the whole body, but got %v",
+			numReqs, matched)
+	}
+}
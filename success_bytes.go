@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// successBytes accounts sizes of requests and responses with 2xx
+// codes for --count-only-successful-for-throughput. Sizes are those of
+// HTTP/1.x messages as clients see them, so TLS and HTTP/2 framing
+// aren't accounted, neither are bodies of requests sent with chunked
+// transfer encoding.
+type successBytes struct {
+	read, written int64
+}
+
+func (s *successBytes) add(read, written int64) {
+	atomic.AddInt64(&s.read, read)
+	atomic.AddInt64(&s.written, written)
+}
+
+func (s *successBytes) load() (read, written int64) {
+	return atomic.LoadInt64(&s.read), atomic.LoadInt64(&s.written)
+}
+
+// fasthttpExchangeSize returns sizes of the response and the request
+// that was sent, body is the response body as received.
+func fasthttpExchangeSize(
+	req *fasthttp.Request, resp *fasthttp.Response, body []byte,
+) (read, written int64) {
+	written = int64(len(req.Header.Header()))
+	if n := req.Header.ContentLength(); n > 0 {
+		written += int64(n)
+	}
+	read = int64(len(resp.Header.Header()) + len(body))
+	return read, written
+}
+
+// httpExchangeSize returns sizes of the response and the request that
+// was sent, bodyRead is the number of bytes of response body read.
+// net/http doesn't expose messages it sends and receives, so headers
+// are accounted as its transport writes them, including the ones it
+// adds itself, if compression is set, Accept-Encoding among them.
+func httpExchangeSize(
+	req *http.Request, resp *http.Response, bodyRead int64,
+	compression bool,
+) (read, written int64) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	// request line, Host and headers, followed by an empty line
+	size := len(req.Method) + 1 + len(req.URL.RequestURI()) +
+		len(" HTTP/1.1\r\n") + len("Host: \r\n") + len(host) +
+		httpHeaderSize(req.Header) + 2
+	if ua, ok := req.Header["User-Agent"]; !ok {
+		size += len("User-Agent: Go-http-client/1.1\r\n")
+	} else if len(ua) > 0 && ua[0] == "" {
+		// empty one suppresses the default and isn't sent itself
+		size -= len("User-Agent: \r\n")
+	}
+	switch {
+	case req.ContentLength > 0:
+		size += len("Content-Length: \r\n") +
+			len(strconv.FormatInt(req.ContentLength, 10)) +
+			int(req.ContentLength)
+	case req.ContentLength < 0:
+		size += len("Transfer-Encoding: chunked\r\n")
+	case req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH":
+		size += len("Content-Length: 0\r\n")
+	}
+	// transport asks for gzip unless told otherwise
+	if compression && req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == "" && req.Method != "HEAD" {
+		size += len("Accept-Encoding: gzip\r\n")
+	}
+	written = int64(size)
+	// resp.Status includes the code
+	read = int64(len(resp.Proto)+1+len(resp.Status)+2+
+		httpHeaderSize(resp.Header)+2) + bodyRead
+	return read, written
+}
+
+func httpHeaderSize(h http.Header) int {
+	size := 0
+	for k, vs := range h {
+		for _, v := range vs {
+			size += len(k) + len(": ") + len(v) + len("\r\n")
+		}
+	}
+	return size
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBombardierSuccessfulThroughput(t *testing.T) {
+	testAllClients(t, testBombardierSuccessfulThroughput)
+}
+
+func testBombardierSuccessfulThroughput(clientType clientTyp, t *testing.T) {
+	body := strings.Repeat("x", 1024)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/fail" {
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+			_, _ = rw.Write([]byte(body))
+		}),
+	)
+	defer s.Close()
+	for _, path := range []string{"/", "/fail"} {
+		numReqs := uint64(10)
+		b, e := newBombardier(config{
+			numConns:             1,
+			numReqs:              &numReqs,
+			url:                  s.URL + path,
+			headers:              new(headersList),
+			timeout:              defaultTimeout,
+			method:               "POST",
+			body:                 "abracadabra",
+			clientType:           clientType,
+			format:               knownFormat("plain-text"),
+			successfulThroughput: true,
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		b.disableOutput()
+		b.bombard()
+		res := b.gatherInfo().Result
+		if !res.SuccessfulOnly {
+			t.Fatal("Expected throughput of successful requests")
+		}
+		read, written := res.SuccessfulBytesRead, res.SuccessfulBytesWritten
+		switch {
+		case path == "/fail" && (read != 0 || written != 0):
+			t.Errorf("Expected failed requests not to be accounted, "+
+				"but got %v read and %v written", read, written)
+		case path == "/fail" && res.Throughput() != 0:
+			t.Errorf("Expected zero throughput, but got %v",
+				res.Throughput())
+		case path == "/" && clientType != nhttp2 &&
+			(read != res.BytesRead || written != res.BytesWritten):
+			// HTTP/1.x messages are accounted exactly
+			t.Errorf("Expected %v read and %v written, but got %v and %v",
+				res.BytesRead, res.BytesWritten, read, written)
+		case path == "/" &&
+			(read < int64(numReqs)*int64(len(body)) ||
+				written < int64(numReqs)*int64(len("abracadabra"))):
+			t.Errorf("Expected bodies to be accounted, but got %v read "+
+				"and %v written", read, written)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// latencyTarget implements --target-p99. Every targetP99Interval it
+// measures p99 latency of requests completed since the last time and
+// nudges the rate towards the one that keeps p99 at the target:
+// proportionally to how far p99 is off, so that the rate keeps
+// changing until it's there.
+type latencyTarget struct {
+	targetUs uint64
+
+	// holds *latencyWindow, which is replaced once it's measured
+	window atomic.Value
+
+	// mu guards the rest
+	mu    sync.Mutex
+	rate  uint64
+	p99Us uint64
+	// within is the number of the last intervals in a row p99 was
+	// within targetP99Tolerance, withinSum is the sum of their rates
+	within, withinSum uint64
+}
+
+// latencyWindow accumulates requests completed during an interval.
+type latencyWindow struct {
+	// accessed atomically
+	reqs      uint64
+	latencies liveLatencies
+}
+
+// latencyWindowResult is what was measured during an interval with
+// some rate.
+type latencyWindowResult struct {
+	rate  uint64
+	rps   float64
+	p99Us uint64
+	reqs  uint64
+}
+
+func newLatencyTarget(target time.Duration, rate uint64) *latencyTarget {
+	t := &latencyTarget{
+		targetUs: uint64(target / time.Microsecond),
+		rate:     rate,
+	}
+	t.window.Store(new(latencyWindow))
+	return t
+}
+
+func (t *latencyTarget) record(usTaken uint64) {
+	w := t.window.Load().(*latencyWindow)
+	atomic.AddUint64(&w.reqs, 1)
+	w.latencies.record(usTaken)
+}
+
+// measure returns results of the interval that lasted elapsed and
+// starts a new one.
+func (t *latencyTarget) measure(elapsed time.Duration) latencyWindowResult {
+	w := t.window.Load().(*latencyWindow)
+	t.window.Store(new(latencyWindow))
+	t.mu.Lock()
+	res := latencyWindowResult{rate: t.rate}
+	t.mu.Unlock()
+	res.reqs = atomic.LoadUint64(&w.reqs)
+	if res.reqs == 0 {
+		return res
+	}
+	res.rps = float64(res.reqs) / elapsed.Seconds()
+	res.p99Us, _ = w.latencies.percentile(0.99)
+	return res
+}
+
+// adjust accounts r and returns the rate to use from now on.
+func (t *latencyTarget) adjust(r latencyWindowResult) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r.reqs == 0 {
+		// nothing completed, there is nothing to go by
+		return t.rate
+	}
+	t.p99Us = r.p99Us
+	diff := (float64(t.targetUs) - float64(r.p99Us)) / float64(t.targetUs)
+	if math.Abs(diff) <= targetP99Tolerance {
+		t.within++
+		t.withinSum += t.rate
+	} else {
+		t.within, t.withinSum = 0, 0
+	}
+	step := math.Max(-targetP99MaxStep,
+		math.Min(targetP99MaxStep, targetP99Gain*diff))
+	if step > 0 && r.rps < float64(r.rate)*findMaxRPSMinAchieved {
+		// the rate isn't what holds requests back, raising it would
+		// only let it run away
+		return t.rate
+	}
+	// step is at least -0.5, so the rate doesn't drop below 1
+	t.rate = uint64(float64(t.rate)*(1+step) + 0.5)
+	return t.rate
+}
+
+// followTargetP99 adjusts the rate every targetP99Interval until the
+// test is done.
+func (b *bombardier) followTargetP99() {
+	ticker := time.NewTicker(targetP99Interval)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	windowStart := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		res := b.latencyTarget.measure(time.Since(windowStart))
+		windowStart = time.Now()
+		b.rateLimiter.setRate(b.latencyTarget.adjust(res))
+	}
+}
+
+func (t *latencyTarget) result() *internal.TargetP99Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := &internal.TargetP99Result{
+		Target:     t.targetUs,
+		Rate:       t.rate,
+		P99Latency: t.p99Us,
+		Settled:    t.within >= targetP99SettleIntervals,
+	}
+	if res.Settled {
+		res.Rate = t.withinSum / t.within
+	}
+	return res
+}
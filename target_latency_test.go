@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyTargetAdjust(t *testing.T) {
+	type adjust struct {
+		window  latencyWindowResult
+		next    uint64
+		settled bool
+		rate    uint64
+	}
+	steps := []adjust{
+		{latencyWindowResult{100, 100, 5000, 50}, 125, false, 125},
+		{latencyWindowResult{125, 124, 8000, 62}, 138, false, 138},
+		{latencyWindowResult{138, 138, 9500, 69}, 141, false, 141},
+		{latencyWindowResult{141, 141, 10500, 70}, 137, false, 137},
+		{latencyWindowResult{137, 137, 10000, 68}, 137, true, 138},
+		// not even the current rate is achieved
+		{latencyWindowResult{137, 60, 5000, 30}, 137, false, 137},
+		{latencyWindowResult{137, 137, 40000, 68}, 69, false, 69},
+		// nothing completed
+		{latencyWindowResult{69, 0, 0, 0}, 69, false, 69},
+	}
+	lt := newLatencyTarget(10*time.Millisecond, 100)
+	for i, s := range steps {
+		next := lt.adjust(s.window)
+		res := lt.result()
+		if next != s.next || res.Settled != s.settled || res.Rate != s.rate {
+			t.Errorf("step %v: expected %v (settled %v at %v), "+
+				"but got %v (settled %v at %v)", i+1, s.next, s.settled,
+				s.rate, next, res.Settled, res.Rate)
+		}
+	}
+	if res := lt.result(); res.Target != 10000 || res.P99Latency != 40000 {
+		t.Errorf("Expected target 10000us and last p99 40000us, "+
+			"but got %+v", res)
+	}
+}
+
+func TestLatencyTargetMeasure(t *testing.T) {
+	lt := newLatencyTarget(time.Millisecond, 100)
+	for i := 0; i < 100; i++ {
+		lt.record(1000)
+	}
+	res := lt.measure(time.Second)
+	if res.rate != 100 || res.reqs != 100 || res.rps != 100 ||
+		res.p99Us < 1000 || res.p99Us > 1100 {
+		t.Errorf("Unexpected measurement: %+v", res)
+	}
+	if res := lt.measure(time.Second); res.reqs != 0 {
+		t.Errorf("Expected a new window, but got %+v", res)
+	}
+}
+
+func TestBombardierTargetP99(t *testing.T) {
+	// requests are served one at a time, taking 2ms each, so latency
+	// grows with the rate once it approaches 500 reqs/sec
+	var mu sync.Mutex
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			time.Sleep(2 * time.Millisecond)
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	duration := 3 * time.Second
+	b, e := newBombardier(config{
+		numConns:  50,
+		duration:  &duration,
+		url:       s.URL,
+		headers:   new(headersList),
+		timeout:   defaultTimeout,
+		method:    "GET",
+		targetP99: 20 * time.Millisecond,
+		format:    knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.TargetP99
+	if res == nil {
+		t.Fatal("Expected target p99 result")
+	}
+	// it starts at 100 and latency stays low until about 500
+	if res.Rate <= targetP99Start || res.Rate > 2000 {
+		t.Errorf("Expected rate to be raised from %v, but got %+v",
+			targetP99Start, res)
+	}
+}
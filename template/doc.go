@@ -8,12 +8,25 @@ There are a bunch of helper methods available inside a template
 besides those described in aforementioned documentation, namely:
 	- WithLatencies()
 		Tells whether --latencies flag were activated.
+	- WithWriteRead()
+		Tells whether --print-write-read flag were activated.
+	- SummaryPercentiles() []float64
+		Percentiles (as fractions in (0, 1]) to be used in summary
+		formats, either those requested with --summary-percentiles
+		or the default ones.
+	- LatencyPercentiles() []float64
+		Percentiles (as fractions in (0, 1]) of the latency
+		distribution printed with --latencies, either those requested
+		with --percentiles or the default ones. Their values are in
+		Percentiles of .Result.LatenciesStats LatencyPercentiles.
 	- FormatBinary(numberOfBytes float64) string
 		Converts bytes to kilo-, mega-, giga-, etc.- bytes, and
 		appends appropriate suffix "KB", "MB", "GB", etc.
 	- FormatTimeUs(us float64) string
 		Converts microseconds to milliseconds, seconds, minutes or
-		hours and appends appropriate suffix.
+		hours and appends appropriate suffix, keeping as many
+		digits after the decimal point as --percentile-precision
+		requests (2 by default).
 	- FormatTimeUsUint64(us uint64) string
 		Same as above, but for uint64, since type conversions are
 		not available in templates.
@@ -29,6 +29,8 @@ func formatFromString(formatSpec string) format {
 		return knownFormat("plain-text")
 	case "j", "json":
 		return knownFormat("json")
+	case "csv":
+		return knownFormat("csv")
 	}
 	// nil represents unknown format
 	return nil
@@ -42,21 +44,182 @@ const (
 {{ else }}
 	{{- print "  There wasn't enough data to compute statistics for requests." }}
 {{ end }}
-{{ with .Result.LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+{{ with .Result.LatenciesStats LatencyPercentiles }}
 	{{- printf "  %-10v %10v %10v %10v" "Latency" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
 	{{- if WithLatencies }}
   		{{- "\n  Latency Distribution" }}
 		{{- range $pc, $lat := .Percentiles }}
-			{{- printf "\n     %2.0f%% %10s" (Multiply $pc 100) (FormatTimeUsUint64 $lat) -}}
+			{{- printf "\n     %2.6g%% %10s" (Multiply $pc 100) (FormatTimeUsUint64 $lat) -}}
 		{{ end -}}
 	{{ end }}
 {{ else }}
 	{{- print "  There wasn't enough data to compute statistics for latencies." }}
 {{ end -}}
+{{ if WithWriteRead -}}
+{{ with .Result.ConnectLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v" "Connect" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+{{ else }}
+	{{- print "  There wasn't enough data to compute statistics for connects." }}
+{{ end -}}
+{{ with .Result.WriteLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v" "Write" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+{{ else }}
+	{{- print "  There wasn't enough data to compute statistics for writes." }}
+{{ end -}}
+{{ with .Result.ReadLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v" "Read" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+{{ else }}
+	{{- print "  There wasn't enough data to compute statistics for reads." }}
+{{ end -}}
+{{ end -}}
+{{ if WithDNS -}}
+{{ with .Result.DNSLatenciesStats (FloatsToArray 0.5 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v" "DNS" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+	{{- printf "\n    %v lookups, p50 %v, p99 %v" $.Result.DNSLookups (FormatTimeUsUint64 (index .Percentiles 0.5)) (FormatTimeUsUint64 (index .Percentiles 0.99)) }}
+{{ else }}
+	{{- print "  No DNS lookups were made." }}
+{{ end -}}
+{{ end -}}
+{{ with .Result.QueueTimesStats (FloatsToArray 0.5 0.9 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v" "Queue time" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+	{{- printf "\n    p50 %v, p90 %v, p99 %v" (FormatTimeUsUint64 (index .Percentiles 0.5)) (FormatTimeUsUint64 (index .Percentiles 0.9)) (FormatTimeUsUint64 (index .Percentiles 0.99)) }}
+{{ end -}}
+{{ with .Result.Steps -}}
+{{ printf "  %-20v %10v %10v %10v %10v %10v" "Steps" "Reqs" "Errors" "Passed" "Avg" "Max" }}
+	{{- range . }}
+		{{- printf "\n    %-18v %10v %10v %9.2f%%" .Name .Requests .Errors .PassRate }}
+		{{- with .LatenciesStats nil }}
+			{{- printf " %10v %10v" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+		{{- end }}
+		{{- with .Timeouts }}
+			{{- printf " (%v timed out)" . }}
+		{{- end }}
+	{{- end }}
+{{ end -}}
 {{ with .Result -}}
 {{ "  HTTP codes:" }}
 {{ printf "    1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v" .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX }}
 	{{- printf "\n    others - %v" .Others }}
+	{{- with .Aborted }}
+		{{- printf "\n    aborted - %v" . }}
+	{{- end }}
+	{{- if or .ConnectionErrors .RequestErrors }}
+		{{- printf "\n  Failed: %v connection errors, %v request errors" .ConnectionErrors .RequestErrors }}
+	{{- end }}
+	{{- with .LatencyCapped }}
+		{{- printf "\n  %v requests exceeded the latency cap" . }}
+	{{- end }}
+	{{- with .ContentLengthMismatches }}
+		{{- printf "\n  %v responses didn't match their Content-Length" . }}
+	{{- end }}
+	{{- with .Canceled }}
+		{{- printf "\n  %v requests canceled on interrupt" . }}
+	{{- end }}
+	{{- with .Hosts }}
+		{{- "\n  HTTP codes by host:" }}
+		{{- range . }}
+			{{- printf "\n    %v: 1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v, others - %v" .Host .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX .Others }}
+		{{- end }}
+	{{- end }}
+	{{- with .Schemes }}
+		{{- "\n  HTTP codes by scheme:" }}
+		{{- range . }}
+			{{- printf "\n    %v: 1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v, others - %v" .Scheme .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX .Others }}
+			{{- with .LatenciesStats nil }}
+				{{- printf ", latency avg %v, max %v" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+			{{- end }}
+		{{- end }}
+	{{- end }}
+	{{- with .Methods }}
+		{{- "\n  HTTP codes by method:" }}
+		{{- range . }}
+			{{- printf "\n    %v: 1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v, others - %v" .Method .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX .Others }}
+		{{- end }}
+	{{- end }}
+	{{- with .LatenciesByCode }}
+		{{- "\n  Latency by code:" }}
+		{{- range . }}
+			{{- $class := .Class }}
+			{{- with .LatenciesStats (FloatsToArray 0.5 0.99) }}
+				{{- printf "\n    %v: mean %v, p50 %v, p99 %v, max %v" $class (FormatTimeUs .Mean) (FormatTimeUsUint64 (index .Percentiles 0.5)) (FormatTimeUsUint64 (index .Percentiles 0.99)) (FormatTimeUs .Max) }}
+			{{- end }}
+		{{- end }}
+	{{- end }}
+	{{- with .TLS }}
+		{{- printf "\n  TLS: %v, %v" .Version .CipherSuite }}
+		{{- with .NegotiatedProtocol }}
+			{{- printf ", ALPN %v" . }}
+		{{- end }}
+		{{- if .Verified }}
+			{{- ", certificate verified" }}
+		{{- else }}
+			{{- ", certificate not verified" }}
+		{{- end }}
+	{{- end }}
+	{{- with .ConnectionCount }}
+		{{- printf "\n  Connections opened: %v (configured concurrency %v)" .Opened .Configured }}
+	{{- end }}
+	{{- with .DialRetries }}
+		{{- printf "\n  Dial retries: %v, %v dial(s) failed after all retries" .Retries .Failed }}
+	{{- end }}
+	{{- with .HTTPSUpgrade }}
+		{{- printf "\n  Upgraded to HTTPS: %v" .Upgraded }}
+		{{- with .LatenciesStats nil }}
+			{{- printf " (redirect hop mean %v, max %v)" (FormatTimeUs .Mean) (FormatTimeUs .Max) }}
+		{{- end }}
+	{{- end }}
+	{{- with .Pipeline }}
+		{{- printf "\n  Pipelining: %v requests over %v connections (%.2f per connection)" .Requests .Connections .RequestsPerConnection }}
+		{{- range .Depths }}
+			{{- printf "\n    depth %v: %.2f%% of the time" .Depth .Share }}
+		{{- end }}
+	{{- end }}
+	{{- with .FirstError }}
+		{{- printf "\n  Stopped by the first error (request %v): %v" .Request .Error }}
+	{{- end }}
+	{{- with .StatsReset }}
+		{{- if .Reached }}
+			{{- printf "\n  First 2xx after %v, %v earlier request(s) discarded" (FormatTimeUs (Multiply .FirstSuccess.Seconds 1e6)) .Discarded }}
+		{{- else }}
+			{{- printf "\n  No 2xx responses, all %v request(s) discarded" .Discarded }}
+		{{- end }}
+	{{- end }}
+	{{- with .Slowloris }}
+		{{- printf "\n  Slowloris: %v opened, %v refused, %v closed by server, at most %v held at once" .Opened .Refused .ClosedByServer .MaxHeld }}
+		{{- if .Refused }}
+			{{- printf ", %v held when first refused" .HeldAtFirstRefusal }}
+		{{- end }}
+	{{- end }}
+	{{- with .PacedUploads }}
+		{{- printf "\n  Paced uploads: %v completed, %v interrupted" .Completed .Interrupted }}
+	{{- end }}
+	{{- with .BodyFileErrors }}
+		{{- "\n  Body files with errors:" }}
+		{{- range . }}
+			{{- printf "\n    %10v - %v" .Errors .File }}
+		{{- end }}
+	{{- end }}
+	{{- with .BodyCommand }}
+		{{- printf "\n  Body command: %v bodies generated, %v runs failed, %v requests waited for a body" .Generated .Failed .Waits }}
+	{{- end }}
+	{{- with .ConnectionsAuto }}
+		{{- printf "\n  Connections (auto): %v at %.2f reqs/sec, mean latency %v" .Connections .RequestsPerSecond (FormatTimeUs .MeanLatency) }}
+		{{- if not .Settled }}
+			{{- " (throughput didn't plateau before the end of the test)" }}
+		{{- end }}
+	{{- end }}
+	{{- with .MaxRPS }}
+		{{- printf "\n  Max sustained rate: %v reqs/sec (achieved %.2f reqs/sec, p99 latency %v, %.2f%% errors)" .Rate .RequestsPerSecond (FormatTimeUsUint64 .P99Latency) .ErrorRate }}
+		{{- if not .Settled }}
+			{{- " (search didn't finish before the end of the test)" }}
+		{{- end }}
+	{{- end }}
+	{{- with .TargetP99 }}
+		{{- printf "\n  Rate for p99 latency of %v: %v reqs/sec (last p99 latency %v)" (FormatTimeUsUint64 .Target) .Rate (FormatTimeUsUint64 .P99Latency) }}
+		{{- if not .Settled }}
+			{{- " (p99 latency didn't settle before the end of the test)" }}
+		{{- end }}
+	{{- end }}
 	{{- with .Errors }}
 		{{- "\n  Errors:"}}
 		{{- range . }}
@@ -64,7 +227,18 @@ const (
 		{{- end -}}
 	{{ end -}}
 {{ end }}
-{{ printf "  %-10v %10v/s\n" "Throughput:" (FormatBinary .Result.Throughput)}}`
+{{ printf "  %-10v %10v/s" "Throughput:" (FormatBinary .Result.Throughput)}}
+{{- if .Result.SuccessfulOnly }}
+	{{- " (2xx responses only)" }}
+{{- end }}
+{{- "\n" }}
+{{- with .Result.Goodput }}
+	{{- printf "  %-11v %10v/s" "Goodput:" (FormatBinary .Goodput) }}
+	{{- printf " (%.2f%% of bytes read)\n" .Share }}
+{{- end }}
+{{- with .Result.CompressionRatio }}
+	{{- printf "  Compression ratio: %.2f\n" . }}
+{{- end }}`
 	jsonTemplate = `{"spec":{
 {{- with .Spec -}}
 "numberOfConnections":{{ .NumberOfConnections }}
@@ -101,6 +275,13 @@ const (
 
 ,"stream":{{ .Stream }},"timeoutSeconds":{{ .Timeout.Seconds }}
 
+{{- with .AbortSlowerThan -}}
+,"abortSlowerThanSeconds":{{ .Seconds }}
+{{- end -}}
+{{- with .LatencyCap -}}
+,"latencyCapSeconds":{{ .Seconds }}
+{{- end -}}
+
 {{- if .IsFastHTTP -}}
 ,"client":"fasthttp"
 {{- end -}}
@@ -111,9 +292,16 @@ const (
 ,"client":"net/http.v2"
 {{- end -}}
 
+{{- with .Pipeline -}}
+,"pipeline":{{ . }}
+{{- end -}}
+
 {{- with .Rate -}}
 ,"rate":{{ . }}
 {{- end -}}
+{{- with .RateBytes -}}
+,"rateBytes":{{ . }}
+{{- end -}}
 {{- end -}}
 },
 
@@ -122,6 +310,16 @@ const (
 ,"bytesWritten":{{ .BytesWritten -}}
 ,"timeTakenSeconds":{{ .TimeTaken.Seconds -}}
 
+{{- if .CompressedBytes -}}
+,"compressedBytes":{{ .CompressedBytes -}}
+,"decompressedBytes":{{ .DecompressedBytes -}}
+{{- end -}}
+
+{{- if .SuccessfulOnly -}}
+,"successfulBytesRead":{{ .SuccessfulBytesRead -}}
+,"successfulBytesWritten":{{ .SuccessfulBytesWritten -}}
+{{- end -}}
+
 ,"req1xx":{{ .Req1XX -}}
 ,"req2xx":{{ .Req2XX -}}
 ,"req3xx":{{ .Req3XX -}}
@@ -129,6 +327,188 @@ const (
 ,"req5xx":{{ .Req5XX -}}
 ,"others":{{ .Others -}}
 
+{{- with .Aborted -}}
+,"aborted":{{ . }}
+{{- end -}}
+{{- if or .ConnectionErrors .RequestErrors -}}
+,"connectionErrors":{{ .ConnectionErrors -}}
+,"requestErrors":{{ .RequestErrors -}}
+{{- end -}}
+{{- with .LatencyCapped -}}
+,"latencyCapped":{{ . }}
+{{- end -}}
+{{- with .ContentLengthMismatches -}}
+,"contentLengthMismatches":{{ . }}
+{{- end -}}
+{{- with .Canceled -}}
+,"canceled":{{ . }}
+{{- end -}}
+
+{{- with .Hosts -}}
+,"hosts":[
+{{- range $index, $host :=  . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"host":{{ .Host | printf "%q" }},"req1xx":{{ .Req1XX -}}
+,"req2xx":{{ .Req2XX -}}
+,"req3xx":{{ .Req3XX -}}
+,"req4xx":{{ .Req4XX -}}
+,"req5xx":{{ .Req5XX -}}
+,"others":{{ .Others }}}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Schemes -}}
+,"schemes":[
+{{- range $index, $scheme :=  . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"scheme":{{ .Scheme | printf "%q" }},"req1xx":{{ .Req1XX -}}
+,"req2xx":{{ .Req2XX -}}
+,"req3xx":{{ .Req3XX -}}
+,"req4xx":{{ .Req4XX -}}
+,"req5xx":{{ .Req5XX -}}
+,"others":{{ .Others }}
+{{- with .LatenciesStats SummaryPercentiles -}}
+,"latency":{"mean":{{ .Mean }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Methods -}}
+,"methods":[
+{{- range $index, $method :=  . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"method":{{ .Method | printf "%q" }},"req1xx":{{ .Req1XX -}}
+,"req2xx":{{ .Req2XX -}}
+,"req3xx":{{ .Req3XX -}}
+,"req4xx":{{ .Req4XX -}}
+,"req5xx":{{ .Req5XX -}}
+,"others":{{ .Others }}}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .TLS -}}
+,"tls":{"version":{{ .Version | printf "%q" -}}
+,"cipherSuite":{{ .CipherSuite | printf "%q" -}}
+,"negotiatedProtocol":{{ .NegotiatedProtocol | printf "%q" -}}
+,"verified":{{ .Verified }}}
+{{- end -}}
+
+{{- with .ConnectionCount -}}
+,"connectionCount":{"opened":{{ .Opened }},"configured":{{ .Configured }}}
+{{- end -}}
+
+{{- with .DialRetries -}}
+,"dialRetries":{"retries":{{ .Retries }},"failed":{{ .Failed }}}
+{{- end -}}
+
+{{- with .HTTPSUpgrade -}}
+,"httpsUpgrade":{"upgraded":{{ .Upgraded -}}
+{{- with .LatenciesStats nil -}}
+,"redirectLatency":{"mean":{{ .Mean }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+
+{{- with .Pipeline -}}
+,"pipelineStats":{"requests":{{ .Requests -}}
+,"connections":{{ .Connections -}}
+,"requestsPerConnection":{{ .RequestsPerConnection -}}
+,"depths":[
+{{- range $i, $d := .Depths -}}
+{{- if ne $i 0 -}},{{- end -}}
+{"depth":{{ .Depth }},"share":{{ .Share }}}
+{{- end -}}
+]}
+{{- end -}}
+
+{{- with .Goodput -}}
+,"goodput":{"bodyBytes":{{ .BodyBytes -}}
+,"bytesPerSecond":{{ .Goodput -}}
+,"share":{{ .Share }}}
+{{- end -}}
+
+{{- with .FirstError -}}
+,"firstError":{"request":{{ .Request }},"error":{{ .Error | printf "%q" }}}
+{{- end -}}
+
+{{- with .StatsReset -}}
+,"statsReset":{"reached":{{ .Reached -}}
+,"firstSuccessSeconds":{{ .FirstSuccess.Seconds -}}
+,"discarded":{{ .Discarded }}}
+{{- end -}}
+
+{{- with .Slowloris -}}
+,"slowloris":{"opened":{{ .Opened -}}
+,"refused":{{ .Refused -}}
+,"closedByServer":{{ .ClosedByServer -}}
+,"maxHeld":{{ .MaxHeld -}}
+,"heldAtFirstRefusal":{{ .HeldAtFirstRefusal }}}
+{{- end -}}
+
+{{- with .PacedUploads -}}
+,"pacedUploads":{"completed":{{ .Completed -}}
+,"interrupted":{{ .Interrupted }}}
+{{- end -}}
+
+{{- with .BodyFileErrors -}}
+,"bodyFileErrors":[
+{{- range $index, $file :=  . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"file":{{ .File | printf "%q" }},"errors":{{ .Errors }}}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .BodyCommand -}}
+,"bodyCommand":{"generated":{{ .Generated -}}
+,"failed":{{ .Failed -}}
+,"waits":{{ .Waits }}}
+{{- end -}}
+
+{{- with .ConnectionsAuto -}}
+,"connectionsAuto":{"connections":{{ .Connections -}}
+,"rps":{{ .RequestsPerSecond -}}
+,"meanLatency":{{ .MeanLatency -}}
+,"settled":{{ .Settled }}}
+{{- end -}}
+
+{{- with .MaxRPS -}}
+,"maxRps":{"rate":{{ .Rate -}}
+,"rps":{{ .RequestsPerSecond -}}
+,"p99Latency":{{ .P99Latency -}}
+,"errorRate":{{ .ErrorRate -}}
+,"settled":{{ .Settled }}}
+{{- end -}}
+
+{{- with .TargetP99 -}}
+,"targetP99":{"target":{{ .Target -}}
+,"rate":{{ .Rate -}}
+,"p99Latency":{{ .P99Latency -}}
+,"settled":{{ .Settled }}}
+{{- end -}}
+
+{{- with .Steps -}}
+,"steps":[
+{{- range $index, $step :=  . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" }},"requests":{{ .Requests -}}
+,"errors":{{ .Errors -}}
+,"passRate":{{ .PassRate }}
+{{- with .Timeouts -}}
+,"timeouts":{{ . }}
+{{- end -}}
+{{- with .LatenciesStats SummaryPercentiles -}}
+,"latency":{"mean":{{ .Mean }},"max":{{ .Max }}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
 {{- with .Errors -}}
 ,"errors":[
 {{- range $index, $error :=  . -}}
@@ -138,31 +518,92 @@ const (
 ]
 {{- end -}}
 
-{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+{{- with .LatenciesStats SummaryPercentiles -}}
 ,"latency":{"mean":{{ .Mean -}}
 ,"stddev":{{ .Stddev -}}
 ,"max":{{ .Max -}}
 
 {{- if WithLatencies -}}
 ,"percentiles":{
-{{- range $pc, $lat := .Percentiles }}
-{{- if ne $pc 0.5 -}},{{- end -}}
-{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- $stats := . -}}
+{{- range $i, $pc := SummaryPercentiles }}
+{{- if ne $i 0 -}},{{- end -}}
+{{- printf "\"%.6g\":%d" (Multiply $pc 100) (index $stats.Percentiles $pc) -}}
+{{- end -}}
+}
+{{- end -}}
+
+}
+{{- end -}}
+
+{{- with .LatenciesByCode -}}
+,"latencyByCode":{
+{{- range $index, $class := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{- .Class | printf "%q" -}}:
+{{- with .LatenciesStats SummaryPercentiles -}}
+{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+,"percentiles":{
+{{- $stats := . -}}
+{{- range $i, $pc := SummaryPercentiles }}
+{{- if ne $i 0 -}},{{- end -}}
+{{- printf "\"%.6g\":%d" (Multiply $pc 100) (index $stats.Percentiles $pc) -}}
+{{- end -}}
+}}
+{{- end -}}
 {{- end -}}
 }
 {{- end -}}
 
+{{- if WithWriteRead -}}
+{{- with .ConnectLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"connectLatency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
 }
 {{- end -}}
+{{- with .WriteLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"writeLatency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+}
+{{- end -}}
+{{- with .ReadLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"readLatency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+}
+{{- end -}}
+{{- end -}}
+
+{{- if WithDNS -}}
+{{- with .DNSLatenciesStats (FloatsToArray 0.5 0.99) -}}
+,"dnsLatency":{"lookups":{{ $.Result.DNSLookups -}}
+,"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+,"percentiles":{"50":{{ index .Percentiles 0.5 }},"99":{{ index .Percentiles 0.99 }}}}
+{{- end -}}
+{{- end -}}
+
+{{- with .QueueTimesStats (FloatsToArray 0.5 0.9 0.99) -}}
+,"queueTime":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+,"percentiles":{"50":{{ index .Percentiles 0.5 }},"90":{{ index .Percentiles 0.9 }},"99":{{ index .Percentiles 0.99 }}}}
+{{- end -}}
 
-{{- with .RequestsStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+{{- with .RequestsStats SummaryPercentiles -}}
 ,"rps":{"mean":{{ .Mean -}}
 ,"stddev":{{ .Stddev -}}
 ,"max":{{ .Max -}}
 ,"percentiles":{
-{{- range $pc, $rps := .Percentiles }}
-{{- if ne $pc 0.5 -}},{{- end -}}
-{{- printf "\"%2.0f\":%f" (Multiply $pc 100) $rps -}}
+{{- $stats := . -}}
+{{- range $i, $pc := SummaryPercentiles }}
+{{- if ne $i 0 -}},{{- end -}}
+{{- printf "\"%.6g\":%f" (Multiply $pc 100) (index $stats.Percentiles $pc) -}}
 {{- end -}}
 }}
 {{- end -}}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+// tlsInfo keeps the state of the first TLS connection made by any of
+// the clients. It's recorded from tls.Config.VerifyConnection, which,
+// unlike httptrace, works with fasthttp as well.
+type tlsInfo struct {
+	once  sync.Once
+	state atomic.Value
+}
+
+func (t *tlsInfo) record(cs tls.ConnectionState) error {
+	t.once.Do(func() {
+		t.state.Store(cs)
+	})
+	return nil
+}
+
+func (t *tlsInfo) result() *internal.TLSResult {
+	cs, ok := t.state.Load().(tls.ConnectionState)
+	if !ok {
+		return nil
+	}
+	return &internal.TLSResult{
+		Version:            tlsVersionName(cs.Version),
+		CipherSuite:        tls.CipherSuiteName(cs.CipherSuite),
+		NegotiatedProtocol: cs.NegotiatedProtocol,
+		Verified:           len(cs.VerifiedChains) > 0,
+	}
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTLSVersionName(t *testing.T) {
+	expectations := map[uint16]string{
+		tls.VersionTLS10: "TLS 1.0",
+		tls.VersionTLS12: "TLS 1.2",
+		tls.VersionTLS13: "TLS 1.3",
+		0x0300:           "0x0300",
+	}
+	for version, expected := range expectations {
+		if name := tlsVersionName(version); name != expected {
+			t.Errorf("Expected %q for %x, but got %q", expected, version, name)
+		}
+	}
+}
+
+func TestTLSInfoKeepsFirstConnection(t *testing.T) {
+	info := new(tlsInfo)
+	if info.result() != nil {
+		t.Error("There should be no result before any connection")
+	}
+	_ = info.record(tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		NegotiatedProtocol: "h2",
+		VerifiedChains:     [][]*x509.Certificate{{new(x509.Certificate)}},
+	})
+	_ = info.record(tls.ConnectionState{Version: tls.VersionTLS12})
+	res := info.result()
+	if res == nil || res.Version != "TLS 1.3" ||
+		res.CipherSuite != "TLS_AES_128_GCM_SHA256" ||
+		res.NegotiatedProtocol != "h2" || !res.Verified {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestBombardierPrintTLS(t *testing.T) {
+	testAllClients(t, testBombardierPrintTLS)
+}
+
+func testBombardierPrintTLS(clientType clientTyp, t *testing.T) {
+	s := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	s.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	s.StartTLS()
+	defer s.Close()
+	numReqs := uint64(2)
+	b, e := newBombardier(config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		format:     knownFormat("plain-text"),
+		clientType: clientType,
+		insecure:   true,
+		printTLS:   true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b.disableOutput()
+	b.bombard()
+	res := b.gatherInfo().Result.TLS
+	if res == nil || res.Version != "TLS 1.2" || res.CipherSuite == "" ||
+		res.Verified {
+		t.Fatalf("Unexpected TLS result: %+v (errors: %v)",
+			res, b.errors.byFrequency())
+	}
+	var out bytes.Buffer
+	b.out = &out
+	b.printStats()
+	for _, expected := range []string{
+		"TLS: TLS 1.2, " + res.CipherSuite,
+		"certificate not verified",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in output:\n%v", expected, out.String())
+		}
+	}
+}
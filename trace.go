@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// tracer writes requests and responses of the first --trace-first
+// requests, headers and bodies up to traceMaxBodySize bytes, to
+// --trace-file. Exchanges are written as they complete, so they may be
+// out of order.
+type tracer struct {
+	limit uint64
+	seq   uint64
+
+	mu     sync.Mutex
+	file   *os.File
+	out    *bufio.Writer
+	closed bool
+	err    error
+}
+
+// tracedExchange is a request and its response or error, heads are as
+// sent and received, bodies are cut at traceMaxBodySize.
+type tracedExchange struct {
+	n uint64
+
+	request, requestBody   []byte
+	requestBodySize        int64
+	response, responseBody []byte
+	responseBodySize       int64
+	// streamed is set if the request body was streamed and not captured
+	streamed bool
+	err      error
+}
+
+func newTracer(path string, limit uint64) (*tracer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tracer{limit: limit, file: f, out: bufio.NewWriter(f)}, nil
+}
+
+// next returns the number of the request to trace, zero if the first
+// --trace-first requests were already traced.
+func (t *tracer) next() uint64 {
+	if atomic.LoadUint64(&t.seq) >= t.limit {
+		return 0
+	}
+	n := atomic.AddUint64(&t.seq, 1)
+	if n > t.limit {
+		return 0
+	}
+	return n
+}
+
+func (t *tracer) write(e *tracedExchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed || t.err != nil {
+		return
+	}
+	fmt.Fprintf(t.out, "=== Request %v\n", e.n)
+	t.out.Write(e.request)
+	if e.streamed {
+		t.out.WriteString("(streamed body not captured)\n")
+	} else {
+		t.writeBody(e.requestBody, e.requestBodySize)
+	}
+	if e.err != nil {
+		fmt.Fprintf(t.out, "--- Error %v\n%v\n\n", e.n, e.err)
+	} else {
+		fmt.Fprintf(t.out, "--- Response %v\n", e.n)
+		t.out.Write(e.response)
+		t.writeBody(e.responseBody, e.responseBodySize)
+	}
+	t.err = t.out.Flush()
+}
+
+func (t *tracer) writeBody(body []byte, size int64) {
+	t.out.Write(body)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		t.out.WriteByte('\n')
+	}
+	if left := size - int64(len(body)); left > 0 {
+		fmt.Fprintf(t.out, "(%v more bytes)\n", left)
+	}
+	t.out.WriteByte('\n')
+}
+
+// close closes the file, exchanges completed afterwards aren't
+// written.
+func (t *tracer) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	err := t.file.Close()
+	if t.err != nil {
+		return t.err
+	}
+	return err
+}
+
+// truncatedBody returns at most traceMaxBodySize bytes of body.
+func truncatedBody(body []byte) []byte {
+	if len(body) > traceMaxBodySize {
+		body = body[:traceMaxBodySize]
+	}
+	return append([]byte(nil), body...)
+}
+
+// traceFastHTTP traces an exchange performed by fasthttp, it must be
+// called before req and resp are released.
+func (t *tracer) traceFastHTTP(
+	n uint64, req *fasthttp.Request, resp *fasthttp.Response,
+	streamed bool, err error,
+) {
+	e := &tracedExchange{
+		n:        n,
+		request:  append([]byte(nil), req.Header.Header()...),
+		streamed: streamed,
+		err:      err,
+	}
+	if !streamed {
+		body := req.Body()
+		e.requestBody = truncatedBody(body)
+		e.requestBodySize = int64(len(body))
+	}
+	if err == nil {
+		e.response = append([]byte(nil), resp.Header.Header()...)
+		body := resp.Body()
+		e.responseBody = truncatedBody(body)
+		e.responseBodySize = int64(len(body))
+	}
+	t.write(e)
+}
+
+// tracingTransport traces exchanges of net/http clients, responses
+// are written once their bodies are closed.
+type tracingTransport struct {
+	http.RoundTripper
+	tracer *tracer
+}
+
+func (t *tracingTransport) RoundTrip(
+	req *http.Request,
+) (*http.Response, error) {
+	n := t.tracer.next()
+	if n == 0 {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	e := &tracedExchange{n: n}
+	head, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return nil, err
+	}
+	e.request = head
+	if req.Body != nil {
+		req = req.Clone(req.Context())
+		req.Body = &tracedBody{
+			ReadCloser: req.Body,
+			buf:        new(bytes.Buffer),
+			done: func(b *tracedBody) {
+				// the response may be written concurrently
+				t.tracer.mu.Lock()
+				e.requestBody, e.requestBodySize = b.buf.Bytes(), b.size
+				t.tracer.mu.Unlock()
+			},
+		}
+	}
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		e.err = err
+		t.tracer.write(e)
+		return nil, err
+	}
+	e.response, err = httputil.DumpResponse(resp, false)
+	if err != nil {
+		e.err = err
+		t.tracer.write(e)
+		return resp, nil
+	}
+	resp.Body = &tracedBody{
+		ReadCloser: resp.Body,
+		buf:        new(bytes.Buffer),
+		done: func(b *tracedBody) {
+			e.responseBody, e.responseBodySize = b.buf.Bytes(), b.size
+			t.tracer.write(e)
+		},
+	}
+	return resp, nil
+}
+
+// tracedBody keeps the first traceMaxBodySize bytes read from the
+// body and counts the rest, done is called once it's closed.
+type tracedBody struct {
+	io.ReadCloser
+	buf  *bytes.Buffer
+	size int64
+	done func(*tracedBody)
+	once sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if keep := traceMaxBodySize - b.buf.Len(); keep > 0 {
+		if keep > n {
+			keep = n
+		}
+		b.buf.Write(p[:keep])
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b) })
+	return err
+}
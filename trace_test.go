@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracerNext(t *testing.T) {
+	tr := &tracer{limit: 2}
+	for i, expected := range []uint64{1, 2, 0, 0} {
+		if n := tr.next(); n != expected {
+			t.Errorf("Expected request %v to be traced as %v, but got %v",
+				i, expected, n)
+		}
+	}
+}
+
+func TestBombardierTrace(t *testing.T) {
+	testAllClients(t, testBombardierTrace)
+}
+
+func testBombardierTrace(clientType clientTyp, t *testing.T) {
+	response := strings.Repeat("r", traceMaxBodySize+10)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("X-Traced", "yes")
+			_, _ = rw.Write([]byte(response))
+		}),
+	)
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "bombardier-trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trace.txt")
+	numReqs := uint64(5)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    &headersList{{"X-Request", "traced"}},
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "request body",
+		traceFirst: 2,
+		traceFile:  path,
+		clientType: clientType,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if err := b.tracer.close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.req2xx != numReqs {
+		t.Fatalf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := string(data)
+	for _, expected := range []string{
+		"=== Request 1\nPOST / HTTP/1.1\r\n",
+		"=== Request 2\n",
+		"--- Response 1\nHTTP/1.1 200 OK\r\n",
+		"--- Response 2\n",
+		"\r\n\r\nrequest body\n",
+		"\n(10 more bytes)\n",
+	} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("Expected trace to contain %q, but got %q",
+				expected, trace)
+		}
+	}
+	lower := strings.ToLower(trace)
+	if strings.Count(lower, "x-request: traced") != 2 ||
+		strings.Count(lower, "x-traced: yes") != 2 {
+		t.Errorf("Expected headers of two requests, but got %q", trace)
+	}
+	if strings.Contains(trace, "Request 3") {
+		t.Errorf("Expected only 2 requests to be traced, but got %q", trace)
+	}
+}
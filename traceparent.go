@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// traceContext provides W3C Trace Context headers with --traceparent,
+// each request gets traceparent with its own trace-id and, unless
+// --traceparent-span-id is set, parent-id. Ids are drawn from a source
+// seeded with traceparentSeed, so that runs send the same sequence of
+// them.
+type traceContext struct {
+	spanID string
+	state  string
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newTraceContext(spanID, state string) *traceContext {
+	return &traceContext{
+		spanID: spanID,
+		state:  state,
+		rnd:    rand.New(rand.NewSource(traceparentSeed)),
+	}
+}
+
+// set calls set with traceparent and, if --tracestate is set,
+// tracestate of a new request.
+func (t *traceContext) set(set func(name, value string)) {
+	var ids [16 + 8]byte
+	t.mu.Lock()
+	for {
+		_, _ = t.rnd.Read(ids[:])
+		// all-zero ids are invalid
+		if nonZero(ids[:16]) && nonZero(ids[16:]) {
+			break
+		}
+	}
+	t.mu.Unlock()
+	spanID := t.spanID
+	if spanID == "" {
+		spanID = hex.EncodeToString(ids[16:])
+	}
+	set("traceparent", "00-"+hex.EncodeToString(ids[:16])+"-"+spanID+"-01")
+	if t.state != "" {
+		set("tracestate", t.state)
+	}
+}
+
+func nonZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validSpanID reports whether id can be used as parent-id of
+// traceparent, 16 lowercase hex digits, not all zeros.
+func validSpanID(id string) bool {
+	if len(id) != 16 || strings.Trim(id, "0") == "" {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if !(id[i] >= '0' && id[i] <= '9' || id[i] >= 'a' && id[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
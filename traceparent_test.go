@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+var traceparentRegexp = regexp.MustCompile(
+	"^00-[0-9a-f]{32}-[0-9a-f]{16}-01$")
+
+func TestTraceContextHeaders(t *testing.T) {
+	tc := newTraceContext("", "")
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		tc.set(func(name, value string) {
+			if name != "traceparent" {
+				t.Errorf("Unexpected header %q", name)
+			}
+			if !traceparentRegexp.MatchString(value) {
+				t.Errorf("Invalid traceparent: %q", value)
+			}
+			if seen[value[3:35]] {
+				t.Errorf("Trace-id of %q was sent before", value)
+			}
+			seen[value[3:35]] = true
+		})
+	}
+
+	tc = newTraceContext("00f067aa0ba902b7", "vendor=value")
+	headers := make(map[string]string)
+	tc.set(func(name, value string) {
+		headers[name] = value
+	})
+	if len(headers) != 2 || headers["tracestate"] != "vendor=value" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+	if tp := headers["traceparent"]; !traceparentRegexp.MatchString(tp) ||
+		tp[36:52] != "00f067aa0ba902b7" {
+		t.Errorf("Expected the fixed parent-id, but got %q", tp)
+	}
+}
+
+func TestValidSpanID(t *testing.T) {
+	expectations := []struct {
+		in  string
+		out bool
+	}{
+		{"00f067aa0ba902b7", true},
+		{"0000000000000001", true},
+		{"0000000000000000", false},
+		{"00F067AA0BA902B7", false},
+		{"00f067aa0ba902b", false},
+		{"00f067aa0ba902b7a", false},
+		{"00f067aa0ba902bz", false},
+		{"", false},
+	}
+	for _, e := range expectations {
+		if out := validSpanID(e.in); out != e.out {
+			t.Errorf("Expected %v for %q, but got %v", e.out, e.in, out)
+		}
+	}
+}
+
+func TestBombardierTraceparent(t *testing.T) {
+	testAllClients(t, testBombardierTraceparent)
+}
+
+func testBombardierTraceparent(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			tp := r.Header.Get("Traceparent")
+			if !traceparentRegexp.MatchString(tp) {
+				t.Errorf("Invalid traceparent: %q", tp)
+			}
+			if ts := r.Header.Get("Tracestate"); ts != "a=1" {
+				t.Errorf("Expected tracestate a=1, but got %q", ts)
+			}
+			mu.Lock()
+			seen[tp] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:    2,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		clientType:  clientType,
+		format:      knownFormat("plain-text"),
+		traceparent: true,
+		tracestate:  "a=1",
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if len(seen) != int(numReqs) {
+		t.Errorf("Expected %v distinct traceparents, but got %v",
+			numReqs, len(seen))
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/codesenberg/bombardier/internal"
+)
+
+const (
+	dashboardRefreshRate = 1 * time.Second
+	sparklineWidth       = 60
+	dashboardMaxErrors   = 5
+
+	clearScreen = "\033[H\033[2J"
+)
+
+var sparklineTicks = []rune("▁▂▃▄▅▆▇█")
+
+// dashboard is a live view of the test, shown instead of the progress
+// bar with --tui. It is redrawn every dashboardRefreshRate.
+type dashboard struct {
+	out io.Writer
+
+	// requests and errors since the last sample, fed by rateMeter
+	reqs, errs uint64
+	lastFrame  time.Time
+	// requests per second for the last sparklineWidth samples
+	history []float64
+	// percents of requests that failed in the same samples
+	errorRates []float64
+}
+
+func newDashboard(out io.Writer) *dashboard {
+	return &dashboard{
+		out:        out,
+		lastFrame:  time.Now(),
+		history:    make([]float64, 0, sparklineWidth),
+		errorRates: make([]float64, 0, sparklineWidth),
+	}
+}
+
+func (d *dashboard) addRequests(reqs, errs uint64) {
+	atomic.AddUint64(&d.reqs, reqs)
+	atomic.AddUint64(&d.errs, errs)
+}
+
+func (d *dashboard) sample() {
+	now := time.Now()
+	reqs := atomic.SwapUint64(&d.reqs, 0)
+	errs := atomic.SwapUint64(&d.errs, 0)
+	rps := float64(reqs) / now.Sub(d.lastFrame).Seconds()
+	errorRate := 0.0
+	if reqs > 0 {
+		errorRate = float64(errs) / float64(reqs) * 100
+	}
+	d.lastFrame = now
+	d.history = appendSample(d.history, rps)
+	d.errorRates = appendSample(d.errorRates, errorRate)
+}
+
+// appendSample appends v to samples, dropping the oldest one to keep
+// at most sparklineWidth of them.
+func appendSample(samples []float64, v float64) []float64 {
+	if len(samples) == sparklineWidth {
+		copy(samples, samples[1:])
+		samples = samples[:sparklineWidth-1]
+	}
+	return append(samples, v)
+}
+
+func sparkline(values []float64) string {
+	max := 0.0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	res := make([]rune, len(values))
+	for i, v := range values {
+		tick := 0
+		if max > 0 {
+			tick = int(v / max * float64(len(sparklineTicks)-1))
+		}
+		res[i] = sparklineTicks[tick]
+	}
+	return string(res)
+}
+
+// isTerminal reports whether f is (likely) attached to a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (b *bombardier) renderDashboard() {
+	d := b.dashboard
+	frame := new(bytes.Buffer)
+	frame.WriteString(clearScreen)
+	fmt.Fprintf(frame, "Bombarding %v (%.0f%% done)\n",
+		b.conf.displayURL(), b.barrier.completed()*100)
+
+	current, currentErrorRate := 0.0, 0.0
+	if len(d.history) > 0 {
+		current = d.history[len(d.history)-1]
+		currentErrorRate = d.errorRates[len(d.errorRates)-1]
+	}
+	fmt.Fprintf(frame, "  %-10v %10.2f %v\n",
+		"Reqs/sec", current, sparkline(d.history))
+	fmt.Fprintf(frame, "  %-10v %9.2f%% %v\n",
+		"Errors", currentErrorRate, sparkline(d.errorRates))
+
+	percentiles := []float64{0.5, 0.9, 0.99}
+	results := internal.Results{Latencies: b.sortedLatencies}
+	if lats := results.LatenciesStats(percentiles); lats != nil {
+		fmt.Fprintf(frame, "  %-10v", "Latency")
+		for _, pc := range percentiles {
+			fmt.Fprintf(frame, "  p%.0f %v", pc*100,
+				b.conf.formatLatency(float64(lats.Percentiles[pc])))
+		}
+		frame.WriteString("\n")
+	}
+
+	fmt.Fprintf(frame, "  HTTP codes:\n"+
+		"    1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v\n"+
+		"    others - %v\n",
+		atomic.LoadUint64(&b.req1xx), atomic.LoadUint64(&b.req2xx),
+		atomic.LoadUint64(&b.req3xx), atomic.LoadUint64(&b.req4xx),
+		atomic.LoadUint64(&b.req5xx), atomic.LoadUint64(&b.others))
+
+	if errs := b.errors.byFrequency(); len(errs) > 0 {
+		frame.WriteString("  Errors:\n")
+		for i, e := range errs {
+			if i == dashboardMaxErrors {
+				fmt.Fprintf(frame, "    and %v more\n", len(errs)-i)
+				break
+			}
+			fmt.Fprintf(frame, "    %10v - %v\n", e.count, e.error)
+		}
+	}
+	_, _ = d.out.Write(frame.Bytes())
+}
+
+func (b *bombardier) dashboardUpdater() {
+	ticker := time.NewTicker(dashboardRefreshRate)
+	defer ticker.Stop()
+	done := b.barrier.done()
+	b.renderDashboard()
+	for {
+		select {
+		case <-ticker.C:
+			b.dashboard.sample()
+			b.renderDashboard()
+		case <-done:
+			b.wg.Wait()
+			// the last interval is incomplete, so it isn't sampled
+			b.renderDashboard()
+			fmt.Fprintln(b.out, b.completionMessage())
+			b.doneChan <- struct{}{}
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	expectations := []struct {
+		in  []float64
+		out string
+	}{
+		{nil, ""},
+		{[]float64{0, 0}, "▁▁"},
+		{[]float64{0, 50, 100}, "▁▄█"},
+	}
+	for _, e := range expectations {
+		if s := sparkline(e.in); s != e.out {
+			t.Errorf("Expected %q for %v, but got %q", e.out, e.in, s)
+		}
+	}
+}
+
+func TestDashboardHistoryIsBounded(t *testing.T) {
+	d := newDashboard(new(bytes.Buffer))
+	for i := 0; i < sparklineWidth*2; i++ {
+		d.addRequests(uint64(i), 0)
+		d.sample()
+	}
+	if len(d.history) != sparklineWidth ||
+		len(d.errorRates) != sparklineWidth {
+		t.Errorf("Expected %v samples, but got %v and %v error rates",
+			sparklineWidth, len(d.history), len(d.errorRates))
+	}
+}
+
+func TestDashboardErrorRate(t *testing.T) {
+	d := newDashboard(new(bytes.Buffer))
+	d.addRequests(40, 10)
+	d.sample()
+	// no requests in the interval
+	d.sample()
+	d.addRequests(10, 0)
+	d.sample()
+	expected := []float64{25, 0, 0}
+	if !reflect.DeepEqual(d.errorRates, expected) {
+		t.Errorf("Expected error rates %v, but got %v",
+			expected, d.errorRates)
+	}
+}
+
+func TestBombardierDashboardErrorRate(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:      defaultNumberOfConns,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		format:        knownFormat("plain-text"),
+		printProgress: true,
+		tui:           true,
+		expectStatus:  &statusRanges{{201, 201}},
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.dashboard = newDashboard(new(bytes.Buffer))
+	b.bombard()
+	// the last interval is fed once the test is done
+	b.dashboard.sample()
+	// all requests fail, so do intervals with any of them
+	failed := false
+	for _, rate := range b.dashboard.errorRates {
+		failed = failed || rate == 100
+	}
+	if !failed {
+		t.Errorf("Expected intervals with all requests failed, but got %v",
+			b.dashboard.errorRates)
+	}
+}
+
+func TestBombardierDashboard(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}),
+	)
+	defer s.Close()
+	numReqs := uint64(20)
+	b, e := newBombardier(config{
+		numConns:      defaultNumberOfConns,
+		numReqs:       &numReqs,
+		url:           s.URL,
+		headers:       new(headersList),
+		timeout:       defaultTimeout,
+		method:        "GET",
+		format:        knownFormat("plain-text"),
+		printProgress: true,
+		tui:           true,
+	})
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	out := new(bytes.Buffer)
+	b.out = out
+	// tests aren't run in a terminal, so force the dashboard
+	b.dashboard = newDashboard(out)
+	b.bombard()
+	for _, expected := range []string{
+		"Reqs/sec", "Latency", "2xx - 20", "Completed successfully",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+}
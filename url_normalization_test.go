@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// unnormalizedPath is what fasthttp would send as "/a/c/d/?q=1" if
+// it was allowed to normalize the path
+const unnormalizedPath = "/a//b/./../c%2Fd/?q=1"
+
+func TestBombardierSendsPathAsIs(t *testing.T) {
+	testAllClients(t, func(clientType clientTyp, t *testing.T) {
+		testBombardierSendsPathAsIs(t, clientType, 0, new(headersList))
+	})
+}
+
+func TestBombardierSendsPathAsIsPipelined(t *testing.T) {
+	testBombardierSendsPathAsIs(t, fhttp, 2, new(headersList))
+}
+
+func TestBombardierSendsPathAsIsWithHost(t *testing.T) {
+	headers := &headersList{{"Host", "example.com"}}
+	testAllClients(t, func(clientType clientTyp, t *testing.T) {
+		testBombardierSendsPathAsIs(t, clientType, 0, headers)
+	})
+}
+
+func testBombardierSendsPathAsIs(
+	t *testing.T, clientType clientTyp, pipeline uint64,
+	headers *headersList,
+) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen[r.RequestURI] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+	numReqs := uint64(2)
+	b, e := newBombardier(config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        s.URL + unnormalizedPath,
+		headers:    headers,
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: clientType,
+		pipeline:   pipeline,
+		format:     knownFormat("plain-text"),
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.req2xx != numReqs {
+		t.Errorf("Expected %v 2xx, but got %v (errors: %v)",
+			numReqs, b.req2xx, b.errors.byFrequency())
+	}
+	if len(seen) != 1 || !seen[unnormalizedPath] {
+		t.Errorf("Expected only %q to be received, but got %v",
+			unnormalizedPath, seen)
+	}
+}